@@ -0,0 +1,332 @@
+// Package alert turns collector events into alerts that fire and resolve.
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// KindDown is the alert kind for unreachable nodes. Threshold alerts use
+// the metric name as their kind, condition alerts the condition name.
+const KindDown = "down"
+
+// historySize is how many alert transitions the engine keeps.
+const historySize = 100
+
+// ErrNotFiring is returned for acknowledgements of alerts that aren't
+// firing, e.g. because they resolved in the meantime.
+var ErrNotFiring = errors.New("alert is not firing")
+
+type Alert struct {
+	Key     string
+	Node    config.Node
+	Kind    string
+	Message string
+	Firing  bool
+	// Since is when the alert started firing, Time when it last changed.
+	Since time.Time
+	Time  time.Time
+	// AckedBy is who acknowledged the firing alert, empty until someone
+	// did.
+	AckedBy string
+	AckedAt time.Time
+}
+
+// Silence keeps a node's alerts from firing until it ends, like
+// maintenance but without editing the config. Alerts already firing keep
+// firing until they resolve, alerts that start during the silence fire
+// when it ends if they haven't resolved by then.
+type Silence struct {
+	Node  string
+	By    string
+	Until time.Time
+}
+
+// State is what the engine knows: the firing alerts, oldest first, and
+// the silences in effect.
+type State struct {
+	Active   []Alert
+	Silences []Silence
+}
+
+// Notifier is told about every alert that fires or resolves.
+type Notifier interface {
+	Notify(alert Alert)
+}
+
+// Watcher is given the engine's state whenever it changed, including
+// acknowledgements and silences, which notifiers aren't told about.
+type Watcher interface {
+	AlertsChanged(state State)
+}
+
+// Engine keeps track of firing alerts, their acknowledgements and the
+// silenced nodes. It implements collector.Handler.
+type Engine struct {
+	mu       sync.Mutex
+	active   map[string]Alert
+	history  []Alert
+	silences map[string]Silence
+	// muted are the alerts that would be firing but for a silence or
+	// maintenance, by key, and unsilence the timers ending the silences
+	muted     map[string]Alert
+	unsilence map[string]*time.Timer
+	notifiers []Notifier
+	watchers  []Watcher
+	// watching is held while watchers are told, so they get the states
+	// in the order they changed
+	watching sync.Mutex
+}
+
+func NewEngine() *Engine {
+	return &Engine{
+		active:    make(map[string]Alert),
+		silences:  make(map[string]Silence),
+		muted:     make(map[string]Alert),
+		unsilence: make(map[string]*time.Timer),
+	}
+}
+
+// AddNotifier registers a notifier. It should be called before events
+// start flowing.
+func (e *Engine) AddNotifier(notifier Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notifiers = append(e.notifiers, notifier)
+}
+
+// AddWatcher registers a watcher. It should be called before events
+// start flowing.
+func (e *Engine) AddWatcher(watcher Watcher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.watchers = append(e.watchers, watcher)
+}
+
+func (e *Engine) HandleEvent(event collector.Event) {
+	// the node may have left maintenance since its alerts were muted
+	if e.unmute(event.Node.DisplayName(), &event.Node, event.Time) {
+		e.changed()
+	}
+	switch event.Type {
+	case collector.NodeDown:
+		e.transition(event, KindDown, true, transport.Describe(event.Err))
+	case collector.NodeUp:
+		e.transition(event, KindDown, false, "node is up")
+	case collector.MetricThresholdCrossed:
+		e.transition(event, event.Metric, event.Above, event.String())
+	case collector.ConditionChanged:
+		e.transition(event, event.Condition, event.Active, event.String())
+	}
+}
+
+func (e *Engine) transition(event collector.Event, kind string, firing bool, message string) {
+	key := event.Node.DisplayName() + "/" + kind
+
+	alert := Alert{
+		Key:     key,
+		Node:    event.Node,
+		Kind:    kind,
+		Message: message,
+		Firing:  firing,
+		Since:   event.Time,
+		Time:    event.Time,
+	}
+
+	e.mu.Lock()
+	current, active := e.active[key]
+	if !firing {
+		delete(e.muted, key)
+	}
+	if firing && !active && e.mutedNode(event.Node, event.Time) {
+		// the collector only tells about the change, so it is kept for
+		// when the node is unmuted
+		e.muted[key] = alert
+		e.mu.Unlock()
+		return
+	}
+	if firing == active {
+		e.mu.Unlock()
+		return
+	}
+
+	if firing {
+		e.active[key] = alert
+	} else {
+		alert.Since = current.Since
+		delete(e.active, key)
+	}
+	e.history = append(e.history, alert)
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+	}
+	notifiers := e.notifiers
+	e.mu.Unlock()
+
+	for _, notifier := range notifiers {
+		notifier.Notify(alert)
+	}
+	e.changed()
+}
+
+// unmute fires the muted alerts of the named node if it is neither
+// silenced nor in maintenance anymore, and reports whether any fired.
+// node is the node's current config, nil to go by the one its alerts
+// were muted with.
+func (e *Engine) unmute(name string, node *config.Node, now time.Time) bool {
+	var fired []Alert
+	e.mu.Lock()
+	for key, alert := range e.muted {
+		if alert.Node.DisplayName() != name {
+			continue
+		}
+		if node != nil {
+			alert.Node = *node
+			e.muted[key] = alert
+		}
+		if e.mutedNode(alert.Node, now) {
+			continue
+		}
+		delete(e.muted, key)
+		// Since stays when the problem started
+		alert.Time = now
+		e.active[key] = alert
+		fired = append(fired, alert)
+	}
+	sort.Slice(fired, func(i, j int) bool { return fired[i].Since.Before(fired[j].Since) })
+	e.history = append(e.history, fired...)
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+	}
+	notifiers := e.notifiers
+	e.mu.Unlock()
+
+	for _, alert := range fired {
+		for _, notifier := range notifiers {
+			notifier.Notify(alert)
+		}
+	}
+	return len(fired) > 0
+}
+
+// mutedNode reports whether the node's alerts are kept from firing at
+// now, the caller holds the lock.
+func (e *Engine) mutedNode(node config.Node, now time.Time) bool {
+	return node.Maintenance || e.silenced(node.DisplayName(), now)
+}
+
+// silenced reports whether a silence of the node lasts past now, the
+// caller holds the lock.
+func (e *Engine) silenced(node string, now time.Time) bool {
+	silence, ok := e.silences[node]
+	return ok && silence.Until.After(now)
+}
+
+// Acknowledge records that someone is on a firing alert, so whoever
+// else watches the fleet knows. It lasts until the alert resolves.
+func (e *Engine) Acknowledge(key, by string) error {
+	e.mu.Lock()
+	a, ok := e.active[key]
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNotFiring, key)
+	}
+	a.AckedBy, a.AckedAt = by, time.Now()
+	e.active[key] = a
+	e.mu.Unlock()
+	e.changed()
+	return nil
+}
+
+// Silence keeps the node's alerts from firing for d, or lifts its
+// silence if d isn't positive. The alerts that started in the meantime
+// fire when the silence ends.
+func (e *Engine) Silence(node, by string, d time.Duration) error {
+	e.mu.Lock()
+	if timer := e.unsilence[node]; timer != nil {
+		timer.Stop()
+		delete(e.unsilence, node)
+	}
+	if d > 0 {
+		e.silences[node] = Silence{Node: node, By: by, Until: time.Now().Add(d)}
+		e.unsilence[node] = time.AfterFunc(d, func() {
+			e.unmute(node, nil, time.Now())
+			e.changed()
+		})
+	} else {
+		delete(e.silences, node)
+	}
+	e.mu.Unlock()
+	if d <= 0 {
+		e.unmute(node, nil, time.Now())
+	}
+	e.changed()
+	return nil
+}
+
+// State returns the firing alerts and the silences in effect.
+func (e *Engine) State() State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state()
+}
+
+func (e *Engine) state() State {
+	state := State{Active: e.activeLocked()}
+	now := time.Now()
+	for node, silence := range e.silences {
+		if !silence.Until.After(now) {
+			delete(e.silences, node)
+			continue
+		}
+		state.Silences = append(state.Silences, silence)
+	}
+	sort.Slice(state.Silences, func(i, j int) bool {
+		return state.Silences[i].Node < state.Silences[j].Node
+	})
+	return state
+}
+
+// changed tells the watchers the new state.
+func (e *Engine) changed() {
+	e.watching.Lock()
+	defer e.watching.Unlock()
+	e.mu.Lock()
+	state, watchers := e.state(), e.watchers
+	e.mu.Unlock()
+	for _, watcher := range watchers {
+		watcher.AlertsChanged(state)
+	}
+}
+
+// Active returns the currently firing alerts, oldest first.
+func (e *Engine) Active() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.activeLocked()
+}
+
+func (e *Engine) activeLocked() []Alert {
+	alerts := make([]Alert, 0, len(e.active))
+	for _, alert := range e.active {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Since.Before(alerts[j].Since)
+	})
+	return alerts
+}
+
+// History returns the most recent alert transitions, oldest first.
+func (e *Engine) History() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Alert(nil), e.history...)
+}
@@ -0,0 +1,126 @@
+package alert
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// recorder is a notifier keeping what it was told.
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recorder) Notify(alert Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+}
+
+func (r *recorder) keys() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []string
+	for _, a := range r.alerts {
+		state := "resolved"
+		if a.Firing {
+			state = "firing"
+		}
+		keys = append(keys, a.Key+" "+state)
+	}
+	return keys
+}
+
+func condition(node config.Node, active bool, at time.Time) collector.Event {
+	return collector.Event{Type: collector.ConditionChanged, Node: node, Time: at, Condition: "pi.throttled", Active: active, Detail: "is throttled"}
+}
+
+func TestMutedAlerts(t *testing.T) {
+	node := config.Node{Name: "fra-1", IP: "10.0.0.1"}
+	maintenance := node
+	maintenance.Maintenance = true
+	started := time.Now()
+
+	tests := []struct {
+		name string
+		// mute silences or marks the node, returns the node the events
+		// are for
+		mute func(e *Engine) config.Node
+		// unmute ends it, with a last event for the node if needed
+		unmute func(e *Engine)
+		// clear resolves the condition while muted
+		clear bool
+		want  []string
+	}{
+		{"silence lifted", func(e *Engine) config.Node {
+			e.Silence("fra-1", "ops", time.Hour)
+			return node
+		}, func(e *Engine) { e.Silence("fra-1", "ops", 0) }, false,
+			[]string{"fra-1/pi.throttled firing"}},
+		{"silence expired", func(e *Engine) config.Node {
+			e.Silence("fra-1", "ops", 50*time.Millisecond)
+			return node
+		}, func(e *Engine) { time.Sleep(100 * time.Millisecond) }, false,
+			[]string{"fra-1/pi.throttled firing"}},
+		{"maintenance over", func(e *Engine) config.Node { return maintenance },
+			func(e *Engine) { e.HandleEvent(collector.Event{Type: collector.NodeUp, Node: node, Time: time.Now()}) }, false,
+			[]string{"fra-1/pi.throttled firing"}},
+		{"resolved while silenced", func(e *Engine) config.Node {
+			e.Silence("fra-1", "ops", time.Hour)
+			return node
+		}, func(e *Engine) { e.Silence("fra-1", "ops", 0) }, true, nil},
+		{"still in maintenance", func(e *Engine) config.Node { return maintenance },
+			func(e *Engine) { e.Silence("fra-1", "ops", 0) }, false, nil},
+	}
+	for _, tt := range tests {
+		e := NewEngine()
+		notified := &recorder{}
+		e.AddNotifier(notified)
+		muted := tt.mute(e)
+		e.HandleEvent(condition(muted, true, started))
+		if tt.clear {
+			e.HandleEvent(condition(muted, false, started.Add(time.Second)))
+		}
+		if got := notified.keys(); len(got) > 0 {
+			t.Errorf("%s: notified while muted: %v", tt.name, got)
+		}
+
+		tt.unmute(e)
+		// an expiring silence fires its alerts from a timer
+		got := notified.keys()
+		for deadline := time.Now().Add(time.Second); len(got) < len(tt.want) && time.Now().Before(deadline); got = notified.keys() {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("%s: notified %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		if active := e.Active(); len(active) != len(tt.want) {
+			t.Errorf("%s: active %+v", tt.name, active)
+		} else if len(active) > 0 && !active[0].Since.Equal(started) {
+			t.Errorf("%s: alert since %v, want when the condition started, %v", tt.name, active[0].Since, started)
+		}
+	}
+}
+
+func TestUnmutedResolves(t *testing.T) {
+	node := config.Node{Name: "fra-1", IP: "10.0.0.1"}
+	e := NewEngine()
+	notified := &recorder{}
+	e.AddNotifier(notified)
+	e.Silence("fra-1", "ops", time.Hour)
+	e.HandleEvent(condition(node, true, time.Now()))
+	e.Silence("fra-1", "ops", 0)
+	e.HandleEvent(condition(node, false, time.Now()))
+	want := []string{"fra-1/pi.throttled firing", "fra-1/pi.throttled resolved"}
+	if got := notified.keys(); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("notified %v, want %v", got, want)
+	}
+	if active := e.Active(); len(active) != 0 {
+		t.Errorf("active %+v after resolving", active)
+	}
+}
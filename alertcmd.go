@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/export"
+)
+
+// sender delivers an alert off the monitor host and reports whether it
+// did.
+type sender interface {
+	Send(a alert.Alert) error
+}
+
+// runAlert implements `q-monitor alert test [--sink name]`, sending a
+// synthetic alert through every configured alert destination, or just
+// the named one, and reporting which of them took it.
+func runAlert(args []string) error {
+	if len(args) == 0 || args[0] != "test" {
+		return errors.New("usage: q-monitor alert test [-config file] [--sink telegram|syslog]")
+	}
+
+	flags := flag.NewFlagSet("alert test", flag.ExitOnError)
+	configFile := flags.String("config", configFileName, "config `file` with the alert destinations")
+	sink := flags.String("sink", "", "only test the destination with this `name`")
+	flags.Parse(args[1:])
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	sinks, err := alertSinks(cfg)
+	if err != nil {
+		return err
+	}
+	if *sink != "" {
+		s, ok := sinks[*sink]
+		if !ok {
+			return fmt.Errorf("%s is not configured", *sink)
+		}
+		sinks = map[string]sender{*sink: s}
+	}
+	if len(sinks) == 0 {
+		return errors.New("no alert destinations configured, set telegram or syslog")
+	}
+
+	host, _ := os.Hostname()
+	now := time.Now()
+	test := alert.Alert{
+		Key:     "q-monitor/test",
+		Node:    config.Node{Name: "q-monitor"},
+		Kind:    "test",
+		Message: fmt.Sprintf("test alert from q-monitor on %s, alerts reach this destination", host),
+		Firing:  true,
+		Since:   now,
+		Time:    now,
+	}
+	failed := 0
+	for _, name := range []string{"telegram", "syslog"} {
+		s, ok := sinks[name]
+		if !ok {
+			continue
+		}
+		if err := s.Send(test); err != nil {
+			fmt.Printf("%-9s failed: %v\n", name, err)
+			failed++
+		} else {
+			fmt.Printf("%-9s delivered\n", name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d alert destinations failed", failed, len(sinks))
+	}
+	return nil
+}
+
+// alertSinks sets up the configured alert destinations by name.
+func alertSinks(cfg *config.Config) (map[string]sender, error) {
+	sinks := make(map[string]sender)
+	if cfg.Telegram != nil {
+		telegram, err := export.NewTelegram(*cfg.Telegram)
+		if err != nil {
+			return nil, err
+		}
+		sinks["telegram"] = telegram
+	}
+	if cfg.Syslog != nil {
+		syslog, err := export.NewSyslog(*cfg.Syslog)
+		if err != nil {
+			return nil, err
+		}
+		sinks["syslog"] = syslog
+	}
+	return sinks, nil
+}
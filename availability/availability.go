@@ -0,0 +1,209 @@
+// Package availability tracks when nodes were down, from their failed
+// polls, and works out how available they were over a period, e.g. for
+// a dispute with a hosting provider.
+package availability
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// Periods are the periods availability is reported for.
+var Periods = []time.Duration{24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour}
+
+// PeriodName names a period in hours up to a day and in days above, e.g.
+// 24h or 7d.
+func PeriodName(d time.Duration) string {
+	if d > 24*time.Hour {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
+
+// Outage is a period a node's polls failed, from its first failed poll
+// to its next successful one. End is zero while it lasts.
+type Outage struct {
+	Start  time.Time
+	End    time.Time
+	Reason string
+}
+
+// Duration is how long the outage lasted, up to now if it still does.
+func (o Outage) Duration(now time.Time) time.Duration {
+	if o.End.IsZero() {
+		return now.Sub(o.Start)
+	}
+	return o.End.Sub(o.Start)
+}
+
+// record is a line of the log file: a node was seen up or down. Only
+// changes are written, and the first poll of a node after the monitor
+// starts so the time it is tracked from is known.
+type record struct {
+	Node   string    `json:"node"`
+	Time   time.Time `json:"time"`
+	Down   bool      `json:"down"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Tracker keeps the outages of every node by its display name. It
+// implements collector.Sink.
+type Tracker struct {
+	mu      sync.Mutex
+	outages map[string][]Outage
+	// since is when each node was first tracked
+	since map[string]time.Time
+	// seen are the nodes polled since the monitor started
+	seen map[string]bool
+	file *os.File
+}
+
+// Open loads the outages recorded in path, if it exists, and appends to
+// it from then on. An empty path keeps them in memory only.
+//
+// An outage still open when the monitor stopped lasts until the node's
+// first successful poll after the restart.
+func Open(path string) (*Tracker, error) {
+	t := &Tracker{
+		outages: make(map[string][]Outage),
+		since:   make(map[string]time.Time),
+		seen:    make(map[string]bool),
+	}
+	if path == "" {
+		return t, nil
+	}
+	if err := t.load(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open availability log: %w", err)
+	}
+	t.file = file
+	return t, nil
+}
+
+func (t *Tracker) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		t.apply(r)
+	}
+	return scanner.Err()
+}
+
+// apply adds a record to the outages, the caller holds the lock or has
+// the tracker to itself.
+func (t *Tracker) apply(r record) {
+	if _, ok := t.since[r.Node]; !ok {
+		t.since[r.Node] = r.Time
+	}
+	outages := t.outages[r.Node]
+	down := len(outages) > 0 && outages[len(outages)-1].End.IsZero()
+	switch {
+	case r.Down && !down:
+		t.outages[r.Node] = append(outages, Outage{Start: r.Time, Reason: r.Reason})
+	case !r.Down && down:
+		outages[len(outages)-1].End = r.Time
+	}
+}
+
+// Consume records a node going down on its first failed poll and coming
+// back on its next successful one. Polls of nodes in maintenance don't
+// start outages. Records that can't be written are only kept in memory.
+func (t *Tracker) Consume(snapshot collector.Snapshot) {
+	name := snapshot.Node.DisplayName()
+	down := snapshot.Err != nil && !snapshot.Node.Maintenance
+	r := record{Node: name, Time: snapshot.Time, Down: down}
+	if down {
+		r.Reason = transport.Describe(snapshot.Err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	outages := t.outages[name]
+	wasDown := len(outages) > 0 && outages[len(outages)-1].End.IsZero()
+	if t.seen[name] && down == wasDown {
+		return
+	}
+	t.seen[name] = true
+	t.apply(r)
+	if t.file != nil {
+		if line, err := json.Marshal(r); err == nil {
+			t.file.Write(append(line, '\n'))
+		}
+	}
+}
+
+// Nodes returns the names of the tracked nodes, sorted.
+func (t *Tracker) Nodes() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	names := make([]string, 0, len(t.since))
+	for name := range t.since {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Availability returns the share of the period up to now the node was
+// up, from 0 to 1. Only the part of the period the node was tracked
+// counts; ok is false if it wasn't tracked in it at all.
+func (t *Tracker) Availability(node string, period time.Duration, now time.Time) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	since, tracked := t.since[node]
+	from := now.Add(-period)
+	if since.After(from) {
+		from = since
+	}
+	if !tracked || !from.Before(now) {
+		return 0, false
+	}
+	var down time.Duration
+	for _, o := range t.outages[node] {
+		start, end := o.Start, o.End
+		if end.IsZero() || end.After(now) {
+			end = now
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(start) {
+			down += end.Sub(start)
+		}
+	}
+	return 1 - float64(down)/float64(now.Sub(from)), true
+}
+
+// Outages returns the node's outages that lasted into the time after
+// from, oldest first.
+func (t *Tracker) Outages(node string, from time.Time) []Outage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var outages []Outage
+	for _, o := range t.outages[node] {
+		if o.End.IsZero() || o.End.After(from) {
+			outages = append(outages, o)
+		}
+	}
+	return outages
+}
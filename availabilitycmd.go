@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/availability"
+)
+
+// runAvailability implements `q-monitor availability [-node name]`, a
+// report of the nodes' availability from the outage log, with the
+// outages of one node if it is named.
+func runAvailability(args []string) error {
+	flags := flag.NewFlagSet("availability", flag.ExitOnError)
+	file := flags.String("file", availabilityFileName, "outage log `file` the monitor writes")
+	node := flags.String("node", "", "list the outages of the node with this `name`")
+	flags.Parse(args)
+
+	if _, err := os.Stat(*file); err != nil {
+		return fmt.Errorf("no outage log: %w", err)
+	}
+	tracker, err := availability.Open(*file)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	longest := availability.Periods[len(availability.Periods)-1]
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	if *node != "" {
+		fmt.Fprintln(w, "START\tEND\tDURATION\tREASON")
+		for _, o := range tracker.Outages(*node, now.Add(-longest)) {
+			end := "ongoing"
+			if !o.End.IsZero() {
+				end = o.End.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.Start.Local().Format(time.DateTime), end,
+				o.Duration(now).Round(time.Second), o.Reason)
+		}
+		return w.Flush()
+	}
+
+	header := []string{"NODE"}
+	for _, period := range availability.Periods {
+		header = append(header, strings.ToUpper(availability.PeriodName(period)))
+	}
+	fmt.Fprintln(w, strings.Join(append(header, "OUTAGES", "DOWNTIME"), "\t"))
+	for _, name := range tracker.Nodes() {
+		row := []string{name}
+		for _, period := range availability.Periods {
+			value := "-"
+			if share, ok := tracker.Availability(name, period, now); ok {
+				value = fmt.Sprintf("%.3f%%", share*100)
+			}
+			row = append(row, value)
+		}
+		var downtime time.Duration
+		outages := tracker.Outages(name, now.Add(-longest))
+		for _, o := range outages {
+			downtime += o.Duration(now)
+		}
+		row = append(row, fmt.Sprint(len(outages)), downtime.Round(time.Second).String())
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
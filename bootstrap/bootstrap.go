@@ -0,0 +1,132 @@
+// Package bootstrap checks that libp2p bootstrap peers are reachable from
+// the monitor host, to tell a broken node from a bootstrap that is down.
+package bootstrap
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Timeout is how long a peer gets to answer a probe.
+const Timeout = 3 * time.Second
+
+// ErrUnreachable is wrapped by the errors of probes the peer didn't
+// answer.
+var ErrUnreachable = errors.New("no answer")
+
+// Addr is the part of a multiaddr needed to reach the peer.
+type Addr struct {
+	Host string
+	Port string
+	// Transport is tcp, or quic / quic-v1 over udp.
+	Transport string
+	PeerID    string
+}
+
+// Status is the result of a successful probe.
+type Status struct {
+	Addr    Addr
+	Latency time.Duration
+}
+
+// Parse reads a multiaddr like /dns/bootstrap.example/udp/8336/quic-v1/p2p/Qm...
+// Other protocols than the ones needed to reach the peer are ignored.
+func Parse(multiaddr string) (Addr, error) {
+	parts := strings.Split(strings.Trim(multiaddr, "/"), "/")
+	var addr Addr
+	var network string
+	for i := 0; i < len(parts); i++ {
+		protocol := parts[i]
+		value := ""
+		switch protocol {
+		case "ip4", "ip6", "dns", "dns4", "dns6", "tcp", "udp", "p2p", "ipfs":
+			if i+1 >= len(parts) {
+				return Addr{}, fmt.Errorf("multiaddr %q: %s needs a value", multiaddr, protocol)
+			}
+			i++
+			value = parts[i]
+		}
+		switch protocol {
+		case "ip4", "ip6", "dns", "dns4", "dns6":
+			addr.Host = value
+		case "tcp", "udp":
+			network, addr.Port = protocol, value
+		case "quic", "quic-v1":
+			addr.Transport = protocol
+		case "p2p", "ipfs":
+			addr.PeerID = value
+		}
+	}
+
+	switch {
+	case addr.Host == "" || addr.Port == "":
+		return Addr{}, fmt.Errorf("multiaddr %q has no host and port", multiaddr)
+	case network == "tcp":
+		addr.Transport = "tcp"
+	case addr.Transport == "":
+		return Addr{}, fmt.Errorf("multiaddr %q: only tcp and quic peers can be probed", multiaddr)
+	}
+	return addr, nil
+}
+
+// Probe checks that the peer at multiaddr answers and measures the round
+// trip. TCP peers have to accept a connection. QUIC peers are sent an
+// Initial packet with an unsupported version, which they have to answer
+// with a version negotiation, so no handshake is needed.
+func Probe(multiaddr string) (*Status, error) {
+	addr, err := Parse(multiaddr)
+	if err != nil {
+		return nil, err
+	}
+	address := net.JoinHostPort(addr.Host, addr.Port)
+
+	start := time.Now()
+	if addr.Transport == "tcp" {
+		conn, err := net.DialTimeout("tcp", address, Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnreachable, err)
+		}
+		conn.Close()
+		return &Status{Addr: addr, Latency: time.Since(start)}, nil
+	}
+
+	conn, err := net.DialTimeout("udp", address, Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(Timeout))
+	start = time.Now()
+	if _, err := conn.Write(versionProbe()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	response := make([]byte, 1500)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w to the quic probe: %w", ErrUnreachable, err)
+	}
+	latency := time.Since(start)
+	if n < 5 || response[0]&0x80 == 0 || string(response[1:5]) != "\x00\x00\x00\x00" {
+		return nil, errors.New("the peer answered with something that is not a quic version negotiation")
+	}
+	return &Status{Addr: addr, Latency: latency}, nil
+}
+
+// versionProbe is a QUIC long header packet with a reserved version
+// (RFC 9000, section 15), padded to the 1200 bytes servers need before
+// they answer.
+func versionProbe() []byte {
+	packet := make([]byte, 1200)
+	packet[0] = 0xc0
+	copy(packet[1:5], []byte{0x1a, 0x2a, 0x3a, 0x4a})
+	// destination and source connection id, 8 random bytes each
+	packet[5] = 8
+	rand.Read(packet[6:14])
+	packet[14] = 8
+	rand.Read(packet[15:23])
+	return packet
+}
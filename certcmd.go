@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/server"
+)
+
+// certDir is where `q-monitor cert` keeps the CA and the certificates
+// it issues, unless told otherwise.
+const certDir = "certs"
+
+// runCert implements `q-monitor cert init` and `q-monitor cert issue
+// [--host h,...] name`, a small CA for mutual TLS between a monitor
+// server and its TUIs.
+func runCert(args []string) error {
+	usage := errors.New("usage: q-monitor cert init [-dir dir] | q-monitor cert issue [-dir dir] [-host host,...] name")
+	if len(args) == 0 {
+		return usage
+	}
+	flags := flag.NewFlagSet("cert "+args[0], flag.ExitOnError)
+	dir := flags.String("dir", certDir, "`directory` of the CA and certificates")
+	hosts := flags.String("host", "", "comma separated `names` and addresses a server is reached at")
+	flags.Parse(args[1:])
+
+	switch {
+	case args[0] == "init" && flags.NArg() == 0:
+		if err := server.InitCA(*dir); err != nil {
+			return err
+		}
+		fmt.Printf("created the CA in %s, keep ca-key.pem safe and copy ca.pem to every end\n", *dir)
+	case args[0] == "issue" && flags.NArg() == 1:
+		name := flags.Arg(0)
+		var list []string
+		if *hosts != "" {
+			list = strings.Split(*hosts, ",")
+		}
+		if err := server.IssueCert(*dir, name, list); err != nil {
+			return err
+		}
+		fmt.Printf("issued %s and %s\n", filepath.Join(*dir, name+".pem"), filepath.Join(*dir, name+"-key.pem"))
+	default:
+		return usage
+	}
+	return nil
+}
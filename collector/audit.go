@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// rulesetCommand reads the firewall rules, which takes root. sudo -n
+// fails instead of asking for a password.
+const rulesetCommand = "sudo -n nft list ruleset"
+
+// auditTools are the programs of the audit, firewallTools those of its
+// firewall part.
+var (
+	auditTools    = []string{"ss"}
+	firewallTools = []string{"sudo", "nft"}
+)
+
+// Audit is what the last audit of a node found.
+type Audit struct {
+	// Ports are the ports the node listens on other than on loopback,
+	// e.g. 22/tcp, and Unexpected those of them the config doesn't
+	// expect.
+	Ports      []string
+	Unexpected []string
+	// MissingRules are the expected firewall rules the node's ruleset
+	// doesn't have.
+	MissingRules []string
+	Checked      time.Time
+}
+
+// auditNode lists the node's open ports and, if cfg expects rules, its
+// firewall rules, and compares them with the baseline.
+func auditNode(runner transport.Runner, profile profiles.Profile, node config.Node, cfg config.Audit) (*Audit, error) {
+	if profile.ListenersCommand == "" {
+		return nil, fmt.Errorf("the audit is not available on %s nodes", profile.Name)
+	}
+	output, err := runner.Run(profile.ListenersCommand)
+	if err != nil {
+		return nil, err
+	}
+	listeners, err := parsers.ParseListeners(output)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &Audit{Checked: time.Now()}
+	expected := cfg.ExpectedPorts(node)
+	for _, listener := range listeners {
+		port := listener.String()
+		if listener.Loopback() || slices.Contains(audit.Ports, port) {
+			continue
+		}
+		audit.Ports = append(audit.Ports, port)
+		if !slices.Contains(expected, port) && !slices.Contains(expected, strconv.Itoa(listener.Port)) {
+			audit.Unexpected = append(audit.Unexpected, port)
+		}
+	}
+	slices.Sort(audit.Ports)
+	slices.Sort(audit.Unexpected)
+
+	if len(cfg.Rules) == 0 {
+		return audit, nil
+	}
+	ruleset, err := runner.Run(rulesetCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the firewall rules: %w", err)
+	}
+	lines := strings.Split(ruleset, "\n")
+	for _, rule := range cfg.Rules {
+		if !slices.ContainsFunc(lines, func(line string) bool { return strings.Contains(line, rule) }) {
+			audit.MissingRules = append(audit.MissingRules, rule)
+		}
+	}
+	return audit, nil
+}
+
+// auditPrograms returns the programs an audit with cfg runs.
+func auditPrograms(cfg config.Audit) []string {
+	tools := slices.Clone(auditTools)
+	if len(cfg.Rules) > 0 {
+		tools = append(tools, firewallTools...)
+	}
+	return tools
+}
+
+// carryAudit keeps the node's last audit in polls that didn't audit,
+// and its failure until the next audit.
+func (c *Collector) carryAudit(state *nodeState, status *Status, audited bool) {
+	if audited {
+		state.audit, state.auditErr = status.Audit, status.Errors[SectionAudit]
+		return
+	}
+	status.Audit = state.audit
+	if state.auditErr != nil {
+		status.setError(SectionAudit, state.auditErr)
+	}
+}
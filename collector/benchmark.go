@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// speedtestTimeout bounds a speedtest-cli run, which picks its own
+// durations.
+const speedtestTimeout = 2 * time.Minute
+
+// MeasureBandwidth runs the configured benchmark on the i-th node.
+// It takes a connection of its own, so polls go on meanwhile.
+func (c *Collector) MeasureBandwidth(i int) (parsers.Bandwidth, error) {
+	if c.Benchmark == nil {
+		return parsers.Bandwidth{}, errors.New("no benchmark in the config")
+	}
+	if i < 0 || i >= len(c.nodes) {
+		return parsers.Bandwidth{}, fmt.Errorf("no node %d", i)
+	}
+	return Benchmark(c.Dialer, c.nodes[i], *c.Benchmark)
+}
+
+// Benchmark measures a node's bandwidth with the tool cfg picks, which
+// has to be installed on the node.
+func Benchmark(dialer transport.Dialer, node config.Node, cfg config.Benchmark) (parsers.Bandwidth, error) {
+	if node.Bootstrap != "" {
+		return parsers.Bandwidth{}, errors.New("bootstrap peer nodes can't be benchmarked")
+	}
+	if err := cfg.Validate(); err != nil {
+		return parsers.Bandwidth{}, err
+	}
+	timeout := speedtestTimeout
+	if cfg.ToolName() == config.BenchmarkIperf3 {
+		// both directions, and the connects
+		timeout = 2*cfg.Duration() + 30*time.Second
+	}
+
+	raw, err := dialer.Dial(node)
+	if err != nil {
+		return parsers.Bandwidth{}, err
+	}
+	conn := transport.Watch(raw, timeout)
+	defer conn.Close()
+	profile, err := nodeProfile(conn, node, Options{})
+	if err != nil {
+		return parsers.Bandwidth{}, err
+	}
+	if profile.Name == profiles.Windows {
+		return parsers.Bandwidth{}, fmt.Errorf("benchmarks are not available on %s nodes", profile.Name)
+	}
+	shell, err := nodeShell(conn, node, Options{})
+	if err != nil {
+		return parsers.Bandwidth{}, err
+	}
+	runner := transport.WithPrefix(transport.WithShell(conn, shell), node)
+
+	if cfg.ToolName() == config.BenchmarkSpeedtest {
+		cmd := "speedtest-cli --json --secure"
+		if cfg.Server != "" {
+			cmd += " --server " + transport.ShellQuote(cfg.Server)
+		}
+		output, err := runner.Run(cmd)
+		if err != nil {
+			return parsers.Bandwidth{}, err
+		}
+		return parsers.ParseSpeedtest(output)
+	}
+
+	cmd := fmt.Sprintf("iperf3 -J -c %s -t %d", transport.ShellQuote(cfg.Server), int(cfg.Duration().Seconds()))
+	if cfg.Port > 0 {
+		cmd += " -p " + strconv.Itoa(cfg.Port)
+	}
+	// iperf3 sends by default and receives in reverse
+	upload, server, err := iperf3(runner, cmd)
+	if err != nil {
+		return parsers.Bandwidth{}, err
+	}
+	download, _, err := iperf3(runner, cmd+" -R")
+	if err != nil {
+		return parsers.Bandwidth{}, err
+	}
+	return parsers.Bandwidth{Download: download, Upload: upload, Server: server}, nil
+}
+
+// iperf3 runs one direction of an iperf3 benchmark. A failed run says
+// why in its JSON, a missing iperf3 on stderr.
+func iperf3(runner transport.Runner, cmd string) (float64, string, error) {
+	output, err := runner.Run("command -v iperf3 >/dev/null || exit 127; " + cmd + " || true")
+	if err != nil {
+		return 0, "", err
+	}
+	return parsers.ParseIperf3(output)
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// checkExit marks the line a check's exit status is printed on. The
+// check is run so it always succeeds, a failed command's output isn't
+// returned.
+const checkExit = "q-monitor-check-exit "
+
+// CheckResult is the outcome of one of the node's checks, see
+// config.Check.
+type CheckResult struct {
+	Name string
+	// State is SignalOK, SignalWarning or SignalCritical.
+	State string
+	// Output is the first line the check printed, to stdout or stderr,
+	// or why it couldn't be run.
+	Output string
+}
+
+// runChecks runs the node's checks one after the other, each as its own
+// user or the one the node runs checks as.
+func runChecks(runner transport.Runner, node config.Node) []CheckResult {
+	results := make([]CheckResult, len(node.Checks))
+	for i, check := range node.Checks {
+		user := check.User
+		if user == "" {
+			user = node.User(SectionChecks)
+		}
+		results[i] = runCheck(runAs(runner, node, user), check)
+	}
+	return results
+}
+
+func runCheck(runner transport.Runner, check config.Check) CheckResult {
+	result := CheckResult{Name: check.Name, State: SignalCritical}
+	output, err := runner.Run(fmt.Sprintf(`sh -c %s 2>&1; printf '\n%s%%s\n' "$?"`, transport.ShellQuote(check.Command), checkExit))
+	if err != nil {
+		result.Output = err.Error()
+		return result
+	}
+	i := strings.LastIndex(output, "\n"+checkExit)
+	if i < 0 {
+		result.Output = "no exit status"
+		return result
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(output[i+1+len(checkExit):]))
+	if err != nil {
+		result.Output = "no exit status"
+		return result
+	}
+	switch code {
+	case 0:
+		result.State = SignalOK
+	case 1:
+		result.State = SignalWarning
+	}
+	result.Output = checkOutput(output[:i])
+	if result.Output == "" && code != 0 {
+		result.Output = fmt.Sprintf("exit status %d", code)
+	}
+	return result
+}
+
+// checkOutput returns the first line a check printed, without its
+// performance data.
+func checkOutput(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line, _, _ = strings.Cut(line, "|")
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
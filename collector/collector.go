@@ -0,0 +1,779 @@
+// Package collector polls Q nodes and publishes what it finds to
+// a Pipeline. It is the part of the monitor meant to be embedded by other
+// tools; the TUI in package ui is just one consumer.
+//
+// New makes a Collector for a node list, its exported fields set the
+// poll interval, thresholds and the optional checks, and Run polls
+// until its context is done. Every poll of a node is a Snapshot, its
+// Status or error, handed to the Sinks registered with the Pipeline or
+// read from a channel of Pipeline.Subscribe. Transitions like a node
+// going down or a threshold crossed are Events, published to the
+// Handlers of Collector.Events. GetNodeStatus polls a single node once,
+// without a Collector.
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/bootstrap"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/proxmox"
+	"github.com/fid1699/q-monitor-cli/readers"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// DefaultInterval is the time between two polls of the whole fleet.
+const DefaultInterval = 1 * time.Minute
+
+// Status is everything collected from a node in one poll.
+type Status struct {
+	// OS is the name of the profile the stats were collected with.
+	OS string
+	// Shell is the kind of the node's login shell, empty if it didn't
+	// report one.
+	Shell string
+	// Location is the node's time zone, nil if it is not configured and
+	// couldn't be detected.
+	Location *time.Location
+	// Address is the address the node was polled at, the resolved one
+	// for nodes configured by hostname.
+	Address string
+	// PreviousAddress is set once a hostname resolved to a new address,
+	// at AddressChanged.
+	PreviousAddress string
+	AddressChanged  time.Time
+	CPU             parsers.CPUUsage
+	Memory          parsers.MemoryUsage
+	Disks           []parsers.DiskUsage
+	// Storage is the raw output of the storage command.
+	Storage string
+	// Service is the state of the Q service on profiles that check it.
+	Service string
+	// Pi is set for nodes with the Raspberry Pi check enabled.
+	Pi *parsers.PiStatus
+	// Proxmox is set for nodes configured as Proxmox guests.
+	Proxmox *proxmox.Guest
+	// Metrics are the samples scraped from the node's local Prometheus
+	// endpoint, for nodes configured with one.
+	Metrics []parsers.Metric
+	// Mesh are the pings of the other nodes of the mesh, for nodes in
+	// one.
+	Mesh []MeshPing
+	// Audit is the last audit of the node's open ports and firewall,
+	// if audits are configured.
+	Audit *Audit
+	// Logins are the node's recent logins from unexpected sources, if
+	// logins are watched.
+	Logins *Logins
+	// logins are the logins read in the poll, loginSelf the address
+	// the monitor connected from
+	logins    []parsers.Login
+	loginSelf string
+	// Keys are the node's key files, if they are checked.
+	Keys []KeyFile
+	// keyFiles are the key files read in the poll
+	keyFiles []parsers.KeyFile
+	// Checks are the outcomes of the node's own checks, if it has any.
+	Checks []CheckResult
+	// Signals are what other systems reported about the node, if the
+	// webhook is set up.
+	Signals []Signal
+	// Bootstrap is set for bootstrap peer nodes, which report nothing
+	// else.
+	Bootstrap *bootstrap.Status
+	// Visibility is set once the explorer answered for the node.
+	Visibility *Visibility
+	// Connectivity is how the node reaches its peers, as far as its
+	// logs told so far; nil until they tell anything.
+	Connectivity *parsers.Connectivity
+	// connectivity is what the logs read in the poll told
+	connectivity *parsers.Connectivity
+	// Logs only holds entries newer than the previous poll.
+	Logs []parsers.LogMessage
+	// LogLines are the raw lines of the watched entries in Logs, all of
+	// them rather than the latest per message.
+	LogLines []parsers.LogLine
+	// Disabled are the collectors the node's config disables, see
+	// config.Node.Disable. Their sections are left out.
+	Disabled []string
+	// LogsSkipped explains why logs were not read, if they weren't.
+	LogsSkipped string
+	// LastActivity is the timestamp of the newest watched log entry seen
+	// so far, zero if none had one.
+	LastActivity time.Time
+	// Window is the time the log message counts cover, i.e. since the
+	// previous successful poll. It is zero on the first one.
+	Window time.Duration
+	// Missing are the programs the poll needs that are not installed on
+	// the node, if they were checked for.
+	Missing []string
+	// Commands are the results of the commands run in the poll.
+	Commands []transport.Result
+	// Errors holds the failures of the sections by name. A failed
+	// section is left out and the others still show; the poll only
+	// fails if none of the stats could be collected.
+	Errors map[string]error
+	// hung is the first section error of a session the watchdog tore
+	// down, the command that hung; the sections after it weren't run
+	hung error
+}
+
+// statsSections are the sections every poll collects, whose failures
+// fire the poll.partial condition. Optional checks show theirs in their
+// section only.
+var statsSections = []string{SectionCPU, SectionMemory, SectionStorage, SectionService, SectionLogs}
+
+// Section names of Status.Errors.
+const (
+	SectionCPU      = "cpu"
+	SectionMemory   = "memory"
+	SectionStorage  = "storage"
+	SectionService  = "service"
+	SectionLogs     = "logs"
+	SectionPi       = "pi"
+	SectionProxmox  = "proxmox"
+	SectionMetrics  = "metrics"
+	SectionMesh     = "mesh"
+	SectionAudit    = "audit"
+	SectionLogins   = "logins"
+	SectionKeys     = "keys"
+	SectionChecks   = "checks"
+	SectionExplorer = "explorer"
+)
+
+// Failed reports whether the section failed in the poll, e.g. the CPU
+// stats whose command timed out, which leaves their fields zero.
+func (s Status) Failed(section string) bool {
+	return s.Errors[section] != nil
+}
+
+// Collected reports whether the poll has the section, i.e. the node
+// collects it and it didn't fail.
+func (s Status) Collected(section string) bool {
+	return !s.Failed(section) && !slices.Contains(s.Disabled, section)
+}
+
+func (s *Status) setError(section string, err error) {
+	if s.Errors == nil {
+		s.Errors = make(map[string]error)
+	}
+	s.Errors[section] = err
+	if s.hung == nil && errors.Is(err, transport.ErrHung) {
+		s.hung = err
+	}
+}
+
+// Options tune a single GetNodeStatus call.
+type Options struct {
+	Reader   readers.LogReader
+	Messages []string
+	// Since drops log entries that are not newer, the zero value keeps
+	// everything in the reader's window.
+	Since time.Time
+	// OS overrides the node's os setting, e.g. with a previously
+	// detected profile name.
+	OS string
+	// Shell overrides the node's shell setting, e.g. with a previously
+	// detected one.
+	Shell string
+	// Location overrides detecting the node's time zone, e.g. with a
+	// previously detected one.
+	Location *time.Location
+	// ToolsChecked skips the check for missing programs, e.g. because an
+	// earlier poll found all of them.
+	ToolsChecked bool
+	// Deadline bounds the session from the dial on, a command still
+	// running after it fails with transport.ErrHung and the commands
+	// after it aren't run. CommandTimeout bounds each command, see
+	// transport.Timeout. Zero doesn't bound them.
+	Deadline       time.Duration
+	CommandTimeout time.Duration
+	// Mesh are the nodes the node pings for the mesh check.
+	Mesh []MeshTarget
+	// Audit, if set, audits the node in this poll.
+	Audit *config.Audit
+	// Logins, if set, reads the node's logins since LoginsSince.
+	Logins      *config.Logins
+	LoginsSince time.Time
+	// KeyCheck, if set, reads the node's key files.
+	KeyCheck *config.KeyCheck
+	// LowBandwidth has the node send its logins and logs compressed and
+	// skips its metrics scrape.
+	LowBandwidth bool
+}
+
+// Collector polls a fixed set of nodes and publishes a Snapshot per node
+// per poll.
+type Collector struct {
+	nodes    []config.Node
+	reader   readers.LogReader
+	pipeline *Pipeline
+	events   *EventBus
+	states   []nodeState
+
+	Interval   time.Duration
+	Thresholds config.Thresholds
+	// Timeouts bound the commands and polls of the nodes, polls are
+	// budgeted Interval by default.
+	Timeouts config.Timeouts
+	// Messages are the watched log messages for nodes that don't set
+	// their own, see config.WatchedMessages.
+	Messages []string
+	// Dialer opens the connections to the nodes, SSH by default.
+	Dialer transport.Dialer
+	// Explorer, if set, is asked whether the network sees the nodes
+	// that poll successfully.
+	Explorer *config.Explorer
+	// Mesh, if set, has its nodes ping each other every poll.
+	Mesh *config.Mesh
+	// Benchmark, if set, is how MeasureBandwidth measures a node.
+	Benchmark *config.Benchmark
+	// Audit, if set, audits the nodes once per its interval.
+	Audit *config.Audit
+	// Logins, if set, watches the nodes' logins.
+	Logins *config.Logins
+	// KeyCheck, if set, checks the nodes' key files.
+	KeyCheck *config.KeyCheck
+	// Signals, if set, are merged into the polls of their nodes.
+	Signals *Signals
+	// Schedule is when in Interval Run polls the nodes.
+	Schedule config.Schedule
+	// LowBandwidth, if set, makes the polls frugal. It doesn't change
+	// Interval, see config.LowBandwidth.
+	LowBandwidth *config.LowBandwidth
+}
+
+// New returns a collector for the given nodes. reader is used for nodes
+// that don't configure a log reader of their own, nil picks the service
+// reader (see readers.ForNode).
+func New(nodes []config.Node, reader readers.LogReader, pipeline *Pipeline) *Collector {
+	return &Collector{
+		nodes:    nodes,
+		reader:   reader,
+		pipeline: pipeline,
+		events:   NewEventBus(),
+		states:   make([]nodeState, len(nodes)),
+		Interval: DefaultInterval,
+		Dialer:   transport.SSH{},
+	}
+}
+
+// Events returns the bus node state transitions are published on.
+// Handlers should be registered before Run is called.
+func (c *Collector) Events() *EventBus {
+	return c.events
+}
+
+// Run polls all nodes until ctx is cancelled: all at once at the start,
+// so every node shows up right away, then each node once per Interval
+// at its own offset in it, see config.Schedule.
+func (c *Collector) Run(ctx context.Context) {
+	if c.Schedule.AllAtOnce {
+		for {
+			c.PollOnce()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.Interval):
+			}
+		}
+	}
+
+	start := time.Now()
+	c.PollOnce()
+	var wg sync.WaitGroup
+	for i, node := range c.nodes {
+		wg.Add(1)
+		go func(i int, node config.Node) {
+			defer wg.Done()
+			c.schedule(ctx, i, node, start)
+		}(i, node)
+	}
+	wg.Wait()
+}
+
+// schedule polls the node once per Interval, at the i-th of as many
+// slots of the interval as there are nodes, randomly delayed by the
+// schedule's jitter. A poll running past its next slot skips it.
+func (c *Collector) schedule(ctx context.Context, i int, node config.Node, start time.Time) {
+	next := start.Add(c.Interval * time.Duration(i) / time.Duration(len(c.nodes)))
+	jitter := c.Schedule.Jitter(c.Interval)
+	for {
+		next = next.Add(c.Interval)
+		for now := time.Now(); next.Before(now); {
+			next = next.Add(c.Interval)
+		}
+		wait := time.Until(next)
+		if jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if !c.states[i].paused.Load() {
+			c.pollNode(i, node)
+		}
+	}
+}
+
+// PollOnce polls every node that isn't paused concurrently and returns
+// once all of their snapshots have been published.
+func (c *Collector) PollOnce() {
+	var wg sync.WaitGroup
+	for i, node := range c.nodes {
+		if c.states[i].paused.Load() {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, node config.Node) {
+			defer wg.Done()
+			c.pollNode(i, node)
+		}(i, node)
+	}
+	wg.Wait()
+}
+
+// pollNode polls the i-th node and publishes its snapshot.
+func (c *Collector) pollNode(i int, node config.Node) {
+	state := &c.states[i]
+	state.polling.Lock()
+	defer state.polling.Unlock()
+	var status Status
+	var address string
+	var err error
+	if node.Bootstrap != "" {
+		// there is nothing to log in to, the peer is dialed
+		// from here
+		status.Bootstrap, err = bootstrap.Probe(node.Bootstrap)
+	} else {
+		status, address, err = c.poll(state, node)
+	}
+	now := time.Now()
+	if err == nil {
+		state.os = status.OS
+		state.shell = status.Shell
+		state.location = status.Location
+		state.toolsChecked = len(status.Missing) == 0
+		status.Address = address
+		status.PreviousAddress, status.AddressChanged = state.previousAddress, state.addressChanged
+		if status.LastActivity.After(state.lastActivity) {
+			state.lastActivity = status.LastActivity
+		}
+		status.LastActivity = state.lastActivity
+		if !state.lastPoll.IsZero() {
+			status.Window = now.Sub(state.lastPoll)
+		}
+		state.lastPoll = now
+		if c.Signals != nil {
+			status.Signals = c.Signals.For(node.DisplayName(), now)
+		}
+	}
+	snapshot := Snapshot{
+		Index:  i,
+		Node:   node,
+		Status: status,
+		Err:    err,
+		Time:   now,
+	}
+	c.pipeline.Publish(snapshot)
+	c.emitEvents(snapshot)
+}
+
+// poll collects the status of a Q node, at the address its hostname
+// currently resolves to.
+func (c *Collector) poll(state *nodeState, node config.Node) (Status, string, error) {
+	reader, err := readers.ForNode(node, c.reader)
+	if err != nil {
+		return Status{}, "", err
+	}
+	address, err := resolve(state, node, time.Now())
+	if err != nil {
+		return Status{}, "", err
+	}
+	// dial the resolved address, the snapshot keeps the configured node
+	resolved := node
+	resolved.IP = address
+	// the mesh and the audit are skipped on metered links, the previous
+	// audit is still shown
+	audited := c.Audit != nil && c.LowBandwidth == nil && node.Collects(SectionAudit) && time.Since(state.audited) >= c.Audit.Interval()
+	var audit *config.Audit
+	if audited {
+		audit = c.Audit
+	}
+	mesh := c.meshTargets(node)
+	if c.LowBandwidth != nil {
+		reader = readers.WithLines(reader, c.LowBandwidth.Lines())
+		mesh = nil
+	}
+	if !node.Collects(SectionMesh) {
+		mesh = nil
+	}
+	logins, keyCheck := c.Logins, c.KeyCheck
+	if !node.Collects(SectionLogins) {
+		logins = nil
+	}
+	if !node.Collects(SectionKeys) {
+		keyCheck = nil
+	}
+	status, err := GetNodeStatus(c.Dialer, resolved, Options{
+		Reader:   reader,
+		Messages: config.WatchedMessages(node, c.Messages),
+		Since:    state.lastActivity,
+		OS:       state.os,
+		Shell:    state.shell,
+		Location: state.location,
+		// nodes missing programs are checked again every poll, so
+		// installing them is picked up
+		ToolsChecked: state.toolsChecked,
+		// a session still running when its budget is used up, by default
+		// when the next poll is due, is hung
+		Deadline:       c.Timeouts.For(node).Poll(c.Interval),
+		CommandTimeout: c.Timeouts.For(node).Command(),
+		Mesh:           mesh,
+		Audit:          audit,
+		Logins:         logins,
+		LoginsSince:    loginsSince(state, time.Now()),
+		KeyCheck:       keyCheck,
+		LowBandwidth:   c.LowBandwidth != nil,
+	})
+	if err == nil && c.Audit != nil && node.Collects(SectionAudit) {
+		if audited {
+			state.audited = time.Now()
+		}
+		c.carryAudit(state, &status, audited)
+	}
+	if err == nil && logins != nil {
+		c.trackLogins(state, &status, time.Now())
+	}
+	if err == nil && keyCheck != nil {
+		c.checkKeys(state, &status, time.Now())
+	}
+	if err == nil && c.Explorer != nil {
+		c.checkVisibility(state, node, &status)
+	}
+	if err == nil {
+		trackConnectivity(state, &status)
+	}
+	return status, address, err
+}
+
+// GetNodeStatus connects to a node and collects its cpu, memory, disk and
+// log status, keeping the latest entry of each of the watched messages.
+//
+// The returned status lists the commands that ran even if the poll
+// failed.
+func GetNodeStatus(dialer transport.Dialer, node config.Node, opts Options) (status Status, err error) {
+	format, err := parsers.NewLogFormat(node.LogFormat, node.LogPattern)
+	if err != nil {
+		return Status{}, err
+	}
+
+	raw, err := dialer.Dial(node)
+	if err != nil {
+		return Status{}, err
+	}
+	dialed := transport.Watch(raw, opts.Deadline)
+	defer dialed.Close()
+	conn := transport.Record(transport.Timeout(dialed, opts.CommandTimeout))
+	defer func() { status.Commands = conn.Results() }()
+
+	profile, err := nodeProfile(conn, node, opts)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status = Status{OS: profile.Name, Disabled: node.Disabled()}
+	if profile.Name != profiles.Windows {
+		if status.Shell, err = nodeShell(conn, node, opts); err != nil {
+			return Status{}, err
+		}
+		conn.Conn = transport.WithShell(conn.Conn, status.Shell)
+	}
+	if status.Location, err = nodeLocation(conn, node, profile, opts); err != nil {
+		return Status{}, err
+	}
+	conn.Conn = transport.WithPrefix(conn.Conn, node)
+	// as runs a collector's commands as the user the node's config
+	// names for it
+	as := func(collector string) transport.Runner {
+		if profile.Name == profiles.Windows {
+			return conn
+		}
+		return runAs(conn, node, node.User(collector))
+	}
+
+	if !opts.ToolsChecked && len(profile.Tools) > 0 {
+		if status.Missing, err = checkTools(conn, profile, opts, node); err != nil {
+			return Status{Missing: status.Missing}, err
+		}
+	}
+
+	if node.Collects(SectionCPU) {
+		output, err := as(SectionCPU).Run(profile.CPUCommand)
+		if err == nil {
+			status.CPU, err = profile.ParseCPU(output)
+		}
+		if err != nil {
+			status.setError(SectionCPU, err)
+		}
+	}
+
+	if node.Collects(SectionMemory) {
+		output, err := as(SectionMemory).Run(profile.MemoryCommand)
+		if err == nil {
+			status.Memory, err = profile.ParseMemory(output)
+		}
+		if err != nil {
+			status.setError(SectionMemory, err)
+		}
+	}
+
+	if node.Collects(SectionStorage) {
+		storage := profile.StorageCommand
+		if profile.StorageMounts {
+			mounts := make([]string, len(node.StorageMounts()))
+			for i, mount := range node.StorageMounts() {
+				mounts[i] = transport.ShellQuote(mount)
+			}
+			storage = fmt.Sprintf(storage, strings.Join(mounts, " "))
+		}
+		status.Storage, err = as(SectionStorage).Run(storage)
+		if err == nil {
+			status.Disks, err = profile.ParseStorage(status.Storage)
+			status.Disks = uniqueMounts(status.Disks)
+		}
+		if err != nil {
+			status.setError(SectionStorage, err)
+		}
+	}
+	var collected, failed []string
+	for _, section := range []string{SectionCPU, SectionMemory, SectionStorage} {
+		if node.Collects(section) {
+			collected = append(collected, section)
+		}
+		if status.Failed(section) {
+			failed = append(failed, section)
+		}
+	}
+	if len(collected) > 0 && len(failed) == len(collected) {
+		// nothing to show, e.g. a session hung from the start
+		return Status{}, status.Errors[failed[0]]
+	}
+
+	if profile.ServiceCommand != "" && node.Collects(SectionService) {
+		output, err := as(SectionService).Run(fmt.Sprintf(profile.ServiceCommand, node.ServiceName()))
+		switch {
+		case err != nil:
+			status.setError(SectionService, err)
+		case profile.ParseService != nil:
+			status.Service = profile.ParseService(output)
+		default:
+			status.Service = strings.TrimSpace(output)
+		}
+	}
+
+	pi := node.RaspberryPi && node.Collects(SectionPi)
+	if missing := missingFor(status.Missing, piTools); pi && len(missing) > 0 {
+		status.setError(SectionPi, fmt.Errorf("missing: %s", strings.Join(missing, ", ")))
+	} else if pi {
+		status.Pi, err = piStatus(as(SectionPi))
+		if err != nil {
+			status.setError(SectionPi, err)
+		}
+	}
+
+	if node.Proxmox != nil && node.Collects(SectionProxmox) {
+		status.Proxmox, err = proxmox.Fetch(*node.Proxmox)
+		if err != nil {
+			status.setError(SectionProxmox, err)
+		}
+	}
+
+	if node.Metrics != nil && !opts.LowBandwidth && node.Collects(SectionMetrics) {
+		// the tunnel is a feature of the connection itself, not of the
+		// wrappers running commands
+		status.Metrics, err = scrapeMetrics(raw, *node.Metrics)
+		if err != nil {
+			status.setError(SectionMetrics, err)
+		}
+	}
+
+	if missing := missingFor(status.Missing, meshTools); len(opts.Mesh) > 0 && len(missing) > 0 {
+		status.setError(SectionMesh, fmt.Errorf("missing: %s", strings.Join(missing, ", ")))
+	} else if len(opts.Mesh) > 0 {
+		status.Mesh, err = pingMesh(as(SectionMesh), profile, opts.Mesh)
+		if err != nil {
+			status.setError(SectionMesh, err)
+		}
+	}
+
+	if opts.Audit != nil {
+		if missing := missingFor(status.Missing, auditPrograms(*opts.Audit)); len(missing) > 0 {
+			status.setError(SectionAudit, fmt.Errorf("missing: %s", strings.Join(missing, ", ")))
+		} else if status.Audit, err = auditNode(as(SectionAudit), profile, node, *opts.Audit); err != nil {
+			status.setError(SectionAudit, err)
+		}
+	}
+
+	checks := len(node.Checks) > 0 && node.Collects(SectionChecks)
+	if checks && profile.Name == profiles.Windows {
+		status.setError(SectionChecks, fmt.Errorf("checks are not available on %s nodes", profile.Name))
+	} else if checks {
+		status.Checks = runChecks(conn, node)
+	}
+
+	if opts.LowBandwidth && profile.Name != profiles.Windows && len(missingFor(status.Missing, transport.CompressTools)) == 0 {
+		// the commands left are the ones whose output grows with the
+		// node's activity
+		conn.Conn = transport.Compress(conn.Conn)
+	}
+
+	if opts.Logins != nil {
+		status.logins, status.loginSelf, err = readLogins(as(SectionLogins), profile, *opts.Logins, opts.LoginsSince, status.Location)
+		if err != nil {
+			status.setError(SectionLogins, err)
+		}
+	}
+
+	if opts.KeyCheck != nil {
+		if missing := missingFor(status.Missing, keyPrograms(profile)); len(missing) > 0 {
+			status.setError(SectionKeys, fmt.Errorf("missing: %s", strings.Join(missing, ", ")))
+		} else if status.keyFiles, err = readKeyFiles(as(SectionKeys), profile, node, *opts.KeyCheck); err != nil {
+			status.setError(SectionKeys, err)
+		}
+	}
+
+	if !node.Collects(SectionLogs) {
+		return status, nil
+	}
+	if !profile.SupportsReader(opts.Reader) {
+		status.LogsSkipped = fmt.Sprintf("%s logs are not available on %s nodes", opts.Reader.Name(), profile.Name)
+		return status, nil
+	}
+	if missing := missingFor(status.Missing, readers.Tools(opts.Reader)); len(missing) > 0 {
+		status.LogsSkipped = fmt.Sprintf("missing: %s", strings.Join(missing, ", "))
+		return status, nil
+	}
+
+	// we exec the logs command separately so we can use a reader
+	logs, err := opts.Reader.ReadLogs(as(SectionLogs), format.Filter(append(slices.Clone(opts.Messages), parsers.ConnectivityMessages...)))
+	if err != nil {
+		status.setError(SectionLogs, err)
+		return status, nil
+	}
+	status.Logs = parsers.ExtractLogMessages(logs, opts.Messages, format, opts.Since, status.Location)
+	status.LogLines = parsers.WatchedLines(logs, opts.Messages, format, opts.Since, status.Location)
+	if connectivity, ok := parsers.ParseConnectivity(logs, format, opts.Since, status.Location); ok {
+		status.connectivity = &connectivity
+	}
+	for _, message := range status.Logs {
+		if message.Time.After(status.LastActivity) {
+			status.LastActivity = message.Time
+		}
+	}
+
+	return status, nil
+}
+
+// timedOut reports whether err is a command cut short by its timeout or
+// by the poll's deadline.
+func timedOut(err error) bool {
+	return errors.Is(err, transport.ErrTimeout) || errors.Is(err, transport.ErrHung)
+}
+
+// nodeProfile picks the command set for a node, detecting it when
+// neither the options nor the config name one.
+func nodeProfile(runner transport.Runner, node config.Node, opts Options) (profiles.Profile, error) {
+	name := opts.OS
+	if name == "" {
+		name = node.OS
+	}
+	if name == "" || name == "auto" {
+		return profiles.Detect(runner)
+	}
+	return profiles.For(name)
+}
+
+func nodeShell(runner transport.Runner, node config.Node, opts Options) (string, error) {
+	if opts.Shell != "" {
+		return opts.Shell, nil
+	}
+	if node.Shell != "" {
+		return node.Shell, nil
+	}
+	shell, err := transport.DetectShell(runner)
+	if err == nil && shell == "" {
+		// $SHELL isn't set, the session runs in whatever sh is
+		shell = config.ShellPOSIX
+	}
+	return shell, err
+}
+
+// nodeLocation returns the node's configured time zone, or detects the
+// zone it is in now. POSIX nodes that don't know their zone's name get
+// a fixed offset, Windows nodes nil.
+func nodeLocation(runner transport.Runner, node config.Node, profile profiles.Profile, opts Options) (*time.Location, error) {
+	if opts.Location != nil {
+		return opts.Location, nil
+	}
+	if node.TimeZone != "" {
+		return time.LoadLocation(node.TimeZone)
+	}
+	if profile.Name == profiles.Windows {
+		return nil, nil
+	}
+	output, err := runner.Run("date +'%Z %z'")
+	if err != nil {
+		return nil, err
+	}
+	return parsers.ParseZone(output)
+}
+
+// runAs returns a runner that runs commands as user, switching to them
+// the way the node's config says, or runner itself for an empty user.
+func runAs(runner transport.Runner, node config.Node, user string) transport.Runner {
+	if user == "" {
+		return runner
+	}
+	return transport.AsUser(runner, user, node.RunAsWith)
+}
+
+// piStatus runs the Raspberry Pi check. vcgencmd needs the monitor user
+// to be in the video group.
+func piStatus(runner transport.Runner) (*parsers.PiStatus, error) {
+	output, err := runner.Run("vcgencmd get_throttled && vcgencmd measure_temp")
+	if err != nil {
+		return nil, err
+	}
+	pi, err := parsers.ParsePiStatus(output)
+	if err != nil {
+		return nil, err
+	}
+	return &pi, nil
+}
+
+// uniqueMounts drops repeated filesystems, which df reports once per
+// path asked for, e.g. for two directories on the root filesystem.
+func uniqueMounts(disks []parsers.DiskUsage) []parsers.DiskUsage {
+	seen := make(map[string]bool)
+	unique := disks[:0]
+	for _, disk := range disks {
+		if !seen[disk.Mount] {
+			seen[disk.Mount] = true
+			unique = append(unique, disk)
+		}
+	}
+	return unique
+}
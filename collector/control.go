@@ -0,0 +1,25 @@
+package collector
+
+import "fmt"
+
+// Pause stops the scheduled polls of the i-th node, or resumes them. A
+// poll of the node that is running meanwhile still publishes its
+// snapshot.
+func (c *Collector) Pause(i int, paused bool) error {
+	if i < 0 || i >= len(c.nodes) {
+		return fmt.Errorf("no node %d", i)
+	}
+	c.states[i].paused.Store(paused)
+	return nil
+}
+
+// Refresh polls the i-th node now, out of its schedule and even if it
+// is paused, right after the poll of it that is running if there is
+// one. It returns without waiting for the snapshot.
+func (c *Collector) Refresh(i int) error {
+	if i < 0 || i >= len(c.nodes) {
+		return fmt.Errorf("no node %d", i)
+	}
+	go c.pollNode(i, c.nodes[i])
+	return nil
+}
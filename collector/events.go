@@ -0,0 +1,425 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+type EventType int
+
+const (
+	// NodeUp is emitted the first time a node polls successfully and
+	// whenever it recovers after being down.
+	NodeUp EventType = iota
+	// NodeDown is emitted when a poll fails after the node was up (or on
+	// the very first poll).
+	NodeDown
+	// MetricThresholdCrossed is emitted when a metric goes above its
+	// configured threshold, and again when it drops back below.
+	MetricThresholdCrossed
+	// LogMessageSeen is emitted when a new entry of a watched log message
+	// shows up.
+	LogMessageSeen
+	// ConditionChanged is emitted when a named problem on a node, e.g. a
+	// throttled Raspberry Pi, starts or clears.
+	ConditionChanged
+	// AddressChanged is emitted when the hostname of a node resolves to
+	// another address than before.
+	AddressChanged
+	// SessionHung is emitted for every poll whose session the watchdog
+	// tore down, see transport.Watch, whether the poll failed or kept
+	// the sections it collected before.
+	SessionHung
+)
+
+func (t EventType) String() string {
+	switch t {
+	case NodeUp:
+		return "NodeUp"
+	case NodeDown:
+		return "NodeDown"
+	case MetricThresholdCrossed:
+		return "MetricThresholdCrossed"
+	case LogMessageSeen:
+		return "LogMessageSeen"
+	case ConditionChanged:
+		return "ConditionChanged"
+	case AddressChanged:
+		return "AddressChanged"
+	case SessionHung:
+		return "SessionHung"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event is a state transition of a single node. Which of the optional
+// fields are set depends on Type.
+type Event struct {
+	Type  EventType
+	Index int
+	Node  config.Node
+	Time  time.Time
+
+	// NodeDown, SessionHung
+	Err error
+
+	// MetricThresholdCrossed
+	Metric    string
+	Value     float64
+	Threshold float64
+	Above     bool
+
+	// LogMessageSeen
+	Message parsers.LogMessage
+
+	// ConditionChanged
+	Condition string
+	Active    bool
+	Detail    string
+
+	// AddressChanged
+	PreviousAddress string
+	Address         string
+}
+
+func (e Event) String() string {
+	prefix := fmt.Sprintf("%s %s", e.Time.Format("15:04:05"), e.Node.DisplayName())
+	switch e.Type {
+	case NodeUp:
+		return prefix + " is up"
+	case NodeDown:
+		return fmt.Sprintf("%s is down: %v", prefix, e.Err)
+	case MetricThresholdCrossed:
+		direction := "below"
+		if e.Above {
+			direction = "above"
+		}
+		return fmt.Sprintf("%s %s %.1f%% %s threshold %.1f%%", prefix, e.Metric, e.Value, direction, e.Threshold)
+	case LogMessageSeen:
+		return fmt.Sprintf("%s logged %q", prefix, e.Message.Msg)
+	case ConditionChanged:
+		if e.Active {
+			return fmt.Sprintf("%s %s", prefix, e.Detail)
+		}
+		return fmt.Sprintf("%s %s cleared", prefix, e.Condition)
+	case AddressChanged:
+		return fmt.Sprintf("%s IP changed from %s to %s", prefix, e.PreviousAddress, e.Address)
+	case SessionHung:
+		return fmt.Sprintf("%s session hung and was closed: %v", prefix, e.Err)
+	default:
+		return prefix + " " + e.Type.String()
+	}
+}
+
+// Handler receives every event published to the bus.
+type Handler interface {
+	HandleEvent(event Event)
+}
+
+// EventBus fans events out from the collector to the UI, alerting and
+// anything else interested in node state transitions.
+type EventBus struct {
+	fanout fanout[Event]
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Register subscribes a handler to the bus, fed from its own goroutine.
+func (b *EventBus) Register(handler Handler) {
+	events := b.fanout.subscribe(snapshotBuffer)
+	go func() {
+		for event := range events {
+			handler.HandleEvent(event)
+		}
+	}()
+}
+
+// Publish delivers an event to all registered handlers.
+func (b *EventBus) Publish(event Event) {
+	b.fanout.publish(event)
+}
+
+// nodeState is what the collector remembers about a node between polls to
+// detect transitions.
+type nodeState struct {
+	// polling is held while the node is polled, so a refresh doesn't
+	// run alongside a scheduled poll; paused stops the scheduled polls
+	polling sync.Mutex
+	paused  atomic.Bool
+
+	polled       bool
+	up           bool
+	above        map[string]bool
+	logs         map[string]parsers.LogMessage
+	lastActivity time.Time
+	lastPoll     time.Time
+	os           string
+	shell        string
+	location     *time.Location
+	toolsChecked bool
+	conditions   map[string]bool
+
+	// peerID is the last peer ID the node logged. The explorer was last
+	// asked about explorerPeerID at explorerChecked, visibility is its
+	// last answer.
+	peerID          string
+	explorerPeerID  string
+	explorerChecked time.Time
+	explorerErr     error
+	visibility      *Visibility
+
+	// connectivity is what the node's logs told about its connectivity
+	// so far
+	connectivity *parsers.Connectivity
+
+	// audited is when the node was last audited, audit and auditErr
+	// what that found
+	audited  time.Time
+	audit    *Audit
+	auditErr error
+
+	// loginSources are the unexpected sources of logins in the window,
+	// by address, loginsNewest the time of the newest login read
+	loginSources map[string]*LoginSource
+	loginsNewest time.Time
+
+	// keys are the baselines of the node's key files, by path
+	keys map[string]*keyBaseline
+
+	// address is the last address the node's hostname resolved to, at
+	// resolved
+	address         string
+	resolved        time.Time
+	previousAddress string
+	addressChanged  time.Time
+	// announcedAddress is the address the last AddressChanged event
+	// was emitted for
+	announcedAddress string
+}
+
+// emitEvents compares a snapshot with the previous state of its node and
+// publishes the resulting events.
+func (c *Collector) emitEvents(snapshot Snapshot) {
+	state := &c.states[snapshot.Index]
+	event := Event{Index: snapshot.Index, Node: snapshot.Node, Time: snapshot.Time}
+
+	if snapshot.Err != nil {
+		if errors.Is(snapshot.Err, transport.ErrHung) {
+			event.Type = SessionHung
+			event.Err = snapshot.Err
+			c.events.Publish(event)
+		}
+		if !state.polled || state.up {
+			event.Type = NodeDown
+			event.Err = snapshot.Err
+			c.events.Publish(event)
+		}
+		state.polled, state.up = true, false
+		return
+	}
+
+	if snapshot.Status.hung != nil {
+		hung := event
+		hung.Type = SessionHung
+		hung.Err = snapshot.Status.hung
+		c.events.Publish(hung)
+	}
+	if !state.polled || !state.up {
+		event.Type = NodeUp
+		c.events.Publish(event)
+	}
+	state.polled, state.up = true, true
+
+	if previous := state.announcedAddress; snapshot.Status.Address != previous {
+		if previous != "" {
+			event.Type = AddressChanged
+			event.PreviousAddress, event.Address = previous, snapshot.Status.Address
+			c.events.Publish(event)
+		}
+		state.announcedAddress = snapshot.Status.Address
+	}
+
+	if state.above == nil {
+		state.above = make(map[string]bool)
+		state.logs = make(map[string]parsers.LogMessage)
+		state.conditions = make(map[string]bool)
+	}
+
+	for metric, value := range metricValues(snapshot.Status) {
+		threshold := c.Thresholds.For(metric)
+		if threshold <= 0 {
+			continue
+		}
+		above := value > threshold
+		if above != state.above[metric] {
+			event.Type = MetricThresholdCrossed
+			event.Metric, event.Value, event.Threshold, event.Above = metric, value, threshold, above
+			c.events.Publish(event)
+		}
+		state.above[metric] = above
+	}
+
+	current := conditions(snapshot.Status)
+	for name, detail := range current {
+		if !state.conditions[name] {
+			event.Type = ConditionChanged
+			event.Condition, event.Active, event.Detail = name, true, detail
+			c.events.Publish(event)
+		}
+	}
+	for name := range state.conditions {
+		if _, ok := current[name]; !ok {
+			event.Type = ConditionChanged
+			event.Condition, event.Active, event.Detail = name, false, ""
+			c.events.Publish(event)
+		}
+	}
+	state.conditions = make(map[string]bool, len(current))
+	for name := range current {
+		state.conditions[name] = true
+	}
+
+	for _, message := range snapshot.Status.Logs {
+		previous, seen := state.logs[message.Msg]
+		if seen && previous.Time.Equal(message.Time) && reflect.DeepEqual(previous.Fields, message.Fields) {
+			continue
+		}
+		state.logs[message.Msg] = message
+		event.Type = LogMessageSeen
+		event.Message = message
+		c.events.Publish(event)
+	}
+}
+
+// metricValues returns the metrics thresholds can be configured for, as
+// percentages.
+func metricValues(status Status) map[string]float64 {
+	values := make(map[string]float64)
+	if status.Collected(SectionCPU) {
+		values[config.MetricCPU] = status.CPU.User + status.CPU.System
+	}
+	if status.Memory.TotalMB > 0 {
+		values[config.MetricMemory] = float64(status.Memory.UsedMB) / float64(status.Memory.TotalMB) * 100
+	}
+	return values
+}
+
+// piConditions map the current Raspberry Pi problems to condition names.
+var piConditions = []struct {
+	flag   uint32
+	name   string
+	detail string
+}{
+	{parsers.PiUnderVoltage, "pi.undervoltage", "is under-voltage, check the power supply"},
+	{parsers.PiThrottled, "pi.throttled", "is throttled"},
+	{parsers.PiFreqCapped, "pi.freq_capped", "has its arm frequency capped"},
+	{parsers.PiSoftTempLimit, "pi.temp_limit", "hit the soft temperature limit"},
+}
+
+// conditions returns the problems currently present in a status, by
+// condition name.
+func conditions(status Status) map[string]string {
+	active := make(map[string]string)
+	if v := status.Visibility; v != nil && !v.Visible {
+		active["explorer.invisible"] = "is up but the network doesn't see peer " + v.PeerID
+	}
+	if c := status.Connectivity; c != nil && c.RelayedOnly() {
+		active["network.relayed"] = fmt.Sprintf("reaches its %d peers only through relays", c.Relayed)
+	}
+	if a := status.Audit; a != nil && len(a.Unexpected) > 0 {
+		active["audit.ports"] = "listens on unexpected ports: " + strings.Join(a.Unexpected, ", ")
+	}
+	if a := status.Audit; a != nil && len(a.MissingRules) > 0 {
+		active["audit.firewall"] = "is missing firewall rules: " + strings.Join(a.MissingRules, "; ")
+	}
+	if l := status.Logins; l != nil && len(l.New) > 0 {
+		addresses := make([]string, len(l.New))
+		for i, source := range l.New {
+			addresses[i] = source.Address
+		}
+		active["logins.new"] = fmt.Sprintf("was logged in to from %d unexpected sources: %s", len(l.New), strings.Join(addresses, ", "))
+	}
+	var loose, owners, changed, missing []string
+	for _, file := range status.Keys {
+		if file.Missing {
+			missing = append(missing, file.Path)
+			continue
+		}
+		if file.Loose {
+			loose = append(loose, fmt.Sprintf("%s (%03o)", file.Path, file.Mode.Perm()))
+		}
+		if file.WrongOwner() {
+			owners = append(owners, fmt.Sprintf("%s is owned by %s, not %s", file.Path, file.Owner, file.ExpectedOwner))
+		}
+		if !file.Changed.IsZero() {
+			changed = append(changed, file.Path)
+		}
+	}
+	if len(loose) > 0 {
+		active["keys.permissions"] = "has key files others can access: " + strings.Join(loose, ", ")
+	}
+	if len(owners) > 0 {
+		active["keys.owner"] = "has key files with the wrong owner: " + strings.Join(owners, "; ")
+	}
+	if len(changed) > 0 {
+		active["keys.changed"] = "has key files whose contents changed: " + strings.Join(changed, ", ")
+	}
+	if len(missing) > 0 {
+		active["keys.missing"] = "is missing key files: " + strings.Join(missing, ", ")
+	}
+	for _, check := range status.Checks {
+		if check.State != SignalOK {
+			active["check."+check.Name] = fmt.Sprintf("fails check %s (%s): %s", check.Name, check.State, check.Output)
+		}
+	}
+	for _, signal := range status.Signals {
+		switch {
+		case signal.Stale:
+			active["external."+signal.Name] = fmt.Sprintf("hasn't reported %s since %s", signal.Name, signal.Reported.Format("15:04"))
+		case signal.State != SignalOK:
+			detail := fmt.Sprintf("reports %s %s", signal.Name, signal.State)
+			if signal.Message != "" {
+				detail += ": " + signal.Message
+			}
+			active["external."+signal.Name] = detail
+		}
+	}
+	var timeouts, failed []string
+	for section, err := range status.Errors {
+		if timedOut(err) {
+			timeouts = append(timeouts, section)
+		} else if slices.Contains(statsSections, section) {
+			failed = append(failed, section)
+		}
+	}
+	if len(timeouts) > 0 {
+		sort.Strings(timeouts)
+		active["poll.timeout"] = "timed out collecting " + strings.Join(timeouts, ", ")
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		active["poll.partial"] = "failed to collect " + strings.Join(failed, ", ")
+	}
+	if status.Pi != nil {
+		for _, c := range piConditions {
+			if status.Pi.Active(c.flag) {
+				active[c.name] = c.detail
+			}
+		}
+	}
+	return active
+}
@@ -0,0 +1,29 @@
+package collector
+
+import "sync"
+
+// fanout delivers every published value to all subscribers. It backs both
+// the snapshot Pipeline and the EventBus.
+type fanout[T any] struct {
+	mu          sync.RWMutex
+	subscribers []chan T
+}
+
+func (f *fanout[T]) subscribe(buffer int) <-chan T {
+	ch := make(chan T, buffer)
+
+	f.mu.Lock()
+	f.subscribers = append(f.subscribers, ch)
+	f.mu.Unlock()
+
+	return ch
+}
+
+func (f *fanout[T]) publish(value T) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, ch := range f.subscribers {
+		ch <- value
+	}
+}
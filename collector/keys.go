@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// KeyFile is a checked key file of a node.
+type KeyFile struct {
+	parsers.KeyFile
+	// Loose is set if others than its owner may access it.
+	Loose bool
+	// ExpectedOwner is the owner it must have: the configured one, or
+	// its owner on the first check.
+	ExpectedOwner string
+	// Changed is when its checksum first differed from the one on the
+	// first check, zero if it hasn't.
+	Changed time.Time
+}
+
+// WrongOwner reports whether the file belongs to someone else than it
+// should.
+func (f KeyFile) WrongOwner() bool {
+	return !f.Missing && f.ExpectedOwner != "" && f.Owner != f.ExpectedOwner
+}
+
+// keyBaseline is what a key file was like on the first check of the
+// run, and when its checksum first changed from it.
+type keyBaseline struct {
+	owner    string
+	checksum string
+	changed  time.Time
+}
+
+// keyPrograms returns the programs the key check runs with profile.
+func keyPrograms(profile profiles.Profile) []string {
+	var tools []string
+	for _, cmd := range []string{profile.FileStatCommand, profile.ChecksumCommand} {
+		if fields := strings.Fields(cmd); len(fields) > 0 {
+			tools = append(tools, fields[0])
+		}
+	}
+	return tools
+}
+
+// readKeyFiles stats and checksums the node's key files in one command.
+func readKeyFiles(runner transport.Runner, profile profiles.Profile, node config.Node, cfg config.KeyCheck) ([]parsers.KeyFile, error) {
+	if profile.FileStatCommand == "" || profile.ChecksumCommand == "" {
+		return nil, fmt.Errorf("key files are not checked on %s nodes", profile.Name)
+	}
+	files := cfg.NodeFiles(node)
+	quoted := make([]string, len(files))
+	for i, file := range files {
+		if rest, ok := strings.CutPrefix(file, "~/"); ok {
+			quoted[i] = `"$HOME"/` + transport.ShellQuote(rest)
+		} else {
+			quoted[i] = transport.ShellQuote(file)
+		}
+	}
+	output, err := runner.Run(fmt.Sprintf(`for f in %s; do if [ -e "$f" ]; then echo "$(%s "$f")|$(%s < "$f" 2>/dev/null)|$f"; else echo "missing||||$f"; fi; done`,
+		strings.Join(quoted, " "), profile.FileStatCommand, profile.ChecksumCommand))
+	if err != nil {
+		return nil, err
+	}
+	return parsers.ParseKeyFiles(output)
+}
+
+// checkKeys compares the key files read in a poll with the config and
+// the node's baseline, and sets status.Keys.
+func (c *Collector) checkKeys(state *nodeState, status *Status, now time.Time) {
+	if state.keys == nil {
+		state.keys = make(map[string]*keyBaseline)
+	}
+	status.Keys = nil
+	for _, file := range status.keyFiles {
+		checked := KeyFile{KeyFile: file, ExpectedOwner: c.KeyCheck.Owner}
+		if !file.Missing {
+			checked.Loose = file.Mode.Perm()&0o077 != 0
+			baseline, ok := state.keys[file.Path]
+			if !ok {
+				baseline = &keyBaseline{owner: file.Owner}
+				state.keys[file.Path] = baseline
+			}
+			if baseline.checksum == "" {
+				// unreadable so far
+				baseline.checksum = file.Checksum
+			} else if file.Checksum != "" && file.Checksum != baseline.checksum && baseline.changed.IsZero() {
+				baseline.changed = now
+			}
+			if checked.ExpectedOwner == "" {
+				checked.ExpectedOwner = baseline.owner
+			}
+			checked.Changed = baseline.changed
+		}
+		status.Keys = append(status.Keys, checked)
+	}
+}
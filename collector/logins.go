@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// loginWindow is how far back the logins of a node are kept.
+const loginWindow = 24 * time.Hour
+
+// loginLines picks sshd's lines about logins.
+const loginLines = `grep -E 'Accepted|Failed' || true`
+
+// LoginSource is an unexpected address that logged in to a node, or
+// tried to. It is forgotten loginWindow after its last attempt.
+type LoginSource struct {
+	Address string
+	// Users are the users it logged in as or tried to.
+	Users    []string
+	Accepted int
+	Failed   int
+	First    time.Time
+	Last     time.Time
+}
+
+// Logins are the SSH logins of a node within loginWindow from sources
+// the config doesn't expect.
+type Logins struct {
+	// New are the sources that logged in, the latest first.
+	New []LoginSource
+	// Failed counts the failed attempts, from FailedSources sources.
+	Failed        int
+	FailedSources int
+}
+
+// readLogins reads the logins in sshd's log since since, and the
+// address the monitor itself connects from.
+func readLogins(runner transport.Runner, profile profiles.Profile, cfg config.Logins, since time.Time, loc *time.Location) ([]parsers.Login, string, error) {
+	if profile.Name != profiles.Linux {
+		return nil, "", fmt.Errorf("logins are not watched on %s nodes", profile.Name)
+	}
+	cmd := fmt.Sprintf(`echo "$SSH_CONNECTION"; journalctl _COMM=sshd --since @%d -o short-unix -q --no-pager | %s`, since.Unix(), loginLines)
+	if cfg.AuthLog != "" {
+		file := transport.ShellQuote(cfg.AuthLog)
+		cmd = fmt.Sprintf(`echo "$SSH_CONNECTION"; test -r %s || { echo "can't read %s" >&2; exit 1; }; %s < %s`,
+			file, cfg.AuthLog, loginLines, file)
+	}
+	output, err := runner.Run(cmd)
+	if err != nil {
+		return nil, "", err
+	}
+	connection, rest, _ := strings.Cut(output, "\n")
+	self, _, _ := strings.Cut(connection, " ")
+	return parsers.ParseLogins(rest, loc, time.Now()), self, nil
+}
+
+// loginsSince is where the next read of a node's logins starts: at the
+// newest login read so far, at most a window back.
+func loginsSince(state *nodeState, now time.Time) time.Time {
+	if start := now.Add(-loginWindow); state.loginsNewest.Before(start) {
+		return start
+	}
+	return state.loginsNewest
+}
+
+// trackLogins adds the unexpected logins of a poll to the node's window
+// and sets status.Logins from it.
+func (c *Collector) trackLogins(state *nodeState, status *Status, now time.Time) {
+	if state.loginSources == nil {
+		state.loginSources = make(map[string]*LoginSource)
+	}
+	for _, login := range status.logins {
+		if !login.Time.After(state.loginsNewest) {
+			// read in the previous poll already
+			continue
+		}
+		if login.Source == status.loginSelf || c.Logins.Expected(login.Source) {
+			continue
+		}
+		source, ok := state.loginSources[login.Source]
+		if !ok {
+			source = &LoginSource{Address: login.Source, First: login.Time}
+			state.loginSources[login.Source] = source
+		}
+		if login.Accepted {
+			source.Accepted++
+		} else {
+			source.Failed++
+		}
+		if !slices.Contains(source.Users, login.User) {
+			source.Users = append(source.Users, login.User)
+		}
+		source.Last = login.Time
+	}
+	for _, login := range status.logins {
+		if login.Time.After(state.loginsNewest) {
+			state.loginsNewest = login.Time
+		}
+	}
+
+	logins := &Logins{}
+	for address, source := range state.loginSources {
+		if now.Sub(source.Last) > loginWindow {
+			delete(state.loginSources, address)
+			continue
+		}
+		if source.Accepted > 0 {
+			copied := *source
+			copied.Users = slices.Clone(source.Users)
+			logins.New = append(logins.New, copied)
+		}
+		if source.Failed > 0 {
+			logins.Failed += source.Failed
+			logins.FailedSources++
+		}
+	}
+	sort.Slice(logins.New, func(i, j int) bool { return logins.New[i].Last.After(logins.New[j].Last) })
+	status.Logins = logins
+}
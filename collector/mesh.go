@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// meshTools are the programs of the mesh check.
+var meshTools = []string{"ping"}
+
+// MeshTarget is a node of the mesh that another one pings.
+type MeshTarget struct {
+	// Name is the node's name as shown, Address where it is pinged.
+	Name    string
+	Address string
+}
+
+// MeshPing is how well a node reached another one of the mesh.
+type MeshPing struct {
+	Target string
+	parsers.Ping
+	// Error is why the target couldn't be pinged, e.g. because the
+	// node can't resolve its name.
+	Error string `json:",omitempty"`
+}
+
+// meshTargets returns the other nodes of the mesh node pings, none if
+// it isn't in the mesh.
+func (c *Collector) meshTargets(node config.Node) []MeshTarget {
+	if c.Mesh == nil {
+		return nil
+	}
+	members := c.Mesh.Members(c.nodes)
+	var targets []MeshTarget
+	in := false
+	for _, member := range members {
+		if member.DisplayName() == node.DisplayName() {
+			in = true
+			continue
+		}
+		targets = append(targets, MeshTarget{Name: member.DisplayName(), Address: c.Mesh.Address(member)})
+	}
+	if !in {
+		return nil
+	}
+	return targets
+}
+
+// pingMesh pings the targets from the node, all at once so the check
+// takes about as long as a single ping.
+func pingMesh(runner transport.Runner, profile profiles.Profile, targets []MeshTarget) ([]MeshPing, error) {
+	if profile.PingCommand == "" {
+		return nil, fmt.Errorf("the mesh check is not available on %s nodes", profile.Name)
+	}
+	var cmd strings.Builder
+	for _, target := range targets {
+		ping := fmt.Sprintf(profile.PingCommand, transport.ShellQuote(target.Address))
+		fmt.Fprintf(&cmd, `(out=$(%s 2>&1); printf '## %%s\n%%s\n' %s "$out") & `, ping, transport.ShellQuote(target.Name))
+	}
+	cmd.WriteString("wait")
+	output, err := runner.Run(cmd.String())
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make(map[string]string)
+	var name string
+	for _, line := range strings.Split(output, "\n") {
+		if after, ok := strings.CutPrefix(line, "## "); ok {
+			name = after
+			continue
+		}
+		outputs[name] += line + "\n"
+	}
+	pings := make([]MeshPing, len(targets))
+	for i, target := range targets {
+		pings[i].Target = target.Name
+		out, ok := outputs[target.Name]
+		if !ok {
+			pings[i].Error = "no answer from ping"
+			continue
+		}
+		ping, err := parsers.ParsePing(out)
+		if err != nil {
+			pings[i].Error = pingError(out, err).Error()
+			continue
+		}
+		pings[i].Ping = ping
+	}
+	return pings, nil
+}
+
+// pingError is what ping said went wrong, e.g. "ping: db1: Name or
+// service not known", or err if it said nothing.
+func pingError(output string, err error) error {
+	if line, _, _ := strings.Cut(strings.TrimSpace(output), "\n"); line != "" && !strings.Contains(line, "PING") {
+		return errors.New(line)
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+const (
+	// metricsTimeout bounds a scrape through the tunnel.
+	metricsTimeout = 10 * time.Second
+	// maxMetricsSize is the most of an endpoint's answer that is read.
+	maxMetricsSize = 4 * 1024 * 1024
+)
+
+// scrapeMetrics fetches the node's local Prometheus endpoint through
+// the connection and keeps the metrics cfg shows.
+func scrapeMetrics(conn transport.Conn, cfg config.Metrics) ([]parsers.Metric, error) {
+	tunnel, ok := conn.(transport.Tunnel)
+	if !ok {
+		return nil, errors.New("the connection can't forward ports")
+	}
+	if cfg.Port <= 0 {
+		return nil, errors.New("metrics needs the port of the endpoint")
+	}
+	client := &http.Client{
+		Timeout: metricsTimeout,
+		Transport: &http.Transport{
+			// the tunnel closes with the connection after the poll
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return tunnel.DialNode(addr)
+			},
+		},
+	}
+	url := "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.Port)) + cfg.MetricsPath()
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics endpoint answered %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetricsSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape metrics: %w", err)
+	}
+	all, err := parsers.ParsePrometheus(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	var shown []parsers.Metric
+	for _, m := range all {
+		if len(cfg.Show) > 0 && slices.Contains(cfg.Show, m.Name) ||
+			len(cfg.Show) == 0 && !strings.HasSuffix(m.Name, "_bucket") {
+			shown = append(shown, m)
+		}
+	}
+	return shown, nil
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// Snapshot is the result of polling a single node once. Index is the
+// position of the node in the configured node list. Status is only valid
+// when Err is nil.
+type Snapshot struct {
+	Index  int
+	Node   config.Node
+	Status Status
+	Err    error
+	Time   time.Time
+}
+
+// snapshotBuffer is the channel buffer given to each registered sink.
+const snapshotBuffer = 64
+
+// Sink receives every snapshot published to the pipeline. The TUI is one
+// sink; exporters, history, alerting or webhooks plug in the same way
+// without the polling code having to know about them.
+type Sink interface {
+	Consume(snapshot Snapshot)
+}
+
+// Pipeline fans snapshots out from the collectors to every subscriber.
+type Pipeline struct {
+	fanout fanout[Snapshot]
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Subscribe returns a channel that receives every snapshot published from
+// now on. Subscribers must keep reading, a full buffer blocks the
+// collectors.
+func (p *Pipeline) Subscribe(buffer int) <-chan Snapshot {
+	return p.fanout.subscribe(buffer)
+}
+
+// Register subscribes a sink to the pipeline. Each sink is fed from its
+// own goroutine, so a slow sink only delays the others once its buffer
+// is full.
+func (p *Pipeline) Register(sink Sink) {
+	snapshots := p.Subscribe(snapshotBuffer)
+	go func() {
+		for snapshot := range snapshots {
+			sink.Consume(snapshot)
+		}
+	}()
+}
+
+// Publish delivers a snapshot to all current subscribers.
+func (p *Pipeline) Publish(snapshot Snapshot) {
+	p.fanout.publish(snapshot)
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// resolveInterval is how often the address of a node configured by
+// hostname is looked up again.
+const resolveInterval = 5 * time.Minute
+
+// resolve returns the address to dial for a node. Hostnames are looked
+// up again every resolveInterval and a changed address is remembered in
+// the node state. If a lookup fails the last known address is kept.
+func resolve(state *nodeState, node config.Node, now time.Time) (string, error) {
+	if net.ParseIP(node.IP) != nil {
+		return node.IP, nil
+	}
+	if state.address != "" && now.Sub(state.resolved) < resolveInterval {
+		return state.address, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupHost(ctx, node.IP)
+	if err != nil || len(addresses) == 0 {
+		if state.address != "" {
+			return state.address, nil
+		}
+		return "", fmt.Errorf("failed to resolve %s: %w", node.IP, err)
+	}
+	state.resolved = now
+
+	// round robin records list the same addresses in another order
+	if slices.Contains(addresses, state.address) {
+		return state.address, nil
+	}
+	if state.address != "" {
+		state.previousAddress = state.address
+		state.addressChanged = now
+	}
+	state.address = addresses[0]
+	return state.address, nil
+}
@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// States of a Signal.
+const (
+	SignalOK       = "ok"
+	SignalWarning  = "warning"
+	SignalCritical = "critical"
+)
+
+// Signal is the health of a node as another system reported it, e.g. a
+// backup script or a provider's status hook.
+type Signal struct {
+	// Name tells the signals of a node apart, e.g. backup.
+	Name string
+	// State is SignalOK, SignalWarning or SignalCritical.
+	State   string
+	Message string
+	// Reported is when the signal was received, Expires when it goes
+	// stale unless reported again, zero if it doesn't.
+	Reported time.Time
+	Expires  time.Time
+	// Stale is set if Expires passed when the signal was taken.
+	Stale bool
+}
+
+// maxSignals is how many signals a node keeps. Reports of further names
+// are refused, so a client can't grow the signals without bound.
+const maxSignals = 32
+
+// Signals are the latest signals reported for the nodes, by node name.
+// They are safe for concurrent use, reports come in from an HTTP
+// handler while the collector polls.
+type Signals struct {
+	mu     sync.Mutex
+	byNode map[string]map[string]Signal
+}
+
+func NewSignals() *Signals {
+	return &Signals{byNode: make(map[string]map[string]Signal)}
+}
+
+// Report replaces the node's signal of the same name. A node with
+// maxSignals signals takes no new names.
+func (s *Signals) Report(node string, signal Signal) error {
+	switch signal.State {
+	case SignalOK, SignalWarning, SignalCritical:
+	default:
+		return fmt.Errorf("unknown state %q, use %s, %s or %s", signal.State, SignalOK, SignalWarning, SignalCritical)
+	}
+	if signal.Name == "" {
+		return fmt.Errorf("a signal needs a name")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byNode[node] == nil {
+		s.byNode[node] = make(map[string]Signal)
+	}
+	signals := s.byNode[node]
+	if _, ok := signals[signal.Name]; !ok && len(signals) >= maxSignals {
+		return fmt.Errorf("%s has %d signals already, the most a node keeps", node, maxSignals)
+	}
+	signals[signal.Name] = signal
+	return nil
+}
+
+// For returns the node's signals by name, marked stale as of now.
+func (s *Signals) For(node string, now time.Time) []Signal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var signals []Signal
+	for _, signal := range s.byNode[node] {
+		signal.Stale = !signal.Expires.IsZero() && now.After(signal.Expires)
+		signals = append(signals, signal)
+	}
+	sort.Slice(signals, func(i, j int) bool { return signals[i].Name < signals[j].Name })
+	return signals
+}
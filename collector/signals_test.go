@@ -0,0 +1,31 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignalsReportCap(t *testing.T) {
+	s := NewSignals()
+	for i := 0; i < maxSignals; i++ {
+		if err := s.Report("fra-1", Signal{Name: fmt.Sprintf("job-%d", i), State: SignalOK}); err != nil {
+			t.Fatalf("Report(job-%d): %v", i, err)
+		}
+	}
+	err := s.Report("fra-1", Signal{Name: "one-more", State: SignalOK})
+	if err == nil || !strings.Contains(err.Error(), "the most a node keeps") {
+		t.Errorf("Report(one-more) = %v, want the cap refused", err)
+	}
+	// known names are still replaced, and other nodes have their own
+	if err := s.Report("fra-1", Signal{Name: "job-0", State: SignalCritical}); err != nil {
+		t.Errorf("Report(job-0) again: %v", err)
+	}
+	if err := s.Report("fra-2", Signal{Name: "one-more", State: SignalOK}); err != nil {
+		t.Errorf("Report for another node: %v", err)
+	}
+	if got := s.For("fra-1", time.Now()); len(got) != maxSignals {
+		t.Errorf("fra-1 has %d signals, want %d", len(got), maxSignals)
+	}
+}
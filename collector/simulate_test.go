@@ -0,0 +1,160 @@
+package collector_test
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/simulate"
+)
+
+// The simulated nodes are seeded by their names, so the first poll of a
+// fresh server is the same on every run: all four nodes are up, sim-03 is
+// reachable only through relays and sim-02 answers on its metrics port.
+
+// pollSimulated polls nodes simulated over SSH once, with alerts on
+// metrics over the given thresholds, and returns the snapshots by node
+// name and the engine fed by the poll's events.
+func pollSimulated(t *testing.T, edit func([]config.Node), thresholds config.Thresholds) (map[string]collector.Snapshot, *alert.Engine) {
+	t.Helper()
+	srv, err := simulate.Listen(4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	go srv.Serve()
+
+	// the server keeps serving its own copy of the nodes
+	nodes := slices.Clone(srv.Nodes)
+	if edit != nil {
+		edit(nodes)
+	}
+	pipeline := collector.NewPipeline()
+	snaps := pipeline.Subscribe(len(nodes))
+	c := collector.New(nodes, nil, pipeline)
+	c.Thresholds = thresholds
+	engine := alert.NewEngine()
+	c.Events().Register(engine)
+
+	c.PollOnce()
+	got := make(map[string]collector.Snapshot)
+	for range nodes {
+		select {
+		case s := <-snaps:
+			got[s.Node.Name] = s
+		case <-time.After(10 * time.Second):
+			t.Fatalf("got %d of %d snapshots", len(got), len(nodes))
+		}
+	}
+	return got, engine
+}
+
+// waitAlerts returns the keys of the engine's active alerts once it has at
+// least want of them, as events reach it concurrently.
+func waitAlerts(t *testing.T, engine *alert.Engine, want int) []string {
+	t.Helper()
+	var keys []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		keys = keys[:0]
+		for _, a := range engine.Active() {
+			keys = append(keys, a.Key)
+		}
+		if len(keys) >= want {
+			break
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestPollSimulated(t *testing.T) {
+	snaps, engine := pollSimulated(t, nil, config.Thresholds{})
+	if len(snaps) != 4 {
+		t.Fatalf("got snapshots for %d nodes, want 4", len(snaps))
+	}
+	for name, s := range snaps {
+		if s.Err != nil {
+			t.Errorf("%s: %v", name, s.Err)
+			continue
+		}
+		status := s.Status
+		if len(status.Errors) > 0 {
+			t.Errorf("%s: failed sections %v", name, status.Errors)
+		}
+		if cpu := status.CPU.User + status.CPU.System; cpu <= 0 || cpu > 100 {
+			t.Errorf("%s: cpu %+v", name, status.CPU)
+		}
+		if m := status.Memory; m.TotalMB == 0 || m.UsedMB <= 0 || m.UsedMB > m.TotalMB {
+			t.Errorf("%s: memory %+v", name, m)
+		}
+		if len(status.Disks) != 1 || status.Disks[0].Mount != "/" || status.Disks[0].Used <= 0 ||
+			status.Disks[0].Used+status.Disks[0].Avail != status.Disks[0].Total {
+			t.Errorf("%s: disks %+v", name, status.Disks)
+		}
+		messages := make(map[string]bool)
+		for _, message := range status.Logs {
+			messages[message.Msg] = true
+		}
+		if !messages["peers in store"] || !messages["broadcasting self-test info"] {
+			t.Errorf("%s: logs %+v", name, status.Logs)
+		}
+		if c := status.Connectivity; c == nil || !c.Counted || c.Direct+c.Relayed == 0 {
+			t.Errorf("%s: connectivity %+v", name, c)
+		}
+	}
+	if c := snaps["sim-03"].Status.Connectivity; c == nil || c.Reachability != "private" || c.Direct != 0 {
+		t.Errorf("sim-03: connectivity %+v, want private and relayed only", c)
+	}
+	if len(snaps["sim-02"].Status.Metrics) == 0 {
+		t.Errorf("sim-02: no samples from its metrics port")
+	}
+
+	want := []string{"sim-03/network.relayed"}
+	if got := waitAlerts(t, engine, len(want)); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("active alerts %v, want %v", got, want)
+	}
+}
+
+func TestPollSimulatedFailures(t *testing.T) {
+	snaps, engine := pollSimulated(t, func(nodes []config.Node) {
+		// the darwin commands are unknown to the simulator, or print what
+		// the darwin parsers can't read
+		nodes[3].OS = "darwin"
+		nodes[1].Metrics = &config.Metrics{Port: 9999}
+	}, config.Thresholds{CPUPercent: 1})
+
+	sim04 := snaps["sim-04"]
+	if sim04.Err != nil {
+		t.Fatalf("sim-04: %v", sim04.Err)
+	}
+	for _, section := range []string{collector.SectionCPU, collector.SectionMemory} {
+		if sim04.Status.Errors[section] == nil {
+			t.Errorf("sim-04: no %s error in %v", section, sim04.Status.Errors)
+		}
+	}
+	// the rest of the node is still collected
+	if len(sim04.Status.Disks) == 0 {
+		t.Errorf("sim-04: no disks")
+	}
+	if err := snaps["sim-02"].Status.Errors[collector.SectionMetrics]; err == nil || !strings.Contains(err.Error(), "9999") {
+		t.Errorf("sim-02: metrics error %v, want one naming port 9999", err)
+	}
+
+	want := []string{
+		"sim-01/cpu", "sim-02/cpu", "sim-03/cpu", "sim-03/network.relayed", "sim-04/poll.partial",
+	}
+	got := waitAlerts(t, engine, len(want))
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("active alerts %v, want %v", got, want)
+	}
+	for _, a := range engine.Active() {
+		if a.Key == "sim-04/poll.partial" && !strings.Contains(a.Message, "cpu, memory") {
+			t.Errorf("poll.partial message %q, want it to name cpu and memory", a.Message)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/readers"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// piTools are the programs of the Raspberry Pi check.
+var piTools = []string{"vcgencmd"}
+
+// checkTools looks for the programs a poll of the node needs and returns
+// the missing ones. Missing stats programs fail the poll with their
+// names instead of a command error every poll, missing log, Pi, mesh,
+// audit or key check programs only skip their section (see missingFor),
+// missing compression programs the compression.
+func checkTools(runner transport.Runner, profile profiles.Profile, opts Options, node config.Node) ([]string, error) {
+	tools := slices.Clone(profile.Tools)
+	for _, tool := range readers.Tools(opts.Reader) {
+		if !slices.Contains(tools, tool) && node.Collects(SectionLogs) {
+			tools = append(tools, tool)
+		}
+	}
+	if node.RaspberryPi && node.Collects(SectionPi) {
+		tools = append(tools, piTools...)
+	}
+	if len(opts.Mesh) > 0 {
+		tools = append(tools, meshTools...)
+	}
+	if opts.Audit != nil {
+		tools = append(tools, auditPrograms(*opts.Audit)...)
+	}
+	if opts.KeyCheck != nil {
+		tools = append(tools, keyPrograms(profile)...)
+	}
+	if opts.LowBandwidth && profile.Name != profiles.Windows {
+		tools = append(tools, transport.CompressTools...)
+	}
+
+	output, err := runner.Run(fmt.Sprintf(`for tool in %s; do command -v "$tool" >/dev/null 2>&1 || echo "$tool"; done`,
+		strings.Join(tools, " ")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for required programs: %w", err)
+	}
+	missing := strings.Fields(output)
+
+	if core := missingFor(missing, profile.Tools); len(core) > 0 {
+		return missing, fmt.Errorf("missing on the node: %s", strings.Join(core, ", "))
+	}
+	return missing, nil
+}
+
+// missingFor returns which of tools are missing.
+func missingFor(missing, tools []string) []string {
+	var result []string
+	for _, tool := range tools {
+		if slices.Contains(missing, tool) {
+			result = append(result, tool)
+		}
+	}
+	return result
+}
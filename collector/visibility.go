@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"errors"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/explorer"
+	"github.com/fid1699/q-monitor-cli/parsers"
+)
+
+// Visibility is what the explorer said about a node the last time it
+// was asked.
+type Visibility struct {
+	PeerID  string
+	Visible bool
+	Checked time.Time
+}
+
+// checkVisibility asks the explorer whether the network sees the node,
+// at most once per explorer interval. Polls in between carry the last
+// answer, or the last error.
+func (c *Collector) checkVisibility(state *nodeState, node config.Node, status *Status) {
+	if id := logPeerID(status.Logs); id != "" {
+		state.peerID = id
+	}
+	peerID := node.PeerID
+	if peerID == "" {
+		peerID = state.peerID
+	}
+	if peerID == "" {
+		status.setError(SectionExplorer, errors.New("no peer ID logged yet, set peer_id on the node"))
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(state.explorerChecked) >= c.Explorer.Interval() || peerID != state.explorerPeerID {
+		visible, err := explorer.Visible(*c.Explorer, peerID)
+		state.explorerChecked, state.explorerPeerID, state.explorerErr = now, peerID, err
+		if err == nil {
+			state.visibility = &Visibility{PeerID: peerID, Visible: visible, Checked: now}
+		}
+	}
+	if state.explorerErr != nil {
+		status.setError(SectionExplorer, state.explorerErr)
+		return
+	}
+	status.Visibility = state.visibility
+}
+
+// logPeerID returns the peer ID a node logged, empty if none of the
+// messages has one.
+func logPeerID(messages []parsers.LogMessage) string {
+	for _, message := range messages {
+		if id, ok := message.Fields["peer_id"].(string); ok && id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// trackConnectivity carries what the node's logs told about its
+// connectivity over to the polls whose logs don't tell it again.
+func trackConnectivity(state *nodeState, status *Status) {
+	if status.connectivity != nil {
+		if state.connectivity == nil {
+			state.connectivity = &parsers.Connectivity{}
+		}
+		state.connectivity.Update(*status.connectivity)
+	}
+	if state.connectivity != nil {
+		connectivity := *state.connectivity
+		status.Connectivity = &connectivity
+	}
+}
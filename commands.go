@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// commands are the subcommands, run as `q-monitor <name> [args]`. Without
+// one the monitor itself starts.
+var commands = map[string]func(args []string) error{
+	"import":            runImport,
+	"config":            runConfig,
+	"demo":              runDemo,
+	"watch":             runWatch,
+	"provision":         runProvision,
+	"permissions":       runPermissions,
+	"alert":             runAlert,
+	"availability":      runAvailability,
+	"serve":             runServe,
+	"cert":              runCert,
+	"get":               runGet,
+	"grafana-dashboard": runGrafanaDashboard,
+}
+
+// runCommand runs the subcommand named by the first argument. ok is false
+// when there is none and the monitor should start.
+func runCommand(args []string) (ok bool, err error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return false, nil
+	}
+	command, found := commands[args[0]]
+	if !found {
+		return true, fmt.Errorf("unknown command %q, available: %s", args[0], strings.Join(commandNames(), ", "))
+	}
+	return true, command(args[1:])
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exitError is a subcommand error with an exit code of its own, for
+// scripts telling failures apart.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string { return e.err.Error() }
+
+func (e exitError) Unwrap() error { return e.err }
+
+// fail prints a subcommand error the way flag does for usage errors, and
+// exits with its code, 1 unless it has one.
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "q-monitor: %v\n", err)
+	var exit exitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.code)
+	}
+	os.Exit(1)
+}
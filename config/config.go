@@ -0,0 +1,1188 @@
+// Package config loads the node list the monitor polls.
+//
+// Load reads a Config from its JSON file, with the defaults and
+// templates of its nodes applied and the secrets it references read;
+// Save and Write store it again, references kept. A Node is how to
+// reach a host and what to poll on it, the rest of Config configures
+// the optional parts of the monitor, e.g. Thresholds, Telegram or
+// Server, each nil or zero when off. Config.Redact returns a copy safe
+// to share.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Node struct {
+	// Name is shown instead of the address when set.
+	Name string `json:"name,omitempty"`
+	// IP is the node's address, a hostname works too.
+	IP string `json:"ip"`
+	// Port is the SSH port, DefaultPort if zero.
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// KeyFile is a private key to log in with, tried before Password. A
+	// leading ~/ is the user's home directory.
+	KeyFile string   `json:"key_file,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// Messages overrides the watched log messages for this node.
+	Messages []string `json:"messages,omitempty"`
+	// LogFormat is how the node writes its logs: json (the default),
+	// logfmt or regex. LogPattern is the regular expression used by the
+	// regex format, with named groups and at least a "msg" group.
+	LogFormat  string `json:"log_format,omitempty"`
+	LogPattern string `json:"log_pattern,omitempty"`
+	// OS selects the command set used for stats: linux, windows or darwin.
+	// Empty (or "auto") detects it on first connect.
+	OS string `json:"os,omitempty"`
+	// Service is the name of the Q service, DefaultService if empty.
+	Service string `json:"service,omitempty"`
+	// LogReader is where the node's logs are read from: service (the
+	// systemd journal of Service, the default) or tmux (TmuxPane).
+	LogReader string `json:"log_reader,omitempty"`
+	TmuxPane  string `json:"tmux_pane,omitempty"`
+	// Mounts are the paths whose filesystems are checked for free space,
+	// DefaultMounts if empty. Windows nodes report all local drives.
+	Mounts []string `json:"mounts,omitempty"`
+	// RaspberryPi enables the vcgencmd throttling and temperature check.
+	RaspberryPi bool `json:"raspberry_pi,omitempty"`
+	// Proxmox is set for nodes running as a Proxmox VE guest.
+	Proxmox *Proxmox `json:"proxmox,omitempty"`
+	// Metrics is set for nodes serving Prometheus metrics on their own
+	// localhost, which are scraped through the SSH connection.
+	Metrics *Metrics `json:"metrics,omitempty"`
+	// AuditPorts replace the audit's ports for this node, e.g. for a
+	// prover that serves nothing but SSH.
+	AuditPorts []string `json:"audit_ports,omitempty"`
+	// KeyFiles replace the key check's files for this node, e.g. for a
+	// node installed somewhere else.
+	KeyFiles []string `json:"key_files,omitempty"`
+	// Checks are the operator's own checks run on the node every poll,
+	// POSIX nodes only.
+	Checks []Check `json:"checks,omitempty"`
+	// Disable are the collectors not run on the node, see Collectors and
+	// CollectorAliases, e.g. for an account that may not read the
+	// journal.
+	Disable []string `json:"disable,omitempty"`
+	// RunAs runs collectors as other users of the node, by collector,
+	// e.g. {"logs": "quil"} reads the journal as the service's user.
+	// RunAsWith is how the monitor user becomes them: SwitchSudo (the
+	// default) or SwitchSu. Both only apply to POSIX nodes.
+	RunAs     map[string]string `json:"run_as,omitempty"`
+	RunAsWith string            `json:"run_as_with,omitempty"`
+	// CommandPrefix is put in front of every command run on the node,
+	// e.g. "nice -n 19", "doas" or "chroot /srv/q". Env sets variables
+	// for them. Both only apply to POSIX nodes.
+	CommandPrefix string            `json:"command_prefix,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+	// Shell is the kind of the node's login shell: ShellPOSIX, ShellFish
+	// or ShellCsh. Empty detects it on first connect.
+	Shell string `json:"shell,omitempty"`
+	// TimeZone is the node's time zone, an IANA name like Europe/Berlin.
+	// Log timestamps without an offset are read in it. Empty detects the
+	// current offset on first connect (POSIX nodes only).
+	TimeZone string `json:"timezone,omitempty"`
+	// Probe is how the node's reachability is checked before polling:
+	// ProbeTCP (the default), ProbeICMP or ProbeNone.
+	Probe string `json:"probe,omitempty"`
+	// Maintenance marks a node that is being worked on, so it is shown
+	// as such and doesn't fire alerts.
+	Maintenance bool `json:"maintenance,omitempty"`
+	// Timeouts replace the config's timeouts that they set for this
+	// node, e.g. for a slow Raspberry Pi.
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+	// Group puts the node in a section of the grid that can be
+	// collapsed to one line, e.g. a datacenter or an owner.
+	Group string `json:"group,omitempty"`
+	// Bootstrap makes this a bootstrap peer node: instead of logging in
+	// anywhere the monitor host dials the peer at this multiaddr, e.g.
+	// /dns/bootstrap.example/udp/8336/quic-v1/p2p/Qm..., and reports
+	// whether it answers and how fast.
+	Bootstrap string `json:"bootstrap,omitempty"`
+	// PeerID is the node's libp2p peer ID for the explorer check. Empty
+	// takes it from the peer_id field of the node's logs.
+	PeerID string `json:"peer_id,omitempty"`
+	// RewardPerDay is the QUIL the node earns per day for the earnings
+	// estimate, Earnings.RewardPerDay if zero.
+	RewardPerDay float64 `json:"reward_per_day,omitempty"`
+	// Template names the entry of the config's templates the node's
+	// unset fields come from, on top of the defaults.
+	Template string `json:"template,omitempty"`
+}
+
+// Proxmox locates a node's VM on its hypervisor. The API token only needs
+// the PVEAuditor role.
+type Proxmox struct {
+	URL         string `json:"url"`
+	TokenID     string `json:"token_id"`
+	TokenSecret string `json:"token_secret"`
+	Node        string `json:"node"`
+	VMID        int    `json:"vmid"`
+	// Insecure skips verification of the API's TLS certificate.
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// Metrics is a Prometheus endpoint a node only serves on its localhost,
+// reached through an SSH port forward so it needn't be exposed.
+type Metrics struct {
+	// Port is the port of the endpoint on the node.
+	Port int `json:"port"`
+	// Path is the endpoint's path, DefaultMetricsPath if empty.
+	Path string `json:"path,omitempty"`
+	// Show are the names of the metrics shown, with all their labels.
+	// Empty shows every metric but histogram buckets.
+	Show []string `json:"show,omitempty"`
+}
+
+// Check is a command of the operator's that checks something on the
+// node the monitor doesn't know, e.g. a local load balancer. It exits
+// like a Nagios plugin: 0 is ok, 1 a warning and anything else
+// critical, and the first line it prints is shown, less any
+// performance data after a "|".
+type Check struct {
+	// Name tells the node's checks apart, e.g. haproxy.
+	Name string `json:"name"`
+	// Command is run with sh on the node, behind its command prefix.
+	Command string `json:"command"`
+	// User runs the check as another user of the node, instead of the
+	// one the node's RunAs names for checks, if any.
+	User string `json:"user,omitempty"`
+}
+
+// ValidateChecks checks the node's checks have distinct names and a
+// command.
+func (n Node) ValidateChecks() error {
+	names := make(map[string]bool)
+	for _, check := range n.Checks {
+		switch {
+		case check.Name == "":
+			return fmt.Errorf("node %s has a check without a name", n.DisplayName())
+		case names[check.Name]:
+			return fmt.Errorf("node %s has two checks named %s", n.DisplayName(), check.Name)
+		case check.Command == "":
+			return fmt.Errorf("check %s of node %s has no command", check.Name, n.DisplayName())
+		}
+		names[check.Name] = true
+	}
+	return nil
+}
+
+// CollectorMesh is the collector pinging the other nodes of the mesh.
+const CollectorMesh = "mesh"
+
+// Collectors are what a node can disable, see Node.Disable. All but
+// CollectorMesh are named like the panel section showing them. The
+// sections read from the logs, network and connectivity, go with logs,
+// and external signals and explorer visibility don't run on the node.
+var Collectors = []string{
+	SectionCPU, SectionMemory, SectionStorage, SectionService, SectionLogs,
+	SectionPi, SectionProxmox, SectionMetrics, CollectorMesh, SectionAudit,
+	SectionLogins, SectionKeys, SectionChecks,
+}
+
+// CollectorAliases are the other names Node.Disable takes, by the
+// collectors they stand for: disk for storage, and security for the
+// security checks.
+var CollectorAliases = map[string][]string{
+	"disk":     {SectionStorage},
+	"security": {SectionAudit, SectionLogins, SectionKeys},
+}
+
+// Disabled returns the collectors the node disables, its aliases
+// resolved, see Disable.
+func (n Node) Disabled() []string {
+	var disabled []string
+	for _, name := range n.Disable {
+		if collectors, ok := CollectorAliases[name]; ok {
+			disabled = append(disabled, collectors...)
+		} else {
+			disabled = append(disabled, name)
+		}
+	}
+	return disabled
+}
+
+// Collects reports whether the node runs a collector, see Disable.
+func (n Node) Collects(collector string) bool {
+	return !slices.Contains(n.Disabled(), collector)
+}
+
+// ValidateDisable checks the node only disables collectors that exist.
+func (n Node) ValidateDisable() error {
+	for _, name := range n.Disable {
+		if _, ok := CollectorAliases[name]; !ok && !slices.Contains(Collectors, name) {
+			return fmt.Errorf("node %s disables unknown collector %q, use one of %s, or disk or security", n.DisplayName(), name, strings.Join(Collectors, ", "))
+		}
+	}
+	return nil
+}
+
+// Ways of running commands as another user, see Node.RunAsWith.
+// SwitchSudo runs sudo -n, which fails rather than asks for a password;
+// SwitchSu needs a monitor user su lets switch without one, e.g. root.
+const (
+	SwitchSudo = "sudo"
+	SwitchSu   = "su"
+)
+
+// User returns the user the node runs a collector as, empty for the
+// monitor user.
+func (n Node) User(collector string) string {
+	return n.RunAs[collector]
+}
+
+// ValidateRunAs checks the node only runs collectors as other users
+// that run commands, and knows how to switch to them.
+func (n Node) ValidateRunAs() error {
+	if w := n.RunAsWith; w != "" && w != SwitchSudo && w != SwitchSu {
+		return fmt.Errorf("node %s has unknown run_as_with %q, use %s or %s", n.DisplayName(), w, SwitchSudo, SwitchSu)
+	}
+	for collector, user := range n.RunAs {
+		switch {
+		case !slices.Contains(Collectors, collector):
+			return fmt.Errorf("node %s runs unknown collector %q as %s, use one of %s", n.DisplayName(), collector, user, strings.Join(Collectors, ", "))
+		case collector == SectionMetrics:
+			return fmt.Errorf("node %s runs the metrics as %s, but they are scraped through the connection, not by a command", n.DisplayName(), user)
+		case collector == SectionProxmox:
+			return fmt.Errorf("node %s runs proxmox as %s, but its hypervisor is asked from the monitor host, not by a command", n.DisplayName(), user)
+		case user == "":
+			return fmt.Errorf("node %s runs %s as an empty user", n.DisplayName(), collector)
+		}
+	}
+	return nil
+}
+
+// DefaultMetricsPath is where Prometheus endpoints usually serve.
+const DefaultMetricsPath = "/metrics"
+
+// MetricsPath returns the endpoint's path.
+func (m Metrics) MetricsPath() string {
+	if m.Path == "" {
+		return DefaultMetricsPath
+	}
+	return m.Path
+}
+
+// DefaultService is the service name Q is usually installed under.
+const DefaultService = "ceremonyclient"
+
+// DefaultMounts are the paths checked for free space on nodes that don't
+// set their own.
+var DefaultMounts = []string{"/"}
+
+// DefaultPort is the SSH port used for nodes that don't set one.
+const DefaultPort = 22
+
+// Login shell kinds, see Node.Shell.
+const (
+	ShellPOSIX = "posix"
+	ShellFish  = "fish"
+	ShellCsh   = "csh"
+)
+
+// Reachability probes a node can use.
+const (
+	ProbeTCP  = "tcp"
+	ProbeICMP = "icmp"
+	ProbeNone = "none"
+)
+
+// DisplayName returns the node's name, or its address if it has none.
+func (n Node) DisplayName() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	if n.IP == "" {
+		return n.Bootstrap
+	}
+	return n.IP
+}
+
+// SSHPort returns the node's SSH port.
+func (n Node) SSHPort() int {
+	if n.Port != 0 {
+		return n.Port
+	}
+	return DefaultPort
+}
+
+// StorageMounts returns the paths whose free space is checked.
+func (n Node) StorageMounts() []string {
+	if len(n.Mounts) > 0 {
+		return n.Mounts
+	}
+	return DefaultMounts
+}
+
+// ServiceName returns the node's service name.
+func (n Node) ServiceName() string {
+	if n.Service != "" {
+		return n.Service
+	}
+	return DefaultService
+}
+
+// Metric names thresholds can be set for.
+const (
+	MetricCPU    = "cpu"
+	MetricMemory = "memory"
+)
+
+// Thresholds are alerting limits in percent. A zero value disables the
+// check.
+type Thresholds struct {
+	CPUPercent    float64 `json:"cpu_percent,omitempty"`
+	MemoryPercent float64 `json:"memory_percent,omitempty"`
+}
+
+// For returns the threshold configured for a metric.
+func (t Thresholds) For(metric string) float64 {
+	switch metric {
+	case MetricCPU:
+		return t.CPUPercent
+	case MetricMemory:
+		return t.MemoryPercent
+	default:
+		return 0
+	}
+}
+
+// DefaultMessages are the log messages "we care about" when the config
+// doesn't list any. I care about the three types included below, but
+// you can add your own with "messages" (globally or per node) if you
+// want anything else to show up.
+var DefaultMessages = []string{
+	"connecting to bootstrap",
+	"broadcasting self-test info",
+	"peers in store",
+}
+
+// Display holds settings for how the monitor renders values.
+type Display struct {
+	// Locale picks the decimal and thousands separators, e.g. "en"
+	// (1,234.5) or "de" (1.234,5). Defaults to "en".
+	Locale string `json:"locale,omitempty"`
+	// Layout is how the TUI lays out the nodes: LayoutGrid, LayoutList
+	// or LayoutTable. Empty picks one by the node count and the size of
+	// the terminal.
+	Layout string `json:"layout,omitempty"`
+	// Compact shows every node in two lines, for fleets that don't fit
+	// the panel grid. It is the same as Layout LayoutList.
+	Compact bool `json:"compact,omitempty"`
+	// Accessible spells out node health as OK/WARN/CRIT instead of
+	// signaling it with color alone.
+	Accessible bool `json:"accessible,omitempty"`
+	// ASCII uses plain characters for the status glyphs, for terminals
+	// and fonts without them.
+	ASCII bool `json:"ascii,omitempty"`
+	// Sections are the panel sections to show below the node's name, in
+	// this order. Empty shows DefaultSections.
+	Sections []string `json:"sections,omitempty"`
+	// Times picks the clock panels show times in: TimesMonitor (the
+	// default) or TimesNode, each node's own time zone.
+	Times string `json:"times,omitempty"`
+	// ErrorLength is how many characters of an error and its hint
+	// panels show before cutting them short. Zero uses
+	// DefaultErrorLength, negative values never cut errors.
+	ErrorLength int `json:"error_length,omitempty"`
+	// Wrap wraps long panel lines instead of cutting them off at the
+	// panel's border.
+	Wrap bool `json:"wrap,omitempty"`
+	// Keys are the key bindings of the TUI.
+	Keys Keys `json:"keys"`
+}
+
+// Keys picks the TUI's key bindings.
+type Keys struct {
+	// Preset is the set of bindings to start from: KeysDefault or
+	// KeysVi.
+	Preset string `json:"preset,omitempty"`
+	// Bind binds actions to other keys than the preset does, by the
+	// action's name, e.g. {"stats": "S", "query": ": /"}. Keys are
+	// separated by spaces and are a character, two characters typed
+	// one after the other, or a key name such as Tab or Ctrl-R; see
+	// the README for the actions.
+	Bind map[string]string `json:"bind,omitempty"`
+}
+
+// Key binding presets, see Keys.Preset. KeysVi moves the focus with
+// hjkl and to the first and last node with gg and G.
+const (
+	KeysDefault = "default"
+	KeysVi      = "vi"
+)
+
+// DefaultErrorLength is how much of an error panels show by default,
+// about a line of a panel on a laptop screen.
+const DefaultErrorLength = 80
+
+// ErrorCutoff returns how many characters of an error panels show, zero
+// for all of them.
+func (d Display) ErrorCutoff() int {
+	switch {
+	case d.ErrorLength < 0:
+		return 0
+	case d.ErrorLength == 0:
+		return DefaultErrorLength
+	}
+	return d.ErrorLength
+}
+
+// Layouts of the TUI, see Display.Layout. LayoutGrid shows a panel per
+// node, LayoutList every node in two lines and LayoutTable in one, both
+// with the focused one in full below them.
+const (
+	LayoutGrid  = "grid"
+	LayoutList  = "list"
+	LayoutTable = "table"
+)
+
+// PanelLayout returns the layout the TUI starts with, empty to pick one
+// by the size of the fleet and the terminal.
+func (d Display) PanelLayout() string {
+	if d.Layout == "" && d.Compact {
+		return LayoutList
+	}
+	return d.Layout
+}
+
+// Clocks times can be shown in, see Display.Times.
+const (
+	TimesMonitor = "monitor"
+	TimesNode    = "node"
+)
+
+// Panel sections, see Display.Sections. SectionQueries are the queries
+// pinned to the panel.
+const (
+	SectionCPU      = "cpu"
+	SectionMemory   = "memory"
+	SectionStorage  = "storage"
+	SectionService  = "service"
+	SectionPi       = "pi"
+	SectionProxmox  = "proxmox"
+	SectionMetrics  = "metrics"
+	SectionAudit    = "audit"
+	SectionLogins   = "logins"
+	SectionKeys     = "keys"
+	SectionChecks   = "checks"
+	SectionExternal = "external"
+	SectionNetwork  = "network"
+	// SectionConnectivity is how the node reaches its peers, from its
+	// logs.
+	SectionConnectivity = "connectivity"
+	SectionLogs         = "logs"
+	SectionQueries      = "queries"
+)
+
+// DefaultSections are all panel sections in their default order.
+var DefaultSections = []string{
+	SectionCPU, SectionMemory, SectionStorage, SectionService, SectionPi,
+	SectionProxmox, SectionMetrics, SectionAudit, SectionLogins,
+	SectionKeys, SectionChecks, SectionExternal, SectionNetwork, SectionConnectivity,
+	SectionLogs, SectionQueries,
+}
+
+// PanelSections returns the sections panels show.
+func (d Display) PanelSections() []string {
+	if len(d.Sections) > 0 {
+		return d.Sections
+	}
+	return DefaultSections
+}
+
+// Validate checks the display settings that can't fall back to a
+// default.
+func (d Display) Validate() error {
+	if d.Times != "" && d.Times != TimesMonitor && d.Times != TimesNode {
+		return fmt.Errorf("unknown times %q, use %s or %s", d.Times, TimesMonitor, TimesNode)
+	}
+	if l := d.Layout; l != "" && l != LayoutGrid && l != LayoutList && l != LayoutTable {
+		return fmt.Errorf("unknown layout %q, use %s, %s or %s", l, LayoutGrid, LayoutList, LayoutTable)
+	}
+	if p := d.Keys.Preset; p != "" && p != KeysDefault && p != KeysVi {
+		return fmt.Errorf("unknown key preset %q, use %s or %s", p, KeysDefault, KeysVi)
+	}
+	for _, section := range d.Sections {
+		if !slices.Contains(DefaultSections, section) {
+			return fmt.Errorf("unknown panel section %q, available: %s", section, strings.Join(DefaultSections, ", "))
+		}
+	}
+	return nil
+}
+
+type Config struct {
+	Nodes []Node `json:"nodes"`
+	// Defaults are node fields every node starts from, and Templates
+	// named sets of them a node picks with its template, e.g. the user,
+	// key and log reader of a fleet of alike provers. The node's own
+	// fields win, maps like env are merged.
+	Defaults   json.RawMessage            `json:"defaults,omitempty"`
+	Templates  map[string]json.RawMessage `json:"templates,omitempty"`
+	Thresholds Thresholds                 `json:"thresholds"`
+	Display    Display                    `json:"display"`
+	SSH        SSHLimits                  `json:"ssh"`
+	Timeouts   Timeouts                   `json:"timeouts"`
+	Schedule   Schedule                   `json:"schedule"`
+	// Messages are the log messages watched on every node that doesn't
+	// set its own.
+	Messages []string `json:"messages,omitempty"`
+	// Syslog forwards node events to syslog when set.
+	Syslog *Syslog `json:"syslog,omitempty"`
+	// Loki receives the watched log lines of every poll when set.
+	Loki *Loki `json:"loki,omitempty"`
+	// Snapshots writes the state of every node to disk regularly when
+	// set.
+	Snapshots *Snapshots `json:"snapshots,omitempty"`
+	// Explorer is asked whether the network sees the nodes when set.
+	Explorer *Explorer `json:"explorer,omitempty"`
+	// Earnings shows an estimate of the fleet's daily earnings when set.
+	Earnings *Earnings `json:"earnings,omitempty"`
+	// Telegram gets the alerts that fire and resolve when set.
+	Telegram *Telegram `json:"telegram,omitempty"`
+	// Digest sends a summary of the fleet to telegram and syslog on a
+	// schedule when set.
+	Digest *Digest `json:"digest,omitempty"`
+	// Server secures `q-monitor serve` and the TUIs connecting to it
+	// when set.
+	Server *Server `json:"server,omitempty"`
+	// Mesh has the nodes ping each other for the latency matrix when
+	// set.
+	Mesh *Mesh `json:"mesh,omitempty"`
+	// Benchmark lets the TUI measure a node's bandwidth on demand when
+	// set.
+	Benchmark *Benchmark `json:"benchmark,omitempty"`
+	// Audit checks the nodes' open ports and firewall rules when set.
+	Audit *Audit `json:"audit,omitempty"`
+	// Logins watches the nodes' SSH logins when set.
+	Logins *Logins `json:"logins,omitempty"`
+	// KeyCheck checks the nodes' key files when set.
+	KeyCheck *KeyCheck `json:"key_check,omitempty"`
+	// Webhook takes reports of the nodes' health from other systems
+	// when set.
+	Webhook *Webhook `json:"webhook,omitempty"`
+	// LowBandwidth makes the polls frugal when set, for monitoring over
+	// a metered link.
+	LowBandwidth *LowBandwidth `json:"low_bandwidth,omitempty"`
+
+	// references are the values resolved on load, see interpolate.
+	references []reference
+}
+
+// SSHLimits keep the monitor's connections polite, so a restart against
+// a large fleet doesn't look like an attack to fail2ban or an IDS on the
+// nodes. Zero values don't limit.
+type SSHLimits struct {
+	// ConnectionsPerSecond caps how many connections are opened per
+	// second across all nodes.
+	ConnectionsPerSecond float64 `json:"connections_per_second,omitempty"`
+	// RetryBudget is how many times per hour a failed connection to a
+	// node is retried. Failed logins are never retried, they are what
+	// intrusion prevention counts.
+	RetryBudget int `json:"retry_budget,omitempty"`
+}
+
+// Timeouts bound the polls of a node. A command still running after
+// CommandSeconds is given up on and leaves its section out, the poll
+// goes on with the next one. A poll still running after PollSeconds is
+// cut short: its connection is closed, and it shows what it collected
+// so far. Zero values use the defaults.
+type Timeouts struct {
+	CommandSeconds int `json:"command_seconds,omitempty"`
+	PollSeconds    int `json:"poll_seconds,omitempty"`
+}
+
+// DefaultCommandTimeout is how long a command may run when the config
+// doesn't say. Polls are budgeted the poll interval by default.
+const DefaultCommandTimeout = 30 * time.Second
+
+// For returns the timeouts of node, its own where it sets them.
+func (t Timeouts) For(node Node) Timeouts {
+	if node.Timeouts != nil && node.Timeouts.CommandSeconds > 0 {
+		t.CommandSeconds = node.Timeouts.CommandSeconds
+	}
+	if node.Timeouts != nil && node.Timeouts.PollSeconds > 0 {
+		t.PollSeconds = node.Timeouts.PollSeconds
+	}
+	return t
+}
+
+// Command returns how long a command may run.
+func (t Timeouts) Command() time.Duration {
+	if t.CommandSeconds > 0 {
+		return time.Duration(t.CommandSeconds) * time.Second
+	}
+	return DefaultCommandTimeout
+}
+
+// Poll returns how long a poll may run, interval unless set.
+func (t Timeouts) Poll(interval time.Duration) time.Duration {
+	if t.PollSeconds > 0 {
+		return time.Duration(t.PollSeconds) * time.Second
+	}
+	return interval
+}
+
+// Schedule is when in the poll interval the nodes are polled. By
+// default their polls are spread evenly across it, so the monitor host
+// and shared bastions see one connection at a time instead of all of
+// them at once.
+type Schedule struct {
+	// AllAtOnce polls every node at the start of the interval.
+	AllAtOnce bool `json:"all_at_once,omitempty"`
+	// JitterSeconds delays every poll by a random time of up to this
+	// long, at most half the interval, so fleets of monitors don't poll
+	// in step.
+	JitterSeconds int `json:"jitter_seconds,omitempty"`
+}
+
+// Jitter returns the longest delay of a poll.
+func (s Schedule) Jitter(interval time.Duration) time.Duration {
+	return min(time.Duration(s.JitterSeconds)*time.Second, interval/2)
+}
+
+// Syslog is a syslog endpoint node events are forwarded to.
+type Syslog struct {
+	// Network is udp (the default), tcp or unix. Without an Address the
+	// local syslog daemon is used.
+	Network string `json:"network,omitempty"`
+	Address string `json:"address,omitempty"`
+	// Tag is the program name of the messages, "q-monitor" if empty.
+	Tag string `json:"tag,omitempty"`
+	// Facility is e.g. daemon or local0, user if empty.
+	Facility string `json:"facility,omitempty"`
+}
+
+// Loki is a Grafana Loki endpoint the watched log lines are pushed to.
+type Loki struct {
+	// URL is the base URL of Loki, e.g. http://loki:3100.
+	URL string `json:"url"`
+	// TenantID is sent as X-Scope-OrgID to multi tenant setups.
+	TenantID string `json:"tenant_id,omitempty"`
+	// Username and Password are sent as basic auth, e.g. a Grafana Cloud
+	// user and API token.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Labels are added to every stream next to the node's, e.g.
+	// {"job": "q-monitor"}.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Snapshot formats, see Snapshots.
+const (
+	SnapshotJSON = "json"
+	SnapshotCSV  = "csv"
+)
+
+// Snapshots are files with the last poll of every node, written to Dir
+// every interval, so there is a recent picture of the fleet on disk
+// after a crash or power loss.
+type Snapshots struct {
+	// Dir is where the files go, created if needed.
+	Dir string `json:"dir"`
+	// IntervalMinutes is the time between two files, 10 if zero.
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+	// Format is SnapshotJSON, the default, or SnapshotCSV.
+	Format string `json:"format,omitempty"`
+	// Keep is how many files are kept, the oldest are removed first.
+	// 144 if zero, a day at the default interval.
+	Keep int `json:"keep,omitempty"`
+}
+
+// Interval returns the time between two snapshot files.
+func (s Snapshots) Interval() time.Duration {
+	if s.IntervalMinutes > 0 {
+		return time.Duration(s.IntervalMinutes) * time.Minute
+	}
+	return 10 * time.Minute
+}
+
+// Files returns how many snapshot files are kept.
+func (s Snapshots) Files() int {
+	if s.Keep > 0 {
+		return s.Keep
+	}
+	return 144
+}
+
+// Validate checks the directory is set and the format known.
+func (s Snapshots) Validate() error {
+	if s.Dir == "" {
+		return errors.New("snapshots.dir is needed")
+	}
+	switch s.Format {
+	case "", SnapshotJSON, SnapshotCSV:
+		return nil
+	default:
+		return fmt.Errorf("unknown snapshots.format %q, use %s or %s", s.Format, SnapshotJSON, SnapshotCSV)
+	}
+}
+
+// LowBandwidth trades detail for traffic, for an operator watching the
+// fleet over LTE or a metered VPN: the nodes are polled less often,
+// their logs are read in a smaller window and sent compressed, and the
+// mesh, audit and metrics checks are skipped.
+type LowBandwidth struct {
+	// IntervalSeconds is the time between two polls, 300 if zero.
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+	// LogLines is how many lines of a node's logs are read, 20 if zero.
+	LogLines int `json:"log_lines,omitempty"`
+}
+
+// Interval returns the time between two polls.
+func (l LowBandwidth) Interval() time.Duration {
+	if l.IntervalSeconds > 0 {
+		return time.Duration(l.IntervalSeconds) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// Lines returns how many lines of a node's logs are read.
+func (l LowBandwidth) Lines() int {
+	if l.LogLines > 0 {
+		return l.LogLines
+	}
+	return 20
+}
+
+// Server is how a monitor server and the TUIs connecting to it prove
+// who they are: a shared bearer token, mutual TLS with certificates of
+// a common CA, or both. The same settings are used on either end, with
+// each end's own certificate.
+type Server struct {
+	// Token, if set, is sent by TUIs and required by the server.
+	Token string `json:"token,omitempty"`
+	// CA is the PEM file of the CA the other end's certificate must be
+	// signed by. Setting it enables TLS.
+	CA string `json:"ca,omitempty"`
+	// Cert and Key are the PEM files of this end's certificate, see
+	// `q-monitor cert`.
+	Cert string `json:"cert,omitempty"`
+	Key  string `json:"key,omitempty"`
+}
+
+// Mesh is a set of nodes that ping each other every poll, to show the
+// latency and loss between them, e.g. to find peering problems within a
+// cluster.
+type Mesh struct {
+	// Nodes are the names of the nodes in the mesh, as shown. Empty
+	// takes every node but bootstrap peers.
+	Nodes []string `json:"nodes,omitempty"`
+	// Addresses are what the other nodes ping a node at by its name,
+	// e.g. its address on a private network. Nodes not listed are
+	// pinged at their IP.
+	Addresses map[string]string `json:"addresses,omitempty"`
+}
+
+// Members returns the nodes in the mesh, in config order.
+func (m Mesh) Members(nodes []Node) []Node {
+	var members []Node
+	for _, node := range nodes {
+		if node.Bootstrap != "" {
+			continue
+		}
+		if len(m.Nodes) == 0 || slices.Contains(m.Nodes, node.DisplayName()) {
+			members = append(members, node)
+		}
+	}
+	return members
+}
+
+// Address returns the address node is pinged at.
+func (m Mesh) Address(node Node) string {
+	if address, ok := m.Addresses[node.DisplayName()]; ok {
+		return address
+	}
+	return node.IP
+}
+
+// Audit is the expected baseline of what nodes expose: the ports they
+// listen on other than on loopback, and the rules of their firewall.
+// Anything else listening is flagged, e.g. a debug endpoint left open
+// on a prover that holds keys.
+type Audit struct {
+	// Ports are the ports nodes may listen on, as port/protocol like
+	// 22/tcp or 8336/udp, or a port for both protocols. A node's
+	// audit_ports replace them.
+	Ports []string `json:"ports,omitempty"`
+	// Rules are what the nft ruleset must have, each matched as part of
+	// a line, e.g. "tcp dport 22 accept" or "policy drop". Empty skips
+	// the firewall, which is read through sudo, see q-monitor
+	// permissions.
+	Rules []string `json:"rules,omitempty"`
+	// IntervalMinutes is how often a node is audited,
+	// DefaultAuditInterval if zero.
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+}
+
+// DefaultAuditInterval is how often a node is audited when the config
+// doesn't say.
+const DefaultAuditInterval = 30 * time.Minute
+
+// Interval returns the time between two audits of a node.
+func (a Audit) Interval() time.Duration {
+	if a.IntervalMinutes > 0 {
+		return time.Duration(a.IntervalMinutes) * time.Minute
+	}
+	return DefaultAuditInterval
+}
+
+// ExpectedPorts returns the ports node may listen on.
+func (a Audit) ExpectedPorts(node Node) []string {
+	if len(node.AuditPorts) > 0 {
+		return node.AuditPorts
+	}
+	return a.Ports
+}
+
+// Logins watches the SSH logins of the nodes for sources the config
+// doesn't expect. The monitor's own address is always expected.
+type Logins struct {
+	// Sources are the addresses or CIDR ranges logins are expected
+	// from, e.g. a VPN's 10.8.0.0/24.
+	Sources []string `json:"sources,omitempty"`
+	// AuthLog is the sshd log file read instead of the journal, e.g.
+	// /var/log/auth.log on nodes without journald.
+	AuthLog string `json:"auth_log,omitempty"`
+}
+
+// Validate checks that the sources are addresses or CIDR ranges.
+func (l Logins) Validate() error {
+	for _, source := range l.Sources {
+		if _, _, err := net.ParseCIDR(source); err != nil && net.ParseIP(source) == nil {
+			return fmt.Errorf("logins.sources: %q is neither an address nor a CIDR range", source)
+		}
+	}
+	return nil
+}
+
+// Expected reports whether logins from address are expected.
+func (l Logins) Expected(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, source := range l.Sources {
+		if _, network, err := net.ParseCIDR(source); err == nil && network.Contains(ip) {
+			return true
+		}
+		if expected := net.ParseIP(source); expected != nil && expected.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyCheck checks the files holding a node's keys: that only their owner
+// can access them, that the owner stays the same, and that their
+// contents don't change, which would replace the node's identity.
+type KeyCheck struct {
+	// Files are the files and directories checked, relative to the
+	// monitor user's home unless absolute, DefaultKeyFiles if empty. A
+	// node's key_files replace them.
+	Files []string `json:"files,omitempty"`
+	// Owner is the user the files must belong to. If empty, whoever
+	// owned a file on the first check has to keep it.
+	Owner string `json:"owner,omitempty"`
+}
+
+// DefaultKeyFiles are the key files of a node installed in the monitor
+// user's home.
+var DefaultKeyFiles = []string{
+	"ceremonyclient/node/.config",
+	"ceremonyclient/node/.config/keys.yml",
+	"ceremonyclient/node/.config/config.yml",
+}
+
+// NodeFiles returns the files checked on node.
+func (k KeyCheck) NodeFiles(node Node) []string {
+	if len(node.KeyFiles) > 0 {
+		return node.KeyFiles
+	}
+	if len(k.Files) > 0 {
+		return k.Files
+	}
+	return DefaultKeyFiles
+}
+
+// DefaultWebhookAddr is the address the webhook listens on unless
+// configured otherwise.
+const DefaultWebhookAddr = "localhost:9371"
+
+// Webhook is an HTTP endpoint other systems, like a node's own scripts
+// or a provider's status hooks, report the health of nodes to, for
+// what the monitor can't poll itself.
+type Webhook struct {
+	// Listen is the address to listen on, DefaultWebhookAddr if empty.
+	Listen string `json:"listen,omitempty"`
+	// Token, if set, is required as a bearer token. Addresses other
+	// hosts can reach need one, and TLS to keep it from going over plain
+	// HTTP.
+	Token string `json:"token,omitempty"`
+	// Cert and Key are the PEM files of the webhook's TLS certificate.
+	// Setting them serves HTTPS.
+	Cert string `json:"cert,omitempty"`
+	Key  string `json:"key,omitempty"`
+	// Insecure lets the token go over plain HTTP to other hosts, e.g.
+	// within a private network.
+	Insecure bool `json:"insecure,omitempty"`
+	// ExpireSeconds is how long a report holds unless it says itself,
+	// after that it counts as stale. Zero keeps reports until replaced.
+	ExpireSeconds int `json:"expire_seconds,omitempty"`
+}
+
+// Addr returns the address the webhook listens on.
+func (w Webhook) Addr() string {
+	if w.Listen != "" {
+		return w.Listen
+	}
+	return DefaultWebhookAddr
+}
+
+// Expire returns how long a report without a ttl of its own holds, zero
+// for as long as it isn't replaced.
+func (w Webhook) Expire() time.Duration {
+	return time.Duration(w.ExpireSeconds) * time.Second
+}
+
+// Benchmark is how a node's bandwidth is measured, e.g. to check what
+// its provider promises when its peer count sags.
+type Benchmark struct {
+	// Tool is BenchmarkIperf3, the default, or BenchmarkSpeedtest.
+	Tool string `json:"tool,omitempty"`
+	// Server is the iperf3 server measured against, which iperf3
+	// needs, or the ID of a speedtest server; speedtest-cli picks the
+	// closest without one.
+	Server string `json:"server,omitempty"`
+	// Port is the iperf3 server's port, its default if zero.
+	Port int `json:"port,omitempty"`
+	// Seconds is how long iperf3 sends each way,
+	// DefaultBenchmarkSeconds if zero.
+	Seconds int `json:"seconds,omitempty"`
+}
+
+// Bandwidth benchmark tools, see Benchmark.Tool.
+const (
+	BenchmarkIperf3    = "iperf3"
+	BenchmarkSpeedtest = "speedtest"
+)
+
+// DefaultBenchmarkSeconds is how long iperf3 sends each way when the
+// config doesn't say.
+const DefaultBenchmarkSeconds = 5
+
+// ToolName returns the tool the bandwidth is measured with.
+func (b Benchmark) ToolName() string {
+	if b.Tool == "" {
+		return BenchmarkIperf3
+	}
+	return b.Tool
+}
+
+// Duration returns how long iperf3 sends each way.
+func (b Benchmark) Duration() time.Duration {
+	if b.Seconds > 0 {
+		return time.Duration(b.Seconds) * time.Second
+	}
+	return DefaultBenchmarkSeconds * time.Second
+}
+
+// Validate checks that the benchmark can run.
+func (b Benchmark) Validate() error {
+	switch b.ToolName() {
+	case BenchmarkIperf3:
+		if b.Server == "" {
+			return fmt.Errorf("benchmark.server is needed for %s", BenchmarkIperf3)
+		}
+	case BenchmarkSpeedtest:
+	default:
+		return fmt.Errorf("unknown benchmark tool %q, use %s or %s", b.Tool, BenchmarkIperf3, BenchmarkSpeedtest)
+	}
+	return nil
+}
+
+// Telegram is a Telegram bot that sends alerts to a chat.
+type Telegram struct {
+	// Token is the bot's API token from @BotFather.
+	Token string `json:"token"`
+	// ChatID is the chat, group or channel the alerts go to; the bot
+	// has to be a member.
+	ChatID string `json:"chat_id"`
+	// APIURL is the Bot API to use, e.g. a local Bot API server. Empty
+	// uses Telegram's.
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// Digest is a summary of the fleet since the digest before, sent to
+// the alert destinations on a schedule: uptime, peers, the alerts that
+// fired and the disks that fill up soon.
+type Digest struct {
+	// Cron is when the digest is sent, a cron expression in the
+	// monitor's local time, e.g. "0 9 * * *" for every morning at 9.
+	Cron string `json:"cron"`
+}
+
+// Explorer is a public Quilibrium explorer or RPC endpoint that knows
+// which peers the network sees.
+type Explorer struct {
+	// URL is requested for each node with {peer_id} replaced by the
+	// node's peer ID, e.g. https://explorer.example/api/peers/{peer_id}.
+	// The endpoint answers 200 for peers it sees and 404 otherwise.
+	URL string `json:"url"`
+	// IntervalMinutes is how often a node is checked,
+	// DefaultExplorerInterval if zero. Public endpoints are rate
+	// limited, so this is much longer than a poll.
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+}
+
+// DefaultExplorerInterval is how often a node is checked with the
+// explorer when the config doesn't say.
+const DefaultExplorerInterval = 15 * time.Minute
+
+// Interval returns the time between two checks of a node.
+func (e Explorer) Interval() time.Duration {
+	if e.IntervalMinutes > 0 {
+		return time.Duration(e.IntervalMinutes) * time.Minute
+	}
+	return DefaultExplorerInterval
+}
+
+// Earnings estimates what the fleet earns per day from the reward rates
+// of its nodes and the token price. It is only as good as the rates.
+type Earnings struct {
+	// PriceURL answers with the token price in JSON, e.g.
+	// https://api.coingecko.com/api/v3/simple/price?ids=wrapped-quil&vs_currencies=usd.
+	// PriceField is the dotted path of the price in the answer, e.g.
+	// wrapped-quil.usd.
+	PriceURL   string `json:"price_url"`
+	PriceField string `json:"price_field"`
+	// Currency is shown with the price, DefaultCurrency if empty.
+	Currency string `json:"currency,omitempty"`
+	// RewardPerDay is the QUIL per day of nodes that don't set their own.
+	RewardPerDay float64 `json:"reward_per_day,omitempty"`
+	// IntervalMinutes is how often the price is fetched,
+	// DefaultPriceInterval if zero.
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+}
+
+// DefaultCurrency labels prices when the config doesn't say.
+const DefaultCurrency = "USD"
+
+// DefaultPriceInterval is how often the token price is fetched when the
+// config doesn't say.
+const DefaultPriceInterval = 10 * time.Minute
+
+// Interval returns the time between two price fetches.
+func (e Earnings) Interval() time.Duration {
+	if e.IntervalMinutes > 0 {
+		return time.Duration(e.IntervalMinutes) * time.Minute
+	}
+	return DefaultPriceInterval
+}
+
+// Reward returns the QUIL per day a node earns, zero for bootstrap peer
+// nodes.
+func (e Earnings) Reward(node Node) float64 {
+	if node.Bootstrap != "" {
+		return 0
+	}
+	if node.RewardPerDay > 0 {
+		return node.RewardPerDay
+	}
+	return e.RewardPerDay
+}
+
+// CurrencyName returns the currency prices are shown in.
+func (e Earnings) CurrencyName() string {
+	if e.Currency != "" {
+		return e.Currency
+	}
+	return DefaultCurrency
+}
+
+// WatchedMessages returns the log messages to watch for a node: its own
+// list, the global one, or the defaults.
+func WatchedMessages(node Node, global []string) []string {
+	if len(node.Messages) > 0 {
+		return node.Messages
+	}
+	if len(global) > 0 {
+		return global
+	}
+	return DefaultMessages
+}
+
+// Load loads node information from a config file
+// the expected format matches the above structs, i.e.
+// {"nodes": [{"ip":"...","username":"...","password":"..."},{...}]}
+//
+// do not use root as the user for this script. It's best to have a
+// dedicated monitor user with the minimum required perms.
+func Load(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if err := config.expand(data); err != nil {
+		return nil, err
+	}
+	if err := config.interpolate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Save writes a config file, readable by the owner only since it holds
+// credentials.
+func Save(filename string, config *Config) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, config); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Write encodes a config as indented JSON, with the ${NAME} and file:
+// references it was loaded with. With defaults or templates the nodes
+// only get the fields that differ from them, see foldNode.
+func Write(w io.Writer, config *Config) error {
+	config = config.withReferences()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if len(config.Defaults) == 0 && len(config.Templates) == 0 {
+		return encoder.Encode(config)
+	}
+
+	folded := struct {
+		Nodes []json.RawMessage `json:"nodes"`
+		*Config
+	}{Config: config}
+	for _, node := range config.Nodes {
+		entry, err := config.foldNode(node)
+		if err != nil {
+			return fmt.Errorf("%s: %w", node.DisplayName(), err)
+		}
+		folded.Nodes = append(folded.Nodes, entry)
+	}
+	return encoder.Encode(folded)
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateDisable(t *testing.T) {
+	tests := []struct {
+		disable []string
+		err     string
+	}{
+		{nil, ""},
+		{Collectors, ""},
+		{[]string{SectionProxmox, SectionLogs}, ""},
+		{[]string{"disk", "security"}, ""},
+		{[]string{"gpu"}, `disables unknown collector "gpu"`},
+		{[]string{"version"}, `disables unknown collector "version"`},
+	}
+	for _, tt := range tests {
+		err := Node{IP: "a", Disable: tt.disable}.ValidateDisable()
+		if (tt.err == "") != (err == nil) || err != nil && !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ValidateDisable(%v) = %v, want %q", tt.disable, err, tt.err)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	tests := []struct {
+		disable []string
+		want    []string
+	}{
+		{nil, nil},
+		{[]string{SectionLogs}, []string{SectionLogs}},
+		{[]string{"disk", SectionPi}, []string{SectionStorage, SectionPi}},
+		{[]string{"security"}, []string{SectionAudit, SectionLogins, SectionKeys}},
+	}
+	for _, tt := range tests {
+		node := Node{IP: "a", Disable: tt.disable}
+		if got := node.Disabled(); !slices.Equal(got, tt.want) {
+			t.Errorf("Disabled(%v) = %v, want %v", tt.disable, got, tt.want)
+		}
+		for _, collector := range tt.want {
+			if node.Collects(collector) {
+				t.Errorf("Node{Disable: %v}.Collects(%q) = true", tt.disable, collector)
+			}
+		}
+	}
+	if !(Node{Disable: []string{"security"}}).Collects(SectionChecks) {
+		t.Errorf("security disables the operator's checks")
+	}
+}
+
+func TestValidateRunAs(t *testing.T) {
+	tests := []struct {
+		runAs map[string]string
+		with  string
+		err   string
+	}{
+		{map[string]string{SectionLogs: "quil"}, "", ""},
+		{map[string]string{SectionLogs: "quil"}, SwitchSu, ""},
+		{map[string]string{SectionLogs: "quil"}, "doas", `unknown run_as_with "doas"`},
+		{map[string]string{"gpu": "quil"}, "", `unknown collector "gpu"`},
+		{map[string]string{SectionMetrics: "quil"}, "", "scraped through the connection"},
+		{map[string]string{SectionProxmox: "quil"}, "", "asked from the monitor host"},
+		{map[string]string{SectionLogs: ""}, "", "as an empty user"},
+	}
+	for _, tt := range tests {
+		err := Node{IP: "a", RunAs: tt.runAs, RunAsWith: tt.with}.ValidateRunAs()
+		if (tt.err == "") != (err == nil) || err != nil && !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ValidateRunAs(%v, %q) = %v, want %q", tt.runAs, tt.with, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// envReference matches ${NAME} in a value, and $${NAME} which stays as
+// it is but for the first $.
+var envReference = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// filePrefix starts a value that is read from the file it names.
+const filePrefix = "file:"
+
+// reference is a value of the config file that was resolved on load.
+type reference struct {
+	field    string
+	original string
+	resolved string
+}
+
+// secretField is a config value references are resolved in, by its
+// place in the file.
+type secretField struct {
+	name  string
+	value *string
+}
+
+// secretFields are the values references are resolved in: credentials
+// and endpoints, which are what shouldn't be in the file. Other values,
+// e.g. a node's env, are passed on as they are written.
+func (c *Config) secretFields() []secretField {
+	var fields []secretField
+	add := func(name string, value *string) {
+		fields = append(fields, secretField{name: name, value: value})
+	}
+	for i := range c.Nodes {
+		node := &c.Nodes[i]
+		prefix := fmt.Sprintf("nodes[%d].", i)
+		add(prefix+"password", &node.Password)
+		if node.Proxmox != nil {
+			add(prefix+"proxmox.url", &node.Proxmox.URL)
+			add(prefix+"proxmox.token_id", &node.Proxmox.TokenID)
+			add(prefix+"proxmox.token_secret", &node.Proxmox.TokenSecret)
+		}
+	}
+	if c.Syslog != nil {
+		add("syslog.address", &c.Syslog.Address)
+	}
+	if c.Loki != nil {
+		add("loki.url", &c.Loki.URL)
+		add("loki.username", &c.Loki.Username)
+		add("loki.password", &c.Loki.Password)
+	}
+	if c.Explorer != nil {
+		add("explorer.url", &c.Explorer.URL)
+	}
+	if c.Earnings != nil {
+		add("earnings.price_url", &c.Earnings.PriceURL)
+	}
+	if c.Telegram != nil {
+		add("telegram.token", &c.Telegram.Token)
+		add("telegram.chat_id", &c.Telegram.ChatID)
+		add("telegram.api_url", &c.Telegram.APIURL)
+	}
+	if c.Server != nil {
+		add("server.token", &c.Server.Token)
+	}
+	if c.Webhook != nil {
+		add("webhook.token", &c.Webhook.Token)
+	}
+	return fields
+}
+
+// interpolate resolves the references in the secret fields: ${NAME}
+// anywhere in a value is replaced by the environment variable, and a
+// value of file:path by the contents of the file, less the trailing
+// newline. The references are kept so Write puts them back.
+func (c *Config) interpolate() error {
+	for _, field := range c.secretFields() {
+		resolved, err := resolve(*field.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+		if resolved != *field.value {
+			c.references = append(c.references, reference{field: field.name, original: *field.value, resolved: resolved})
+			*field.value = resolved
+		}
+	}
+	return nil
+}
+
+func resolve(value string) (string, error) {
+	if path, ok := strings.CutPrefix(value, filePrefix); ok {
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			path = filepath.Join(home, rest)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	var err error
+	resolved := envReference.ReplaceAllStringFunc(value, func(match string) string {
+		groups := envReference.FindStringSubmatch(match)
+		if groups[1] != "" {
+			return match[1:]
+		}
+		env, ok := os.LookupEnv(groups[2])
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", groups[2])
+		}
+		return env
+	})
+	return resolved, err
+}
+
+// withReferences returns a copy of the config with the values resolved
+// on load put back as they were written, unless they changed since.
+func (c *Config) withReferences() *Config {
+	if len(c.references) == 0 {
+		return c
+	}
+	restored := *c
+	restored.references = nil
+	restored.Nodes = make([]Node, len(c.Nodes))
+	for i, node := range c.Nodes {
+		if node.Proxmox != nil {
+			proxmox := *node.Proxmox
+			node.Proxmox = &proxmox
+		}
+		restored.Nodes[i] = node
+	}
+	restored.Syslog = clone(c.Syslog)
+	restored.Loki = clone(c.Loki)
+	restored.Explorer = clone(c.Explorer)
+	restored.Earnings = clone(c.Earnings)
+	restored.Telegram = clone(c.Telegram)
+	restored.Server = clone(c.Server)
+	restored.Webhook = clone(c.Webhook)
+
+	fields := make(map[string]*string)
+	for _, field := range restored.secretFields() {
+		fields[field.name] = field.value
+	}
+	for _, ref := range c.references {
+		if value := fields[ref.field]; value != nil && *value == ref.resolved {
+			*value = ref.original
+		}
+	}
+	return &restored
+}
+
+func clone[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	copied := *v
+	return &copied
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("Q_TOKEN", "s3cret")
+	t.Setenv("Q_EMPTY", "")
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("from file\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{"plain", "plain", ""},
+		{"${Q_TOKEN}", "s3cret", ""},
+		{"Bearer ${Q_TOKEN}!", "Bearer s3cret!", ""},
+		{"${Q_TOKEN}${Q_TOKEN}", "s3cret" + "s3cret", ""},
+		{"${Q_EMPTY}", "", ""},
+		// $$ escapes a reference
+		{"$${Q_TOKEN}", "${Q_TOKEN}", ""},
+		// not references
+		{"$Q_TOKEN", "$Q_TOKEN", ""},
+		{"${1X}", "${1X}", ""},
+		{"file:" + secret, "from file", ""},
+		// file: only counts at the start
+		{"x file:" + secret, "x file:" + secret, ""},
+		{"${Q_UNSET_VARIABLE}", "", "environment variable Q_UNSET_VARIABLE is not set"},
+		{"file:" + filepath.Join(dir, "missing"), "", "no such file"},
+	}
+	for _, tt := range tests {
+		got, err := resolve(tt.value)
+		switch {
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("resolve(%q) error = %v, want %q", tt.value, err, tt.err)
+		case tt.err == "" && err != nil:
+			t.Errorf("resolve(%q) error = %v", tt.value, err)
+		case tt.err == "" && got != tt.want:
+			t.Errorf("resolve(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolateKeepsReferences(t *testing.T) {
+	t.Setenv("Q_PASSWORD", "hunter2")
+	t.Setenv("Q_TOKEN", "s3cret")
+	cfg, err := loadString(t, `{
+  "nodes": [{"ip": "a", "username": "u", "password": "${Q_PASSWORD}", "env": {"X": "${Q_TOKEN}"}}],
+  "server": {"token": "tok-${Q_TOKEN}"}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Nodes[0].Password != "hunter2" || cfg.Server.Token != "tok-s3cret" {
+		t.Errorf("resolved password %q and token %q", cfg.Nodes[0].Password, cfg.Server.Token)
+	}
+	// only secret fields are resolved
+	if got := cfg.Nodes[0].Env["X"]; got != "${Q_TOKEN}" {
+		t.Errorf("env resolved to %q", got)
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"password": "${Q_PASSWORD}"`, `"token": "tok-${Q_TOKEN}"`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("written config lacks %s:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "hunter2") {
+		t.Errorf("written config has the resolved password:\n%s", b.String())
+	}
+	// the loaded config keeps the resolved values
+	if cfg.Nodes[0].Password != "hunter2" {
+		t.Errorf("Write changed the config's password to %q", cfg.Nodes[0].Password)
+	}
+
+	// a value changed since load is written as it is now
+	cfg.Nodes[0].Password = "new"
+	b.Reset()
+	if err := Write(&b, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"password": "new"`) {
+		t.Errorf("written config lacks the changed password:\n%s", b.String())
+	}
+}
+
+func TestInterpolateErrorNamesField(t *testing.T) {
+	_, err := loadString(t, `{"nodes": [{"ip": "a"}, {"ip": "b", "password": "${Q_UNSET_VARIABLE}"}]}`)
+	if err == nil || !strings.HasPrefix(err.Error(), "nodes[1].password: ") {
+		t.Errorf("Load error = %v, want one for nodes[1].password", err)
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import "strings"
+
+// Redacted replaces secret values in place of which a shared config
+// keeps this placeholder.
+const Redacted = "<redacted>"
+
+// Redact returns a copy of the config with every secret (passwords, API
+// tokens, node env values) replaced by Redacted, safe to attach to bug
+// reports. Empty values stay empty so it's still visible which ones were
+// set. URLs lose their credentials and query strings. The copy is
+// expanded, since defaults and templates can hold secrets too.
+func (c *Config) Redact() *Config {
+	redacted := *c.Expanded()
+	redacted.Nodes = make([]Node, len(c.Nodes))
+	for i, node := range c.Nodes {
+		redacted.Nodes[i] = node.Redact()
+	}
+	if c.Loki != nil {
+		loki := *c.Loki
+		loki.URL = redactURL(loki.URL)
+		loki.Username = redact(loki.Username)
+		loki.Password = redact(loki.Password)
+		redacted.Loki = &loki
+	}
+	if c.Telegram != nil {
+		telegram := *c.Telegram
+		telegram.Token = redact(telegram.Token)
+		telegram.APIURL = redactURL(telegram.APIURL)
+		redacted.Telegram = &telegram
+	}
+	if c.Syslog != nil {
+		syslog := *c.Syslog
+		syslog.Address = redactURL(syslog.Address)
+		redacted.Syslog = &syslog
+	}
+	if c.Explorer != nil {
+		explorer := *c.Explorer
+		explorer.URL = redactURL(explorer.URL)
+		redacted.Explorer = &explorer
+	}
+	if c.Earnings != nil {
+		earnings := *c.Earnings
+		earnings.PriceURL = redactURL(earnings.PriceURL)
+		redacted.Earnings = &earnings
+	}
+	if c.Server != nil {
+		server := *c.Server
+		server.Token = redact(server.Token)
+		redacted.Server = &server
+	}
+	if c.Webhook != nil {
+		webhook := *c.Webhook
+		webhook.Token = redact(webhook.Token)
+		redacted.Webhook = &webhook
+	}
+	return &redacted
+}
+
+// Redact returns a copy of the node with its secrets replaced by
+// Redacted, its env values among them.
+func (n Node) Redact() Node {
+	n.Password = redact(n.Password)
+	if n.Env != nil {
+		env := make(map[string]string, len(n.Env))
+		for name, value := range n.Env {
+			env[name] = redact(value)
+		}
+		n.Env = env
+	}
+	if n.Proxmox != nil {
+		proxmox := *n.Proxmox
+		proxmox.URL = redactURL(proxmox.URL)
+		proxmox.TokenSecret = redact(proxmox.TokenSecret)
+		n.Proxmox = &proxmox
+	}
+	return n
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// redactURL drops the credentials and query string of a URL, where
+// tokens tend to be passed. It cuts the string rather than parse it,
+// which would escape placeholders like {peer_id}. Addresses that aren't
+// URLs, like a syslog host:port, are kept.
+func redactURL(raw string) string {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return raw
+	}
+	if end := strings.IndexAny(rest, "?#"); end >= 0 {
+		rest = rest[:end]
+	}
+	host := rest
+	if end := strings.IndexByte(rest, '/'); end >= 0 {
+		host = rest[:end]
+	}
+	if at := strings.LastIndexByte(host, '@'); at >= 0 {
+		rest = rest[at+1:]
+	}
+	return scheme + "://" + rest
+}
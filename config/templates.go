@@ -0,0 +1,174 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// expand decodes the config's nodes again, each on top of the defaults
+// and its template. data is the config file.
+func (c *Config) expand(data []byte) error {
+	if len(c.Defaults) == 0 && len(c.Templates) == 0 {
+		return nil
+	}
+	var raw struct {
+		Nodes []json.RawMessage `json:"nodes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for i, entry := range raw.Nodes {
+		node, err := c.expandNode(entry)
+		if err != nil {
+			return fmt.Errorf("nodes[%d]: %w", i, err)
+		}
+		c.Nodes[i] = node
+	}
+	return nil
+}
+
+// expandNode decodes a node's entry on top of the defaults and its
+// template, so fields it leaves out are theirs. Maps are merged, other
+// fields replaced.
+func (c *Config) expandNode(entry json.RawMessage) (Node, error) {
+	var node Node
+	if len(c.Defaults) > 0 {
+		if err := json.Unmarshal(c.Defaults, &node); err != nil {
+			return Node{}, fmt.Errorf("defaults: %w", err)
+		}
+	}
+	// the template is the node's own, or the defaults'
+	var picked struct {
+		Template *string `json:"template"`
+	}
+	if err := json.Unmarshal(entry, &picked); err != nil {
+		return Node{}, err
+	}
+	if picked.Template != nil {
+		node.Template = *picked.Template
+	}
+	if name := node.Template; name != "" {
+		template, ok := c.Templates[name]
+		if !ok {
+			return Node{}, fmt.Errorf("unknown template %q", name)
+		}
+		if err := json.Unmarshal(template, &node); err != nil {
+			return Node{}, fmt.Errorf("templates.%s: %w", name, err)
+		}
+		// templates don't pick other templates
+		node.Template = name
+	}
+	if err := json.Unmarshal(entry, &node); err != nil {
+		return Node{}, err
+	}
+	return node, nil
+}
+
+// nodeField is a field of Node as it is written to a config file.
+type nodeField struct {
+	name string
+	zero json.RawMessage
+}
+
+// nodeFields are Node's fields in their order.
+var nodeFields = func() []nodeField {
+	var fields []nodeField
+	t := reflect.TypeOf(Node{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		zero, _ := json.Marshal(reflect.Zero(t.Field(i).Type).Interface())
+		fields = append(fields, nodeField{name: name, zero: zero})
+	}
+	return fields
+}()
+
+// foldNode returns the entry a node is written as: the fields that
+// differ from what the defaults and its template give it. Fields it
+// cleared are written with their zero value, so they stay cleared.
+func (c *Config) foldNode(node Node) (json.RawMessage, error) {
+	picked, err := json.Marshal(struct {
+		Template string `json:"template"`
+	}{node.Template})
+	if err != nil {
+		return nil, err
+	}
+	base, err := c.expandNode(picked)
+	if err != nil {
+		return nil, err
+	}
+	own, err := fieldValues(node)
+	if err != nil {
+		return nil, err
+	}
+	inherited, err := fieldValues(base)
+	if err != nil {
+		return nil, err
+	}
+
+	var defaults struct {
+		Template string `json:"template"`
+	}
+	if len(c.Defaults) > 0 {
+		if err := json.Unmarshal(c.Defaults, &defaults); err != nil {
+			return nil, fmt.Errorf("defaults: %w", err)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for _, field := range nodeFields {
+		value, set := own[field.name]
+		_, wasSet := inherited[field.name]
+		switch {
+		case field.name == "template":
+			// written unless the defaults pick it anyway
+			if node.Template == defaults.Template {
+				continue
+			}
+			value, _ = json.Marshal(node.Template)
+		case set && bytes.Equal(value, inherited[field.name]):
+			continue
+		case !set && wasSet:
+			value = field.zero
+		case !set:
+			continue
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%q:%s", field.name, value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// fieldValues returns the fields a node is encoded with, by name.
+func fieldValues(node Node) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// Expanded returns a copy of the config without defaults and templates,
+// its nodes written out in full as the monitor uses them.
+func (c *Config) Expanded() *Config {
+	expanded := *c
+	expanded.Defaults = nil
+	expanded.Templates = nil
+	expanded.Nodes = make([]Node, len(c.Nodes))
+	for i, node := range c.Nodes {
+		node.Template = ""
+		expanded.Nodes[i] = node
+	}
+	return &expanded
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadString loads a config file with the given contents.
+func loadString(t *testing.T, data string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+const templatesConfig = `{
+  "defaults": {"username": "quil", "port": 2222, "env": {"A": "1"}},
+  "templates": {
+    "pi": {"raspberry_pi": true, "mounts": ["/", "/data"], "env": {"B": "2"}},
+    "box": {"username": "root", "port": 0}
+  },
+  "nodes": [
+    {"ip": "10.0.0.1"},
+    {"ip": "10.0.0.2", "template": "pi", "env": {"C": "3"}},
+    {"ip": "10.0.0.3", "template": "box", "mounts": ["/srv"]},
+    {"ip": "10.0.0.4", "template": "pi", "raspberry_pi": false, "username": "pi"}
+  ]
+}`
+
+func TestExpandNodes(t *testing.T) {
+	cfg, err := loadString(t, templatesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip       string
+		username string
+		port     int
+		pi       bool
+		mounts   []string
+		env      map[string]string
+		template string
+	}{
+		{"10.0.0.1", "quil", 2222, false, nil, map[string]string{"A": "1"}, ""},
+		// maps are merged, the node's keys over the template's over the
+		// defaults'
+		{"10.0.0.2", "quil", 2222, true, []string{"/", "/data"}, map[string]string{"A": "1", "B": "2", "C": "3"}, "pi"},
+		// a template may clear what the defaults set
+		{"10.0.0.3", "root", 0, false, []string{"/srv"}, map[string]string{"A": "1"}, "box"},
+		{"10.0.0.4", "pi", 2222, false, []string{"/", "/data"}, map[string]string{"A": "1", "B": "2"}, "pi"},
+	}
+	if len(cfg.Nodes) != len(tests) {
+		t.Fatalf("got %d nodes, want %d", len(cfg.Nodes), len(tests))
+	}
+	for i, tt := range tests {
+		node := cfg.Nodes[i]
+		if node.IP != tt.ip || node.Username != tt.username || node.Port != tt.port || node.RaspberryPi != tt.pi ||
+			!reflect.DeepEqual(node.Mounts, tt.mounts) || !reflect.DeepEqual(node.Env, tt.env) || node.Template != tt.template {
+			t.Errorf("nodes[%d] = %+v, want %+v", i, node, tt)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{`{"templates": {"pi": {}}, "nodes": [{"ip": "a", "template": "gpu"}]}`, `nodes[0]: unknown template "gpu"`},
+		{`{"defaults": {"template": "gpu"}, "templates": {"pi": {}}, "nodes": [{"ip": "a"}]}`, `nodes[0]: unknown template "gpu"`},
+		{`{"defaults": {"port": "x"}, "nodes": [{"ip": "a"}]}`, `nodes[0]: defaults:`},
+		{`{"templates": {"pi": {"port": "x"}}, "nodes": [{"ip": "a", "template": "pi"}]}`, `nodes[0]: templates.pi:`},
+	}
+	for _, tt := range tests {
+		_, err := loadString(t, tt.config)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%s) = %v, want an error with %q", tt.config, err, tt.want)
+		}
+	}
+}
+
+func TestFoldNode(t *testing.T) {
+	cfg, err := loadString(t, templatesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		edit func(*Node)
+		want string
+	}{
+		{func(*Node) {}, `{"ip":"10.0.0.1"}`},
+		{func(n *Node) { n.Name = "one" }, `{"name":"one","ip":"10.0.0.1"}`},
+		// a field cleared that the defaults set stays cleared
+		{func(n *Node) { n.Port = 0 }, `{"ip":"10.0.0.1","port":0}`},
+		// the env lacks the template's B, so it is written whole
+		{func(n *Node) { n.Template = "pi"; n.RaspberryPi = true; n.Mounts = []string{"/", "/data"} },
+			`{"ip":"10.0.0.1","env":{"A":"1"},"template":"pi"}`},
+		{func(n *Node) { n.Template = "box"; n.Username = "root"; n.Port = 0 }, `{"ip":"10.0.0.1","template":"box"}`},
+	}
+	for i, tt := range tests {
+		node := cfg.Nodes[0]
+		node.Env = map[string]string{"A": "1"}
+		tt.edit(&node)
+		got, err := cfg.foldNode(node)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("test %d: foldNode = %s, want %s", i, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFoldsRoundTrip(t *testing.T) {
+	cfg, err := loadString(t, templatesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := Write(&b, cfg); err != nil {
+		t.Fatal(err)
+	}
+	var written struct {
+		Nodes []map[string]json.RawMessage `json:"nodes"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &written); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := written.Nodes[0]["username"]; ok {
+		t.Errorf("nodes[0] written with the defaults' username: %s", b.String())
+	}
+	reloaded, err := loadString(t, b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.Nodes, cfg.Nodes) {
+		t.Errorf("reloaded nodes = %+v, want %+v", reloaded.Nodes, cfg.Nodes)
+	}
+}
+
+func TestExpanded(t *testing.T) {
+	cfg, err := loadString(t, templatesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded := cfg.Expanded()
+	if expanded.Defaults != nil || expanded.Templates != nil {
+		t.Errorf("Expanded kept the defaults or templates")
+	}
+	for i, node := range expanded.Nodes {
+		if node.Template != "" {
+			t.Errorf("nodes[%d] still has template %q", i, node.Template)
+		}
+	}
+	if cfg.Nodes[1].Template != "pi" {
+		t.Errorf("Expanded changed the original's nodes")
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// runConfig implements `q-monitor config export [--redact]`, printing the
+// config as the monitor understands it.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "export" {
+		return errors.New("usage: q-monitor config export [-config file] [--redact]")
+	}
+
+	flags := flag.NewFlagSet("config export", flag.ExitOnError)
+	configFile := flags.String("config", configFileName, "config `file` to export")
+	redact := flags.Bool("redact", false, "replace passwords, tokens and env values with "+config.Redacted+" and strip credentials from URLs")
+	flags.Parse(args[1:])
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	// templates are written out into the nodes
+	cfg = cfg.Expanded()
+	if *redact {
+		cfg = cfg.Redact()
+	}
+
+	return config.Write(os.Stdout, cfg)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/simulate"
+	"github.com/fid1699/q-monitor-cli/transport"
+	"github.com/fid1699/q-monitor-cli/ui"
+)
+
+// runDemo implements `q-monitor demo [--nodes N] [--serve]`: the TUI on
+// simulated nodes served by an SSH server in the process, on localhost,
+// so unlike --simulate the polls go through the SSH transport, the
+// readers and parsers as they do on a real fleet, and the config's
+// alerting and exports as well. With --serve it only serves the nodes
+// and prints a config to poll them with, for a monitor run from another
+// terminal or a working copy being developed.
+func runDemo(args []string) error {
+	flags := flag.NewFlagSet("demo", flag.ExitOnError)
+	n := flags.Int("nodes", 6, "serve `N` simulated nodes")
+	port := flags.Int("port", 0, "serve the nodes on the ports from `P` on, by default on free ones")
+	serve := flags.Bool("serve", false, "only serve the nodes and print the config of them, until interrupted")
+	flags.Parse(args)
+	if *n <= 0 {
+		return errors.New("--nodes has to be at least 1")
+	}
+
+	srv, err := simulate.Listen(*n, *port)
+	if err != nil {
+		return fmt.Errorf("failed to serve the demo nodes: %w", err)
+	}
+	defer srv.Close()
+	go srv.Serve()
+
+	if *serve {
+		if err := config.Write(os.Stdout, &config.Config{Nodes: srv.Nodes}); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "serving %d nodes on 127.0.0.1, password %q, until interrupted\n", len(srv.Nodes), simulate.ServerPassword)
+		return waitForInterrupt()
+	}
+
+	// the config is optional like with --simulate, which also keeps the
+	// demo's outages out of the availability log
+	cfg := loadConfig(*n)
+	cfg.Nodes = srv.Nodes
+	if err := ui.ValidateKeys(cfg.Display.Keys); err != nil {
+		return fmt.Errorf("in config: %w", err)
+	}
+	pipeline := collector.NewPipeline()
+	c, alerts, tracker := newCollector(cfg, pipeline, *n)
+	// polled over SSH in place of the simulated dialer
+	c.Dialer = transport.Limit(transport.SSH{}, cfg.SSH)
+
+	tui := pollingTUI(cfg, c, alerts, tracker)
+	pipeline.Register(tui)
+	go c.Run(context.Background())
+	return tui.Run()
+}
@@ -0,0 +1,145 @@
+package digest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// macros are the cron shorthands Parse knows.
+var macros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+}
+
+// field is the range of a cron field.
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [...]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Cron is a parsed cron expression: the minutes, hours, days of the
+// month, months and days of the week it matches.
+type Cron struct {
+	sets [len(fields)]uint64
+	// anyDay and anyWeekday are set for a * day of month and day of
+	// week, which cron treats differently when only one of them is.
+	anyDay     bool
+	anyWeekday bool
+}
+
+// ParseCron parses a cron expression of five fields, each a *, a number
+// or a range, lists of them, and steps like */15, or one of @hourly,
+// @daily and @weekly. Days of the week are 0 to 7, both Sunday.
+func ParseCron(expr string) (Cron, error) {
+	if macro, ok := macros[strings.TrimSpace(expr)]; ok {
+		expr = macro
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return Cron{}, fmt.Errorf("cron expression %q has %d fields, want minute, hour, day of month, month and day of week", expr, len(parts))
+	}
+	var c Cron
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return Cron{}, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		c.sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if c.sets[4]&(1<<7) != 0 {
+		c.sets[4] |= 1
+	}
+	c.anyDay, c.anyWeekday = parts[2] == "*", parts[4] == "*"
+	return c, nil
+}
+
+// parseField returns the values a field matches as bits.
+func parseField(part string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, stepPart, stepped := strings.Cut(item, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q in the %s", stepPart, f.name)
+			}
+			step = n
+		}
+		lo, hi := f.min, f.max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("bad %s %q", f.name, item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("bad %s %q", f.name, item)
+				}
+			} else if stepped {
+				// 5/15 is 5, 20, 35 and 50
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s %q is out of %d-%d", f.name, item, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (c Cron) has(i, v int) bool {
+	return c.sets[i]&(1<<v) != 0
+}
+
+// day reports whether the cron matches the day of t. Like cron, a day
+// matches either restricted field if both the day of the month and the
+// day of the week are.
+func (c Cron) day(t time.Time) bool {
+	monthDay, weekday := c.has(2, t.Day()), c.has(4, int(t.Weekday()))
+	if !c.anyDay && !c.anyWeekday {
+		return monthDay || weekday
+	}
+	return monthDay && weekday
+}
+
+// Next returns the first minute after after that the cron matches, in
+// after's location, or the zero time if none does within five years,
+// like the 31st of February.
+func (c Cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !c.has(3, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.day(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.has(1, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.has(0, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
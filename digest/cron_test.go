@@ -0,0 +1,97 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * *", "has 4 fields"},
+		{"* * * * * *", "has 6 fields"},
+		{"60 * * * *", "minute \"60\" is out of 0-59"},
+		{"* 24 * * *", "hour \"24\" is out of 0-23"},
+		{"* * 0 * *", "day of month \"0\" is out of 1-31"},
+		{"* * * 13 *", "month \"13\" is out of 1-12"},
+		{"* * * * 8", "day of week \"8\" is out of 0-7"},
+		{"5-1 * * * *", "minute \"5-1\" is out of 0-59"},
+		{"*/0 * * * *", "bad step \"0\" in the minute"},
+		{"*/x * * * *", "bad step \"x\" in the minute"},
+		{"a * * * *", "bad minute \"a\""},
+		{"1-b * * * *", "bad minute \"1-b\""},
+		{"@yearly", "has 1 fields"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCron(tt.expr)
+		if err == nil {
+			t.Errorf("ParseCron(%q) = nil error, want %q", tt.expr, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseCron(%q) = %q, want it to contain %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// a Wednesday
+	after := time.Date(2024, time.May, 15, 10, 7, 30, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", at(time.May, 15, 10, 8)},
+		{"*/15 * * * *", at(time.May, 15, 10, 15)},
+		{"5/20 * * * *", at(time.May, 15, 10, 25)},
+		{"0 * * * *", at(time.May, 15, 11, 0)},
+		{"@hourly", at(time.May, 15, 11, 0)},
+		{"0 8 * * *", at(time.May, 16, 8, 0)},
+		{"@daily", at(time.May, 16, 0, 0)},
+		{"@midnight", at(time.May, 16, 0, 0)},
+		{"30 9,18 * * *", at(time.May, 15, 18, 30)},
+		{"0 8-11 * * *", at(time.May, 15, 11, 0)},
+		{"0 0 * * 0", at(time.May, 19, 0, 0)},
+		{"0 0 * * 7", at(time.May, 19, 0, 0)},
+		{"@weekly", at(time.May, 19, 0, 0)},
+		{"0 9 * * 1-5", at(time.May, 16, 9, 0)},
+		{"0 0 1 * *", at(time.June, 1, 0, 0)},
+		{"0 0 1 1 *", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// either restricted day field matches, like cron: the 20th or a
+		// Friday
+		{"0 0 20 * 5", at(time.May, 17, 0, 0)},
+		{"0 0 16 * 0", at(time.May, 16, 0, 0)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := cron.Next(after); !got.Equal(tt.want) {
+			t.Errorf("ParseCron(%q).Next(%v) = %v, want %v", tt.expr, after, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextKeepsLocation(t *testing.T) {
+	// a zone off the hour, where truncating to hours would be wrong
+	kolkata := time.FixedZone("IST", 5*3600+1800)
+	cron, err := ParseCron("0 8 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Date(2024, time.May, 15, 7, 59, 0, 0, kolkata)
+	want := time.Date(2024, time.May, 15, 8, 0, 0, 0, kolkata)
+	if got := cron.Next(after); !got.Equal(want) {
+		t.Errorf("Next(%v) = %v, want %v", after, got, want)
+	}
+}
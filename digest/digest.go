@@ -0,0 +1,316 @@
+// Package digest sends a summary of the fleet to the alert destinations
+// on a cron schedule, e.g. every morning, so quiet days don't need a
+// look at the TUI.
+package digest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const (
+	// digestNodes is how many nodes a digest lists, the worst ones
+	// first.
+	digestNodes = 20
+	// projectionSpan is how long the disk usage of a mount has to be
+	// followed before its growth is projected.
+	projectionSpan = time.Hour
+	// projectionHorizon is how soon a disk has to fill up for the
+	// digest to mention it.
+	projectionHorizon = 30 * 24 * time.Hour
+)
+
+// Sender delivers a digest, one message of lines.
+type Sender interface {
+	SendText(text string) error
+}
+
+// nodeStats is what a digest tells about a node, since the digest
+// before.
+type nodeStats struct {
+	name      string
+	polls     int
+	up        int
+	peers     float64
+	peerPolls int
+	// disks are the free space of the node's mounts, by mount
+	disks map[string]*diskSamples
+	fired int
+}
+
+// diskSamples are the free space of a mount first and last in the
+// digest's window, and when it was measured.
+type diskSamples struct {
+	firstAvail, lastAvail int64
+	firstTime, lastTime   time.Time
+	usedPercent           float64
+}
+
+// Digest keeps the numbers of the fleet between digests and sends them
+// when the cron schedule says. It implements collector.Sink and
+// alert.Notifier.
+type Digest struct {
+	cron    Cron
+	senders []Sender
+
+	mu    sync.Mutex
+	since time.Time
+	nodes []*nodeStats
+	// fired are the alerts that fired since the digest before by kind,
+	// firing the ones firing now by key
+	fired  map[string]int
+	firing map[string]bool
+}
+
+// New returns a digest of the nodes on the schedule of cfg, sent to
+// every sender.
+func New(cfg config.Digest, nodes []config.Node, senders ...Sender) (*Digest, error) {
+	cron, err := ParseCron(cfg.Cron)
+	if err != nil {
+		return nil, err
+	}
+	if cron.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", cfg.Cron)
+	}
+	if len(senders) == 0 {
+		return nil, fmt.Errorf("the digest needs telegram or syslog to be sent to")
+	}
+	d := &Digest{cron: cron, senders: senders, firing: make(map[string]bool)}
+	for _, node := range nodes {
+		d.nodes = append(d.nodes, &nodeStats{name: node.DisplayName()})
+	}
+	d.reset(time.Now())
+	return d, nil
+}
+
+// reset starts the window of the next digest at now, the caller holds
+// the lock unless it is New.
+func (d *Digest) reset(now time.Time) {
+	d.since = now
+	d.fired = make(map[string]int)
+	for i, n := range d.nodes {
+		d.nodes[i] = &nodeStats{name: n.name, disks: make(map[string]*diskSamples)}
+	}
+}
+
+// Consume counts the snapshot's poll in its node's uptime, peers and
+// disk usage.
+func (d *Digest) Consume(snapshot collector.Snapshot) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if snapshot.Index < 0 || snapshot.Index >= len(d.nodes) {
+		return
+	}
+	n := d.nodes[snapshot.Index]
+	n.polls++
+	if snapshot.Err != nil {
+		return
+	}
+	n.up++
+	if peers, ok := peerCount(snapshot.Status); ok {
+		n.peers += peers
+		n.peerPolls++
+	}
+	for _, disk := range snapshot.Status.Disks {
+		samples, ok := n.disks[disk.Mount]
+		if !ok {
+			samples = &diskSamples{firstAvail: disk.Avail, firstTime: snapshot.Time}
+			n.disks[disk.Mount] = samples
+		}
+		samples.lastAvail, samples.lastTime = disk.Avail, snapshot.Time
+		samples.usedPercent = disk.UsedPercent()
+	}
+}
+
+// peerCount is the peer count of the status' latest log entry with one.
+func peerCount(status collector.Status) (float64, bool) {
+	var latest time.Time
+	peers, found := 0.0, false
+	for _, message := range status.Logs {
+		if v, ok := message.Fields["network_peer_count"].(float64); ok && (!found || message.Time.After(latest)) {
+			peers, latest, found = v, message.Time, true
+		}
+	}
+	return peers, found
+}
+
+// Notify counts the alerts that fire, and keeps which are firing.
+func (d *Digest) Notify(a alert.Alert) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !a.Firing {
+		delete(d.firing, a.Key)
+		return
+	}
+	d.firing[a.Key] = true
+	d.fired[a.Kind]++
+	for _, n := range d.nodes {
+		if n.name == a.Node.DisplayName() {
+			n.fired++
+		}
+	}
+}
+
+// Run sends the digests on the schedule, forever. Failed sends are
+// dropped like alerts, `q-monitor alert test` shows why they fail.
+func (d *Digest) Run() {
+	for {
+		next := d.cron.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		time.Sleep(time.Until(next))
+		text := d.Flush(time.Now())
+		for _, sender := range d.senders {
+			sender.SendText(text)
+		}
+	}
+}
+
+// Flush returns the digest of the window up to now and starts the next
+// one.
+func (d *Digest) Flush(now time.Time) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	text := d.summary(now)
+	d.reset(now)
+	return text
+}
+
+// summary renders the digest: a line on the fleet, its alerts and
+// disks that fill up soon, then a line per node, down nodes first. The
+// caller holds the lock.
+func (d *Digest) summary(now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "q-monitor digest, %s to %s\n", d.since.Format("Jan 2 15:04"), now.Format("Jan 2 15:04"))
+
+	polls, up, peers, peerPolls := 0, 0, 0.0, 0
+	for _, n := range d.nodes {
+		polls, up = polls+n.polls, up+n.up
+		peers, peerPolls = peers+n.peers, peerPolls+n.peerPolls
+	}
+	fleet := fmt.Sprintf("Fleet: %d nodes", len(d.nodes))
+	if polls > 0 {
+		fleet += fmt.Sprintf(", up %s of the polls", percent(up, polls))
+	}
+	if peerPolls > 0 {
+		fleet += fmt.Sprintf(", %.0f peers on average", peers/float64(peerPolls))
+	}
+	b.WriteString(fleet + "\n")
+	b.WriteString(d.alertsLine() + "\n")
+	if disks := d.projections(); len(disks) > 0 {
+		b.WriteString("Disks: " + strings.Join(disks, "; ") + "\n")
+	}
+
+	nodes := append([]*nodeStats(nil), d.nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool { return uptime(nodes[i]) < uptime(nodes[j]) })
+	for i, n := range nodes {
+		if i == digestNodes {
+			fmt.Fprintf(&b, "and %d more\n", len(nodes)-i)
+			break
+		}
+		line := n.name + ": "
+		if n.polls == 0 {
+			b.WriteString(line + "not polled\n")
+			continue
+		}
+		line += "up " + percent(n.up, n.polls)
+		if n.peerPolls > 0 {
+			line += fmt.Sprintf(", %.0f peers", n.peers/float64(n.peerPolls))
+		}
+		if n.fired > 0 {
+			line += fmt.Sprintf(", %d alerts", n.fired)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// alertsLine counts the alerts that fired by kind, and the ones still
+// firing.
+func (d *Digest) alertsLine() string {
+	total := 0
+	kinds := make([]string, 0, len(d.fired))
+	for kind, n := range d.fired {
+		total += n
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for i, kind := range kinds {
+		kinds[i] = fmt.Sprintf("%s %d", kind, d.fired[kind])
+	}
+	line := "Alerts: none fired"
+	if total > 0 {
+		line = fmt.Sprintf("Alerts: %d fired (%s)", total, strings.Join(kinds, ", "))
+	}
+	if len(d.firing) > 0 {
+		keys := make([]string, 0, len(d.firing))
+		for key := range d.firing {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		line += fmt.Sprintf(", %d firing: %s", len(keys), strings.Join(keys, ", "))
+	}
+	return line
+}
+
+// projections are the mounts that fill up within projectionHorizon at
+// the rate they grew in the window, soonest first.
+func (d *Digest) projections() []string {
+	type projection struct {
+		text string
+		full time.Duration
+	}
+	var found []projection
+	for _, n := range d.nodes {
+		for mount, s := range n.disks {
+			span := s.lastTime.Sub(s.firstTime)
+			shrunk := s.firstAvail - s.lastAvail
+			if span < projectionSpan || shrunk <= 0 {
+				continue
+			}
+			full := time.Duration(float64(s.lastAvail) / float64(shrunk) * float64(span))
+			if full > projectionHorizon {
+				continue
+			}
+			found = append(found, projection{
+				text: fmt.Sprintf("%s %s %.0f%% used, full in %s", n.name, mount, s.usedPercent, days(full)),
+				full: full,
+			})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].full < found[j].full })
+	texts := make([]string, len(found))
+	for i, p := range found {
+		texts[i] = p.text
+	}
+	return texts
+}
+
+// uptime is the share of the node's polls that succeeded, 1 for a node
+// not polled yet.
+func uptime(n *nodeStats) float64 {
+	if n.polls == 0 {
+		return 1
+	}
+	return float64(n.up) / float64(n.polls)
+}
+
+func percent(n, of int) string {
+	return fmt.Sprintf("%.1f%%", float64(n)/float64(of)*100)
+}
+
+// days rounds a duration to days, or hours below two days.
+func days(d time.Duration) string {
+	if d < 48*time.Hour {
+		return fmt.Sprintf("%.0f hours", d.Hours())
+	}
+	return fmt.Sprintf("%.0f days", d.Hours()/24)
+}
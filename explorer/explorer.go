@@ -0,0 +1,39 @@
+// Package explorer asks a public Quilibrium explorer or RPC endpoint
+// whether the network sees a peer, to catch nodes that look healthy
+// locally but are invisible to everyone else.
+package explorer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Visible reports whether the endpoint knows the peer: it answers 200
+// for peers the network sees and 404 for those it doesn't.
+func Visible(cfg config.Explorer, peerID string) (bool, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	address := strings.ReplaceAll(cfg.URL, "{peer_id}", url.PathEscape(peerID))
+	resp, err := client.Get(address)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("explorer: %s", resp.Status)
+	}
+}
@@ -0,0 +1,115 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// grafanaInput is the datasource a dashboard asks for when imported.
+const grafanaInput = "${DS_PROMETHEUS}"
+
+// nodeSelector picks the series of the nodes chosen in the dashboard's
+// variables.
+const nodeSelector = `node=~"$node",group=~"$group"`
+
+// grafanaPanel is a panel of the generated dashboard.
+type grafanaPanel struct {
+	title  string
+	kind   string
+	unit   string
+	expr   string
+	legend string
+	width  int
+	height int
+}
+
+var grafanaPanels = []grafanaPanel{
+	{title: "Nodes up", kind: "stat", expr: fmt.Sprintf("sum(%s{%s})", MetricUp, nodeSelector), width: 6, height: 4},
+	{title: "Nodes down", kind: "stat", expr: fmt.Sprintf("count(%s{%s} == 0) or vector(0)", MetricUp, nodeSelector), width: 6, height: 4},
+	{title: "Peers in total", kind: "stat", expr: fmt.Sprintf("sum(%s{%s})", MetricPeers, nodeSelector), width: 6, height: 4},
+	{title: "Highest frame", kind: "stat", expr: fmt.Sprintf("max(%s{%s})", MetricFrame, nodeSelector), width: 6, height: 4},
+	{title: "Up", kind: "state-timeline", expr: fmt.Sprintf("%s{%s}", MetricUp, nodeSelector), legend: "{{node}}", width: 24, height: 6},
+	{title: "CPU", kind: "timeseries", unit: "percent", expr: fmt.Sprintf(`sum by (node) (%s{%s,mode=~"user|system"})`, MetricCPU, nodeSelector), legend: "{{node}}", width: 12, height: 8},
+	{title: "Memory", kind: "timeseries", unit: "percent", expr: fmt.Sprintf("100 * %s{%s} / %s{%s}", MetricMemoryUsed, nodeSelector, MetricMemoryTotal, nodeSelector), legend: "{{node}}", width: 12, height: 8},
+	{title: "Disk used", kind: "timeseries", unit: "percent", expr: fmt.Sprintf("%s{%s}", MetricDiskUsed, nodeSelector), legend: "{{node}} {{mount}}", width: 12, height: 8},
+	{title: "Disk available", kind: "timeseries", unit: "bytes", expr: fmt.Sprintf("%s{%s}", MetricDiskAvail, nodeSelector), legend: "{{node}} {{mount}}", width: 12, height: 8},
+	{title: "Peers", kind: "timeseries", expr: fmt.Sprintf("%s{%s}", MetricPeers, nodeSelector), legend: "{{node}}", width: 12, height: 8},
+	{title: "Frame", kind: "timeseries", expr: fmt.Sprintf("%s{%s}", MetricFrame, nodeSelector), legend: "{{node}}", width: 12, height: 8},
+	{title: "Difficulty", kind: "timeseries", expr: fmt.Sprintf("%s{%s}", MetricDifficulty, nodeSelector), legend: "{{node}}", width: 12, height: 8},
+	{title: "Pi temperature", kind: "timeseries", unit: "celsius", expr: fmt.Sprintf("%s{%s}", MetricPiTemp, nodeSelector), legend: "{{node}}", width: 12, height: 8},
+}
+
+// GrafanaDashboard returns a Grafana dashboard of the metrics the
+// Prometheus exporter serves, with variables to pick groups and nodes.
+// Without a datasource uid the dashboard asks for one on import.
+func GrafanaDashboard(title, datasource string) ([]byte, error) {
+	source := map[string]string{"type": "prometheus", "uid": datasource}
+	if datasource == "" {
+		source["uid"] = grafanaInput
+	}
+
+	var panels []map[string]any
+	x, y, rowHeight := 0, 0, 0
+	for i, p := range grafanaPanels {
+		if x+p.width > 24 {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+		panel := map[string]any{
+			"id":         i + 1,
+			"type":       p.kind,
+			"title":      p.title,
+			"datasource": source,
+			"gridPos":    map[string]int{"x": x, "y": y, "w": p.width, "h": p.height},
+			"targets": []map[string]any{{
+				"refId":        "A",
+				"datasource":   source,
+				"expr":         p.expr,
+				"legendFormat": p.legend,
+			}},
+			"fieldConfig": map[string]any{"defaults": map[string]any{"unit": p.unit}, "overrides": []any{}},
+		}
+		panels = append(panels, panel)
+		x += p.width
+		rowHeight = max(rowHeight, p.height)
+	}
+
+	variable := func(name, query string) map[string]any {
+		return map[string]any{
+			"name":       name,
+			"label":      name,
+			"type":       "query",
+			"datasource": source,
+			"query":      query,
+			"definition": query,
+			"refresh":    2,
+			"includeAll": true,
+			"multi":      true,
+			"allValue":   ".*",
+			"current":    map[string]any{"text": "All", "value": "$__all"},
+			"sort":       1,
+		}
+	}
+	dashboard := map[string]any{
+		"uid":           "q-monitor",
+		"title":         title,
+		"tags":          []string{"q-monitor"},
+		"schemaVersion": 39,
+		"refresh":       "1m",
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"panels":        panels,
+		"templating": map[string]any{"list": []any{
+			variable("group", fmt.Sprintf("label_values(%s, group)", MetricUp)),
+			variable("node", fmt.Sprintf(`label_values(%s{group=~"$group"}, node)`, MetricUp)),
+		}},
+	}
+	if datasource == "" {
+		dashboard["__inputs"] = []map[string]string{{
+			"name":       "DS_PROMETHEUS",
+			"label":      "Prometheus",
+			"type":       "datasource",
+			"pluginId":   "prometheus",
+			"pluginName": "Prometheus",
+		}}
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
@@ -0,0 +1,320 @@
+// Package export writes snapshots in machine readable formats, for tools
+// like jq, vector or fluent-bit to consume.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// Record is the JSON form of a snapshot, one per node and poll. Sections
+// a poll didn't report are left out.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Node    string    `json:"node"`
+	Address string    `json:"address,omitempty"`
+	Group   string    `json:"group,omitempty"`
+	// Up is false if the poll failed, with Error saying why and Failure
+	// the kind of failure, see transport.Failure.
+	Up      bool    `json:"up"`
+	Error   string  `json:"error,omitempty"`
+	Failure string  `json:"failure,omitempty"`
+	OS      string  `json:"os,omitempty"`
+	CPU     *CPU    `json:"cpu,omitempty"`
+	Memory  *Memory `json:"memory,omitempty"`
+	Disks   []Disk  `json:"disks,omitempty"`
+	Service string  `json:"service,omitempty"`
+	Pi      *Pi     `json:"pi,omitempty"`
+	Logs    []Log   `json:"logs,omitempty"`
+	// Metrics are the node's scraped Prometheus metrics by series, e.g.
+	// `peers{kind="mesh"}`.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// Mesh are the node's pings of the other nodes of the mesh.
+	Mesh []Ping `json:"mesh,omitempty"`
+	// Audit is the node's last audit, if audits are configured.
+	Audit *Audit `json:"audit,omitempty"`
+	// Logins are the unexpected SSH logins of the last day, if they
+	// are watched.
+	Logins *Logins `json:"logins,omitempty"`
+	// Keys are the node's key files, if they are checked.
+	Keys []KeyFile `json:"keys,omitempty"`
+	// Checks are the outcomes of the node's own checks.
+	Checks []Check `json:"checks,omitempty"`
+	// Signals are what other systems reported about the node through
+	// the webhook.
+	Signals []Signal          `json:"signals,omitempty"`
+	Missing []string          `json:"missing,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+	// Bootstrap is set for bootstrap peer nodes instead of the stats.
+	Bootstrap *Bootstrap `json:"bootstrap,omitempty"`
+	// Visible is whether the explorer sees the node, left out if it
+	// wasn't asked.
+	Visible *bool `json:"visible,omitempty"`
+	// Connectivity is how the node reaches its peers, left out until
+	// its logs tell.
+	Connectivity *Connectivity `json:"connectivity,omitempty"`
+}
+
+// CPU usage in percent.
+type CPU struct {
+	User   float64 `json:"user"`
+	System float64 `json:"system"`
+	Steal  float64 `json:"steal"`
+}
+
+type Memory struct {
+	TotalMB     int     `json:"total_mb"`
+	UsedMB      int     `json:"used_mb"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+type Disk struct {
+	Mount       string  `json:"mount"`
+	TotalBytes  int64   `json:"total_bytes"`
+	UsedBytes   int64   `json:"used_bytes"`
+	AvailBytes  int64   `json:"avail_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+type Pi struct {
+	Temperature float64 `json:"temperature"`
+	// Throttled lists the problems the Pi has right now.
+	Throttled []string `json:"throttled"`
+}
+
+// Bootstrap is how a bootstrap peer answered.
+type Bootstrap struct {
+	Multiaddr string  `json:"multiaddr"`
+	Transport string  `json:"transport"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Connectivity is how a node reaches its peers, as far as its logs
+// told. Direct and Relayed are left out if it didn't log its
+// connections.
+type Connectivity struct {
+	Reachability string   `json:"reachability,omitempty"`
+	Direct       *int     `json:"direct,omitempty"`
+	Relayed      *int     `json:"relayed,omitempty"`
+	Protocols    []string `json:"protocols,omitempty"`
+}
+
+// Ping is how a node reached another one of the mesh.
+type Ping struct {
+	Node        string  `json:"node"`
+	LossPercent float64 `json:"loss_percent"`
+	// RTTMS is the average round trip time, zero if every ping was lost.
+	RTTMS float64 `json:"rtt_ms"`
+	Error string  `json:"error,omitempty"`
+}
+
+// Audit is what the last audit of a node found.
+type Audit struct {
+	Ports        []string  `json:"ports"`
+	Unexpected   []string  `json:"unexpected,omitempty"`
+	MissingRules []string  `json:"missing_rules,omitempty"`
+	Checked      time.Time `json:"checked"`
+}
+
+// Logins are the SSH logins of a node in the last day from sources
+// the config doesn't expect.
+type Logins struct {
+	New           []LoginSource `json:"new"`
+	Failed        int           `json:"failed"`
+	FailedSources int           `json:"failed_sources"`
+}
+
+// LoginSource is an unexpected address that logged in to a node.
+type LoginSource struct {
+	Address  string    `json:"address"`
+	Users    []string  `json:"users"`
+	Accepted int       `json:"accepted"`
+	Failed   int       `json:"failed,omitempty"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+}
+
+// KeyFile is a checked key file of a node.
+type KeyFile struct {
+	Path    string `json:"path"`
+	Missing bool   `json:"missing,omitempty"`
+	// Mode is the permissions in octal, e.g. "600".
+	Mode       string     `json:"mode,omitempty"`
+	Owner      string     `json:"owner,omitempty"`
+	Checksum   string     `json:"sha256,omitempty"`
+	Loose      bool       `json:"loose,omitempty"`
+	WrongOwner bool       `json:"wrong_owner,omitempty"`
+	Changed    *time.Time `json:"changed,omitempty"`
+}
+
+// Check is the outcome of one of the node's own checks.
+type Check struct {
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Output string `json:"output,omitempty"`
+}
+
+// Signal is a node's health as another system reported it.
+type Signal struct {
+	Name     string     `json:"name"`
+	State    string     `json:"state"`
+	Message  string     `json:"message,omitempty"`
+	Reported time.Time  `json:"reported"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Stale    bool       `json:"stale,omitempty"`
+}
+
+// Log is a watched log message seen in the poll.
+type Log struct {
+	Msg    string                 `json:"msg"`
+	Time   *time.Time             `json:"time,omitempty"`
+	Count  int                    `json:"count"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// NewRecord converts a snapshot.
+func NewRecord(snapshot collector.Snapshot) Record {
+	record := Record{
+		Time:  snapshot.Time,
+		Node:  snapshot.Node.DisplayName(),
+		Group: snapshot.Node.Group,
+		Up:    snapshot.Err == nil,
+	}
+	if snapshot.Err != nil {
+		record.Error = snapshot.Err.Error()
+		record.Failure = string(transport.Classify(snapshot.Err))
+		return record
+	}
+
+	status := snapshot.Status
+	if status.Bootstrap != nil {
+		record.Bootstrap = &Bootstrap{
+			Multiaddr: snapshot.Node.Bootstrap,
+			Transport: status.Bootstrap.Addr.Transport,
+			LatencyMS: float64(status.Bootstrap.Latency.Microseconds()) / 1000,
+		}
+		return record
+	}
+	record.Address = status.Address
+	record.OS = status.OS
+	if status.Collected(collector.SectionCPU) {
+		record.CPU = &CPU{User: status.CPU.User, System: status.CPU.System, Steal: status.CPU.Steal}
+	}
+	if status.Memory.TotalMB > 0 {
+		record.Memory = &Memory{
+			TotalMB:     status.Memory.TotalMB,
+			UsedMB:      status.Memory.UsedMB,
+			UsedPercent: float64(status.Memory.UsedMB) / float64(status.Memory.TotalMB) * 100,
+		}
+	}
+	for _, disk := range status.Disks {
+		record.Disks = append(record.Disks, Disk{
+			Mount:       disk.Mount,
+			TotalBytes:  disk.Total,
+			UsedBytes:   disk.Used,
+			AvailBytes:  disk.Avail,
+			UsedPercent: disk.UsedPercent(),
+		})
+	}
+	record.Service = status.Service
+	if status.Pi != nil {
+		record.Pi = &Pi{Temperature: status.Pi.Temperature, Throttled: append([]string{}, status.Pi.Current()...)}
+	}
+	for _, message := range status.Logs {
+		log := Log{Msg: message.Msg, Count: message.Count, Fields: message.Fields}
+		if !message.Time.IsZero() {
+			log.Time = &message.Time
+		}
+		record.Logs = append(record.Logs, log)
+	}
+	for _, m := range status.Metrics {
+		if record.Metrics == nil {
+			record.Metrics = make(map[string]float64)
+		}
+		record.Metrics[m.Series()] = m.Value
+	}
+	for _, ping := range status.Mesh {
+		record.Mesh = append(record.Mesh, Ping{
+			Node:        ping.Target,
+			LossPercent: ping.Loss,
+			RTTMS:       float64(ping.RTT) / float64(time.Millisecond),
+			Error:       ping.Error,
+		})
+	}
+	if a := status.Audit; a != nil {
+		record.Audit = &Audit{Ports: a.Ports, Unexpected: a.Unexpected, MissingRules: a.MissingRules, Checked: a.Checked}
+	}
+	if l := status.Logins; l != nil {
+		record.Logins = &Logins{New: []LoginSource{}, Failed: l.Failed, FailedSources: l.FailedSources}
+		for _, source := range l.New {
+			record.Logins.New = append(record.Logins.New, LoginSource{Address: source.Address, Users: source.Users,
+				Accepted: source.Accepted, Failed: source.Failed, First: source.First, Last: source.Last})
+		}
+	}
+	for _, file := range status.Keys {
+		key := KeyFile{Path: file.Path, Missing: file.Missing, Owner: file.Owner, Checksum: file.Checksum,
+			Loose: file.Loose, WrongOwner: file.WrongOwner()}
+		if !file.Missing {
+			key.Mode = fmt.Sprintf("%03o", file.Mode.Perm())
+		}
+		if !file.Changed.IsZero() {
+			changed := file.Changed
+			key.Changed = &changed
+		}
+		record.Keys = append(record.Keys, key)
+	}
+	for _, check := range status.Checks {
+		record.Checks = append(record.Checks, Check{Name: check.Name, State: check.State, Output: check.Output})
+	}
+	for _, signal := range status.Signals {
+		reported := Signal{Name: signal.Name, State: signal.State, Message: signal.Message, Reported: signal.Reported, Stale: signal.Stale}
+		if !signal.Expires.IsZero() {
+			expires := signal.Expires
+			reported.Expires = &expires
+		}
+		record.Signals = append(record.Signals, reported)
+	}
+	if status.Visibility != nil {
+		record.Visible = &status.Visibility.Visible
+	}
+	if c := status.Connectivity; c != nil {
+		record.Connectivity = &Connectivity{Reachability: c.Reachability, Protocols: c.Protocols}
+		if c.Counted {
+			direct, relayed := c.Direct, c.Relayed
+			record.Connectivity.Direct, record.Connectivity.Relayed = &direct, &relayed
+		}
+	}
+	record.Missing = status.Missing
+	for section, err := range status.Errors {
+		if record.Errors == nil {
+			record.Errors = make(map[string]string)
+		}
+		record.Errors[section] = err.Error()
+	}
+	return record
+}
+
+// JSONL writes a Record per snapshot, one JSON object per line. It is a
+// collector.Sink.
+type JSONL struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONL(w io.Writer) *JSONL {
+	return &JSONL{enc: json.NewEncoder(w)}
+}
+
+// Consume writes the snapshot's record. Write errors are dropped, a
+// closed stdout ends the process anyway.
+func (j *JSONL) Consume(snapshot collector.Snapshot) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.enc.Encode(NewRecord(snapshot))
+}
@@ -0,0 +1,132 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const (
+	// lokiTimeout bounds a push, so a slow Loki doesn't back up the
+	// pipeline for long.
+	lokiTimeout = 10 * time.Second
+	// lokiBacklog is how many lines are kept for the next push while Loki
+	// can't be reached, the oldest are dropped first.
+	lokiBacklog = 5000
+)
+
+// Loki pushes the watched log lines of every poll to Grafana Loki, one
+// stream per node labeled with its name, group and tags, so the history
+// outlasts the few minutes of logs a poll reads. Lines that fail to push
+// are retried with the next poll. It is a collector.Sink.
+type Loki struct {
+	cfg    config.Loki
+	client *http.Client
+	// pending are the lines not pushed yet, by stream
+	pending map[string]*lokiStream
+	lines   int
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func NewLoki(cfg config.Loki) (*Loki, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("loki needs a url")
+	}
+	return &Loki{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: lokiTimeout},
+		pending: make(map[string]*lokiStream),
+	}, nil
+}
+
+// Consume queues the snapshot's log lines and pushes everything pending.
+// Consume is only called from the pipeline's goroutine for this sink, so
+// the pending lines need no lock.
+func (l *Loki) Consume(snapshot collector.Snapshot) {
+	if snapshot.Err != nil || len(snapshot.Status.LogLines) == 0 {
+		return
+	}
+	key := snapshot.Node.DisplayName()
+	stream := l.pending[key]
+	if stream == nil {
+		stream = &lokiStream{Stream: l.labels(snapshot.Node)}
+		l.pending[key] = stream
+	}
+	for _, line := range snapshot.Status.LogLines {
+		ts := line.Time
+		if ts.IsZero() {
+			ts = snapshot.Time
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(ts.UnixNano(), 10), line.Line})
+		l.lines++
+	}
+	for l.lines > lokiBacklog && len(stream.Values) > 0 {
+		stream.Values = stream.Values[1:]
+		l.lines--
+	}
+
+	if l.push() == nil {
+		l.pending = make(map[string]*lokiStream)
+		l.lines = 0
+	}
+}
+
+// labels are the stream labels of a node.
+func (l *Loki) labels(node config.Node) map[string]string {
+	labels := map[string]string{"node": node.DisplayName()}
+	for name, value := range l.cfg.Labels {
+		labels[name] = value
+	}
+	if node.Group != "" {
+		labels["group"] = node.Group
+	}
+	if len(node.Tags) > 0 {
+		labels["tags"] = strings.Join(node.Tags, ",")
+	}
+	return labels
+}
+
+func (l *Loki) push() error {
+	streams := make([]*lokiStream, 0, len(l.pending))
+	for _, stream := range l.pending {
+		streams = append(streams, stream)
+	}
+	body, err := json.Marshal(map[string]interface{}{"streams": streams})
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(l.cfg.URL, "/") + "/loki/api/v1/push"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.cfg.TenantID)
+	}
+	if l.cfg.Username != "" {
+		req.SetBasicAuth(l.cfg.Username, l.cfg.Password)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("loki push failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("loki push: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+)
+
+// PrometheusMetric is a metric the Prometheus exporter serves. Every
+// series is labeled with the node's name and group, disks with their
+// mount and CPU usage with its mode.
+type PrometheusMetric struct {
+	Name string
+	Help string
+	Type string
+}
+
+// Names of the exported metrics.
+const (
+	MetricUp          = "q_monitor_up"
+	MetricPolled      = "q_monitor_last_poll_timestamp_seconds"
+	MetricCPU         = "q_monitor_cpu_percent"
+	MetricMemoryUsed  = "q_monitor_memory_used_bytes"
+	MetricMemoryTotal = "q_monitor_memory_total_bytes"
+	MetricDiskUsed    = "q_monitor_disk_used_percent"
+	MetricDiskAvail   = "q_monitor_disk_avail_bytes"
+	MetricPeers       = "q_monitor_peers"
+	MetricFrame       = "q_monitor_frame"
+	MetricDifficulty  = "q_monitor_difficulty"
+	MetricPiTemp      = "q_monitor_pi_temperature_celsius"
+	MetricVisible     = "q_monitor_visible"
+)
+
+// PrometheusMetrics are the exported metrics in the order they are
+// served.
+var PrometheusMetrics = []PrometheusMetric{
+	{MetricUp, "Whether the node's last poll succeeded.", "gauge"},
+	{MetricPolled, "When the node was last polled, in seconds since the epoch.", "gauge"},
+	{MetricCPU, "CPU usage of the node by mode, in percent.", "gauge"},
+	{MetricMemoryUsed, "Memory used on the node.", "gauge"},
+	{MetricMemoryTotal, "Memory of the node.", "gauge"},
+	{MetricDiskUsed, "Disk usage of the mount, in percent.", "gauge"},
+	{MetricDiskAvail, "Disk space available on the mount.", "gauge"},
+	{MetricPeers, "Peer count of the node's latest log line reporting it.", "gauge"},
+	{MetricFrame, "Frame of the node's latest log line reporting it.", "gauge"},
+	{MetricDifficulty, "Difficulty of the node's latest log line reporting it.", "gauge"},
+	{MetricPiTemp, "SoC temperature of a Raspberry Pi node.", "gauge"},
+	{MetricVisible, "Whether the explorer sees the node.", "gauge"},
+}
+
+// logMetrics are the exported metrics taken from log fields.
+var logMetrics = map[string]string{
+	MetricPeers:      "network_peer_count",
+	MetricFrame:      "current_frame",
+	MetricDifficulty: "difficulty",
+}
+
+// Prometheus serves the last poll of every node in the Prometheus text
+// format, for a Prometheus to scrape. It is a collector.Sink and an
+// http.Handler.
+type Prometheus struct {
+	latest latestRecords
+}
+
+func NewPrometheus() *Prometheus {
+	return &Prometheus{}
+}
+
+// Consume keeps the snapshot's record as its node's latest.
+func (p *Prometheus) Consume(snapshot collector.Snapshot) {
+	p.latest.consume(snapshot)
+}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.Write(w)
+}
+
+// Write writes the metrics of every node polled so far.
+func (p *Prometheus) Write(w io.Writer) error {
+	records := p.latest.records()
+	samples := make(map[string][]string)
+	add := func(name string, record Record, value float64, labels ...string) {
+		series := fmt.Sprintf(`node="%s",group="%s"`, escapeLabel(record.Node), escapeLabel(record.Group))
+		for i := 0; i+1 < len(labels); i += 2 {
+			series += fmt.Sprintf(`,%s="%s"`, labels[i], escapeLabel(labels[i+1]))
+		}
+		samples[name] = append(samples[name], fmt.Sprintf("%s{%s} %s", name, series, strconv.FormatFloat(value, 'g', -1, 64)))
+	}
+	for _, record := range records {
+		up := 0.0
+		if record.Up {
+			up = 1
+		}
+		add(MetricUp, record, up)
+		add(MetricPolled, record, float64(record.Time.Unix()))
+		if cpu := record.CPU; cpu != nil {
+			add(MetricCPU, record, cpu.User, "mode", "user")
+			add(MetricCPU, record, cpu.System, "mode", "system")
+			add(MetricCPU, record, cpu.Steal, "mode", "steal")
+		}
+		if memory := record.Memory; memory != nil {
+			add(MetricMemoryUsed, record, float64(memory.UsedMB)*1024*1024)
+			add(MetricMemoryTotal, record, float64(memory.TotalMB)*1024*1024)
+		}
+		for _, disk := range record.Disks {
+			add(MetricDiskUsed, record, disk.UsedPercent, "mount", disk.Mount)
+			add(MetricDiskAvail, record, float64(disk.AvailBytes), "mount", disk.Mount)
+		}
+		for name, field := range logMetrics {
+			for _, log := range record.Logs {
+				if value, ok := log.Fields[field].(float64); ok {
+					add(name, record, value)
+					break
+				}
+			}
+		}
+		if record.Pi != nil {
+			add(MetricPiTemp, record, record.Pi.Temperature)
+		}
+		if record.Visible != nil {
+			visible := 0.0
+			if *record.Visible {
+				visible = 1
+			}
+			add(MetricVisible, record, visible)
+		}
+	}
+
+	var b strings.Builder
+	for _, metric := range PrometheusMetrics {
+		if len(samples[metric.Name]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", metric.Name, metric.Help, metric.Name, metric.Type)
+		for _, sample := range samples[metric.Name] {
+			b.WriteString(sample + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeLabel escapes a label value of the text format.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+)
+
+// Severities of forwarded events, a subset of the syslog ones.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityNotice  = "notice"
+)
+
+// errorLevels are the log levels of entries forwarded as errors.
+var errorLevels = map[string]bool{
+	"error": true, "err": true, "dpanic": true, "panic": true, "fatal": true, "critical": true,
+}
+
+// severity rates an event for forwarding. Node state changes are always
+// forwarded, watched log messages only if they were logged as errors,
+// since the others repeat every few seconds on a healthy node.
+func severity(event collector.Event) (string, bool) {
+	switch event.Type {
+	case collector.NodeDown:
+		return severityError, true
+	case collector.SessionHung:
+		return severityWarning, true
+	case collector.MetricThresholdCrossed:
+		if event.Above {
+			return severityWarning, true
+		}
+		return severityNotice, true
+	case collector.ConditionChanged:
+		if event.Active {
+			return severityWarning, true
+		}
+		return severityNotice, true
+	case collector.LogMessageSeen:
+		return severityError, errorLevels[strings.ToLower(event.Message.Level)]
+	default:
+		return severityNotice, true
+	}
+}
+
+// eventText describes an event without the time, which the receiving
+// end stamps itself. Error log messages come with their fields.
+func eventText(event collector.Event) string {
+	text := strings.TrimPrefix(event.String(), event.Time.Format("15:04:05")+" ")
+	if event.Type == collector.LogMessageSeen && len(event.Message.Fields) > 0 {
+		if fields, err := json.Marshal(event.Message.Fields); err == nil {
+			text = fmt.Sprintf("%s %s", text, fields)
+		}
+	}
+	return text
+}
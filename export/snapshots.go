@@ -0,0 +1,211 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// snapshotPrefix starts the names of snapshot files, which go on with
+// the time they were written, so they sort by it.
+const snapshotPrefix = "snapshot-"
+
+// snapshotColumns are the columns of CSV snapshots, one row per node.
+var snapshotColumns = []string{
+	"time", "node", "group", "address", "up", "error", "cpu_user", "cpu_system", "memory_used_percent",
+	"disk_used_percent", "service", "peers", "frame",
+}
+
+// Snapshots writes the last poll of every node to a file in a directory
+// every interval, keeping the newest files only. It is a collector.Sink;
+// Run writes the files.
+type Snapshots struct {
+	cfg    config.Snapshots
+	latest latestRecords
+}
+
+// NewSnapshots creates the snapshot directory if needed.
+func NewSnapshots(cfg config.Snapshots) (*Snapshots, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Snapshots{cfg: cfg}, nil
+}
+
+// Consume keeps the snapshot's record as its node's latest.
+func (s *Snapshots) Consume(snapshot collector.Snapshot) {
+	s.latest.consume(snapshot)
+}
+
+// Run writes a file every interval, forever. Failures are dropped, the
+// next interval tries again.
+func (s *Snapshots) Run() {
+	for now := range time.Tick(s.cfg.Interval()) {
+		s.Write(now)
+	}
+}
+
+// Write writes the file of now and removes the oldest ones beyond what
+// is kept. The file is synced and renamed into place, so a power loss
+// leaves the previous files intact.
+func (s *Snapshots) Write(now time.Time) error {
+	records := s.latest.records()
+	if len(records) == 0 {
+		return nil
+	}
+	format := s.cfg.Format
+	if format == "" {
+		format = config.SnapshotJSON
+	}
+	name := filepath.Join(s.cfg.Dir, snapshotPrefix+now.UTC().Format("20060102T150405Z")+"."+format)
+	file, err := os.CreateTemp(s.cfg.Dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if format == config.SnapshotCSV {
+		err = writeSnapshotCSV(file, records)
+	} else {
+		err = writeSnapshotJSON(file, now, records)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), name); err != nil {
+		return err
+	}
+	return s.prune()
+}
+
+// prune removes the oldest snapshot files beyond what is kept.
+func (s *Snapshots) prune() error {
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), snapshotPrefix) && !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for len(names) > s.cfg.Files() {
+		if err := os.Remove(filepath.Join(s.cfg.Dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+func writeSnapshotJSON(w io.Writer, now time.Time, records []Record) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Time  time.Time `json:"time"`
+		Nodes []Record  `json:"nodes"`
+	}{now, records})
+}
+
+func writeSnapshotCSV(w io.Writer, records []Record) error {
+	out := csv.NewWriter(w)
+	out.Write(snapshotColumns)
+	for _, record := range records {
+		row := make([]string, len(snapshotColumns))
+		row[0] = record.Time.Format(time.RFC3339)
+		row[1], row[2], row[3] = record.Node, record.Group, record.Address
+		row[4], row[5] = strconv.FormatBool(record.Up), record.Error
+		if cpu := record.CPU; cpu != nil {
+			row[6], row[7] = formatFloat(cpu.User), formatFloat(cpu.System)
+		}
+		if memory := record.Memory; memory != nil {
+			row[8] = formatFloat(memory.UsedPercent)
+		}
+		if len(record.Disks) > 0 {
+			fullest := record.Disks[0].UsedPercent
+			for _, disk := range record.Disks[1:] {
+				fullest = max(fullest, disk.UsedPercent)
+			}
+			row[9] = formatFloat(fullest)
+		}
+		row[10] = record.Service
+		row[11] = recordField(record, "network_peer_count")
+		row[12] = recordField(record, "current_frame")
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// recordField returns a field of the record's first log message with
+// it, empty if none has it.
+func recordField(record Record, key string) string {
+	for _, log := range record.Logs {
+		switch value := log.Fields[key].(type) {
+		case nil:
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		default:
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 1, 64)
+}
+
+// latestRecords are the records of the last poll of every node, for
+// sinks that write them all at once. The zero value is ready to use.
+type latestRecords struct {
+	mu     sync.Mutex
+	latest map[int]Record
+}
+
+func (l *latestRecords) consume(snapshot collector.Snapshot) {
+	record := NewRecord(snapshot)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.latest == nil {
+		l.latest = make(map[int]Record)
+	}
+	l.latest[snapshot.Index] = record
+}
+
+// records returns the records in the order of the nodes.
+func (l *latestRecords) records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	indexes := make([]int, 0, len(l.latest))
+	for i := range l.latest {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	records := make([]Record, len(indexes))
+	for i, index := range indexes {
+		records[i] = l.latest[index]
+	}
+	return records
+}
@@ -0,0 +1,101 @@
+//go:build !windows && !plan9
+
+package export
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// facilities are the syslog facilities a config can pick.
+var facilities = map[string]syslog.Priority{
+	"user":   syslog.LOG_USER,
+	"daemon": syslog.LOG_DAEMON,
+	"syslog": syslog.LOG_SYSLOG,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// Syslog forwards node events to syslog: state changes, threshold
+// crossings and watched log messages logged as errors. It implements
+// collector.Handler.
+type Syslog struct {
+	w *syslog.Writer
+}
+
+// NewSyslog connects to the endpoint of the config. UDP and unix sockets
+// just send, so an endpoint that is down only shows over TCP.
+func NewSyslog(cfg config.Syslog) (*Syslog, error) {
+	facility := syslog.LOG_USER
+	if cfg.Facility != "" {
+		var ok bool
+		if facility, ok = facilities[cfg.Facility]; !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+		}
+	}
+	network := cfg.Network
+	if network == "" && cfg.Address != "" {
+		network = "udp"
+	}
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "q-monitor"
+	}
+
+	w, err := syslog.Dial(network, cfg.Address, facility|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &Syslog{w: w}, nil
+}
+
+// HandleEvent writes the event if it has a severity. Write errors are
+// dropped, the writer reconnects on the next event.
+func (s *Syslog) HandleEvent(event collector.Event) {
+	level, ok := severity(event)
+	if !ok {
+		return
+	}
+	text := eventText(event)
+	switch level {
+	case severityError:
+		s.w.Err(text)
+	case severityWarning:
+		s.w.Warning(text)
+	default:
+		s.w.Notice(text)
+	}
+}
+
+// Send writes an alert as a warning, or a notice once it resolved, for
+// `q-monitor alert test`; alerts come from the events HandleEvent
+// forwards. Over UDP and unix sockets a nil error only means it was
+// sent.
+func (s *Syslog) Send(a alert.Alert) error {
+	if a.Firing {
+		return s.w.Warning(alertText(a))
+	}
+	return s.w.Notice(alertText(a))
+}
+
+// SendText writes a message, such as a digest, as notices, one per
+// line.
+func (s *Syslog) SendText(text string) error {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if err := s.w.Notice(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
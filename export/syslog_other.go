@@ -0,0 +1,25 @@
+//go:build windows || plan9
+
+package export
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// Syslog is not available on this platform, see syslog.go.
+type Syslog struct{}
+
+func NewSyslog(cfg config.Syslog) (*Syslog, error) {
+	return nil, fmt.Errorf("syslog forwarding is not supported on %s", runtime.GOOS)
+}
+
+func (s *Syslog) HandleEvent(event collector.Event) {}
+
+func (s *Syslog) Send(a alert.Alert) error { return nil }
+
+func (s *Syslog) SendText(text string) error { return nil }
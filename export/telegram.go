@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const (
+	// telegramTimeout bounds sending an alert, which holds up the ones
+	// after it.
+	telegramTimeout = 10 * time.Second
+	// DefaultTelegramAPI is the Bot API alerts are sent through unless
+	// the config names another one.
+	DefaultTelegramAPI = "https://api.telegram.org"
+)
+
+// Telegram sends firing and resolved alerts to a Telegram chat through a
+// bot. It implements alert.Notifier.
+type Telegram struct {
+	cfg    config.Telegram
+	client *http.Client
+}
+
+func NewTelegram(cfg config.Telegram) (*Telegram, error) {
+	if cfg.Token == "" || cfg.ChatID == "" {
+		return nil, fmt.Errorf("telegram needs a token and a chat_id")
+	}
+	return &Telegram{cfg: cfg, client: &http.Client{Timeout: telegramTimeout}}, nil
+}
+
+// Notify sends the alert. Failed sends are dropped, `q-monitor alert
+// test` shows why they fail.
+func (t *Telegram) Notify(a alert.Alert) {
+	t.Send(a)
+}
+
+// Send sends the alert and reports whether the Bot API accepted it.
+func (t *Telegram) Send(a alert.Alert) error {
+	return t.SendText(alertText(a))
+}
+
+// SendText sends a message, such as a digest, and reports whether the
+// Bot API accepted it.
+func (t *Telegram) SendText(text string) error {
+	body, err := json.Marshal(map[string]string{"chat_id": t.cfg.ChatID, "text": text})
+	if err != nil {
+		return err
+	}
+	api := t.cfg.APIURL
+	if api == "" {
+		api = DefaultTelegramAPI
+	}
+	url := strings.TrimSuffix(api, "/") + "/bot" + t.cfg.Token + "/sendMessage"
+	resp, err := t.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// the url has the token in it
+		return fmt.Errorf("telegram send failed: %w", redactURL(err, t.cfg.Token))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var answer struct {
+			Description string `json:"description"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&answer) == nil && answer.Description != "" {
+			return fmt.Errorf("telegram send: %s: %s", resp.Status, answer.Description)
+		}
+		return fmt.Errorf("telegram send: %s", resp.Status)
+	}
+	return nil
+}
+
+// alertText describes an alert in a line, e.g. for a chat message.
+func alertText(a alert.Alert) string {
+	if a.Firing {
+		return fmt.Sprintf("FIRING %s: %s", a.Key, a.Message)
+	}
+	return fmt.Sprintf("RESOLVED %s after %s: %s", a.Key, a.Time.Sub(a.Since).Round(time.Second), a.Message)
+}
+
+// redactURL keeps a secret in a request's url out of its error.
+func redactURL(err error, secret string) error {
+	return errors.New(strings.ReplaceAll(err.Error(), secret, config.Redacted))
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/simulate"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// Exit codes of `q-monitor get` besides 0 for a printed value and 1 for
+// usage and config errors.
+const (
+	// exitDown is a node that couldn't be polled.
+	exitDown = 2
+	// exitNoValue is a metric the node's poll didn't report, e.g. peers
+	// without a recent log line with the count, or a section that failed.
+	exitNoValue = 3
+)
+
+// metric is a value `q-monitor get` prints from a node's poll. value
+// returns false if the poll didn't report it.
+type metric struct {
+	help  string
+	value func(status collector.Status, mount string) (string, bool)
+}
+
+// metrics are what `q-monitor get` knows by name. Log fields and scraped
+// Prometheus series are also read as log.<field> and prom.<series>.
+var metrics = map[string]metric{
+	"cpu.used_pct": {"CPU used by user and system, in percent", func(status collector.Status, _ string) (string, bool) {
+		return percent(status.CPU.User + status.CPU.System), status.Collected(collector.SectionCPU)
+	}},
+	"cpu.steal_pct": {"CPU stolen by the hypervisor, in percent", func(status collector.Status, _ string) (string, bool) {
+		return percent(status.CPU.Steal), status.Collected(collector.SectionCPU)
+	}},
+	"memory.used_pct": {"memory used, in percent", func(status collector.Status, _ string) (string, bool) {
+		if status.Memory.TotalMB == 0 {
+			return "", false
+		}
+		return percent(float64(status.Memory.UsedMB) / float64(status.Memory.TotalMB) * 100), true
+	}},
+	"memory.used_mb": {"memory used, in MB", func(status collector.Status, _ string) (string, bool) {
+		return strconv.Itoa(status.Memory.UsedMB), status.Memory.TotalMB > 0
+	}},
+	"disk.used_pct": {"disk used, in percent, of the fullest mount or --mount", func(status collector.Status, mount string) (string, bool) {
+		disk, ok := pickDisk(status.Disks, mount)
+		return percent(disk.UsedPercent()), ok
+	}},
+	"disk.avail_bytes": {"disk space available, of the fullest mount or --mount", func(status collector.Status, mount string) (string, bool) {
+		disk, ok := pickDisk(status.Disks, mount)
+		return strconv.FormatInt(disk.Avail, 10), ok
+	}},
+	"service": {"state of the Q service, e.g. active", func(status collector.Status, _ string) (string, bool) {
+		return status.Service, status.Service != ""
+	}},
+	"peers": {"peer count of the latest log line reporting it", func(status collector.Status, _ string) (string, bool) {
+		return logField(status, "network_peer_count")
+	}},
+	"frame": {"frame of the latest log line reporting it", func(status collector.Status, _ string) (string, bool) {
+		return logField(status, "current_frame")
+	}},
+	"difficulty": {"difficulty of the latest log line reporting it", func(status collector.Status, _ string) (string, bool) {
+		return logField(status, "difficulty")
+	}},
+	"pi.temperature": {"Raspberry Pi SoC temperature, in °C", func(status collector.Status, _ string) (string, bool) {
+		if status.Pi == nil {
+			return "", false
+		}
+		return strconv.FormatFloat(status.Pi.Temperature, 'f', -1, 64), true
+	}},
+	"missing": {"number of programs the poll needs that are missing", func(status collector.Status, _ string) (string, bool) {
+		return strconv.Itoa(len(status.Missing)), true
+	}},
+}
+
+// runGet implements `q-monitor get <node> <metric>`, polling a single
+// node once and printing just the value, for shell scripts and cron
+// jobs. The exit code tells a down node and an unreported metric apart.
+func runGet(args []string) error {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	configFile := flags.String("config", configFileName, "config `file` with the nodes")
+	mount := flags.String("mount", "", "`path` of the mount the disk metrics are read from, the fullest one if empty")
+	simulateNodes := flags.Int("simulate", 0, "poll `N` simulated nodes instead of the configured ones")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: q-monitor get [-config file] [--mount path] <node> <metric>")
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "\nmetrics:")
+		names := make([]string, 0, len(metrics))
+		for name := range metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(flags.Output(), "  %-17s %s\n", name, metrics[name].help)
+		}
+		fmt.Fprintf(flags.Output(), "  %-17s %s\n", "log.<field>", "a field of the latest watched log line with it")
+		fmt.Fprintf(flags.Output(), "  %-17s %s\n", "prom.<series>", `a scraped Prometheus series, e.g. prom.peers{kind="mesh"}`)
+		fmt.Fprintf(flags.Output(), "\nexit codes: 0 value printed, 1 usage or config error, %d node down, %d metric not reported\n", exitDown, exitNoValue)
+	}
+	// flags may follow the node and metric
+	var positional []string
+	for rest := args; ; {
+		flags.Parse(rest)
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		rest = flags.Args()[1:]
+	}
+	if len(positional) != 2 {
+		flags.Usage()
+		return errors.New("get needs a node and a metric")
+	}
+	name, metricName := positional[0], positional[1]
+	read, err := metricReader(metricName)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && *simulateNodes > 0:
+		cfg = &config.Config{}
+	case err != nil:
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	if *simulateNodes > 0 {
+		cfg.Nodes = simulate.Nodes(*simulateNodes)
+	}
+	i, err := findNode(cfg.Nodes, name)
+	if err != nil {
+		return err
+	}
+
+	pipeline := collector.NewPipeline()
+	snapshots := pipeline.Subscribe(1)
+	setupCollector(cfg, cfg.Nodes[i:i+1], pipeline, *simulateNodes).PollOnce()
+	snapshot := <-snapshots
+	if snapshot.Err != nil {
+		return exitError{code: exitDown, err: fmt.Errorf("%s is down: %s", name, transport.Describe(snapshot.Err))}
+	}
+	value, ok := read(snapshot.Status, *mount)
+	if !ok {
+		return exitError{code: exitNoValue, err: fmt.Errorf("%s didn't report %s", name, metricName)}
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// metricReader returns how the named metric is read from a poll.
+func metricReader(name string) (func(status collector.Status, mount string) (string, bool), error) {
+	if m, ok := metrics[name]; ok {
+		return m.value, nil
+	}
+	if field, ok := strings.CutPrefix(name, "log."); ok && field != "" {
+		return func(status collector.Status, _ string) (string, bool) {
+			return logField(status, field)
+		}, nil
+	}
+	if series, ok := strings.CutPrefix(name, "prom."); ok && series != "" {
+		return func(status collector.Status, _ string) (string, bool) {
+			for _, m := range status.Metrics {
+				if m.Series() == series {
+					return strconv.FormatFloat(m.Value, 'f', -1, 64), true
+				}
+			}
+			return "", false
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown metric %q, see q-monitor get -h", name)
+}
+
+// pickDisk returns the disk mounted at mount, or the fullest one.
+func pickDisk(disks []parsers.DiskUsage, mount string) (parsers.DiskUsage, bool) {
+	var picked parsers.DiskUsage
+	found := false
+	for _, disk := range disks {
+		switch {
+		case mount != "":
+			if disk.Mount == mount {
+				return disk, true
+			}
+		case !found || disk.UsedPercent() > picked.UsedPercent():
+			picked, found = disk, true
+		}
+	}
+	return picked, found
+}
+
+// logField returns a field of the latest watched log message with it.
+// The messages are in the order they are watched in, so it goes by their
+// times; messages without one count as older, the first one winning.
+func logField(status collector.Status, key string) (string, bool) {
+	var field string
+	var latest time.Time
+	found := false
+	for _, message := range status.Logs {
+		if found && !message.Time.After(latest) {
+			continue
+		}
+		switch value := message.Fields[key].(type) {
+		case float64:
+			field = strconv.FormatFloat(value, 'f', -1, 64)
+		case string:
+			field = value
+		case bool:
+			field = strconv.FormatBool(value)
+		default:
+			continue
+		}
+		latest, found = message.Time, true
+	}
+	return field, found
+}
+
+func percent(value float64) string {
+	return strconv.FormatFloat(value, 'f', 1, 64)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/parsers"
+)
+
+func TestLogField(t *testing.T) {
+	at := func(sec int64) time.Time { return time.Unix(sec, 0) }
+	peers := func(n float64, t time.Time) parsers.LogMessage {
+		return parsers.LogMessage{Msg: "peers in store", Time: t, Fields: map[string]interface{}{"network_peer_count": n}}
+	}
+	tests := []struct {
+		logs []parsers.LogMessage
+		want string
+		ok   bool
+	}{
+		{[]parsers.LogMessage{peers(12, at(100))}, "12", true},
+		// in the order of the watched messages, the newer one later
+		{[]parsers.LogMessage{peers(12, at(100)), peers(15, at(200))}, "15", true},
+		{[]parsers.LogMessage{peers(15, at(200)), peers(12, at(100))}, "15", true},
+		// messages without the field don't count, however new
+		{[]parsers.LogMessage{peers(12, at(100)), {Msg: "broadcasting self-test info", Time: at(300), Fields: map[string]interface{}{"current_frame": 100993.0}}}, "12", true},
+		// a message with a time is newer than one without
+		{[]parsers.LogMessage{peers(12, time.Time{}), peers(15, at(100))}, "15", true},
+		{[]parsers.LogMessage{peers(12, time.Time{}), peers(15, time.Time{})}, "12", true},
+		{[]parsers.LogMessage{{Msg: "x", Fields: map[string]interface{}{"network_peer_count": "many"}}}, "many", true},
+		{[]parsers.LogMessage{{Msg: "x", Fields: map[string]interface{}{"network_peer_count": []interface{}{1.0}}}}, "", false},
+		{nil, "", false},
+	}
+	for i, tt := range tests {
+		got, ok := metrics["peers"].value(collector.Status{Logs: tt.logs}, "")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("test %d: peers = %q, %v, want %q, %v", i, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -1,25 +1,25 @@
-module metrics
+module github.com/fid1699/q-monitor-cli
 
 go 1.22.3
 
 require (
 	github.com/fatih/color v1.17.0
+	github.com/gdamore/tcell/v2 v2.7.1
+	github.com/mattn/go-runewidth v0.0.15
+	github.com/rivo/tview v0.0.0-20240524063012-037df494fb76
 	golang.org/x/crypto v0.23.0
+	golang.org/x/term v0.20.0
 )
 
 require (
 	github.com/gdamore/encoding v1.0.0 // indirect
-	github.com/gdamore/tcell/v2 v2.7.1 // indirect
 	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/mattn/go-runewidth v0.0.15 // indirect
-	github.com/rivo/tview v0.0.0-20240524063012-037df494fb76 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/lint v0.0.0-20210508222113-6edffad5e616 // indirect
 	golang.org/x/sys v0.20.0 // indirect
-	golang.org/x/term v0.20.0 // indirect
 	golang.org/x/text v0.15.0 // indirect
 	golang.org/x/tools v0.21.0 // indirect
 )
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/fid1699/q-monitor-cli/export"
+)
+
+// runGrafanaDashboard implements `q-monitor grafana-dashboard`, printing
+// a Grafana dashboard of the metrics `q-monitor serve` exports at
+// /metrics, ready to import.
+func runGrafanaDashboard(args []string) error {
+	flags := flag.NewFlagSet("grafana-dashboard", flag.ExitOnError)
+	title := flags.String("title", "Q nodes", "`title` of the dashboard")
+	datasource := flags.String("datasource", "", "`uid` of the Prometheus datasource, asked for on import if empty")
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		return errors.New("usage: q-monitor grafana-dashboard [--title title] [--datasource uid] > dashboard.json")
+	}
+
+	dashboard, err := export.GrafanaDashboard(*title, *datasource)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(dashboard))
+	return nil
+}
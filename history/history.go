@@ -0,0 +1,69 @@
+// Package history keeps recent metric values in memory, for graphs and
+// anything else that needs more than the latest poll.
+package history
+
+import "time"
+
+// Sample is a value at one point in time.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is a fixed size ring of samples, the oldest sample is dropped
+// once it is full. It is not safe for concurrent use.
+type Series struct {
+	samples []Sample
+	next    int
+	full    bool
+}
+
+// NewSeries returns a series that keeps the last capacity samples.
+func NewSeries(capacity int) *Series {
+	return &Series{samples: make([]Sample, capacity)}
+}
+
+func (s *Series) Add(t time.Time, value float64) {
+	if len(s.samples) == 0 {
+		return
+	}
+	s.samples[s.next] = Sample{Time: t, Value: value}
+	s.next = (s.next + 1) % len(s.samples)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// Len returns how many samples the series holds.
+func (s *Series) Len() int {
+	if s.full {
+		return len(s.samples)
+	}
+	return s.next
+}
+
+// Samples returns the samples from oldest to newest.
+func (s *Series) Samples() []Sample {
+	if !s.full {
+		return append([]Sample(nil), s.samples[:s.next]...)
+	}
+	return append(append([]Sample(nil), s.samples[s.next:]...), s.samples[:s.next]...)
+}
+
+// Values returns the sample values from oldest to newest.
+func (s *Series) Values() []float64 {
+	samples := s.Samples()
+	values := make([]float64, len(samples))
+	for i, sample := range samples {
+		values[i] = sample.Value
+	}
+	return values
+}
+
+// Last returns the newest sample.
+func (s *Series) Last() (Sample, bool) {
+	if s.Len() == 0 {
+		return Sample{}, false
+	}
+	return s.samples[(s.next+len(s.samples)-1)%len(s.samples)], true
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// csvColumns maps accepted header names to the node field they fill.
+var csvColumns = map[string]string{
+	"name":     "name",
+	"host":     "host",
+	"ip":       "host",
+	"port":     "port",
+	"user":     "user",
+	"username": "user",
+	"password": "password",
+	"tags":     "tags",
+}
+
+// runImport implements `q-monitor import csv nodes.csv`. The file needs a
+// header row; host is the only required column and tags are separated by
+// semicolons. Nodes already in the config (same host and port) are
+// skipped.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	configFile := flags.String("config", configFileName, "config `file` to add the nodes to")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: q-monitor import csv [-config file] nodes.csv")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "csv" {
+		flags.Usage()
+		return errors.New("only csv imports are supported")
+	}
+	flags.Parse(args[1:])
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one csv file")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	nodes, err := readCSVNodes(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", flags.Arg(0), err)
+	}
+
+	cfg, err := config.Load(*configFile)
+	if errors.Is(err, os.ErrNotExist) {
+		cfg = &config.Config{}
+	} else if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	existing := make(map[string]bool)
+	for _, node := range cfg.Nodes {
+		existing[nodeAddress(node)] = true
+	}
+	var added, skipped int
+	for _, node := range nodes {
+		if existing[nodeAddress(node)] {
+			skipped++
+			continue
+		}
+		existing[nodeAddress(node)] = true
+		cfg.Nodes = append(cfg.Nodes, node)
+		added++
+	}
+
+	if err := config.Save(*configFile, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("imported %d nodes into %s (%d skipped as duplicates)\n", added, *configFile, skipped)
+	return nil
+}
+
+func nodeAddress(node config.Node) string {
+	return fmt.Sprintf("%s:%d", node.IP, node.SSHPort())
+}
+
+func readCSVNodes(r io.Reader) ([]config.Node, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		field, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns[field] = i
+	}
+	if _, ok := columns["host"]; !ok {
+		return nil, errors.New("missing host column")
+	}
+
+	var nodes []config.Node
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		value := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		node := config.Node{
+			Name:     value("name"),
+			IP:       value("host"),
+			Username: value("user"),
+			Password: value("password"),
+		}
+		if node.IP == "" {
+			return nil, fmt.Errorf("line %d: empty host", line)
+		}
+		if port := value("port"); port != "" {
+			if node.Port, err = strconv.Atoi(port); err != nil {
+				return nil, fmt.Errorf("line %d: invalid port %q", line, port)
+			}
+		}
+		for _, tag := range strings.Split(value("tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				node.Tags = append(node.Tags, tag)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+func TestReadCSVNodes(t *testing.T) {
+	tests := []struct {
+		csv  string
+		want []config.Node
+	}{
+		{"host\n10.0.0.1\n10.0.0.2\n",
+			[]config.Node{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}},
+		{"Name, IP, Port, Username, Password, Tags\nfra-1, 10.0.0.1, 2222, quil, pw, eu; prover ;\n",
+			[]config.Node{{Name: "fra-1", IP: "10.0.0.1", Port: 2222, Username: "quil", Password: "pw", Tags: []string{"eu", "prover"}}}},
+		// columns in any order, user and host as aliases
+		{"user,host\nroot,box.example\n",
+			[]config.Node{{IP: "box.example", Username: "root"}}},
+		{"host,password\n10.0.0.1,\"with,comma\"\n",
+			[]config.Node{{IP: "10.0.0.1", Password: "with,comma"}}},
+		{"host\n", nil},
+	}
+	for _, tt := range tests {
+		got, err := readCSVNodes(strings.NewReader(tt.csv))
+		if err != nil {
+			t.Errorf("readCSVNodes(%q): %v", tt.csv, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readCSVNodes(%q) = %+v, want %+v", tt.csv, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSVNodesErrors(t *testing.T) {
+	tests := []struct {
+		csv  string
+		want string
+	}{
+		{"", "failed to read header"},
+		{"name\nfra-1\n", "missing host column"},
+		{"host,region\n10.0.0.1,eu\n", `unknown column "region"`},
+		{"name,host\nfra-1,10.0.0.1\nfra-2,\n", "line 3: empty host"},
+		{"host,port\n10.0.0.1,ssh\n", `line 2: invalid port "ssh"`},
+		{"host,port\n10.0.0.1\n", "wrong number of fields"},
+	}
+	for _, tt := range tests {
+		_, err := readCSVNodes(strings.NewReader(tt.csv))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("readCSVNodes(%q) = %v, want an error with %q", tt.csv, err, tt.want)
+		}
+	}
+}
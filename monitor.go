@@ -1,293 +1,369 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net/http"
 	"os"
-	"strings"
-	"sync"
-	"time"
-
-	"github.com/rivo/tview"
-	"golang.org/x/crypto/ssh"
+	"os/signal"
+	"syscall"
+	// zone names in node configs load on hosts without a zone database
+	_ "time/tzdata"
+
+	"golang.org/x/term"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/availability"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/digest"
+	"github.com/fid1699/q-monitor-cli/export"
+	"github.com/fid1699/q-monitor-cli/price"
+	"github.com/fid1699/q-monitor-cli/server"
+	"github.com/fid1699/q-monitor-cli/simulate"
+	"github.com/fid1699/q-monitor-cli/transport"
+	"github.com/fid1699/q-monitor-cli/ui"
 )
 
-type Node struct {
-	IP       string `json:"ip"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-}
-
-type Config struct {
-	Nodes []Node `json:"nodes"`
-}
-
-// LogReader is an interface for reading logs from different Q execution methods
-type LogReader interface {
-	ReadLogs(session *ssh.Session) (string, error)
-}
-
-// ServiceLogReader reads logs from a running Q service
-type ServiceLogReader struct {
-	ServiceName string
-}
+const configFileName = ".config.json"
 
-func (s ServiceLogReader) ReadLogs(session *ssh.Session) (string, error) {
-	cmd := fmt.Sprintf("journalctl -u %s.service -n 50 --no-hostname -o cat | grep -E '\"msg\":\"(connecting to bootstrap|broadcasting self-test info|peers in store)\"'", s.ServiceName)
+// availabilityFileName is the log of the nodes' outages, see package
+// availability.
+const availabilityFileName = ".availability.jsonl"
 
-	var b bytes.Buffer
-	session.Stdout = &b
-	if err := session.Run(cmd); err != nil {
-		return "", fmt.Errorf("failed to run command '%s': %w", cmd, err)
+func main() {
+	if ok, err := runCommand(os.Args[1:]); ok {
+		if err != nil {
+			fail(err)
+		}
+		return
 	}
 
-	return b.String(), nil
-}
-
-// TmuxLogReader reads logs from a tmux pane running Q
-type TmuxLogReader struct {
-	PaneName string
-}
-
-func (t TmuxLogReader) ReadLogs(session *ssh.Session) (string, error) {
-	cmd := fmt.Sprintf("tmux capture-pane -t %s -pS -100 | grep -E '\"msg\":\"(connecting to bootstrap|broadcasting self-test info|peers in store)\"' | tail -n 200", t.PaneName)
-
-	var b bytes.Buffer
-	session.Stdout = &b
-	if err := session.Run(cmd); err != nil {
-		return "", fmt.Errorf("failed to run command '%s': %w", cmd, err)
+	simulateNodes := flag.Int("simulate", 0, "poll `N` simulated nodes instead of the configured ones")
+	layout := flag.String("layout", "", "show the nodes in a `grid`, list or table, by default the one the fleet fits best on screen")
+	compact := flag.Bool("compact", false, "show each node in two lines, with the focused one in full, same as --layout list")
+	accessible := flag.Bool("accessible", false, "spell out node health as OK/WARN/CRIT instead of using color alone")
+	lines := flag.Bool("lines", false, "print plain text lines per node and poll instead of the full screen UI, same as --output lines")
+	output := flag.String("output", "tui", "`format` to show the nodes in: tui, lines, or jsonl for one JSON object per node and poll")
+	connect := flag.String("connect", "", "show the nodes of the monitor server at `addr` instead of polling them, see q-monitor serve")
+	supervise := flag.Bool("supervise", false, "poll in a child process that is restarted if it crashes")
+	screenshot := flag.String("screenshot", "", "poll the nodes once and save the TUI to `file` instead, as HTML if it ends in .html and as text otherwise")
+	lowBandwidth := flag.Bool("low-bandwidth", false, "poll less often and less, for a metered link, see low_bandwidth in the config")
+	flag.Parse()
+	if *lines {
+		*output = "lines"
 	}
 
-	return b.String(), nil
-}
-
-const configFileName = ".config.json"
-const pollingInterval = 1 * time.Minute
-
-// loadConfig loads node information from a config file
-// the expected format matches the above structs, i.e.
-// {"nodes": [{"ip":"...","username":"...","password":"..."},{...}]}
-//
-// do not use root as the user for this script. It's best to have a
-// dedicated monitor user with the minimum required perms.
-func loadConfig(filename string) (*Config, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+	var cfg *config.Config
+	if *connect != "" {
+		// the server has the nodes, only the display settings and the
+		// server credentials are used
+		cfg = loadOptionalConfig()
+	} else {
+		cfg = loadConfig(*simulateNodes)
 	}
-	defer file.Close()
-
-	decoder := json.NewDecoder(file)
-	config := &Config{}
-	err = decoder.Decode(config)
-	if err != nil {
-		return nil, err
+	if *compact {
+		cfg.Display.Layout = config.LayoutList
+	}
+	if *layout != "" {
+		cfg.Display.Layout = *layout
+		if err := cfg.Display.Validate(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	if *accessible {
+		cfg.Display.Accessible = true
+	}
+	if *lowBandwidth && cfg.LowBandwidth == nil {
+		cfg.LowBandwidth = &config.LowBandwidth{}
+	}
+	if *screenshot != "" && (*output != "tui" || *connect != "" || *supervise) {
+		log.Fatalf("--screenshot only works with the tui output, polling the nodes itself")
+	}
+	if *connect != "" {
+		if *output != "tui" {
+			log.Fatalf("--connect only works with the tui output")
+		}
+		if *lowBandwidth {
+			log.Fatalf("--low-bandwidth doesn't work with --connect, pass it to q-monitor serve")
+		}
+		if err := runConnected(*connect, cfg); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
 	}
 
-	return config, nil
-}
+	if *supervise {
+		if *output != "tui" {
+			log.Fatalf("--supervise only works with the tui output")
+		}
+		if err := runSupervised(cfg, *simulateNodes); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
 
-func main() {
-	config, err := loadConfig(configFileName)
-	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+	pipeline := collector.NewPipeline()
+	c, alerts, tracker := newCollector(cfg, pipeline, *simulateNodes)
+
+	var run func() error
+	switch *output {
+	case "lines":
+		out := ui.NewLines(os.Stdout, cfg.Display)
+		pipeline.Register(out)
+		alerts.AddNotifier(out)
+		run = out.Run
+	case "jsonl":
+		pipeline.Register(export.NewJSONL(os.Stdout))
+		run = waitForInterrupt
+	case "tui":
+		if err := ui.ValidateKeys(cfg.Display.Keys); err != nil {
+			log.Fatalf("Error in config: %v", err)
+		}
+		tui := pollingTUI(cfg, c, alerts, tracker)
+		if *screenshot != "" {
+			run = screenshotRun(tui, c, pipeline, len(cfg.Nodes), *screenshot)
+			break
+		}
+		pipeline.Register(tui)
+		run = tui.Run
+	default:
+		log.Fatalf("Unknown output %q, use tui, lines or jsonl", *output)
 	}
 
-	// this is the definition of the view. Seems to run well
-	// for up to 10 nodes on a laptop monitor, can probably
-	// work for a few more on a desktop monitor, and you can also
-	// run on multiple monitors with different node configs.
-	app := tview.NewApplication()
-	grid := tview.NewGrid().SetRows(0).SetColumns(0)
-	textViews := make([]*tview.TextView, len(config.Nodes))
-	for i, _ := range config.Nodes {
-		textView := tview.NewTextView().
-			SetDynamicColors(true).
-			SetRegions(true).
-			SetWrap(false)
-		textViews[i] = textView
-		grid.AddItem(textView, i/2, i%2, 1, 1, 0, 0, false)
+	if *screenshot == "" {
+		go c.Run(context.Background())
 	}
 
-	var wg sync.WaitGroup
-	go func() {
-		for {
-			for i, node := range config.Nodes {
-				wg.Add(1)
-				go func(i int, node Node) {
-					defer wg.Done()
-					// this implementation uses the service log reader, but you
-					// can also use the tmux log reader (or add your own e.g. docker)
-					logReader := ServiceLogReader{ServiceName: "ceremonyclient"}
-					output, err := getNodeStatus(node, logReader)
-					if err != nil {
-						textViews[i].SetText(fmt.Sprintf("Error fetching status for node %s: %v", node.IP, err))
-						app.QueueUpdateDraw(func() {
-							textViews[i].SetText(fmt.Sprintf("Error fetching status for node %s: %v", node.IP, err))
-						})
-					} else {
-						textViews[i].SetText(output)
-						app.QueueUpdateDraw(func() {
-							textViews[i].SetText(output)
-						})
-					}
-				}(i, node)
-			}
-			wg.Wait()
-			time.Sleep(pollingInterval)
+	if err := run(); err != nil {
+		if *screenshot != "" {
+			log.Fatalf("Error: %v", err)
 		}
-	}()
-
-	if err := app.SetRoot(grid, true).Run(); err != nil {
 		panic(err)
 	}
 }
 
-func getNodeStatus(node Node, logReader LogReader) (string, error) {
-	config := &ssh.ClientConfig{
-		User: node.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(node.Password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+// pollingTUI returns a TUI on the nodes c polls, with their alerts and
+// availability, for the caller to register on the pipeline and run.
+func pollingTUI(cfg *config.Config, c *collector.Collector, alerts *alert.Engine, tracker *availability.Tracker) *ui.TUI {
+	tui := ui.New(cfg.Nodes, cfg.Display)
+	tui.StaleAfter = 3 * c.Interval
+	tui.Availability = tracker
+	tui.Alerts, tui.User = alerts, currentUser()
+	tui.Polls = c
+	if cfg.Benchmark != nil {
+		tui.Benchmark = c
 	}
-
-	conn, err := ssh.Dial("tcp", node.IP+":22", config)
-	if err != nil {
-		return "", fmt.Errorf("failed to dial: %w", err)
+	alerts.AddWatcher(tui)
+	c.Events().Register(tui)
+	if cfg.Earnings != nil {
+		tui.Earnings = cfg.Earnings
+		go price.Watch(*cfg.Earnings, tui.SetPrice)
 	}
-	defer conn.Close()
+	return tui
+}
 
-	// commands for cpu, memory, disk space
-	statsCommands := []string{
-		"top -b -n 1 | grep 'Cpu(s)'",
-		"free -m",
-		"df -h /",
+// screenshotRun returns a run that polls the n nodes once, draws them on
+// a screen the size of the terminal, or of ui.ScreenshotWidth and
+// ScreenshotHeight without one, and saves it to path.
+func screenshotRun(tui *ui.TUI, c *collector.Collector, pipeline *collector.Pipeline, n int, path string) func() error {
+	width, height := ui.ScreenshotWidth, ui.ScreenshotHeight
+	if isTerminal(os.Stdout) {
+		if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			width, height = w, h
+		}
 	}
+	snapshots := pipeline.Subscribe(n)
+	return func() error {
+		return tui.Screenshot(path, width, height, func() {
+			c.PollOnce()
+			for range n {
+				tui.Consume(<-snapshots)
+			}
+		})
+	}
+}
 
-	var stats []string
-	for _, cmd := range statsCommands {
-		session, err := conn.NewSession()
-		if err != nil {
-			return "", fmt.Errorf("failed to create session: %w", err)
+// loadConfig loads the config file, or runs the setup if there is none
+// and stdin is a terminal. With simulateNodes the simulated nodes replace
+// the configured ones and the config is optional.
+func loadConfig(simulateNodes int) *config.Config {
+	cfg, err := config.Load(configFileName)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist) && simulateNodes > 0:
+		// a config is optional when simulating, the nodes come from the simulator
+		cfg = &config.Config{}
+	case errors.Is(err, os.ErrNotExist) && isTerminal(os.Stdin):
+		if cfg, err = runSetup(configFileName); err != nil {
+			log.Fatalf("Setup failed: %v", err)
 		}
-		defer session.Close()
-		var b bytes.Buffer
-		session.Stdout = &b
-		if err := session.Run(cmd); err != nil {
-			return "", fmt.Errorf("failed to run command '%s': %w", cmd, err)
+	default:
+		log.Fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.Display.Validate(); err != nil {
+		log.Fatalf("Error in config: %v", err)
+	}
+	if cfg.Benchmark != nil {
+		if err := cfg.Benchmark.Validate(); err != nil {
+			log.Fatalf("Error in config: %v", err)
 		}
-
-		stats = append(stats, b.String())
 	}
-
-	// we exec the logs command separately so we can use a reader
-	session, err := conn.NewSession()
-	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+	if cfg.Logins != nil {
+		if err := cfg.Logins.Validate(); err != nil {
+			log.Fatalf("Error in config: %v", err)
+		}
 	}
-	defer session.Close()
-	logs, err := logReader.ReadLogs(session)
-	if err != nil {
-		return "", fmt.Errorf("failed to read logs: %w", err)
+	if cfg.Snapshots != nil {
+		if err := cfg.Snapshots.Validate(); err != nil {
+			log.Fatalf("Error in config: %v", err)
+		}
 	}
-	stats = append(stats, logs)
-
-	output := formatOutput(node.IP, stats)
-	return output, nil
+	for _, node := range cfg.Nodes {
+		if err := node.ValidateChecks(); err != nil {
+			log.Fatalf("Error in config: %v", err)
+		}
+		if err := node.ValidateDisable(); err != nil {
+			log.Fatalf("Error in config: %v", err)
+		}
+		if err := node.ValidateRunAs(); err != nil {
+			log.Fatalf("Error in config: %v", err)
+		}
+	}
+	if simulateNodes > 0 {
+		cfg.Nodes = simulate.Nodes(simulateNodes)
+	}
+	return cfg
 }
 
-func formatOutput(ip string, stats []string) string {
-	cpuUsage := parseCPUUsage(stats[0])
-	memoryUsage := parseMemoryUsage(stats[1])
-
-	output := fmt.Sprintf("[blue::b]Node: %s\n", ip)
-	output += fmt.Sprintf("[green::b]CPU Usage: [white]%s\n", cpuUsage)
-	output += fmt.Sprintf("[green::b]Memory Usage: [white]%s\n", memoryUsage)
-	output += fmt.Sprintf("[green::b]Storage Usage:\n [white]%s", stats[2])
-
-	logs := extractLogMessages(stats[3])
-	output += fmt.Sprintf("[yellow::b]Logs: [white]%s", logs)
-
-	return output
+// loadOptionalConfig loads the config file if there is one, for the
+// settings that don't need nodes.
+func loadOptionalConfig() *config.Config {
+	cfg, err := config.Load(configFileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		cfg = &config.Config{}
+	case err != nil:
+		log.Fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.Display.Validate(); err != nil {
+		log.Fatalf("Error in config: %v", err)
+	}
+	return cfg
 }
 
-// extractLogMessages takes in a bunch of logs and returns the ones
-// "we care about". I care about the three types included below, but
-// you can add your own message keys if you want anything else to show up.
-// If the log key isn't found in the last batch of logs it's omitted.
-func extractLogMessages(logs string) string {
-	var result strings.Builder
-
-	lines := strings.Split(logs, "\n")
-	messageTypes := map[string]map[string]interface{}{
-		"connecting to bootstrap":     nil,
-		"broadcasting self-test info": nil,
-		"peers in store":              nil,
+// newCollector sets up the collector for the config's nodes, feeding
+// pipeline, with the alert engine on its events and the availability
+// tracker on the pipeline. The caller adds its frontend to both and
+// starts the collector.
+func newCollector(cfg *config.Config, pipeline *collector.Pipeline, simulateNodes int) (*collector.Collector, *alert.Engine, *availability.Tracker) {
+	alerts := alert.NewEngine()
+	c := setupCollector(cfg, cfg.Nodes, pipeline, simulateNodes)
+
+	c.Events().Register(alerts)
+	// simulated outages stay out of the log of the real ones
+	path := availabilityFileName
+	if simulateNodes > 0 {
+		path = ""
 	}
-
-	for _, line := range lines {
-		var logEntry map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &logEntry); err != nil {
-			continue
+	tracker, err := availability.Open(path)
+	if err != nil {
+		log.Fatalf("Error opening the availability log: %v", err)
+	}
+	pipeline.Register(tracker)
+	// the digest goes wherever the alerts go
+	var senders []digest.Sender
+	if cfg.Syslog != nil {
+		forward, err := export.NewSyslog(*cfg.Syslog)
+		if err != nil {
+			log.Fatalf("Error setting up syslog: %v", err)
 		}
-
-		msg, ok := logEntry["msg"].(string)
-		if !ok {
-			continue
+		c.Events().Register(forward)
+		senders = append(senders, forward)
+	}
+	if cfg.Telegram != nil {
+		telegram, err := export.NewTelegram(*cfg.Telegram)
+		if err != nil {
+			log.Fatalf("Error setting up telegram: %v", err)
 		}
-
-		if _, exists := messageTypes[msg]; exists {
-			messageTypes[msg] = logEntry
+		alerts.AddNotifier(telegram)
+		senders = append(senders, telegram)
+	}
+	if cfg.Digest != nil {
+		summary, err := digest.New(*cfg.Digest, cfg.Nodes, senders...)
+		if err != nil {
+			log.Fatalf("Error setting up the digest: %v", err)
 		}
+		pipeline.Register(summary)
+		alerts.AddNotifier(summary)
+		go summary.Run()
 	}
-
-	for msg, logEntry := range messageTypes {
-		if logEntry == nil {
-			continue
+	if cfg.Webhook != nil {
+		c.Signals = collector.NewSignals()
+		receiver := server.NewReceiver(*cfg.Webhook, cfg.Nodes, c.Signals)
+		listener, err := receiver.Listen()
+		if err != nil {
+			log.Fatalf("Error setting up the webhook: %v", err)
 		}
-
-		// omit some keys that are not interesting
-		delete(logEntry, "level")
-		delete(logEntry, "ts")
-		delete(logEntry, "caller")
-		delete(logEntry, "msg")
-
-		result.WriteString(fmt.Sprintf("{ msg: %v", msg))
-		for key, value := range logEntry {
-			if key != "msg" {
-				switch v := value.(type) {
-				case float64:
-					result.WriteString(fmt.Sprintf("; %s: %.0f", key, v))
-				case int, int64:
-					result.WriteString(fmt.Sprintf("; %s: %d", key, v))
-				default:
-					result.WriteString(fmt.Sprintf("; %s: %v", key, value))
-				}
+		go func() {
+			if err := http.Serve(listener, receiver.Handler()); err != nil {
+				log.Printf("Error serving the webhook: %v", err)
 			}
+		}()
+	}
+	if cfg.Snapshots != nil {
+		snapshots, err := export.NewSnapshots(*cfg.Snapshots)
+		if err != nil {
+			log.Fatalf("Error setting up snapshots: %v", err)
 		}
-		result.WriteString(" }\n")
+		pipeline.Register(snapshots)
+		go snapshots.Run()
 	}
-
-	return result.String()
+	if cfg.Loki != nil {
+		loki, err := export.NewLoki(*cfg.Loki)
+		if err != nil {
+			log.Fatalf("Error setting up loki: %v", err)
+		}
+		pipeline.Register(loki)
+	}
+	return c, alerts, tracker
 }
 
-func parseCPUUsage(cpuStat string) string {
-	parts := strings.Fields(cpuStat)
-	usage := fmt.Sprintf("User Space: %s%%; System Space: %s%%; Steal: %s%%",
-		parts[1], parts[3], parts[15])
-	return usage
+// setupCollector returns a collector for nodes with the config's checks
+// and limits, feeding pipeline, without any of the outputs.
+func setupCollector(cfg *config.Config, nodes []config.Node, pipeline *collector.Pipeline, simulateNodes int) *collector.Collector {
+	// nodes use the service log reader unless their config picks
+	// another one (tmux, or add your own e.g. docker)
+	c := collector.New(nodes, nil, pipeline)
+	c.Thresholds = cfg.Thresholds
+	c.Messages = cfg.Messages
+	c.Explorer = cfg.Explorer
+	c.Mesh = cfg.Mesh
+	c.Audit = cfg.Audit
+	c.Logins = cfg.Logins
+	c.KeyCheck = cfg.KeyCheck
+	c.Timeouts = cfg.Timeouts
+	c.Schedule = cfg.Schedule
+	c.Benchmark = cfg.Benchmark
+	c.LowBandwidth = cfg.LowBandwidth
+	if cfg.LowBandwidth != nil {
+		c.Interval = cfg.LowBandwidth.Interval()
+	}
+	if simulateNodes > 0 {
+		c.Dialer = simulate.NewDialer()
+		c.Interval = simulate.Interval
+	}
+	c.Dialer = transport.Limit(c.Dialer, cfg.SSH)
+	return c
 }
 
-func parseMemoryUsage(memStat string) string {
-	lines := strings.Split(memStat, "\n")
-	memParts := strings.Fields(lines[1])
-	total := memParts[1]
-	used := memParts[2]
-
-	usage := fmt.Sprintf("Total Memory: %s MB; Used Memory: %s MB",
-		total, used)
-	return usage
+// waitForInterrupt blocks until the process is interrupted, for outputs
+// that only consume the pipeline.
+func waitForInterrupt() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	return nil
 }
@@ -0,0 +1,74 @@
+package parsers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Bandwidth is a measurement of a node's connection.
+type Bandwidth struct {
+	// Download and Upload are in bits per second.
+	Download float64
+	Upload   float64
+	// Latency is the round trip time to the server, zero if the tool
+	// doesn't measure it.
+	Latency time.Duration
+	// Server is what the node measured against, as the tool names it.
+	Server string
+}
+
+// ParseIperf3 parses the output of `iperf3 -J`, returning the bits per
+// second the receiving end saw and the server. An iperf3 that failed
+// still writes its JSON, with the error.
+func ParseIperf3(output string) (float64, string, error) {
+	var result struct {
+		Start struct {
+			ConnectingTo struct {
+				Host string `json:"host"`
+				Port int    `json:"port"`
+			} `json:"connecting_to"`
+		} `json:"start"`
+		End struct {
+			SumReceived *struct {
+				BitsPerSecond float64 `json:"bits_per_second"`
+			} `json:"sum_received"`
+		} `json:"end"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return 0, "", fmt.Errorf("unexpected iperf3 output: %w", err)
+	}
+	if result.Error != "" {
+		return 0, "", errors.New("iperf3: " + result.Error)
+	}
+	if result.End.SumReceived == nil {
+		return 0, "", errors.New("iperf3 reported no transfer")
+	}
+	to := result.Start.ConnectingTo
+	return result.End.SumReceived.BitsPerSecond, fmt.Sprintf("%s:%d", to.Host, to.Port), nil
+}
+
+// ParseSpeedtest parses the output of `speedtest-cli --json`.
+func ParseSpeedtest(output string) (Bandwidth, error) {
+	var result struct {
+		Download float64 `json:"download"`
+		Upload   float64 `json:"upload"`
+		// milliseconds
+		Ping   float64 `json:"ping"`
+		Server struct {
+			Sponsor string `json:"sponsor"`
+			Name    string `json:"name"`
+		} `json:"server"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return Bandwidth{}, fmt.Errorf("unexpected speedtest-cli output: %w", err)
+	}
+	return Bandwidth{
+		Download: result.Download,
+		Upload:   result.Upload,
+		Latency:  time.Duration(result.Ping * float64(time.Millisecond)),
+		Server:   fmt.Sprintf("%s (%s)", result.Server.Sponsor, result.Server.Name),
+	}, nil
+}
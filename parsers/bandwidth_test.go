@@ -0,0 +1,71 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// iperf3Output is the part of an `iperf3 -J` run the parser reads, with
+// the surrounding fields a real run writes.
+const iperf3Output = `{
+	"start": {
+		"connected": [{"socket": 5, "local_host": "10.0.0.2", "local_port": 40022, "remote_host": "10.0.0.1", "remote_port": 5201}],
+		"version": "iperf 3.12",
+		"connecting_to": {"host": "10.0.0.1", "port": 5201},
+		"test_start": {"protocol": "TCP", "num_streams": 1, "duration": 10}
+	},
+	"intervals": [],
+	"end": {
+		"sum_sent": {"start": 0, "end": 10.000183, "seconds": 10.000183, "bytes": 1176502272, "bits_per_second": 941184596.05, "retransmits": 12},
+		"sum_received": {"start": 0, "end": 10.002636, "seconds": 10.000183, "bytes": 1173880832, "bits_per_second": 938857975.27}
+	}
+}`
+
+func TestParseIperf3(t *testing.T) {
+	bps, server, err := ParseIperf3(iperf3Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bps != 938857975.27 || server != "10.0.0.1:5201" {
+		t.Errorf("ParseIperf3 = %v, %q, want 938857975.27, 10.0.0.1:5201", bps, server)
+	}
+}
+
+func TestParseIperf3Errors(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{`{"start": {"connected": []}, "intervals": [], "end": {}, "error": "unable to connect to server: Connection refused"}`,
+			"iperf3: unable to connect to server: Connection refused"},
+		{`{"start": {}, "end": {}}`, "iperf3 reported no transfer"},
+		{"iperf3: error - unable to connect to server\n", "unexpected iperf3 output"},
+		{"", "unexpected iperf3 output"},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseIperf3(tt.output)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseIperf3(%q) = %v, want an error with %q", tt.output, err, tt.want)
+		}
+	}
+}
+
+func TestParseSpeedtest(t *testing.T) {
+	output := `{"download": 482311520.71, "upload": 93704210.25, "ping": 11.482, ` +
+		`"server": {"url": "http://speedtest.example.net:8080/speedtest/upload.php", "name": "Frankfurt", "country": "Germany", "sponsor": "Example Ltd", "id": "12345", "latency": 11.482}, ` +
+		`"timestamp": "2024-05-15T10:07:30.000000Z", "bytes_sent": 117440512, "bytes_received": 602791292, "share": null, ` +
+		`"client": {"ip": "203.0.113.7", "isp": "Example ISP"}}`
+	got, err := ParseSpeedtest(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Bandwidth{Download: 482311520.71, Upload: 93704210.25, Latency: 11482 * time.Microsecond, Server: "Example Ltd (Frankfurt)"}
+	if got != want {
+		t.Errorf("ParseSpeedtest = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseSpeedtest("Cannot retrieve speedtest configuration\n"); err == nil || !strings.Contains(err.Error(), "unexpected speedtest-cli output") {
+		t.Errorf("ParseSpeedtest of an error message = %v, want an unexpected output error", err)
+	}
+}
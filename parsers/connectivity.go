@@ -0,0 +1,100 @@
+package parsers
+
+import (
+	"strings"
+	"time"
+)
+
+// ConnectivityMessages are the networking messages the node logs its
+// connectivity with. They are read besides the watched messages, whose
+// entries may carry the same fields.
+var ConnectivityMessages = []string{"reachability changed", "peer connection stats"}
+
+// Connectivity is how a node reaches its peers, from the networking
+// fields of its log entries.
+type Connectivity struct {
+	// Reachability is what the node's NAT detection found: public,
+	// private or unknown. It is empty if the node didn't log it.
+	Reachability string
+	// Direct and Relayed are the node's peer connections, by whether
+	// they go through a relay. Counted is false if it didn't log them.
+	Direct  int
+	Relayed int
+	Counted bool
+	// Protocols are the protocol versions the node speaks.
+	Protocols []string
+	// Time is when the newest of the entries was logged.
+	Time time.Time
+}
+
+// RelayedOnly reports whether the node has peers, none of them
+// connected directly.
+func (c Connectivity) RelayedOnly() bool {
+	return c.Counted && c.Direct == 0 && c.Relayed > 0
+}
+
+// Update takes what newer logged over c, keeping what it didn't.
+func (c *Connectivity) Update(newer Connectivity) {
+	if newer.Reachability != "" {
+		c.Reachability = newer.Reachability
+	}
+	if newer.Counted {
+		c.Direct, c.Relayed, c.Counted = newer.Direct, newer.Relayed, true
+	}
+	if len(newer.Protocols) > 0 {
+		c.Protocols = newer.Protocols
+	}
+	if newer.Time.After(c.Time) {
+		c.Time = newer.Time
+	}
+}
+
+// ParseConnectivity reads the connectivity fields of the log entries
+// newer than since, the last entry with a field winning:
+// reachability (or nat_status), direct_connections and
+// relayed_connections, and protocol_version, a comma separated list.
+// It returns false if no entry has any of them.
+func ParseConnectivity(logs string, format LogFormat, since time.Time, loc *time.Location) (Connectivity, bool) {
+	var c Connectivity
+	found := false
+	for _, line := range strings.Split(logs, "\n") {
+		entry, ok := format.Parse(line)
+		if !ok {
+			continue
+		}
+		ts, hasTime := entryTime(entry, loc)
+		if hasTime && !ts.After(since) {
+			continue
+		}
+		var newer Connectivity
+		seen := false
+		for _, key := range []string{"reachability", "nat_status"} {
+			if v, ok := entry[key].(string); ok && v != "" {
+				// libp2p names them ReachabilityPublic and so on
+				newer.Reachability = strings.TrimPrefix(strings.ToLower(v), "reachability")
+				seen = true
+			}
+		}
+		direct, hasDirect := entry["direct_connections"].(float64)
+		relayed, hasRelayed := entry["relayed_connections"].(float64)
+		if hasDirect || hasRelayed {
+			newer.Direct, newer.Relayed, newer.Counted = int(direct), int(relayed), true
+			seen = true
+		}
+		if v, ok := entry["protocol_version"].(string); ok && v != "" {
+			for _, protocol := range strings.Split(v, ",") {
+				if protocol = strings.TrimSpace(protocol); protocol != "" {
+					newer.Protocols = append(newer.Protocols, protocol)
+				}
+			}
+			seen = true
+		}
+		if !seen {
+			continue
+		}
+		newer.Time = ts
+		c.Update(newer)
+		found = true
+	}
+	return c, found
+}
@@ -0,0 +1,57 @@
+package parsers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseConnectivity(t *testing.T) {
+	at := func(sec int64) time.Time { return time.Unix(sec, 0) }
+	tests := []struct {
+		logs  string
+		since time.Time
+		want  Connectivity
+		found bool
+	}{
+		{`{"level":"info","ts":1715767650,"msg":"reachability changed","reachability":"ReachabilityPrivate"}
+{"level":"info","ts":1715767651,"msg":"peer connection stats","direct_connections":0,"relayed_connections":41,"protocol_version":"2.0.4, 2.0.3"}
+`, time.Time{}, Connectivity{Reachability: "private", Relayed: 41, Counted: true, Protocols: []string{"2.0.4", "2.0.3"}, Time: at(1715767651)}, true},
+		// the last entry with a field wins, the others are kept
+		{`{"ts":1715767650,"msg":"peer connection stats","direct_connections":3,"relayed_connections":1,"reachability":"public"}
+{"ts":1715767652,"msg":"peer connection stats","direct_connections":5}
+{"ts":1715767651,"msg":"reachability changed","nat_status":"unknown"}
+`, time.Time{}, Connectivity{Reachability: "unknown", Direct: 5, Counted: true, Time: at(1715767652)}, true},
+		// entries up to since are old
+		{`{"ts":1715767650,"msg":"peer connection stats","direct_connections":3}
+{"ts":1715767660,"msg":"reachability changed","reachability":"ReachabilityPublic"}
+`, at(1715767650), Connectivity{Reachability: "public", Time: at(1715767660)}, true},
+		{`{"ts":1715767650,"msg":"peers in store","network_peer_count":41}
+-- No entries --
+{"ts":1715767651,"msg":"reachability changed","reachability":""}
+`, time.Time{}, Connectivity{}, false},
+	}
+	for _, tt := range tests {
+		got, found := ParseConnectivity(tt.logs, JSONFormat{}, tt.since, nil)
+		if found != tt.found || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseConnectivity(%q) = %+v, %v, want %+v, %v", tt.logs, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestConnectivityRelayedOnly(t *testing.T) {
+	tests := []struct {
+		c    Connectivity
+		want bool
+	}{
+		{Connectivity{Relayed: 3, Counted: true}, true},
+		{Connectivity{Direct: 1, Relayed: 3, Counted: true}, false},
+		{Connectivity{Counted: true}, false},
+		{Connectivity{Reachability: "private"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.c.RelayedOnly(); got != tt.want {
+			t.Errorf("%+v.RelayedOnly() = %v, want %v", tt.c, got, tt.want)
+		}
+	}
+}
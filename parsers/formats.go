@@ -0,0 +1,176 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Log format names accepted by NewLogFormat.
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+	FormatRegex  = "regex"
+)
+
+// LogFormat parses the log lines of a node. Every format stores the log
+// message under the "msg" key.
+type LogFormat interface {
+	// Parse returns the fields of a log line, ok is false for lines that
+	// aren't log entries.
+	Parse(line string) (fields map[string]interface{}, ok bool)
+	// Filter returns an extended regular expression (as understood by
+	// grep -E) matching the lines that carry one of the messages.
+	Filter(messages []string) string
+}
+
+// NewLogFormat returns the named format, JSON when name is empty. pattern
+// is only used by the regex format.
+func NewLogFormat(name, pattern string) (LogFormat, error) {
+	switch name {
+	case "", FormatJSON:
+		return JSONFormat{}, nil
+	case FormatLogfmt:
+		return LogfmtFormat{}, nil
+	case FormatRegex:
+		return NewRegexFormat(pattern)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", name)
+	}
+}
+
+// JSONFormat parses structured JSON logs, the node's default output.
+type JSONFormat struct{}
+
+func (JSONFormat) Parse(line string) (map[string]interface{}, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+func (JSONFormat) Filter(messages []string) string {
+	return fmt.Sprintf(`"msg":"%s"`, alternatives(messages))
+}
+
+// LogfmtFormat parses key=value logs, values may be double quoted.
+// Numeric values are returned as float64 like in JSON.
+type LogfmtFormat struct{}
+
+func (LogfmtFormat) Parse(line string) (map[string]interface{}, bool) {
+	fields := make(map[string]interface{})
+	rest := strings.TrimSpace(line)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 || strings.ContainsAny(rest[:eq], " \t\"") {
+			return nil, false
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, false
+			}
+			value, _ = strconv.Unquote(quoted)
+			rest = rest[len(quoted):]
+			fields[key] = value
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+			fields[key] = logfmtValue(value)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+	}
+
+	if _, ok := fields["msg"]; !ok {
+		return nil, false
+	}
+	return fields, true
+}
+
+func logfmtValue(value string) interface{} {
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+func (LogfmtFormat) Filter(messages []string) string {
+	return fmt.Sprintf(`msg="?%s`, alternatives(messages))
+}
+
+// RegexFormat parses plaintext logs with a regular expression. Named
+// groups become fields, a group named "msg" is required.
+type RegexFormat struct {
+	pattern *regexp.Regexp
+}
+
+func NewRegexFormat(pattern string) (RegexFormat, error) {
+	if pattern == "" {
+		return RegexFormat{}, fmt.Errorf("regex log format needs a pattern")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return RegexFormat{}, fmt.Errorf("invalid log pattern: %w", err)
+	}
+	if re.SubexpIndex("msg") < 0 {
+		return RegexFormat{}, fmt.Errorf("log pattern %q has no (?P<msg>...) group", pattern)
+	}
+	return RegexFormat{pattern: re}, nil
+}
+
+func (r RegexFormat) Parse(line string) (map[string]interface{}, bool) {
+	match := r.pattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil, false
+	}
+
+	fields := make(map[string]interface{})
+	for i, name := range r.pattern.SubexpNames() {
+		if name == "" || i >= len(match) {
+			continue
+		}
+		if name == "msg" {
+			fields[name] = strings.TrimSpace(match[i])
+		} else {
+			fields[name] = logfmtValue(match[i])
+		}
+	}
+	return fields, true
+}
+
+// Filter only matches the message text, the line layout is up to the
+// pattern.
+func (RegexFormat) Filter(messages []string) string {
+	return alternatives(messages)
+}
+
+// alternatives returns the messages as an escaped ERE group.
+func alternatives(messages []string) string {
+	escaped := make([]string, len(messages))
+	for i, msg := range messages {
+		escaped[i] = escapeERE(msg)
+	}
+	return "(" + strings.Join(escaped, "|") + ")"
+}
+
+func escapeERE(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\.[]()*+?{}|^$`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
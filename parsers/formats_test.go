@@ -0,0 +1,148 @@
+package parsers
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestJSONFormatParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]interface{}
+		ok   bool
+	}{
+		{`{"msg":"peers in store","network_peer_count":12}`, map[string]interface{}{"msg": "peers in store", "network_peer_count": 12.0}, true},
+		{`{"level":"info","ts":1715767650.5,"msg":"x"}`, map[string]interface{}{"level": "info", "ts": 1715767650.5, "msg": "x"}, true},
+		{`-- No entries --`, nil, false},
+		{`{"msg":`, nil, false},
+		{``, nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := JSONFormat{}.Parse(tt.line)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLogfmtFormatParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]interface{}
+		ok   bool
+	}{
+		{`msg=started`, map[string]interface{}{"msg": "started"}, true},
+		{`level=info msg="peers in store" network_peer_count=12`,
+			map[string]interface{}{"level": "info", "msg": "peers in store", "network_peer_count": 12.0}, true},
+		// quoted values stay strings, even numeric ones
+		{`msg="x" frame="100"`, map[string]interface{}{"msg": "x", "frame": "100"}, true},
+		{`msg="say \"hi\"" ts=1.5`, map[string]interface{}{"msg": `say "hi"`, "ts": 1.5}, true},
+		{"  msg=x \t k=v  ", map[string]interface{}{"msg": "x", "k": "v"}, true},
+		{`msg=`, map[string]interface{}{"msg": ""}, true},
+		{`level=info`, nil, false},
+		{`just some text`, nil, false},
+		{`=value msg=x`, nil, false},
+		{`msg="unterminated`, nil, false},
+		{`"key"=v msg=x`, nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := LogfmtFormat{}.Parse(tt.line)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRegexFormat(t *testing.T) {
+	format, err := NewRegexFormat(`^(?P<time>\S+) (?P<level>\w+) (?P<msg>.*?)(?: peers=(?P<peers>\d+))?$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line string
+		want map[string]interface{}
+		ok   bool
+	}{
+		{"10:00:00 INFO peers in store peers=12",
+			map[string]interface{}{"time": "10:00:00", "level": "INFO", "msg": "peers in store", "peers": 12.0}, true},
+		{"10:00:00 INFO started ",
+			map[string]interface{}{"time": "10:00:00", "level": "INFO", "msg": "started", "peers": ""}, true},
+		{"garbage", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := format.Parse(tt.line)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewLogFormat(t *testing.T) {
+	tests := []struct {
+		name, pattern string
+		want          LogFormat
+		err           bool
+	}{
+		{"", "", JSONFormat{}, false},
+		{FormatJSON, "", JSONFormat{}, false},
+		{FormatLogfmt, "", LogfmtFormat{}, false},
+		{FormatRegex, "", nil, true},
+		{FormatRegex, `(?P<msg>`, nil, true},
+		{FormatRegex, `(?P<message>.*)`, nil, true},
+		{"xml", "", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := NewLogFormat(tt.name, tt.pattern)
+		if (err != nil) != tt.err {
+			t.Errorf("NewLogFormat(%q, %q) error = %v, want error %v", tt.name, tt.pattern, err, tt.err)
+			continue
+		}
+		if !tt.err && got != tt.want {
+			t.Errorf("NewLogFormat(%q, %q) = %#v, want %#v", tt.name, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	messages := []string{"peers in store", "proof (batch) took 1.5s", "a|b"}
+	regex, err := NewRegexFormat(`(?P<msg>.*)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format LogFormat
+		match  []string
+		skip   []string
+	}{
+		{JSONFormat{},
+			[]string{`{"msg":"peers in store","n":1}`, `{"msg":"proof (batch) took 1.5s"}`, `{"msg":"a|b"}`},
+			[]string{`{"msg":"peers"}`, `{"msg":"proof (batch) took 1x5s"}`, `{"msg":"a"}`, `peers in store`}},
+		{LogfmtFormat{},
+			[]string{`msg="peers in store" n=1`, `level=info msg="a|b"`},
+			[]string{`msg=peers`, `msg="a"`, `text="peers in store"`}},
+		{regex,
+			[]string{`peers in store`, `ERROR proof (batch) took 1.5s`},
+			[]string{`proof batch took 1.5s`, `a`}},
+	}
+	for _, tt := range tests {
+		filter := tt.format.Filter(messages)
+		// Go's syntax is a superset of the EREs the filters are written
+		// in
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			t.Errorf("%T filter %q: %v", tt.format, filter, err)
+			continue
+		}
+		for _, line := range tt.match {
+			if !re.MatchString(line) {
+				t.Errorf("%T filter %q doesn't match %q", tt.format, filter, line)
+			}
+		}
+		for _, line := range tt.skip {
+			if re.MatchString(line) {
+				t.Errorf("%T filter %q matches %q", tt.format, filter, line)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package parsers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// KeyFile is what the key check found out about a file.
+type KeyFile struct {
+	Path string
+	// Missing is set if the file doesn't exist or the monitor user can't
+	// get to it; the other fields are empty then.
+	Missing bool
+	Mode    os.FileMode
+	Owner   string
+	Dir     bool
+	// Checksum is the file's SHA-256 in hex, empty for directories and
+	// files the monitor user can't read.
+	Checksum string
+}
+
+// ParseKeyFiles parses the key check's lines, one per file, of
+// "mode|owner|type|checksum|path", or "missing||||path".
+func ParseKeyFiles(output string) ([]KeyFile, error) {
+	var files []KeyFile
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "|", 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected key file line %q", line)
+		}
+		file := KeyFile{Path: fields[4]}
+		if fields[0] == "missing" {
+			file.Missing = true
+			files = append(files, file)
+			continue
+		}
+		mode, err := strconv.ParseUint(fields[0], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected mode in key file line %q", line)
+		}
+		file.Mode = os.FileMode(mode)
+		file.Owner = fields[1]
+		file.Dir = strings.EqualFold(fields[2], "directory")
+		if sum, _, _ := strings.Cut(strings.TrimSpace(fields[3]), " "); !file.Dir {
+			file.Checksum = sum
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
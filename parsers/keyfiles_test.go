@@ -0,0 +1,59 @@
+package parsers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyFiles(t *testing.T) {
+	sum := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	tests := []struct {
+		output string
+		want   []KeyFile
+	}{
+		// GNU stat and sha256sum, which names stdin "-"
+		{"600|quil|regular file|" + sum + "  -|/home/quil/ceremonyclient/node/.config/keys.yml\n" +
+			"700|quil|directory||/home/quil/ceremonyclient/node/.config\n" +
+			"missing||||/home/quil/ceremonyclient/node/.config/config.yml\n",
+			[]KeyFile{
+				{Path: "/home/quil/ceremonyclient/node/.config/keys.yml", Mode: 0o600, Owner: "quil", Checksum: sum},
+				{Path: "/home/quil/ceremonyclient/node/.config", Mode: 0o700, Owner: "quil", Dir: true},
+				{Path: "/home/quil/ceremonyclient/node/.config/config.yml", Missing: true},
+			}},
+		// BSD stat, and a file the monitor user can't read
+		{"644|quil|Regular File|" + sum + "  -|/Users/quil/keys.yml\n" +
+			"640|root|Regular File||/etc/q/store|with|pipes\n",
+			[]KeyFile{
+				{Path: "/Users/quil/keys.yml", Mode: 0o644, Owner: "quil", Checksum: sum},
+				{Path: "/etc/q/store|with|pipes", Mode: 0o640, Owner: "root"},
+			}},
+		{"\n", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseKeyFiles(tt.output)
+		if err != nil {
+			t.Errorf("ParseKeyFiles(%q): %v", tt.output, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseKeyFiles(%q) = %+v, want %+v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeyFilesErrors(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"stat: cannot stat 'keys.yml': Permission denied\n", "unexpected key file line"},
+		{"rw-------|quil|regular file||/keys.yml\n", "unexpected mode in key file line"},
+	}
+	for _, tt := range tests {
+		_, err := ParseKeyFiles(tt.output)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseKeyFiles(%q) = %v, want an error with %q", tt.output, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package parsers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Listener is a socket a node listens on.
+type Listener struct {
+	// Protocol is tcp or udp.
+	Protocol string
+	// Address is the local address, * for any.
+	Address string
+	Port    int
+}
+
+// String names the listener's port, e.g. "22/tcp".
+func (l Listener) String() string {
+	return fmt.Sprintf("%d/%s", l.Port, l.Protocol)
+}
+
+// Loopback reports whether only the node itself can connect.
+func (l Listener) Loopback() bool {
+	ip := net.ParseIP(l.Address)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ParseListeners parses the output of `ss -Hlntu`, e.g.
+// "tcp LISTEN 0 4096 0.0.0.0:22 0.0.0.0:*". Addresses lose their
+// brackets and interface suffixes, like 127.0.0.53%lo.
+func ParseListeners(output string) ([]Listener, error) {
+	var listeners []Listener
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("unexpected ss output: %q", line)
+		}
+		local := fields[4]
+		i := strings.LastIndex(local, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("no port in %q", local)
+		}
+		port, err := strconv.Atoi(local[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in %q", local)
+		}
+		address := strings.Trim(local[:i], "[]")
+		if zone := strings.Index(address, "%"); zone >= 0 {
+			address = address[:zone]
+		}
+		listeners = append(listeners, Listener{Protocol: fields[0], Address: address, Port: port})
+	}
+	return listeners, nil
+}
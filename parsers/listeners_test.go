@@ -0,0 +1,65 @@
+package parsers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseListeners(t *testing.T) {
+	output := `udp   UNCONN 0      0         127.0.0.53%lo:53         0.0.0.0:*
+udp   UNCONN 0      0               0.0.0.0:8336       0.0.0.0:*
+tcp   LISTEN 0      4096            0.0.0.0:22         0.0.0.0:*
+tcp   LISTEN 0      4096          127.0.0.1:8080       0.0.0.0:*
+tcp   LISTEN 0      4096                  *:8336             *:*
+tcp   LISTEN 0      128               [::1]:6060          [::]:*
+tcp   LISTEN 0      4096  [fe80::1%eth0]:9000          [::]:*
+`
+	want := []Listener{
+		{"udp", "127.0.0.53", 53},
+		{"udp", "0.0.0.0", 8336},
+		{"tcp", "0.0.0.0", 22},
+		{"tcp", "127.0.0.1", 8080},
+		{"tcp", "*", 8336},
+		{"tcp", "::1", 6060},
+		{"tcp", "fe80::1", 9000},
+	}
+	got, err := ParseListeners(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseListeners = %+v, want %+v", got, want)
+	}
+
+	loopback := []bool{true, false, false, true, false, true, false}
+	for i, l := range got {
+		if l.Loopback() != loopback[i] {
+			t.Errorf("%+v: Loopback() = %v", l, l.Loopback())
+		}
+	}
+	if s := got[2].String(); s != "22/tcp" {
+		t.Errorf("String() = %q, want 22/tcp", s)
+	}
+
+	if got, err := ParseListeners("\n"); err != nil || got != nil {
+		t.Errorf("ParseListeners of no sockets = %v, %v", got, err)
+	}
+}
+
+func TestParseListenersErrors(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"tcp LISTEN 0 4096\n", "unexpected ss output"},
+		{"tcp LISTEN 0 4096 localhost 0.0.0.0:*\n", `no port in "localhost"`},
+		{"tcp LISTEN 0 4096 0.0.0.0:ssh 0.0.0.0:*\n", `invalid port in "0.0.0.0:ssh"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseListeners(tt.output)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseListeners(%q) = %v, want an error with %q", tt.output, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package parsers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Login is an SSH login to a node, or a failed attempt.
+type Login struct {
+	Time     time.Time
+	User     string
+	Source   string
+	Accepted bool
+}
+
+// loginLine matches sshd's "Accepted publickey for q from 10.0.0.2 port
+// 51234 ssh2" and "Failed password for invalid user admin from ...".
+var loginLine = regexp.MustCompile(`(Accepted|Failed) \S+ for (?:invalid user )?(\S+) from (\S+) port`)
+
+// ParseLogins reads the logins in sshd's log lines, which start with
+// their time: seconds since the epoch as `journalctl -o short-unix`
+// writes them, RFC 3339, or a syslog timestamp like "Oct 14 16:09:07",
+// read in loc (UTC if nil) as the latest such time before now. Lines
+// that aren't logins are skipped.
+func ParseLogins(output string, loc *time.Location, now time.Time) []Login {
+	if loc == nil {
+		loc = time.UTC
+	}
+	var logins []Login
+	for _, line := range strings.Split(output, "\n") {
+		match := loginLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		t, ok := loginTime(line, loc, now)
+		if !ok {
+			continue
+		}
+		logins = append(logins, Login{Time: t, User: match[2], Source: match[3], Accepted: match[1] == "Accepted"})
+	}
+	return logins
+}
+
+func loginTime(line string, loc *time.Location, now time.Time) (time.Time, bool) {
+	first, _, _ := strings.Cut(line, " ")
+	if seconds, err := strconv.ParseFloat(first, 64); err == nil {
+		return time.Unix(0, int64(seconds*1e9)), true
+	}
+	if t, err := time.Parse(time.RFC3339, first); err == nil {
+		return t, true
+	}
+	if len(line) < len(time.Stamp) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(time.Stamp, line[:len(time.Stamp)], loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	// syslog leaves out the year
+	t = t.AddDate(now.In(loc).Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
@@ -0,0 +1,58 @@
+package parsers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLogins(t *testing.T) {
+	now := time.Date(2024, time.May, 15, 10, 7, 30, 0, time.UTC)
+	berlin := time.FixedZone("CEST", 2*3600)
+	tests := []struct {
+		output string
+		loc    *time.Location
+		want   []Login
+	}{
+		// journalctl -o short-unix
+		{`1715767650.123456 node-1 sshd[1234]: Accepted publickey for quil from 10.0.0.2 port 51234 ssh2: ED25519 SHA256:abc
+1715767651.000000 node-1 sshd[1234]: pam_unix(sshd:session): session opened for user quil(uid=1000) by (uid=0)
+1715767652.500000 node-1 sshd[1240]: Failed password for invalid user admin from 203.0.113.9 port 40022 ssh2
+1715767653.000000 node-1 sshd[1241]: Failed password for root from 2001:db8::7 port 40100 ssh2
+`, nil, []Login{
+			{time.Unix(1715767650, 123456000), "quil", "10.0.0.2", true},
+			{time.Unix(1715767652, 500000000), "admin", "203.0.113.9", false},
+			{time.Unix(1715767653, 0), "root", "2001:db8::7", false},
+		}},
+		// rsyslog with RFC 3339 timestamps
+		{"2024-05-15T12:01:00.000000+02:00 node-1 sshd[99]: Accepted password for pi from 10.0.0.3 port 2222 ssh2\n", nil,
+			[]Login{{time.Date(2024, time.May, 15, 10, 1, 0, 0, time.UTC), "pi", "10.0.0.3", true}}},
+		// syslog timestamps are in the node's zone, this year
+		{"May 15 12:01:00 node-1 sshd[99]: Accepted publickey for quil from 10.0.0.2 port 51234 ssh2\n", berlin,
+			[]Login{{time.Date(2024, time.May, 15, 10, 1, 0, 0, time.UTC), "quil", "10.0.0.2", true}}},
+		{"May  3 08:00:00 node-1 sshd[99]: Accepted publickey for quil from 10.0.0.2 port 51234 ssh2\n", nil,
+			[]Login{{time.Date(2024, time.May, 3, 8, 0, 0, 0, time.UTC), "quil", "10.0.0.2", true}}},
+		// or last year's, if this year's would be ahead of now
+		{"Dec 31 23:59:00 node-1 sshd[99]: Accepted publickey for quil from 10.0.0.2 port 51234 ssh2\n", nil,
+			[]Login{{time.Date(2023, time.December, 31, 23, 59, 0, 0, time.UTC), "quil", "10.0.0.2", true}}},
+		// lines without a time they start with are skipped
+		{"sshd[99]: Accepted publickey for quil from 10.0.0.2 port 51234 ssh2\n", nil, nil},
+		{"-- No entries --\n", nil, nil},
+	}
+	for _, tt := range tests {
+		got := ParseLogins(tt.output, tt.loc, now)
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseLogins(%q) = %+v, want %+v", tt.output, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Time.Equal(tt.want[i].Time) {
+				t.Errorf("ParseLogins(%q)[%d].Time = %v, want %v", tt.output, i, got[i].Time, tt.want[i].Time)
+			}
+			got[i].Time, tt.want[i].Time = time.Time{}, time.Time{}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLogins(%q) = %+v, want %+v", tt.output, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+// Package parsers turns raw command output and node logs into values the
+// collector can hand to its sinks.
+//
+// The Parse functions each take the output of one command, e.g.
+// ParseCPUUsage that of /proc/stat and ParseWindowsCPUUsage its
+// PowerShell counterpart, and return its values or an error if the
+// output isn't what the command prints. Logs are read with a LogFormat,
+// see NewLogFormat: ExtractLogMessages returns the fields of the watched
+// messages and WatchedLines the lines themselves. ParseQuery evaluates
+// jq-like paths into those fields.
+package parsers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CPUUsage struct {
+	User   float64
+	System float64
+	Steal  float64
+}
+
+type MemoryUsage struct {
+	TotalMB int
+	UsedMB  int
+}
+
+// LogMessage is the latest log entry seen for one of the watched
+// messages. Fields holds the remaining keys of the entry. Time is taken
+// from the entry's "ts" (or "time") field and is zero if it has none.
+// Count is how many entries of the message were extracted, the latest
+// included. Level is the entry's level, e.g. "info" or "error", empty if
+// it has none.
+type LogMessage struct {
+	Msg    string
+	Level  string
+	Time   time.Time
+	Count  int
+	Fields map[string]interface{}
+}
+
+// ParseCPUUsage parses the Cpu(s) line of `top -b -n 1`, or the CPU line
+// of busybox top.
+func ParseCPUUsage(cpuStat string) (CPUUsage, error) {
+	parts := strings.Fields(cpuStat)
+	if len(parts) > 0 && parts[0] == "CPU:" {
+		return parseBusyboxCPUUsage(parts)
+	}
+	if len(parts) < 16 {
+		return CPUUsage{}, fmt.Errorf("unexpected cpu stat format: %q", cpuStat)
+	}
+
+	var usage CPUUsage
+	var err error
+	if usage.User, err = strconv.ParseFloat(parts[1], 64); err != nil {
+		return CPUUsage{}, fmt.Errorf("failed to parse user cpu: %w", err)
+	}
+	if usage.System, err = strconv.ParseFloat(parts[3], 64); err != nil {
+		return CPUUsage{}, fmt.Errorf("failed to parse system cpu: %w", err)
+	}
+	if usage.Steal, err = strconv.ParseFloat(parts[15], 64); err != nil {
+		return CPUUsage{}, fmt.Errorf("failed to parse steal cpu: %w", err)
+	}
+
+	return usage, nil
+}
+
+// parseBusyboxCPUUsage parses "CPU:  4.0% usr  2.0% sys ..." value and
+// label pairs. Busybox doesn't report steal time.
+func parseBusyboxCPUUsage(parts []string) (CPUUsage, error) {
+	var usage CPUUsage
+	for i := 1; i+1 < len(parts); i += 2 {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(parts[i], "%"), 64)
+		if err != nil {
+			return CPUUsage{}, fmt.Errorf("failed to parse busybox cpu stat: %w", err)
+		}
+		switch parts[i+1] {
+		case "usr":
+			usage.User = value
+		case "sys":
+			usage.System = value
+		}
+	}
+	return usage, nil
+}
+
+// ParseMemoryUsage parses the output of `free -m`.
+func ParseMemoryUsage(memStat string) (MemoryUsage, error) {
+	lines := strings.Split(memStat, "\n")
+	if len(lines) < 2 {
+		return MemoryUsage{}, fmt.Errorf("unexpected memory stat format: %q", memStat)
+	}
+	memParts := strings.Fields(lines[1])
+	if len(memParts) < 3 {
+		return MemoryUsage{}, fmt.Errorf("unexpected memory stat format: %q", memStat)
+	}
+
+	var usage MemoryUsage
+	var err error
+	if usage.TotalMB, err = strconv.Atoi(memParts[1]); err != nil {
+		return MemoryUsage{}, fmt.Errorf("failed to parse total memory: %w", err)
+	}
+	if usage.UsedMB, err = strconv.Atoi(memParts[2]); err != nil {
+		return MemoryUsage{}, fmt.Errorf("failed to parse used memory: %w", err)
+	}
+
+	return usage, nil
+}
+
+// ExtractLogMessages takes in a bunch of logs and returns the latest entry
+// for each of the watched messages, in that order. If the log key isn't
+// found in the last batch of logs it's omitted, and so are entries whose
+// timestamp is not after since (entries without a timestamp are always
+// kept). Timestamps without a UTC offset are read in loc, UTC if nil.
+func ExtractLogMessages(logs string, watched []string, format LogFormat, since time.Time, loc *time.Location) []LogMessage {
+	latest := make(map[string]LogMessage)
+	counts := make(map[string]int)
+
+	for _, line := range strings.Split(logs, "\n") {
+		logEntry, ok := format.Parse(line)
+		if !ok {
+			continue
+		}
+
+		msg, ok := logEntry["msg"].(string)
+		if !ok {
+			continue
+		}
+
+		ts, hasTime := entryTime(logEntry, loc)
+		if hasTime && !ts.After(since) {
+			continue
+		}
+
+		level, _ := logEntry["level"].(string)
+		latest[msg] = LogMessage{Msg: msg, Level: level, Time: ts, Fields: logEntry}
+		counts[msg]++
+	}
+
+	var messages []LogMessage
+	for _, msg := range watched {
+		message, ok := latest[msg]
+		if !ok {
+			continue
+		}
+
+		// omit some keys that are not interesting
+		delete(message.Fields, "level")
+		delete(message.Fields, "ts")
+		delete(message.Fields, "caller")
+		delete(message.Fields, "msg")
+
+		message.Count = counts[msg]
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+// LogLine is a log line that is an entry of a watched message. Time is
+// zero if the entry has no timestamp.
+type LogLine struct {
+	Time time.Time
+	Msg  string
+	Line string
+}
+
+// WatchedLines returns the lines of logs that are entries of the watched
+// messages, in log order, leaving out entries whose timestamp is not
+// after since like ExtractLogMessages.
+func WatchedLines(logs string, watched []string, format LogFormat, since time.Time, loc *time.Location) []LogLine {
+	isWatched := make(map[string]bool, len(watched))
+	for _, msg := range watched {
+		isWatched[msg] = true
+	}
+
+	var lines []LogLine
+	for _, line := range strings.Split(logs, "\n") {
+		logEntry, ok := format.Parse(line)
+		if !ok {
+			continue
+		}
+		msg, _ := logEntry["msg"].(string)
+		if !isWatched[msg] {
+			continue
+		}
+		ts, hasTime := entryTime(logEntry, loc)
+		if hasTime && !ts.After(since) {
+			continue
+		}
+		lines = append(lines, LogLine{Time: ts, Msg: msg, Line: strings.TrimSpace(line)})
+	}
+	return lines
+}
+
+// timeLayouts are the string timestamp layouts recognized in log entries.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02 15:04:05",
+}
+
+// entryTime reads the timestamp of a log entry. zap writes "ts" as
+// fractional epoch seconds by default, or ISO8601 when configured so.
+// Layouts without an offset are read in loc, UTC if nil.
+func entryTime(logEntry map[string]interface{}, loc *time.Location) (time.Time, bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, key := range []string{"ts", "time"} {
+		switch v := logEntry[key].(type) {
+		case float64:
+			sec, frac := math.Modf(v)
+			delete(logEntry, key)
+			return time.Unix(int64(sec), int64(frac*1e9)), true
+		case string:
+			for _, layout := range timeLayouts {
+				if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+					delete(logEntry, key)
+					return t, true
+				}
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseWindowsCPUUsage parses the user and privileged time counters, one
+// per line. Windows has no steal time.
+func ParseWindowsCPUUsage(cpuStat string) (CPUUsage, error) {
+	values := strings.Fields(cpuStat)
+	if len(values) < 2 {
+		return CPUUsage{}, fmt.Errorf("unexpected cpu stat format: %q", cpuStat)
+	}
+
+	var usage CPUUsage
+	var err error
+	if usage.User, err = strconv.ParseFloat(values[0], 64); err != nil {
+		return CPUUsage{}, fmt.Errorf("failed to parse user cpu: %w", err)
+	}
+	if usage.System, err = strconv.ParseFloat(values[1], 64); err != nil {
+		return CPUUsage{}, fmt.Errorf("failed to parse system cpu: %w", err)
+	}
+
+	return usage, nil
+}
+
+// ParseWindowsMemoryUsage parses a "<total MB> <used MB>" line.
+func ParseWindowsMemoryUsage(memStat string) (MemoryUsage, error) {
+	values := strings.Fields(memStat)
+	if len(values) < 2 {
+		return MemoryUsage{}, fmt.Errorf("unexpected memory stat format: %q", memStat)
+	}
+
+	var usage MemoryUsage
+	var err error
+	if usage.TotalMB, err = strconv.Atoi(values[0]); err != nil {
+		return MemoryUsage{}, fmt.Errorf("failed to parse total memory: %w", err)
+	}
+	if usage.UsedMB, err = strconv.Atoi(values[1]); err != nil {
+		return MemoryUsage{}, fmt.Errorf("failed to parse used memory: %w", err)
+	}
+
+	return usage, nil
+}
+
+// ParseDarwinCPUUsage parses the "CPU usage: 5.26% user, 10.52% sys,
+// 84.21% idle" line of macOS `top -l 1`.
+func ParseDarwinCPUUsage(cpuStat string) (CPUUsage, error) {
+	var usage CPUUsage
+	var idle float64
+	line := strings.TrimSpace(cpuStat)
+	if _, err := fmt.Sscanf(line, "CPU usage: %f%% user, %f%% sys, %f%% idle", &usage.User, &usage.System, &idle); err != nil {
+		return CPUUsage{}, fmt.Errorf("unexpected cpu stat format: %q", cpuStat)
+	}
+	return usage, nil
+}
+
+// ParseDarwinMemoryUsage parses `sysctl -n hw.memsize` followed by
+// `vm_stat`. Used memory is counted like Activity Monitor does: active,
+// wired and compressed pages.
+func ParseDarwinMemoryUsage(memStat string) (MemoryUsage, error) {
+	lines := strings.Split(strings.TrimSpace(memStat), "\n")
+	if len(lines) < 2 {
+		return MemoryUsage{}, fmt.Errorf("unexpected memory stat format: %q", memStat)
+	}
+
+	totalBytes, err := strconv.ParseInt(strings.TrimSpace(lines[0]), 10, 64)
+	if err != nil {
+		return MemoryUsage{}, fmt.Errorf("failed to parse total memory: %w", err)
+	}
+
+	var pageSize int64
+	if _, err := fmt.Sscanf(lines[1], "Mach Virtual Memory Statistics: (page size of %d bytes)", &pageSize); err != nil {
+		return MemoryUsage{}, fmt.Errorf("unexpected vm_stat header: %q", lines[1])
+	}
+
+	var usedPages int64
+	for _, line := range lines[2:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "Pages active", "Pages wired down", "Pages occupied by compressor":
+			pages, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), "."), 10, 64)
+			if err != nil {
+				return MemoryUsage{}, fmt.Errorf("failed to parse %s: %w", strings.ToLower(name), err)
+			}
+			usedPages += pages
+		}
+	}
+
+	const mb = 1024 * 1024
+	return MemoryUsage{
+		TotalMB: int(totalBytes / mb),
+		UsedMB:  int(usedPages * pageSize / mb),
+	}, nil
+}
+
+// ParseLaunchctlService turns a `launchctl list` line ("PID Status Label")
+// into a service state.
+func ParseLaunchctlService(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) < 3 {
+		return "not loaded"
+	}
+	if fields[0] == "-" {
+		return fmt.Sprintf("not running (last exit %s)", fields[1])
+	}
+	return fmt.Sprintf("running (pid %s)", fields[0])
+}
+
+// DiskUsage is the usage of one mounted filesystem, in bytes.
+type DiskUsage struct {
+	Mount string
+	Total int64
+	Used  int64
+	Avail int64
+}
+
+// UsedPercent returns the used share of the filesystem like df does, i.e.
+// relative to what is available to non-root users.
+func (d DiskUsage) UsedPercent() float64 {
+	if d.Used+d.Avail == 0 {
+		return 0
+	}
+	return float64(d.Used) / float64(d.Used+d.Avail) * 100
+}
+
+// ParseDiskUsage parses the output of `df -kP`, sizes are in 1024 byte
+// blocks.
+func ParseDiskUsage(diskStat string) ([]DiskUsage, error) {
+	lines := strings.Split(strings.TrimSpace(diskStat), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("unexpected disk stat format: %q", diskStat)
+	}
+
+	var disks []DiskUsage
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("unexpected disk stat line: %q", line)
+		}
+		var blocks [3]int64
+		for i := range blocks {
+			n, err := strconv.ParseInt(fields[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse disk stat line %q: %w", line, err)
+			}
+			blocks[i] = n * 1024
+		}
+		disks = append(disks, DiskUsage{
+			Mount: strings.Join(fields[5:], " "),
+			Total: blocks[0],
+			Used:  blocks[1],
+			Avail: blocks[2],
+		})
+	}
+	return disks, nil
+}
+
+// ParseWindowsDiskUsage parses "<drive> <size bytes> <free bytes>" lines.
+func ParseWindowsDiskUsage(diskStat string) ([]DiskUsage, error) {
+	var disks []DiskUsage
+	for _, line := range strings.Split(strings.TrimSpace(diskStat), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected disk stat line: %q", line)
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse disk size: %w", err)
+		}
+		free, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse disk free space: %w", err)
+		}
+		disks = append(disks, DiskUsage{Mount: fields[0], Total: size, Used: size - free, Avail: free})
+	}
+	return disks, nil
+}
+
+// ParseZone parses the output of date +'%Z %z', e.g. "CEST +0200", into a
+// fixed zone. Only the offset is known, so it is what the node uses now.
+func ParseZone(output string) (*time.Location, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("unexpected time zone format: %q", output)
+	}
+	t, err := time.Parse("-0700", fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time zone offset: %w", err)
+	}
+	_, offset := t.Zone()
+	return time.FixedZone(fields[0], offset), nil
+}
@@ -0,0 +1,230 @@
+package parsers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCPUUsage(t *testing.T) {
+	tests := []struct {
+		output string
+		want   CPUUsage
+	}{
+		// procps-ng top
+		{"%Cpu(s):  2.3 us,  0.8 sy,  0.0 ni, 96.6 id,  0.1 wa,  0.0 hi,  0.2 si,  0.0 st\n", CPUUsage{User: 2.3, System: 0.8}},
+		{"%Cpu(s): 38.1 us,  7.1 sy,  0.0 ni, 52.4 id,  0.0 wa,  0.0 hi,  0.0 si,  2.4 st", CPUUsage{User: 38.1, System: 7.1, Steal: 2.4}},
+		// busybox top
+		{"CPU:  4.0% usr  2.0% sys  0.0% nic 93.0% idle  0.0% io  0.0% irq  1.0% sirq\n", CPUUsage{User: 4, System: 2}},
+	}
+	for _, tt := range tests {
+		got, err := ParseCPUUsage(tt.output)
+		if err != nil {
+			t.Errorf("ParseCPUUsage(%q): %v", tt.output, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCPUUsage(%q) = %+v, want %+v", tt.output, got, tt.want)
+		}
+	}
+
+	for _, output := range []string{"", "top: failed tty get\n", "%Cpu(s): x us,  0.8 sy,  0.0 ni, 96.6 id,  0.1 wa,  0.0 hi,  0.2 si,  0.0 st"} {
+		if _, err := ParseCPUUsage(output); err == nil {
+			t.Errorf("ParseCPUUsage(%q) = nil error", output)
+		}
+	}
+}
+
+func TestParseMemoryUsage(t *testing.T) {
+	output := `               total        used        free      shared  buff/cache   available
+Mem:           15990        4999        2012         135        8978       10554
+Swap:           4095           0        4095
+`
+	got, err := ParseMemoryUsage(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (MemoryUsage{TotalMB: 15990, UsedMB: 4999}); got != want {
+		t.Errorf("ParseMemoryUsage = %+v, want %+v", got, want)
+	}
+
+	for _, output := range []string{"", "free: command not found", "total used\nMem: 15990\n", "total used\nMem: 15990 lots\n"} {
+		if _, err := ParseMemoryUsage(output); err == nil {
+			t.Errorf("ParseMemoryUsage(%q) = nil error", output)
+		}
+	}
+}
+
+func TestParseWindowsUsage(t *testing.T) {
+	// the CookedValue of each counter, then Win32_OperatingSystem's
+	// sizes in MB
+	cpu, err := ParseWindowsCPUUsage("12.4839512963978\r\n3.12098782409945\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CPUUsage{User: 12.4839512963978, System: 3.12098782409945}); cpu != want {
+		t.Errorf("ParseWindowsCPUUsage = %+v, want %+v", cpu, want)
+	}
+	memory, err := ParseWindowsMemoryUsage("16236 9123\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (MemoryUsage{TotalMB: 16236, UsedMB: 9123}); memory != want {
+		t.Errorf("ParseWindowsMemoryUsage = %+v, want %+v", memory, want)
+	}
+
+	// what powershell prints when a counter is missing
+	counterError := "Get-Counter : The specified object was not found on the computer.\r\n"
+	if _, err := ParseWindowsCPUUsage(counterError); err == nil {
+		t.Errorf("ParseWindowsCPUUsage(%q) = nil error", counterError)
+	}
+	for _, output := range []string{"", "16236", "16236 MB"} {
+		if _, err := ParseWindowsMemoryUsage(output); err == nil {
+			t.Errorf("ParseWindowsMemoryUsage(%q) = nil error", output)
+		}
+	}
+}
+
+func TestParseDarwinUsage(t *testing.T) {
+	cpu, err := ParseDarwinCPUUsage("CPU usage: 5.26% user, 10.52% sys, 84.21% idle \n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CPUUsage{User: 5.26, System: 10.52}); cpu != want {
+		t.Errorf("ParseDarwinCPUUsage = %+v, want %+v", cpu, want)
+	}
+	if _, err := ParseDarwinCPUUsage("Processes: 512 total, 2 running\n"); err == nil {
+		t.Errorf("ParseDarwinCPUUsage of the processes line = nil error")
+	}
+
+	// 16 GB, and (400000 active + 150000 wired + 100000 compressed)
+	// pages of 16 KB
+	output := `17179869184
+Mach Virtual Memory Statistics: (page size of 16384 bytes)
+Pages free:                               12345.
+Pages active:                            400000.
+Pages inactive:                          390000.
+Pages speculative:                         5000.
+Pages throttled:                              0.
+Pages wired down:                        150000.
+Pages purgeable:                           2000.
+"Translation faults":                 123456789.
+Pages copy-on-write:                   1234567.
+Pages zero filled:                    98765432.
+Pages reactivated:                       12345.
+Pages purged:                             6789.
+File-backed pages:                      200000.
+Anonymous pages:                        595000.
+Pages stored in compressor:             300000.
+Pages occupied by compressor:           100000.
+Decompressions:                          54321.
+Compressions:                            98765.
+Pageins:                               1234567.
+Pageouts:                                 1234.
+Swapins:                                     0.
+Swapouts:                                    0.
+`
+	memory, err := ParseDarwinMemoryUsage(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (MemoryUsage{TotalMB: 16384, UsedMB: 10156}); memory != want {
+		t.Errorf("ParseDarwinMemoryUsage = %+v, want %+v", memory, want)
+	}
+	for _, output := range []string{
+		"17179869184\n",
+		"sysctl: unknown oid 'hw.memsize'\nMach Virtual Memory Statistics: (page size of 4096 bytes)\n",
+		"17179869184\nvm_stat: command not found\n",
+		"17179869184\nMach Virtual Memory Statistics: (page size of 4096 bytes)\nPages active: many.\n",
+	} {
+		if _, err := ParseDarwinMemoryUsage(output); err == nil {
+			t.Errorf("ParseDarwinMemoryUsage(%q) = nil error", output)
+		}
+	}
+}
+
+func TestParseLaunchctlService(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"1234\t0\tcom.quilibrium.node\n", "running (pid 1234)"},
+		{"-\t78\tcom.quilibrium.node\n", "not running (last exit 78)"},
+		{"", "not loaded"},
+	}
+	for _, tt := range tests {
+		if got := ParseLaunchctlService(tt.output); got != tt.want {
+			t.Errorf("ParseLaunchctlService(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestParseDiskUsage(t *testing.T) {
+	const k = 1024
+	tests := []struct {
+		output string
+		want   []DiskUsage
+	}{
+		// GNU df -kP / /data, a mount point with a space
+		{`Filesystem     1024-blocks      Used Available Capacity Mounted on
+/dev/sda1        309237645 172385220 121112425      59% /
+/dev/sdb1       1236950580 460941080 713140000      40% /mnt/my data
+`, []DiskUsage{
+			{Mount: "/", Total: 309237645 * k, Used: 172385220 * k, Avail: 121112425 * k},
+			{Mount: "/mnt/my data", Total: 1236950580 * k, Used: 460941080 * k, Avail: 713140000 * k},
+		}},
+		// macOS df -kP
+		{`Filesystem     1024-blocks      Used Available Capacity  Mounted on
+/dev/disk3s1s1   482797652  10023456 190123456     6%    /
+/dev/disk3s5     482797652 280000000 190123456    60%    /System/Volumes/Data
+`, []DiskUsage{
+			{Mount: "/", Total: 482797652 * k, Used: 10023456 * k, Avail: 190123456 * k},
+			{Mount: "/System/Volumes/Data", Total: 482797652 * k, Used: 280000000 * k, Avail: 190123456 * k},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := ParseDiskUsage(tt.output)
+		if err != nil {
+			t.Errorf("ParseDiskUsage(%q): %v", tt.output, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseDiskUsage(%q) = %+v, want %+v", tt.output, got, tt.want)
+		}
+	}
+	if p := tests[0].want[0].UsedPercent(); p < 58.7 || p > 58.8 {
+		t.Errorf("UsedPercent() = %v, want df's 59%% before rounding up", p)
+	}
+
+	for _, output := range []string{
+		"Filesystem     1024-blocks      Used Available Capacity Mounted on\n",
+		"df: /data: No such file or directory\n",
+		"Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/sda1 309237645 - 121112425 59% /\n",
+		"Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/sda1 309237645\n",
+	} {
+		if _, err := ParseDiskUsage(output); err == nil {
+			t.Errorf("ParseDiskUsage(%q) = nil error", output)
+		}
+	}
+}
+
+func TestParseWindowsDiskUsage(t *testing.T) {
+	got, err := ParseWindowsDiskUsage("C: 511101784064 236541669376\r\nD: 1000186310656 900000000000\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiskUsage{
+		{Mount: "C:", Total: 511101784064, Used: 511101784064 - 236541669376, Avail: 236541669376},
+		{Mount: "D:", Total: 1000186310656, Used: 1000186310656 - 900000000000, Avail: 900000000000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseWindowsDiskUsage = %+v, want %+v", got, want)
+	}
+
+	for _, output := range []string{"C: 511101784064\r\n", "C: big 236541669376\r\n", "C: 511101784064 free\r\n"} {
+		_, err := ParseWindowsDiskUsage(output)
+		if err == nil || !strings.Contains(err.Error(), "disk") {
+			t.Errorf("ParseWindowsDiskUsage(%q) = %v, want a disk error", output, err)
+		}
+	}
+}
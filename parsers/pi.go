@@ -0,0 +1,94 @@
+package parsers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Bits of `vcgencmd get_throttled`. The low bits are the current state,
+// the same bits shifted by 16 are sticky since boot.
+const (
+	PiUnderVoltage  = 1 << 0
+	PiFreqCapped    = 1 << 1
+	PiThrottled     = 1 << 2
+	PiSoftTempLimit = 1 << 3
+
+	piOccurredShift = 16
+)
+
+var piFlagNames = []struct {
+	bit  uint32
+	name string
+}{
+	{PiUnderVoltage, "under-voltage"},
+	{PiFreqCapped, "arm frequency capped"},
+	{PiThrottled, "throttled"},
+	{PiSoftTempLimit, "soft temperature limit"},
+}
+
+// PiStatus is the throttling state and SoC temperature of a Raspberry Pi.
+type PiStatus struct {
+	Throttled   uint32
+	Temperature float64
+}
+
+// Active reports whether one of the flags is set right now.
+func (p PiStatus) Active(flag uint32) bool {
+	return p.Throttled&flag != 0
+}
+
+// Current lists the problems the Pi has right now.
+func (p PiStatus) Current() []string {
+	return p.flags(p.Throttled)
+}
+
+// SinceBoot lists the problems that happened since boot.
+func (p PiStatus) SinceBoot() []string {
+	return p.flags(p.Throttled >> piOccurredShift)
+}
+
+func (p PiStatus) flags(bits uint32) []string {
+	var names []string
+	for _, flag := range piFlagNames {
+		if bits&flag.bit != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	return names
+}
+
+// ParsePiStatus parses the output of `vcgencmd get_throttled; vcgencmd
+// measure_temp`, i.e. "throttled=0x50005" and "temp=48.3'C".
+func ParsePiStatus(output string) (PiStatus, error) {
+	var status PiStatus
+	var sawThrottled, sawTemp bool
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "throttled":
+			bits, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 32)
+			if err != nil {
+				return PiStatus{}, fmt.Errorf("failed to parse throttled state: %w", err)
+			}
+			status.Throttled = uint32(bits)
+			sawThrottled = true
+		case "temp":
+			temp, err := strconv.ParseFloat(strings.TrimSuffix(value, "'C"), 64)
+			if err != nil {
+				return PiStatus{}, fmt.Errorf("failed to parse temperature: %w", err)
+			}
+			status.Temperature = temp
+			sawTemp = true
+		}
+	}
+
+	if !sawThrottled || !sawTemp {
+		return PiStatus{}, fmt.Errorf("unexpected vcgencmd output: %q", output)
+	}
+	return status, nil
+}
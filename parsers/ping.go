@@ -0,0 +1,48 @@
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ping is the summary of a few pings of one host.
+type Ping struct {
+	// Loss is the share of pings without an answer, in percent.
+	Loss float64
+	// RTT is the average round trip time of the answered pings, zero if
+	// none was.
+	RTT time.Duration
+}
+
+var (
+	pingLoss = regexp.MustCompile(`([\d.]+)% packet loss`)
+	// the min/avg/max line of iputils, BSD and busybox ping
+	pingRTT = regexp.MustCompile(`= [\d.]+/([\d.]+)/`)
+)
+
+// ParsePing parses the summary `ping -q` prints, e.g. "3 packets
+// transmitted, 3 received, 0% packet loss" followed by "rtt
+// min/avg/max/mdev = 0.045/0.052/0.061/0.007 ms".
+func ParsePing(output string) (Ping, error) {
+	var ping Ping
+	match := pingLoss.FindStringSubmatch(output)
+	if match == nil {
+		return ping, fmt.Errorf("unexpected ping output: %q", strings.TrimSpace(output))
+	}
+	loss, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return ping, fmt.Errorf("failed to parse packet loss: %w", err)
+	}
+	ping.Loss = loss
+	if match := pingRTT.FindStringSubmatch(output); match != nil {
+		ms, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return ping, fmt.Errorf("failed to parse round trip time: %w", err)
+		}
+		ping.RTT = time.Duration(ms * float64(time.Millisecond))
+	}
+	return ping, nil
+}
@@ -0,0 +1,66 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePing(t *testing.T) {
+	tests := []struct {
+		output string
+		want   Ping
+	}{
+		// iputils
+		{`PING 10.0.0.2 (10.0.0.2) 56(84) bytes of data.
+
+--- 10.0.0.2 ping statistics ---
+3 packets transmitted, 3 received, 0% packet loss, time 2003ms
+rtt min/avg/max/mdev = 0.401/0.512/0.689/0.126 ms
+`, Ping{Loss: 0, RTT: 512 * time.Microsecond}},
+		// BSD and macOS
+		{`PING 10.0.0.2 (10.0.0.2): 56 data bytes
+
+--- 10.0.0.2 ping statistics ---
+3 packets transmitted, 2 packets received, 33.3% packet loss
+round-trip min/avg/max/stddev = 12.100/14.250/16.400/2.150 ms
+`, Ping{Loss: 33.3, RTT: 14250 * time.Microsecond}},
+		// busybox
+		{`PING 10.0.0.2 (10.0.0.2): 56 data bytes
+
+--- 10.0.0.2 ping statistics ---
+3 packets transmitted, 3 packets received, 0% packet loss
+round-trip min/avg/max = 20.1/25.0/30.2 ms
+`, Ping{Loss: 0, RTT: 25 * time.Millisecond}},
+		// iputils leaves out the rtt line when nothing answered
+		{`PING 10.0.0.9 (10.0.0.9) 56(84) bytes of data.
+
+--- 10.0.0.9 ping statistics ---
+3 packets transmitted, 0 received, 100% packet loss, time 2030ms
+`, Ping{Loss: 100}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePing(tt.output)
+		if err != nil {
+			t.Errorf("ParsePing(%q): %v", tt.output, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePing(%q) = %+v, want %+v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestParsePingErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"ping: unknown host node-9\n",
+		"3 packets transmitted, 3 received\n",
+	}
+	for _, output := range tests {
+		_, err := ParsePing(output)
+		if err == nil || !strings.Contains(err.Error(), "unexpected ping output") {
+			t.Errorf("ParsePing(%q) = %v, want an unexpected output error", output, err)
+		}
+	}
+}
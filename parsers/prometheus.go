@@ -0,0 +1,85 @@
+package parsers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Metric is a sample of a Prometheus metric. Labels are as exposed,
+// e.g. `{peer="Qm..."}`, empty for metrics without labels.
+type Metric struct {
+	Name   string
+	Labels string
+	Value  float64
+}
+
+// Series names the metric with its labels, e.g. `peers{kind="mesh"}`.
+func (m Metric) Series() string {
+	return m.Name + m.Labels
+}
+
+// ParsePrometheus reads the samples of the Prometheus text exposition
+// format, in order. Comments, HELP and TYPE lines are skipped, as are
+// timestamps.
+func ParsePrometheus(output string) ([]Metric, error) {
+	var metrics []Metric
+	for n, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		metric, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+func parseSample(line string) (Metric, error) {
+	var m Metric
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return m, fmt.Errorf("no value in %q", line)
+	}
+	m.Name, line = line[:end], line[end:]
+	if strings.HasPrefix(line, "{") {
+		closing := labelsEnd(line)
+		if closing < 0 {
+			return m, fmt.Errorf("unterminated labels of %s", m.Name)
+		}
+		m.Labels, line = line[:closing+1], line[closing+1:]
+		if m.Labels == "{}" {
+			m.Labels = ""
+		}
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return m, fmt.Errorf("no value for %s", m.Name)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return m, fmt.Errorf("invalid value for %s: %w", m.Name, err)
+	}
+	m.Value = value
+	return m, nil
+}
+
+// labelsEnd finds the brace closing the labels at the start of s, which
+// may be in quoted label values.
+func labelsEnd(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == '}':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,64 @@
+package parsers
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePrometheus(t *testing.T) {
+	tests := []struct {
+		output string
+		want   []Metric
+	}{
+		{`# HELP q_peers Connected peers.
+# TYPE q_peers gauge
+q_peers{kind="mesh"} 18
+q_peers{kind="gossip"} 9
+q_frame 100612
+`, []Metric{{"q_peers", `{kind="mesh"}`, 18}, {"q_peers", `{kind="gossip"}`, 9}, {"q_frame", "", 100612}}},
+		// timestamps are dropped, empty labels are none
+		{"q_frame{} 12 1715767650000\n", []Metric{{"q_frame", "", 12}}},
+		{"q_proof_seconds_sum 5.04\nq_up\t+Inf\n", []Metric{{"q_proof_seconds_sum", "", 5.04}, {"q_up", "", math.Inf(1)}}},
+		{"go_info{version=\"go1.22.3\"} 1.5e+00\n", []Metric{{"go_info", `{version="go1.22.3"}`, 1.5}}},
+		// braces and escaped quotes in label values
+		{`q_err{msg="a } \"b\" {"} 1`, []Metric{{"q_err", `{msg="a } \"b\" {"}`, 1}}},
+		{"\n  # comment\n\n", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParsePrometheus(tt.output)
+		if err != nil {
+			t.Errorf("ParsePrometheus(%q): %v", tt.output, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePrometheus(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestParsePrometheusErrors(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"q_frame\n", `line 1: no value in "q_frame"`},
+		{"q_frame 1\nq_peers{kind=\"mesh\" 18\n", "line 2: unterminated labels of q_peers"},
+		{`q_peers{kind="mesh"}`, "no value for q_peers"},
+		{"q_frame twelve\n", "invalid value for q_frame"},
+		{"{kind=\"mesh\"} 1\n", "no value in"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePrometheus(tt.output)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParsePrometheus(%q) = %v, want an error with %q", tt.output, err, tt.want)
+		}
+	}
+}
+
+func TestMetricSeries(t *testing.T) {
+	if got := (Metric{Name: "q_peers", Labels: `{kind="mesh"}`}).Series(); got != `q_peers{kind="mesh"}` {
+		t.Errorf("Series() = %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query is a jq-like path expression such as `.network_peer_count`,
+// `.peers[0].id` or `.["odd key"]`. Only paths are supported, no pipes or
+// functions.
+type Query struct {
+	expr  string
+	steps []queryStep
+}
+
+type queryStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// ParseQuery compiles a path expression.
+func ParseQuery(expr string) (Query, error) {
+	expr = strings.TrimSpace(expr)
+	q := Query{expr: expr}
+	if !strings.HasPrefix(expr, ".") {
+		return Query{}, fmt.Errorf("query %q must start with '.'", expr)
+	}
+
+	// . alone is the whole entry, anywhere else a key has to follow
+	if expr == "." {
+		return q, nil
+	}
+	rest := expr
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".["), strings.HasPrefix(rest, "["):
+			rest = strings.TrimPrefix(rest, ".")[1:]
+			end := strings.IndexByte(rest, ']')
+			if strings.HasPrefix(rest, `"`) {
+				// a quoted key may contain ']'
+				quoted, err := strconv.QuotedPrefix(rest)
+				if err != nil {
+					return Query{}, fmt.Errorf("query %q: invalid quoted key", expr)
+				}
+				end = len(quoted)
+				if !strings.HasPrefix(rest[end:], "]") {
+					end = -1
+				}
+			}
+			if end < 0 {
+				return Query{}, fmt.Errorf("query %q: missing ']'", expr)
+			}
+			inner := rest[:end]
+			rest = rest[end+1:]
+			if n, err := strconv.Atoi(inner); err == nil {
+				q.steps = append(q.steps, queryStep{index: n, isIndex: true})
+				continue
+			}
+			key, err := strconv.Unquote(inner)
+			if err != nil {
+				return Query{}, fmt.Errorf("query %q: invalid subscript [%s]", expr, inner)
+			}
+			q.steps = append(q.steps, queryStep{key: key})
+		case strings.HasPrefix(rest, `."`):
+			quoted, err := strconv.QuotedPrefix(rest[1:])
+			if err != nil {
+				return Query{}, fmt.Errorf("query %q: invalid quoted key", expr)
+			}
+			key, _ := strconv.Unquote(quoted)
+			q.steps = append(q.steps, queryStep{key: key})
+			rest = rest[1+len(quoted):]
+		case strings.HasPrefix(rest, "."):
+			end := 1
+			for end < len(rest) && isIdentByte(rest[end]) {
+				end++
+			}
+			if end == 1 {
+				return Query{}, fmt.Errorf("query %q: expected a key after '.'", expr)
+			}
+			q.steps = append(q.steps, queryStep{key: rest[1:end]})
+			rest = rest[end:]
+		default:
+			return Query{}, fmt.Errorf("query %q: unexpected %q", expr, rest)
+		}
+	}
+
+	return q, nil
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+func (q Query) String() string {
+	return q.expr
+}
+
+// Eval applies the query to a decoded JSON value. ok is false when the
+// path doesn't exist.
+func (q Query) Eval(value interface{}) (result interface{}, ok bool) {
+	for _, step := range q.steps {
+		if step.isIndex {
+			list, isList := value.([]interface{})
+			if !isList {
+				return nil, false
+			}
+			i := step.index
+			if i < 0 {
+				i += len(list)
+			}
+			if i < 0 || i >= len(list) {
+				return nil, false
+			}
+			value = list[i]
+			continue
+		}
+
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			return nil, false
+		}
+		if value, ok = object[step.key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// FormatValue renders a query result the way jq would print it, minus
+// the quotes around strings.
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return "null"
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	}
+}
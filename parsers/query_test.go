@@ -0,0 +1,107 @@
+package parsers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryEval(t *testing.T) {
+	var entry interface{}
+	if err := json.Unmarshal([]byte(`{
+		"msg": "peers in store",
+		"network_peer_count": 41,
+		"peers": [{"id": "QmA", "addrs": ["/ip4/10.0.0.1"]}, {"id": "QmB"}],
+		"odd key": "spaced",
+		"a]b": "bracketed",
+		"a.b": {"x\"y": true},
+		"nothing": null
+	}`), &entry); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want interface{}
+		ok   bool
+	}{
+		{".", entry, true},
+		{".network_peer_count", 41.0, true},
+		{"  .msg ", "peers in store", true},
+		{".peers[0].id", "QmA", true},
+		{".peers.[1].id", "QmB", true},
+		{".peers[-1].id", "QmB", true},
+		{".peers[0].addrs[0]", "/ip4/10.0.0.1", true},
+		{`.["odd key"]`, "spaced", true},
+		{`."odd key"`, "spaced", true},
+		{`.["a]b"]`, "bracketed", true},
+		{`.["a.b"]["x\"y"]`, true, true},
+		{".nothing", nil, true},
+		// paths that don't exist
+		{".missing", nil, false},
+		{".peers[2]", nil, false},
+		{".peers[-3]", nil, false},
+		{".msg.length", nil, false},
+		{".msg[0]", nil, false},
+		{".peers.id", nil, false},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.expr)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.expr, err)
+			continue
+		}
+		got, ok := q.Eval(entry)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q).Eval = %v, %v, want %v, %v", tt.expr, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "must start with '.'"},
+		{"network_peer_count", "must start with '.'"},
+		{`["odd key"]`, "must start with '.'"},
+		{".foo.", "expected a key after '.'"},
+		{"..foo", "expected a key after '.'"},
+		{".peers[0].", "expected a key after '.'"},
+		{".peers[0", "missing ']'"},
+		{`.["a]b"`, "missing ']'"},
+		{`.["a]b`, "invalid quoted key"},
+		{`."odd key`, "invalid quoted key"},
+		{".peers[]", "invalid subscript []"},
+		{".peers[x]", "invalid subscript [x]"},
+		{".peers | length", "unexpected"},
+		{".foo-bar", "unexpected"},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseQuery(%q) = %v, want an error with %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"QmA", "QmA"},
+		{41.0, "41"},
+		{0.25, "0.25"},
+		{nil, "null"},
+		{true, "true"},
+		{[]interface{}{"a", 1.0}, `["a",1]`},
+		{map[string]interface{}{"id": "QmA"}, `{"id":"QmA"}`},
+	}
+	for _, tt := range tests {
+		if got := FormatValue(tt.value); got != tt.want {
+			t.Errorf("FormatValue(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/readers"
+)
+
+// runPermissions implements `q-monitor permissions [node...]`, printing
+// the setup the monitor users of the nodes need for the checks their
+// config enables and nothing more. Nodes that need the same are listed
+// together.
+func runPermissions(args []string) error {
+	flags := flag.NewFlagSet("permissions", flag.ExitOnError)
+	configFile := flags.String("config", configFileName, "config `file` with the nodes")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: q-monitor permissions [-config file] [node...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	nodes := cfg.Nodes
+	if flags.NArg() > 0 {
+		nodes = nil
+		for _, name := range flags.Args() {
+			i, err := findNode(cfg.Nodes, name)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, cfg.Nodes[i])
+		}
+	}
+
+	// setups in the order they first show up, with the nodes needing them
+	var setups []string
+	names := make(map[string][]string)
+	for _, node := range nodes {
+		if node.Bootstrap != "" {
+			// probed from the monitor host, nothing to set up
+			continue
+		}
+		setup, err := nodeSetup(node, cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", node.DisplayName(), err)
+		}
+		if _, ok := names[setup]; !ok {
+			setups = append(setups, setup)
+		}
+		names[setup] = append(names[setup], node.DisplayName())
+	}
+	for i, setup := range setups {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("# %s\n%s", strings.Join(names[setup], ", "), setup)
+	}
+	return nil
+}
+
+// nodeGroups returns the groups a user needs to be in for the collectors
+// the node runs as them, empty for the monitor user: the log reader's,
+// video for vcgencmd on a Raspberry Pi, and the group that reads sshd's
+// log if the logins are watched, of the collectors the node doesn't
+// disable.
+func nodeGroups(node config.Node, logins *config.Logins, user string) ([]string, error) {
+	reader, err := readers.ForNode(node, nil)
+	if err != nil {
+		return nil, err
+	}
+	runs := func(collector string) bool {
+		return node.Collects(collector) && node.User(collector) == user
+	}
+	var groups []string
+	if node.OS != profiles.Windows && runs(config.SectionLogs) {
+		groups = append(groups, readers.Groups(reader)...)
+	}
+	if node.RaspberryPi && runs(config.SectionPi) {
+		groups = append(groups, "video")
+	}
+	if logins != nil && node.OS != profiles.Windows && runs(config.SectionLogins) {
+		// sshd's log is in the journal or in a file of group adm
+		group := "systemd-journal"
+		if logins.AuthLog != "" {
+			group = "adm"
+		}
+		if !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// nodeSetup is the setup a node's monitor user needs, as commands to run
+// as root on the node and comments for what can't be set up that way.
+func nodeSetup(node config.Node, cfg *config.Config) (string, error) {
+	groups, err := nodeGroups(node, cfg.Logins, "")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	user := node.Username
+	if user == "" {
+		user = "<user>"
+	}
+	switch {
+	case node.OS == profiles.Windows:
+		b.WriteString("# windows nodes only run queries any user can, no setup needed\n")
+	case len(groups) > 0:
+		fmt.Fprintf(&b, "usermod -aG %s %s\n", strings.Join(groups, ","), user)
+	default:
+		b.WriteString("# no groups needed\n")
+	}
+	sudoers := false
+	if node.OS != profiles.Windows {
+		if sudoers, err = runAsSetup(&b, node, cfg, user); err != nil {
+			return "", err
+		}
+	}
+	if node.LogReader == readers.Tmux && node.Collects(config.SectionLogs) {
+		fmt.Fprintf(&b, "# %s must be the user running the tmux server of pane %q, tmux doesn't share it\n", user, node.TmuxPane)
+	}
+
+	if cfg.KeyCheck != nil && node.OS != profiles.Windows && node.Collects(config.SectionKeys) {
+		b.WriteString("# the key check only checksums key files the monitor user can read, e.g. as their owner\n")
+	}
+
+	// the audit's firewall part is the only check that needs root
+	firewall := cfg.Audit != nil && len(cfg.Audit.Rules) > 0 && node.Collects(config.SectionAudit)
+	// a prefix that uses sudo is root for every command
+	prefix := strings.Fields(node.CommandPrefix)
+	switch {
+	case len(prefix) > 0 && (prefix[0] == "sudo" || prefix[0] == "doas"):
+		fmt.Fprintf(&b, "# command_prefix %q runs every command as root through sh, which takes this sudoers rule:\n", node.CommandPrefix)
+		fmt.Fprintf(&b, "#   %s ALL=(root) NOPASSWD: /bin/sh\n", user)
+		b.WriteString("# that is full root access; the checks don't need it with the groups above\n")
+	case firewall && node.OS != profiles.Windows:
+		auditor := user
+		if other := node.User(config.SectionAudit); other != "" {
+			auditor = other
+		}
+		b.WriteString("# the audit reads the firewall rules, which takes this sudoers rule (visudo -f /etc/sudoers.d/q-monitor):\n")
+		fmt.Fprintf(&b, "#   %s ALL=(root) NOPASSWD: /usr/sbin/nft list ruleset\n", auditor)
+	case !sudoers:
+		b.WriteString("# no sudo rules needed\n")
+	}
+	return b.String(), nil
+}
+
+// runAsSetup writes what running collectors as other users takes: the
+// sudoers rule letting the monitor user become each of them, or a note
+// on su, and the groups they need to be in. It reports whether it wrote
+// sudoers rules.
+func runAsSetup(b *strings.Builder, node config.Node, cfg *config.Config, monitor string) (bool, error) {
+	runs := make(map[string][]string)
+	for _, collector := range config.Collectors {
+		// the checks are listed one by one, the metrics run no commands
+		if collector == config.SectionChecks || collector == config.SectionMetrics {
+			continue
+		}
+		if other := node.User(collector); other != "" && node.Collects(collector) {
+			runs[other] = append(runs[other], collector)
+		}
+	}
+	for _, check := range node.Checks {
+		other := check.User
+		if other == "" {
+			other = node.User(config.SectionChecks)
+		}
+		if other != "" && node.Collects(config.SectionChecks) {
+			runs[other] = append(runs[other], "check "+check.Name)
+		}
+	}
+	users := make([]string, 0, len(runs))
+	for other := range runs {
+		users = append(users, other)
+	}
+	slices.Sort(users)
+	for _, other := range users {
+		if node.RunAsWith == config.SwitchSu {
+			fmt.Fprintf(b, "# %s: run as %s with su, which only switches without a password for root\n", strings.Join(runs[other], ", "), other)
+		} else {
+			fmt.Fprintf(b, "# %s: run as %s, which takes this sudoers rule (visudo -f /etc/sudoers.d/q-monitor):\n", strings.Join(runs[other], ", "), other)
+			fmt.Fprintf(b, "#   %s ALL=(%s) NOPASSWD: /bin/sh\n", monitor, other)
+		}
+		groups, err := nodeGroups(node, cfg.Logins, other)
+		if err != nil {
+			return false, err
+		}
+		if len(groups) > 0 && other != "root" {
+			fmt.Fprintf(b, "usermod -aG %s %s\n", strings.Join(groups, ","), other)
+		}
+	}
+	return len(users) > 0 && node.RunAsWith != config.SwitchSu, nil
+}
@@ -0,0 +1,80 @@
+// Package price fetches the token price from a configurable API, for the
+// fleet earnings estimate.
+package price
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Quote is a price and when it was fetched.
+type Quote struct {
+	Price float64
+	Time  time.Time
+}
+
+// Fetch gets the current price from the endpoint of cfg.
+func Fetch(cfg config.Earnings) (Quote, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Get(cfg.PriceURL)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("price api: %s", resp.Status)
+	}
+	var body interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Quote{}, fmt.Errorf("price api: %w", err)
+	}
+	price, err := field(body, cfg.PriceField)
+	if err != nil {
+		return Quote{}, fmt.Errorf("price api: %w", err)
+	}
+	return Quote{Price: price, Time: time.Now()}, nil
+}
+
+// Watch fetches the price every price interval and hands each result to
+// update. It never returns.
+func Watch(cfg config.Earnings, update func(Quote, error)) {
+	for {
+		update(Fetch(cfg))
+		time.Sleep(cfg.Interval())
+	}
+}
+
+// field walks a dotted path like "wrapped-quil.usd" or "data.0.price"
+// through decoded JSON to a number, or a string holding one.
+func field(value interface{}, path string) (float64, error) {
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return 0, fmt.Errorf("no element %q in %s", key, path)
+			}
+			value = v[i]
+		default:
+			return 0, fmt.Errorf("no field %q in %s", key, path)
+		}
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("%s is not a number", path)
+	}
+}
@@ -0,0 +1,144 @@
+// Package profiles holds the per operating system commands used to
+// collect a node's cpu, memory, disk and service stats.
+package profiles
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/readers"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// Profile names, as used in the node's "os" config field.
+const (
+	Linux   = "linux"
+	Windows = "windows"
+	Darwin  = "darwin"
+)
+
+// Profile is the command set for one operating system. Commands are
+// run as is, ServiceCommand has its %s replaced by the service name and
+// is skipped when empty. ParseService may be nil, the trimmed output is
+// the service state then. With StorageMounts set StorageCommand has its
+// %s replaced by the node's mounts. PingCommand pings the quoted
+// address in place of its %s for the mesh check, ListenersCommand
+// lists the listening sockets like `ss -Hlntu` for the audit. For the
+// key check FileStatCommand writes "mode|owner|type" of the file named
+// after it, in octal and with the type as stat names it, and
+// ChecksumCommand the SHA-256 of its input. Empty commands aren't
+// supported by the profile.
+type Profile struct {
+	Name string
+
+	CPUCommand       string
+	ParseCPU         func(output string) (parsers.CPUUsage, error)
+	MemoryCommand    string
+	ParseMemory      func(output string) (parsers.MemoryUsage, error)
+	StorageCommand   string
+	StorageMounts    bool
+	ParseStorage     func(output string) ([]parsers.DiskUsage, error)
+	ServiceCommand   string
+	ParseService     func(output string) string
+	PingCommand      string
+	ListenersCommand string
+	FileStatCommand  string
+	ChecksumCommand  string
+	// Tools are the programs the commands run, checked for on first
+	// connect. Empty skips the check.
+	Tools []string
+
+	// LogReaders are the names of the log readers that work on the
+	// system.
+	LogReaders []string
+}
+
+// SupportsReader reports whether a log reader works with the profile.
+func (p Profile) SupportsReader(reader readers.LogReader) bool {
+	for _, name := range p.LogReaders {
+		if name == reader.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+var profiles = map[string]Profile{
+	Linux: {
+		Name:             Linux,
+		CPUCommand:       "top -b -n 1 | grep -E 'Cpu\\(s\\)|^CPU:'",
+		ParseCPU:         parsers.ParseCPUUsage,
+		MemoryCommand:    "free -m",
+		ParseMemory:      parsers.ParseMemoryUsage,
+		StorageCommand:   "df -kP %s",
+		StorageMounts:    true,
+		ParseStorage:     parsers.ParseDiskUsage,
+		PingCommand:      "ping -c 3 -w 5 -q %s",
+		ListenersCommand: "ss -Hlntu",
+		FileStatCommand:  "stat -c '%a|%U|%F' --",
+		ChecksumCommand:  "sha256sum",
+		Tools:            []string{"top", "grep", "free", "df"},
+		LogReaders:       []string{readers.Service, readers.Tmux},
+	},
+	// Windows OpenSSH starts cmd.exe by default, so everything goes
+	// through powershell explicitly.
+	Windows: {
+		Name:           Windows,
+		CPUCommand:     `powershell -NoProfile -Command "(Get-Counter '\Processor(_Total)\% User Time','\Processor(_Total)\% Privileged Time').CounterSamples | ForEach-Object { $_.CookedValue }"`,
+		ParseCPU:       parsers.ParseWindowsCPUUsage,
+		MemoryCommand:  `powershell -NoProfile -Command "$o = Get-CimInstance Win32_OperatingSystem; '{0} {1}' -f [int]($o.TotalVisibleMemorySize/1KB), [int](($o.TotalVisibleMemorySize - $o.FreePhysicalMemory)/1KB)"`,
+		ParseMemory:    parsers.ParseWindowsMemoryUsage,
+		StorageCommand: `powershell -NoProfile -Command "Get-CimInstance Win32_LogicalDisk -Filter 'DriveType=3' | ForEach-Object { '{0} {1} {2}' -f $_.DeviceID, $_.Size, $_.FreeSpace }"`,
+		ParseStorage:   parsers.ParseWindowsDiskUsage,
+		ServiceCommand: `powershell -NoProfile -Command "(Get-Service -Name '%s').Status"`,
+	},
+	// macOS has no journald, logs can only come from tmux.
+	Darwin: {
+		Name:            Darwin,
+		CPUCommand:      "top -l 1 -n 0 | grep 'CPU usage'",
+		ParseCPU:        parsers.ParseDarwinCPUUsage,
+		MemoryCommand:   "sysctl -n hw.memsize && vm_stat",
+		ParseMemory:     parsers.ParseDarwinMemoryUsage,
+		StorageCommand:  "df -kP %s",
+		StorageMounts:   true,
+		ParseStorage:    parsers.ParseDiskUsage,
+		ServiceCommand:  "launchctl list | grep -F '%s' || true",
+		ParseService:    parsers.ParseLaunchctlService,
+		PingCommand:     "ping -c 3 -t 5 -q %s",
+		FileStatCommand: "stat -f '%Lp|%Su|%HT' --",
+		ChecksumCommand: "shasum -a 256",
+		Tools:           []string{"top", "grep", "sysctl", "vm_stat", "df", "launchctl"},
+		LogReaders:      []string{readers.Tmux},
+	},
+}
+
+// For returns the named profile.
+func For(name string) (Profile, error) {
+	profile, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown os profile %q", name)
+	}
+	return profile, nil
+}
+
+// Detect works out which profile fits a node. uname covers the unix
+// likes; on Windows it fails and cmd.exe's ver answers instead.
+func Detect(runner transport.Runner) (Profile, error) {
+	if output, err := runner.Run("uname -s"); err == nil {
+		switch strings.TrimSpace(output) {
+		case "Linux":
+			return profiles[Linux], nil
+		case "Darwin":
+			return profiles[Darwin], nil
+		default:
+			return Profile{}, fmt.Errorf("unsupported os %q", strings.TrimSpace(output))
+		}
+	}
+
+	if output, err := runner.Run("ver"); err == nil && strings.Contains(output, "Windows") {
+		return profiles[Windows], nil
+	}
+
+	return Profile{}, fmt.Errorf("could not detect the node's os, set \"os\" in its config")
+}
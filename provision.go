@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/readers"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// provisionScript creates the monitor user with the key and adds it to
+// the groups the checks need. It can run again, e.g. with another key,
+// and only adds what is missing. The arguments are the user, the groups
+// and the authorized_keys line, all shell quoted.
+const provisionScript = `set -e
+id -u %[1]s >/dev/null 2>&1 || useradd --system --create-home --shell /bin/sh %[1]s
+for group in %[2]s; do
+	if getent group "$group" >/dev/null; then usermod -aG "$group" %[1]s; fi
+done
+home=$(getent passwd %[1]s | cut -d: -f6)
+install -d -m 700 -o %[1]s -g "$(id -gn %[1]s)" "$home/.ssh"
+touch "$home/.ssh/authorized_keys"
+grep -qxF %[3]s "$home/.ssh/authorized_keys" || echo %[3]s >> "$home/.ssh/authorized_keys"
+chown %[1]s: "$home/.ssh/authorized_keys"
+chmod 600 "$home/.ssh/authorized_keys"
+`
+
+// runProvision implements `q-monitor provision <node>`. It logs in with
+// the node's configured credentials, which need root or passwordless
+// sudo, creates a dedicated monitor user that logs in with a key and can
+// read the journal, checks the new login works and switches the node's
+// config over to it.
+func runProvision(args []string) error {
+	flags := flag.NewFlagSet("provision", flag.ExitOnError)
+	configFile := flags.String("config", configFileName, "config `file` with the node")
+	user := flags.String("user", "q-monitor", "`name` of the monitor user to create")
+	keyFile := flags.String("key", "~/.ssh/q-monitor_ed25519", "private key `file` to log in with, created if it doesn't exist")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: q-monitor provision [-config file] [-user name] [-key file] <node>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one node name or address")
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	i, err := findNode(cfg.Nodes, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	node := cfg.Nodes[i]
+	if node.Bootstrap != "" {
+		return fmt.Errorf("%s is a bootstrap peer, there is nothing to log in to", node.DisplayName())
+	}
+	if node.LogReader == readers.Tmux {
+		return fmt.Errorf("%s reads a tmux pane, which only its own user can read", node.DisplayName())
+	}
+
+	signer, err := provisionKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))) + " q-monitor"
+
+	fmt.Printf("Connecting to %s as %s... ", node.DisplayName(), node.Username)
+	conn, err := transport.SSH{}.Dial(node)
+	if err != nil {
+		fmt.Println("failed")
+		return err
+	}
+	defer conn.Close()
+	profile, err := profiles.Detect(conn)
+	if err != nil {
+		fmt.Println("failed")
+		return err
+	}
+	if profile.Name != profiles.Linux {
+		fmt.Println("failed")
+		return fmt.Errorf("provisioning only supports linux nodes, %s is %s", node.DisplayName(), profile.Name)
+	}
+	fmt.Println("ok")
+
+	groups, err := nodeGroups(node, cfg.Logins, "")
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf(provisionScript, transport.ShellQuote(*user), strings.Join(groups, " "), transport.ShellQuote(authorizedKey))
+	command := "sh -c " + transport.ShellQuote(script)
+	if uid, err := conn.Run("id -u"); err != nil || strings.TrimSpace(uid) != "0" {
+		// -n fails instead of waiting for a password prompt that can't
+		// be answered
+		command = "sudo -n " + command
+	}
+	fmt.Printf("Creating user %s (groups %s)... ", *user, strings.Join(groups, ", "))
+	if _, err := conn.Run(command); err != nil {
+		fmt.Println("failed")
+		return fmt.Errorf("%w (the configured user needs to be root or have passwordless sudo)", err)
+	}
+	fmt.Println("ok")
+
+	provisioned := node
+	provisioned.Username = *user
+	provisioned.Password = ""
+	provisioned.KeyFile = *keyFile
+	if err := verifyProvisioned(provisioned, cfg.Messages); err != nil {
+		return err
+	}
+
+	cfg.Nodes[i] = provisioned
+	if err := config.Save(*configFile, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s: %s now logs in as %s with %s.\n", *configFile, node.DisplayName(), *user, *keyFile)
+	return nil
+}
+
+// findNode returns the index of the node with the given name or address.
+func findNode(nodes []config.Node, name string) (int, error) {
+	for i, node := range nodes {
+		if node.DisplayName() == name || node.IP == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no node %q in the config", name)
+}
+
+// provisionKey loads the key file, or creates an ed25519 key there if
+// there is none yet.
+func provisionKey(path string) (ssh.Signer, error) {
+	signer, err := transport.LoadKey(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		return signer, err
+	}
+
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, rest)
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(key, "q-monitor")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Created key %s.\n", path)
+	return ssh.NewSignerFromKey(key)
+}
+
+// verifyProvisioned logs in as the new user and reads the node's logs
+// like a poll does.
+func verifyProvisioned(node config.Node, messages []string) error {
+	fmt.Printf("Checking login as %s... ", node.Username)
+	conn, err := transport.SSH{}.Dial(node)
+	if err != nil {
+		fmt.Println("failed")
+		return err
+	}
+	defer conn.Close()
+	fmt.Println("ok")
+
+	reader, err := readers.ForNode(node, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Print("Checking journal access... ")
+	format, err := parsers.NewLogFormat(node.LogFormat, node.LogPattern)
+	if err != nil {
+		return err
+	}
+	logs, err := reader.ReadLogs(conn, format.Filter(config.WatchedMessages(node, messages)))
+	if err != nil {
+		fmt.Printf("failed (%v), check the node is running and logging\n", err)
+		return nil
+	}
+	if strings.TrimSpace(logs) == "" {
+		fmt.Println("no watched messages found, check the node is running and logging")
+		return nil
+	}
+	fmt.Printf("ok, %d matching line(s)\n", strings.Count(strings.TrimSpace(logs), "\n")+1)
+	return nil
+}
@@ -0,0 +1,107 @@
+// Package proxmox queries the Proxmox VE API for the hypervisor side of
+// a node running as a VM, to explain contention the guest can't see.
+package proxmox
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Guest is the VM's state as seen by its hypervisor.
+type Guest struct {
+	Host   string
+	Status string
+	// CPUs and MaxMemory are the allocated resources, Memory what the
+	// guest uses and Balloon the current balloon target (0 if ballooning
+	// is off).
+	CPUs      int
+	MaxMemory int64
+	Memory    int64
+	Balloon   int64
+	// HostCPU and HostMemory are the hypervisor's usage as fractions.
+	HostCPU    float64
+	HostMemory float64
+}
+
+type guestResponse struct {
+	Data struct {
+		Status  string  `json:"status"`
+		CPUs    float64 `json:"cpus"`
+		MaxMem  int64   `json:"maxmem"`
+		Mem     int64   `json:"mem"`
+		Balloon int64   `json:"balloon"`
+	} `json:"data"`
+}
+
+type hostResponse struct {
+	Data struct {
+		CPU    float64 `json:"cpu"`
+		Memory struct {
+			Used  int64 `json:"used"`
+			Total int64 `json:"total"`
+		} `json:"memory"`
+	} `json:"data"`
+}
+
+// Fetch returns the guest and host status for the configured VM.
+func Fetch(cfg config.Proxmox) (*Guest, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	if cfg.Insecure {
+		// Proxmox ships with a self-signed certificate
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+
+	var guest guestResponse
+	if err := get(client, cfg, fmt.Sprintf("/nodes/%s/qemu/%d/status/current", cfg.Node, cfg.VMID), &guest); err != nil {
+		return nil, err
+	}
+	var host hostResponse
+	if err := get(client, cfg, fmt.Sprintf("/nodes/%s/status", cfg.Node), &host); err != nil {
+		return nil, err
+	}
+
+	g := &Guest{
+		Host:      cfg.Node,
+		Status:    guest.Data.Status,
+		CPUs:      int(guest.Data.CPUs),
+		MaxMemory: guest.Data.MaxMem,
+		Memory:    guest.Data.Mem,
+		Balloon:   guest.Data.Balloon,
+		HostCPU:   host.Data.CPU,
+	}
+	if host.Data.Memory.Total > 0 {
+		g.HostMemory = float64(host.Data.Memory.Used) / float64(host.Data.Memory.Total)
+	}
+	return g, nil
+}
+
+func get(client *http.Client, cfg config.Proxmox, path string, v interface{}) error {
+	url := strings.TrimSuffix(cfg.URL, "/") + "/api2/json" + path
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", cfg.TokenID, cfg.TokenSecret))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("proxmox request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxmox %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode proxmox response: %w", err)
+	}
+	return nil
+}
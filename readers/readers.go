@@ -0,0 +1,154 @@
+// Package readers implements the different ways of getting Q node logs
+// out of a remote host.
+//
+// A LogReader reads the logs over a transport.Runner: ServiceLogReader
+// from the service manager of the node, TmuxLogReader from the pane of
+// a tmux session. ForNode picks the reader a config.Node asks for, and
+// WithLines bounds how many lines a reader returns.
+package readers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// Reader names, as used in the node's "log_reader" config field.
+const (
+	Service = "service"
+	Tmux    = "tmux"
+)
+
+// LogReader is an interface for reading logs from different Q execution methods.
+// Only lines matching filter, an extended regular expression built by the
+// node's parsers.LogFormat, need to be returned.
+type LogReader interface {
+	ReadLogs(runner transport.Runner, filter string) (string, error)
+	// Name identifies the kind of reader, e.g. Service.
+	Name() string
+}
+
+// ForNode returns the log reader configured for a node. Nodes that don't
+// pick one use fallback if given, the service reader otherwise.
+func ForNode(node config.Node, fallback LogReader) (LogReader, error) {
+	switch node.LogReader {
+	case "":
+		if fallback != nil && node.Service == "" {
+			return fallback, nil
+		}
+		return ServiceLogReader{ServiceName: node.ServiceName()}, nil
+	case Service:
+		return ServiceLogReader{ServiceName: node.ServiceName()}, nil
+	case Tmux:
+		if node.TmuxPane == "" {
+			return nil, errors.New("the tmux log reader needs a tmux_pane")
+		}
+		return TmuxLogReader{PaneName: node.TmuxPane}, nil
+	default:
+		return nil, fmt.Errorf("unknown log reader %q", node.LogReader)
+	}
+}
+
+// Tools returns the programs a reader runs on the node, if it tells.
+func Tools(reader LogReader) []string {
+	if r, ok := reader.(interface{ Tools() []string }); ok {
+		return r.Tools()
+	}
+	return nil
+}
+
+// Groups returns the groups the monitor user needs to be in for the
+// reader, if it tells.
+func Groups(reader LogReader) []string {
+	if r, ok := reader.(interface{ Groups() []string }); ok {
+		return r.Groups()
+	}
+	return nil
+}
+
+// WithLines returns the reader reading the given number of lines, for
+// the readers that take one.
+func WithLines(reader LogReader, lines int) LogReader {
+	switch r := reader.(type) {
+	case ServiceLogReader:
+		r.Lines = lines
+		return r
+	case TmuxLogReader:
+		r.Lines = lines
+		return r
+	default:
+		return reader
+	}
+}
+
+// ServiceLogReader reads logs from a running Q service
+type ServiceLogReader struct {
+	ServiceName string
+	// Lines is how many of the journal's last lines are read, 50 if
+	// zero.
+	Lines int
+}
+
+func (ServiceLogReader) Name() string {
+	return Service
+}
+
+func (ServiceLogReader) Tools() []string {
+	return []string{"journalctl", "grep"}
+}
+
+// Groups is the group that can read the whole journal. Other users only
+// see their own services.
+func (ServiceLogReader) Groups() []string {
+	return []string{"systemd-journal"}
+}
+
+func (s ServiceLogReader) ReadLogs(runner transport.Runner, filter string) (string, error) {
+	lines := s.Lines
+	if lines == 0 {
+		lines = 50
+	}
+	cmd := fmt.Sprintf("journalctl -u %s.service -n %d --no-hostname -o cat | grep -E %s", s.ServiceName, lines, transport.ShellQuote(filter))
+	output, err := runner.Run(cmd)
+	if noMatches(err) {
+		return "", nil
+	}
+	return output, err
+}
+
+// noMatches reports whether a grep failed only because no line matched,
+// which it tells with exit status 1 and nothing on stderr, as for a quiet
+// node. What journalctl complains about, like missing permissions, is
+// still an error.
+func noMatches(err error) bool {
+	var cmdErr *transport.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.ExitCode == 1 && strings.TrimSpace(cmdErr.Stderr) == ""
+}
+
+// TmuxLogReader reads logs from a tmux pane running Q
+type TmuxLogReader struct {
+	PaneName string
+	// Lines is how far back the pane's history is read, 100 lines if
+	// zero.
+	Lines int
+}
+
+func (TmuxLogReader) Name() string {
+	return Tmux
+}
+
+func (TmuxLogReader) Tools() []string {
+	return []string{"tmux", "grep", "tail"}
+}
+
+func (t TmuxLogReader) ReadLogs(runner transport.Runner, filter string) (string, error) {
+	lines := t.Lines
+	if lines == 0 {
+		lines = 100
+	}
+	cmd := fmt.Sprintf("tmux capture-pane -t %s -pS -%d | grep -E %s | tail -n %d", t.PaneName, lines, transport.ShellQuote(filter), 2*lines)
+	return runner.Run(cmd)
+}
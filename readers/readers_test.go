@@ -0,0 +1,38 @@
+package readers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// runnerFunc runs commands with a function.
+type runnerFunc func(cmd string) (string, error)
+
+func (f runnerFunc) Run(cmd string) (string, error) { return f(cmd) }
+
+func TestServiceLogReaderNoMatches(t *testing.T) {
+	lost := errors.New("connection lost")
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   string
+		fails  bool
+	}{
+		{"lines", "a\nb\n", nil, "a\nb\n", false},
+		{"no line matched", "", &transport.CommandError{ExitCode: 1}, "", false},
+		{"journal unreadable", "", &transport.CommandError{ExitCode: 1, Stderr: "No journal files were opened due to insufficient permissions.\n"}, "", true},
+		{"bad filter", "", &transport.CommandError{ExitCode: 2, Stderr: "grep: Unmatched ( or \\(\n"}, "", true},
+		{"not found", "", &transport.CommandError{ExitCode: 127}, "", true},
+		{"transport", "", lost, "", true},
+	}
+	for _, tt := range tests {
+		reader := ServiceLogReader{ServiceName: "ceremonyclient"}
+		got, err := reader.ReadLogs(runnerFunc(func(string) (string, error) { return tt.output, tt.err }), "x")
+		if (err != nil) != tt.fails || got != tt.want {
+			t.Errorf("%s: ReadLogs = %q, %v, want %q, error %v", tt.name, got, err, tt.want, tt.fails)
+		}
+	}
+}
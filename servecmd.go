@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/export"
+	"github.com/fid1699/q-monitor-cli/server"
+	"github.com/fid1699/q-monitor-cli/ui"
+)
+
+// runServe implements `q-monitor serve [--listen addr]`: the monitor
+// without a UI, polling the fleet and firing alerts once for the TUIs
+// connected to it with --connect, which share its acknowledgements and
+// silences, and serving the nodes' metrics to Prometheus at /metrics.
+// It won't serve other hosts without a token or TLS set up in the
+// config's server section, nor with a token but without TLS, unless told
+// --insecure.
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", server.DefaultAddr, "`address` to serve the TUIs on, e.g. :9370 for every interface")
+	simulateNodes := flags.Int("simulate", 0, "poll `N` simulated nodes instead of the configured ones")
+	insecure := flags.Bool("insecure", false, "serve other hosts without authenticating them")
+	lowBandwidth := flags.Bool("low-bandwidth", false, "poll less often and less, for a metered link, see low_bandwidth in the config")
+	flags.Parse(args)
+
+	cfg := loadConfig(*simulateNodes)
+	if *lowBandwidth && cfg.LowBandwidth == nil {
+		cfg.LowBandwidth = &config.LowBandwidth{}
+	}
+	var auth config.Server
+	if cfg.Server != nil {
+		auth = *cfg.Server
+	}
+	if token := os.Getenv(supervisorTokenEnv); token != "" {
+		// run by --supervise, only it connects
+		auth = config.Server{Token: token}
+		go exitWithInput()
+	}
+	if server.Exposed(*listen, auth) && !*insecure {
+		return fmt.Errorf("%s can be reached from other hosts, set a token or TLS under server in the config, or pass --insecure", *listen)
+	}
+	if server.PlainToken(*listen, auth) && !*insecure {
+		return fmt.Errorf("%s can be reached from other hosts and the token would go over plain HTTP, set up TLS under server in the config, or pass --insecure", *listen)
+	}
+	pipeline := collector.NewPipeline()
+	c, alerts, _ := newCollector(cfg, pipeline, *simulateNodes)
+
+	srv := server.New(cfg.Nodes, c.Interval, alerts)
+	pipeline.Register(srv)
+	metrics := export.NewPrometheus()
+	srv.Metrics = metrics
+	pipeline.Register(metrics)
+	c.Events().Register(srv)
+	alerts.AddWatcher(srv)
+
+	go c.Run(context.Background())
+	fmt.Fprintf(os.Stderr, "serving %d nodes on %s\n", len(cfg.Nodes), *listen)
+	return srv.ListenAndServe(*listen, auth)
+}
+
+// runConnected runs the TUI on the nodes of the server at addr, which
+// polls them, with the config's display settings and server
+// credentials.
+func runConnected(addr string, cfg *config.Config) error {
+	if err := ui.ValidateKeys(cfg.Display.Keys); err != nil {
+		return fmt.Errorf("in config: %w", err)
+	}
+	var auth config.Server
+	if cfg.Server != nil {
+		auth = *cfg.Server
+	}
+	client, err := server.Dial(addr, auth)
+	if err != nil {
+		return err
+	}
+	return connectedTUI(client, cfg).Run()
+}
+
+// connectedTUI returns a TUI fed by client, for the caller to run.
+func connectedTUI(client *server.Client, cfg *config.Config) *ui.TUI {
+	tui := ui.New(client.Nodes, cfg.Display)
+	tui.StaleAfter = 3 * client.Interval
+	tui.Alerts, tui.User = client, currentUser()
+	go client.Run(tui, tui, tui)
+	return tui
+}
+
+// currentUser names who acknowledges alerts and silences nodes from
+// this host.
+func currentUser() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		return name + "@" + host
+	}
+	return name
+}
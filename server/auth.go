@@ -0,0 +1,111 @@
+package server
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// tlsConfig returns the TLS settings of one end, nil if auth doesn't
+// enable TLS. Both ends need a certificate signed by the CA: the server
+// only takes TUIs that show one.
+func tlsConfig(auth config.Server, serving bool) (*tls.Config, error) {
+	if auth.CA == "" {
+		if auth.Cert != "" || auth.Key != "" {
+			return nil, errors.New("server.cert needs server.ca, the CA the other end's certificate is checked against")
+		}
+		return nil, nil
+	}
+	if auth.Cert == "" || auth.Key == "" {
+		return nil, errors.New("server.ca needs server.cert and server.key, see q-monitor cert issue")
+	}
+	data, err := os.ReadFile(auth.CA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate in %s", auth.CA)
+	}
+	cert, err := tls.LoadX509KeyPair(auth.Cert, auth.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the certificate: %w", err)
+	}
+
+	t := &tls.Config{MinVersion: tls.VersionTLS13, Certificates: []tls.Certificate{cert}}
+	if serving {
+		t.ClientCAs = pool
+		t.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		t.RootCAs = pool
+	}
+	return t, nil
+}
+
+// requireToken lets only requests with the bearer token through, all
+// of them if there is none.
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="q-monitor"`)
+			http.Error(w, "missing or wrong token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Exposed reports whether a server on addr can be reached from other
+// hosts without authenticating, neither with a token nor a certificate.
+func Exposed(addr string, auth config.Server) bool {
+	if auth.Token != "" || auth.CA != "" {
+		return false
+	}
+	return !loopback(addr)
+}
+
+// PlainToken reports whether a server on addr can be reached from other
+// hosts with a token but without TLS, so the token goes over plain HTTP
+// for anyone on the way to read.
+func PlainToken(addr string, auth config.Server) bool {
+	return auth.Token != "" && auth.CA == "" && !loopback(addr)
+}
+
+// loopback reports whether addr only listens on the host itself.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ListenAndServe serves the TUIs on addr, requiring the token and the
+// client certificates auth asks for.
+func (s *Server) ListenAndServe(addr string, auth config.Server) error {
+	t, err := tlsConfig(auth, true)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Addr: addr, Handler: requireToken(auth.Token, s.Handler()), TLSConfig: t}
+	if t == nil {
+		return srv.ListenAndServe()
+	}
+	// the certificate is in the TLS config already
+	return srv.ListenAndServeTLS("", "")
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+func TestExposed(t *testing.T) {
+	token := config.Server{Token: "t"}
+	tls := config.Server{CA: "ca.pem"}
+	both := config.Server{Token: "t", CA: "ca.pem"}
+	tests := []struct {
+		addr              string
+		auth              config.Server
+		exposed, plainTok bool
+	}{
+		{"localhost:9370", config.Server{}, false, false},
+		{"127.0.0.1:9370", config.Server{}, false, false},
+		{"[::1]:9370", config.Server{}, false, false},
+		{":9370", config.Server{}, true, false},
+		{"0.0.0.0:9370", config.Server{}, true, false},
+		{"192.168.1.5:9370", config.Server{}, true, false},
+		{"monitor.lan:9370", config.Server{}, true, false},
+		{"9370", config.Server{}, true, false},
+		{"localhost:9370", token, false, false},
+		{":9370", token, false, true},
+		{"192.168.1.5:9370", token, false, true},
+		{":9370", tls, false, false},
+		{":9370", both, false, false},
+	}
+	for _, tt := range tests {
+		if got := Exposed(tt.addr, tt.auth); got != tt.exposed {
+			t.Errorf("Exposed(%q, %+v) = %v, want %v", tt.addr, tt.auth, got, tt.exposed)
+		}
+		if got := PlainToken(tt.addr, tt.auth); got != tt.plainTok {
+			t.Errorf("PlainToken(%q, %+v) = %v, want %v", tt.addr, tt.auth, got, tt.plainTok)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// CA files in the certificate directory.
+	caCert = "ca.pem"
+	caKey  = "ca-key.pem"
+	// caValidity and certValidity are how long the CA and the
+	// certificates it issues are valid.
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 2 * 365 * 24 * time.Hour
+)
+
+// InitCA creates a CA in dir for a monitor server and its TUIs: ca.pem,
+// which every end needs, and ca-key.pem, which only needs to be where
+// certificates are issued.
+func InitCA(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template, err := certTemplate("q-monitor CA", caValidity)
+	if err != nil {
+		return err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	return writePair(dir, caCert, caKey, der, key)
+}
+
+// IssueCert issues a certificate for name signed by the CA in dir, as
+// name.pem and name-key.pem. Servers need one for the hosts TUIs
+// connect to them by, names or addresses; TUIs need one without hosts.
+func IssueCert(dir, name string, hosts []string) error {
+	ca, err := tls.LoadX509KeyPair(filepath.Join(dir, caCert), filepath.Join(dir, caKey))
+	if err != nil {
+		return fmt.Errorf("failed to load the CA, run q-monitor cert init first: %w", err)
+	}
+	parent, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template, err := certTemplate(name, certValidity)
+	if err != nil {
+		return err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return err
+	}
+	return writePair(dir, name+".pem", name+"-key.pem", der, key)
+}
+
+func certTemplate(name string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+// writePair writes a certificate and its key as PEM files, refusing to
+// overwrite existing files so a CA isn't replaced by accident.
+func writePair(dir, certName, keyName string, der []byte, key *ecdsa.PrivateKey) error {
+	for _, name := range []string{certName, keyName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return fmt.Errorf("%s exists already", filepath.Join(dir, name))
+		}
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(filepath.Join(dir, keyName), "PRIVATE KEY", keyDER, 0o600); err != nil {
+		return err
+	}
+	return writePEM(filepath.Join(dir, certName), "CERTIFICATE", der, 0o644)
+}
+
+func writePEM(path, kind string, der []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(file, &pem.Block{Type: kind, Bytes: der}); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const (
+	// requestTimeout bounds connecting and the alert changes; the
+	// stream itself is only bounded by the pings.
+	requestTimeout = 10 * time.Second
+	// reconnectDelay is the wait before connecting again after the
+	// stream broke.
+	reconnectDelay = 5 * time.Second
+	// maxLine is the longest message a client reads, snapshots carry
+	// the watched log lines of a poll.
+	maxLine = 16 * 1024 * 1024
+)
+
+// Client is a TUI's connection to a server. It reconnects whenever the
+// stream breaks, the snapshots stop meanwhile and the nodes go stale.
+type Client struct {
+	url   string
+	token string
+	http  *http.Client
+	// Nodes and Interval are the server's, from its hello.
+	Nodes    []config.Node
+	Interval time.Duration
+
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	// cancel ends the stream's request
+	cancel context.CancelFunc
+}
+
+// Dial connects to the server at addr, host:port or a URL, and reads
+// its hello. auth are the token and certificates the server wants;
+// host:port means https if they enable TLS.
+func Dial(addr string, auth config.Server) (*Client, error) {
+	t, err := tlsConfig(auth, false)
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSuffix(addr, "/")
+	if !strings.Contains(url, "://") {
+		scheme := "http://"
+		if t != nil {
+			scheme = "https://"
+		}
+		url = scheme + url
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = t
+	c := &Client{url: url, token: auth.Token, http: &http.Client{Transport: transport}}
+	h, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	c.Nodes, c.Interval = h.Nodes, h.Interval
+	return c, nil
+}
+
+// connect opens the stream and reads up to the hello.
+func (c *Client) connect() (*hello, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := c.request(ctx, http.MethodGet, "/v1/stream", nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// only the answer is timed, the stream lasts
+	timer := time.AfterFunc(requestTimeout, cancel)
+	resp, err := c.http.Do(req)
+	if err == nil && !timer.Stop() {
+		err = context.DeadlineExceeded
+	}
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to connect to the monitor server: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := answerError(resp)
+		resp.Body.Close()
+		cancel()
+		return nil, err
+	}
+	c.body, c.cancel = resp.Body, cancel
+	c.scanner = bufio.NewScanner(resp.Body)
+	c.scanner.Buffer(nil, maxLine)
+
+	m, err := c.next()
+	if err == nil && (m.Type != typeHello || m.Hello == nil) {
+		err = fmt.Errorf("expected a hello, got %q", m.Type)
+	}
+	if err != nil {
+		c.close()
+		return nil, fmt.Errorf("monitor server: %w", err)
+	}
+	return m.Hello, nil
+}
+
+func (c *Client) close() {
+	c.body.Close()
+	c.cancel()
+}
+
+// next reads a message, giving up on the stream if not even a ping
+// came for a while.
+func (c *Client) next() (message, error) {
+	timer := time.AfterFunc(3*pingInterval, c.close)
+	defer timer.Stop()
+	var m message
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return m, err
+		}
+		return m, io.EOF
+	}
+	return m, json.Unmarshal(c.scanner.Bytes(), &m)
+}
+
+// Run feeds the stream to the sink, the handler and the watcher, the
+// TUI usually, and never returns. It reconnects after the stream broke,
+// and the server sends the latest snapshots again.
+func (c *Client) Run(sink collector.Sink, handler collector.Handler, watcher alert.Watcher) {
+	for {
+		for {
+			m, err := c.next()
+			if err != nil {
+				break
+			}
+			switch {
+			case m.Type == typeSnapshot && m.Snapshot != nil:
+				sink.Consume(m.Snapshot.decode())
+			case m.Type == typeEvent && m.Event != nil:
+				handler.HandleEvent(m.Event.decode())
+			case m.Type == typeAlerts && m.Alerts != nil:
+				watcher.AlertsChanged(*m.Alerts)
+			}
+		}
+		c.close()
+		for {
+			time.Sleep(reconnectDelay)
+			if _, err := c.connect(); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// Acknowledge acknowledges a firing alert on the server.
+func (c *Client) Acknowledge(key, by string) error {
+	return c.post("/v1/ack", ackRequest{Key: key, By: by})
+}
+
+// Silence silences a node on the server for d, or lifts its silence if
+// d isn't positive.
+func (c *Client) Silence(node, by string, d time.Duration) error {
+	if d < 0 {
+		d = 0
+	}
+	return c.post("/v1/silence", silenceRequest{Node: node, By: by, Duration: d.String()})
+}
+
+// request builds a request to the server with the token.
+func (c *Client) request(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+func (c *Client) post(path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := c.request(ctx, http.MethodPost, path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("monitor server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return answerError(resp)
+	}
+	return nil
+}
+
+// answerError is the error of a request the server refused, with the
+// reason it gave.
+func answerError(resp *http.Response) error {
+	text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if reason := strings.TrimSpace(string(text)); reason != "" {
+		return fmt.Errorf("monitor server answered %s: %s", resp.Status, reason)
+	}
+	return fmt.Errorf("monitor server answered %s", resp.Status)
+}
@@ -0,0 +1,238 @@
+// Package server shares one monitor between several TUIs: a server
+// polls the fleet and holds the alerts, with who acknowledged them and
+// which nodes are silenced, and streams it all to the TUIs connected
+// to it, so a team sees the same acknowledgements instead of one set
+// per laptop.
+//
+// The protocol is plain HTTP: GET /v1/stream is a never ending JSON
+// line per message, POST /v1/ack and /v1/silence change the alerts.
+// GET /metrics serves the Metrics handler, if there is one.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const (
+	// DefaultAddr is the address a server listens on and clients
+	// connect to unless told otherwise.
+	DefaultAddr = "localhost:9370"
+	// clientBuffer is how many messages a client can fall behind by
+	// before the server drops it; it reconnects and catches up.
+	clientBuffer = 256
+	// pingInterval is how often an idle stream is pinged.
+	pingInterval = 15 * time.Second
+	// recentEvents are how many events a new client is sent, like the
+	// TUI's footer shows.
+	recentEvents = 5
+)
+
+// Server streams the snapshots, events and alert state of a collector
+// to its clients. It implements collector.Sink, collector.Handler and
+// alert.Watcher.
+type Server struct {
+	engine *alert.Engine
+	hello  hello
+	// Metrics, if set, is served at GET /metrics, e.g. the Prometheus
+	// exporter.
+	Metrics http.Handler
+
+	mu sync.Mutex
+	// latest is the last snapshot of each node, events are the most
+	// recent ones and alerts the last alert state, for new clients
+	latest  map[int]message
+	events  []message
+	alerts  message
+	clients map[chan message]bool
+}
+
+// New returns a server for the nodes polled every interval, with engine
+// holding their alerts. Secrets are left out of everything it sends.
+func New(nodes []config.Node, interval time.Duration, engine *alert.Engine) *Server {
+	redacted := make([]config.Node, len(nodes))
+	for i, node := range nodes {
+		redacted[i] = node.Redact()
+	}
+	return &Server{
+		engine:  engine,
+		hello:   hello{Nodes: redacted, Interval: interval},
+		latest:  make(map[int]message),
+		alerts:  message{Type: typeAlerts, Alerts: encodeState(engine.State())},
+		clients: make(map[chan message]bool),
+	}
+}
+
+func (s *Server) Consume(snap collector.Snapshot) {
+	m := message{Type: typeSnapshot, Snapshot: encodeSnapshot(snap)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest[snap.Index] = m
+	s.broadcast(m)
+}
+
+func (s *Server) HandleEvent(e collector.Event) {
+	m := message{Type: typeEvent, Event: encodeEvent(e)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, m)
+	if len(s.events) > recentEvents {
+		s.events = s.events[len(s.events)-recentEvents:]
+	}
+	s.broadcast(m)
+}
+
+func (s *Server) AlertsChanged(state alert.State) {
+	m := message{Type: typeAlerts, Alerts: encodeState(state)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = m
+	s.broadcast(m)
+}
+
+// broadcast sends a message to every client, dropping those too far
+// behind rather than holding up the collector. The caller holds the
+// lock.
+func (s *Server) broadcast(m message) {
+	for client := range s.clients {
+		select {
+		case client <- m:
+		default:
+			delete(s.clients, client)
+			close(client)
+		}
+	}
+}
+
+// Handler serves the stream and the alert changes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/stream", s.stream)
+	mux.HandleFunc("POST /v1/ack", s.ack)
+	mux.HandleFunc("POST /v1/silence", s.silence)
+	if s.Metrics != nil {
+		mux.Handle("GET /metrics", s.Metrics)
+	}
+	return mux
+}
+
+// subscribe registers a client, with what it needs to catch up queued
+// up on its channel.
+func (s *Server) subscribe() chan message {
+	client := make(chan message, clientBuffer+len(s.hello.Nodes)+recentEvents+2)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client <- message{Type: typeHello, Hello: &s.hello}
+	for i := range s.hello.Nodes {
+		if m, ok := s.latest[i]; ok {
+			client <- m
+		}
+	}
+	for _, m := range s.events {
+		client <- m
+	}
+	client <- s.alerts
+	s.clients[client] = true
+	return client
+}
+
+func (s *Server) unsubscribe(client chan message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clients[client] {
+		delete(s.clients, client)
+		close(client)
+	}
+}
+
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	client := s.subscribe()
+	defer s.unsubscribe(client)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		var m message
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			m = message{Type: typePing}
+		case queued, open := <-client:
+			if !open {
+				// dropped for falling behind
+				return
+			}
+			m = queued
+		}
+		if err := encoder.Encode(m); err != nil {
+			return
+		}
+		if len(client) == 0 {
+			flusher.Flush()
+		}
+	}
+}
+
+// ackRequest is the body of POST /v1/ack.
+type ackRequest struct {
+	Key string `json:"key"`
+	By  string `json:"by"`
+}
+
+// silenceRequest is the body of POST /v1/silence. Duration is like
+// "1h", zero lifts the silence.
+type silenceRequest struct {
+	Node     string `json:"node"`
+	By       string `json:"by"`
+	Duration string `json:"duration"`
+}
+
+func (s *Server) ack(w http.ResponseWriter, r *http.Request) {
+	var req ackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	switch err := s.engine.Acknowledge(req.Key, req.By); {
+	case errors.Is(err, alert.ErrNotFiring):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) silence(w http.ResponseWriter, r *http.Request) {
+	var req silenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || req.Node == "" {
+		http.Error(w, "invalid request: needs a node and a duration", http.StatusBadRequest)
+		return
+	}
+	if err := s.engine.Silence(req.Node, req.By, d); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// report is the body of a POST to the webhook.
+type report struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+	// TTLSeconds is how long the report holds, the webhook's
+	// expire_seconds if zero.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+// Receiver is the webhook other systems report the health of nodes to
+// with POST /v1/nodes/{node}/signals, the node by its name or address.
+// The reports reach the panels and alerts with the node's next poll.
+type Receiver struct {
+	cfg     config.Webhook
+	signals *collector.Signals
+	// names are the nodes' names by name and address
+	names map[string]string
+}
+
+// NewReceiver returns a webhook for the nodes, keeping their reports in
+// signals.
+func NewReceiver(cfg config.Webhook, nodes []config.Node, signals *collector.Signals) *Receiver {
+	names := make(map[string]string)
+	for _, node := range nodes {
+		names[node.IP] = node.DisplayName()
+		names[node.DisplayName()] = node.DisplayName()
+	}
+	return &Receiver{cfg: cfg, signals: signals, names: names}
+}
+
+// Handler serves the reports, requiring the webhook's token.
+func (r *Receiver) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/nodes/{node}/signals", r.report)
+	return requireToken(r.cfg.Token, mux)
+}
+
+// Listen listens on the webhook's address, for Handler to be served
+// on, with TLS if the webhook has a certificate. It won't serve other
+// hosts without a token, nor the token over plain HTTP unless the
+// webhook is set insecure.
+func (r *Receiver) Listen() (net.Listener, error) {
+	addr := r.cfg.Addr()
+	if Exposed(addr, config.Server{Token: r.cfg.Token}) {
+		return nil, fmt.Errorf("%s can be reached from other hosts, set webhook.token", addr)
+	}
+	if (r.cfg.Cert == "") != (r.cfg.Key == "") {
+		return nil, errors.New("webhook.cert and webhook.key go together")
+	}
+	if r.cfg.Cert == "" && !r.cfg.Insecure && PlainToken(addr, config.Server{Token: r.cfg.Token}) {
+		return nil, fmt.Errorf("%s can be reached from other hosts and the token would go over plain HTTP, set webhook.cert and webhook.key, or webhook.insecure", addr)
+	}
+	var t *tls.Config
+	if r.cfg.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(r.cfg.Cert, r.cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the webhook certificate: %w", err)
+		}
+		t = &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil || t == nil {
+		return listener, err
+	}
+	return tls.NewListener(listener, t), nil
+}
+
+func (r *Receiver) report(w http.ResponseWriter, req *http.Request) {
+	node, ok := r.names[req.PathValue("node")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no node %q", req.PathValue("node")), http.StatusNotFound)
+		return
+	}
+	var body report
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<16)).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	signal := collector.Signal{Name: body.Name, State: body.State, Message: body.Message, Reported: now}
+	ttl := r.cfg.Expire()
+	if body.TTLSeconds > 0 {
+		ttl = time.Duration(body.TTLSeconds) * time.Second
+	}
+	if ttl > 0 {
+		signal.Expires = now.Add(ttl)
+	}
+	if err := r.signals.Report(node, signal); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// webhookCert issues a certificate for 127.0.0.1 from a new CA, and
+// returns its files and the CA's pool.
+func webhookCert(t *testing.T) (cert, key string, pool *x509.CertPool) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := InitCA(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := IssueCert(dir, "webhook", []string{"127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, caCert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AppendCertsFromPEM(data)
+	return filepath.Join(dir, "webhook.pem"), filepath.Join(dir, "webhook-key.pem"), pool
+}
+
+func TestReceiverListen(t *testing.T) {
+	cert, key, _ := webhookCert(t)
+	tests := []struct {
+		cfg  config.Webhook
+		want string
+	}{
+		{config.Webhook{Listen: "127.0.0.1:0"}, ""},
+		{config.Webhook{Listen: ":0"}, "set webhook.token"},
+		{config.Webhook{Listen: ":0", Token: "t"}, "the token would go over plain HTTP"},
+		{config.Webhook{Listen: ":0", Token: "t", Insecure: true}, ""},
+		{config.Webhook{Listen: "127.0.0.1:0", Token: "t"}, ""},
+		{config.Webhook{Listen: ":0", Token: "t", Cert: cert, Key: key}, ""},
+		{config.Webhook{Listen: ":0", Token: "t", Cert: cert}, "webhook.cert and webhook.key go together"},
+		{config.Webhook{Listen: ":0", Token: "t", Cert: cert, Key: cert}, "failed to load the webhook certificate"},
+	}
+	for _, tt := range tests {
+		listener, err := NewReceiver(tt.cfg, nil, collector.NewSignals()).Listen()
+		if listener != nil {
+			listener.Close()
+		}
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("Listen(%+v): %v", tt.cfg, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("Listen(%+v) = %v, want an error with %q", tt.cfg, err, tt.want)
+		}
+	}
+}
+
+func TestReceiverServesTLS(t *testing.T) {
+	cert, key, pool := webhookCert(t)
+	signals := collector.NewSignals()
+	receiver := NewReceiver(config.Webhook{Listen: "127.0.0.1:0", Token: "t", Cert: cert, Key: key},
+		[]config.Node{{Name: "fra-1", IP: "10.0.0.1"}}, signals)
+	listener, err := receiver.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go http.Serve(listener, receiver.Handler())
+
+	url := "https://" + listener.Addr().String() + "/v1/nodes/fra-1/signals"
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"name": "backup", "state": "ok"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer t")
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("POST over TLS = %s, want 204", resp.Status)
+	}
+	if got := signals.For("fra-1", time.Now()); len(got) != 1 || got[0].Name != "backup" {
+		t.Errorf("signals = %+v, want the backup signal", got)
+	}
+
+	// plain HTTP gets no further than the handshake
+	plain, err := http.Post("http://"+listener.Addr().String()+"/v1/nodes/fra-1/signals", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Body.Close()
+	if plain.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST over plain HTTP = %s, want 400", plain.Status)
+	}
+}
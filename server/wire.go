@@ -0,0 +1,186 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// Types of the messages on the stream.
+const (
+	typeHello    = "hello"
+	typeSnapshot = "snapshot"
+	typeEvent    = "event"
+	typeAlerts   = "alerts"
+	// typePing keeps the stream busy, so clients notice a dead server
+	typePing = "ping"
+)
+
+// message is a line of the stream a server sends its clients: the
+// hello first, then the latest snapshots and alerts, then everything as
+// it happens.
+type message struct {
+	Type     string       `json:"type"`
+	Hello    *hello       `json:"hello,omitempty"`
+	Snapshot *snapshot    `json:"snapshot,omitempty"`
+	Event    *event       `json:"event,omitempty"`
+	Alerts   *alert.State `json:"alerts,omitempty"`
+}
+
+// hello is what a client needs to know before the first snapshot.
+type hello struct {
+	Nodes    []config.Node `json:"nodes"`
+	Interval time.Duration `json:"interval"`
+}
+
+// remoteError is the wire form of an error, see transport.RemoteError.
+type remoteError struct {
+	Message string                  `json:"message"`
+	Failure transport.Failure       `json:"failure"`
+	Command *transport.CommandError `json:"command,omitempty"`
+}
+
+func encodeError(err error) *remoteError {
+	if err == nil {
+		return nil
+	}
+	e := &remoteError{Message: err.Error(), Failure: transport.Classify(err)}
+	errors.As(err, &e.Command)
+	return e
+}
+
+func (e *remoteError) decode() error {
+	if e == nil {
+		return nil
+	}
+	if e.Failure == "" {
+		e.Failure = transport.FailureOther
+	}
+	return &transport.RemoteError{Message: e.Message, Kind: e.Failure, Command: e.Command}
+}
+
+// location is the wire form of a time zone: its name, and its offset
+// for zones the client's zone database doesn't know, e.g. fixed ones.
+type location struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+}
+
+func encodeLocation(loc *time.Location) *location {
+	if loc == nil {
+		return nil
+	}
+	_, offset := time.Now().In(loc).Zone()
+	return &location{Name: loc.String(), Offset: offset}
+}
+
+func (l *location) decode() *time.Location {
+	if l == nil {
+		return nil
+	}
+	if loc, err := time.LoadLocation(l.Name); err == nil {
+		return loc
+	}
+	return time.FixedZone(l.Name, l.Offset)
+}
+
+// snapshot is the wire form of a collector.Snapshot. The errors and
+// the time zone, which don't survive JSON, go next to the status.
+type snapshot struct {
+	Index    int                     `json:"index"`
+	Node     config.Node             `json:"node"`
+	Time     time.Time               `json:"time"`
+	Err      *remoteError            `json:"error,omitempty"`
+	Status   *collector.Status       `json:"status,omitempty"`
+	Location *location               `json:"location,omitempty"`
+	Errors   map[string]*remoteError `json:"errors,omitempty"`
+	// Commands are the errors of Status.Commands by position
+	Commands map[int]*remoteError `json:"commands,omitempty"`
+}
+
+func encodeSnapshot(s collector.Snapshot) *snapshot {
+	w := &snapshot{Index: s.Index, Node: s.Node.Redact(), Time: s.Time, Err: encodeError(s.Err)}
+	if s.Err != nil {
+		return w
+	}
+	status := s.Status
+	w.Status = &status
+	w.Location = encodeLocation(status.Location)
+	status.Location = nil
+	if len(status.Errors) > 0 {
+		w.Errors = make(map[string]*remoteError, len(status.Errors))
+		for section, err := range status.Errors {
+			w.Errors[section] = encodeError(err)
+		}
+		status.Errors = nil
+	}
+	status.Commands = append([]transport.Result(nil), status.Commands...)
+	for i, result := range status.Commands {
+		if result.Err != nil {
+			if w.Commands == nil {
+				w.Commands = make(map[int]*remoteError)
+			}
+			w.Commands[i] = encodeError(result.Err)
+			status.Commands[i].Err = nil
+		}
+	}
+	return w
+}
+
+func (w *snapshot) decode() collector.Snapshot {
+	s := collector.Snapshot{Index: w.Index, Node: w.Node, Time: w.Time, Err: w.Err.decode()}
+	if w.Status == nil {
+		if s.Err == nil {
+			s.Err = errors.New("the server sent a snapshot without a status")
+		}
+		return s
+	}
+	s.Status = *w.Status
+	s.Status.Location = w.Location.decode()
+	if len(w.Errors) > 0 {
+		s.Status.Errors = make(map[string]error, len(w.Errors))
+		for section, err := range w.Errors {
+			s.Status.Errors[section] = err.decode()
+		}
+	}
+	for i, err := range w.Commands {
+		if i >= 0 && i < len(s.Status.Commands) {
+			s.Status.Commands[i].Err = err.decode()
+		}
+	}
+	return s
+}
+
+// event is the wire form of a collector.Event.
+type event struct {
+	collector.Event
+	Err *remoteError `json:"error,omitempty"`
+}
+
+func encodeEvent(e collector.Event) *event {
+	w := &event{Event: e, Err: encodeError(e.Err)}
+	w.Event.Err = nil
+	w.Event.Node = e.Node.Redact()
+	return w
+}
+
+func (w *event) decode() collector.Event {
+	e := w.Event
+	e.Err = w.Err.decode()
+	return e
+}
+
+// encodeState redacts the nodes of the alerts.
+func encodeState(state alert.State) *alert.State {
+	active := make([]alert.Alert, len(state.Active))
+	for i, a := range state.Active {
+		a.Node = a.Node.Redact()
+		active[i] = a
+	}
+	state.Active = active
+	return &state
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/readers"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// prompter asks questions on the terminal.
+type prompter struct {
+	in *bufio.Reader
+}
+
+// ask prints a question and returns the answer, or def when it's empty.
+func (p prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && answer != "") {
+		return "", err
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+func (p prompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := p.ask(question+" ("+hint+")", "")
+	if err != nil || answer == "" {
+		return def, err
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+func (p prompter) password(question string) (string, error) {
+	fmt.Printf("%s: ", question)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	return string(password), err
+}
+
+// runSetup is the first-run setup: it asks for the first node(s), tests
+// SSH and the log reader on each, and writes the config file.
+func runSetup(filename string) (*config.Config, error) {
+	fmt.Printf("No %s found, let's create one.\n", filename)
+	fmt.Println("Tip: use a dedicated monitor user on the node rather than root.")
+	fmt.Println()
+
+	p := prompter{in: bufio.NewReader(os.Stdin)}
+	cfg := &config.Config{}
+	for {
+		node, err := setupNode(p)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Nodes = append(cfg.Nodes, node)
+
+		another, err := p.confirm("Add another node?", false)
+		if err != nil {
+			return nil, err
+		}
+		if !another {
+			break
+		}
+		fmt.Println()
+	}
+
+	if err := config.Save(filename, cfg); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Saved %s with %d node(s). Edit it to add thresholds, tags and more.\n", filename, len(cfg.Nodes))
+	return cfg, nil
+}
+
+func setupNode(p prompter) (config.Node, error) {
+	var node config.Node
+	var err error
+
+	for {
+		if node.IP, err = p.ask("Node address (ip or hostname)", node.IP); err != nil {
+			return node, err
+		}
+		port, err := p.ask("SSH port", strconv.Itoa(node.SSHPort()))
+		if err != nil {
+			return node, err
+		}
+		if node.Port, err = strconv.Atoi(port); err != nil {
+			fmt.Printf("Invalid port %q.\n", port)
+			continue
+		}
+		if node.Port == config.DefaultPort {
+			node.Port = 0
+		}
+		if node.Username, err = p.ask("Username", node.Username); err != nil {
+			return node, err
+		}
+		if node.Password, err = p.password("Password"); err != nil {
+			return node, err
+		}
+
+		fmt.Print("Testing SSH connection... ")
+		conn, err := transport.SSH{}.Dial(node)
+		if err == nil {
+			var profile profiles.Profile
+			if profile, err = profiles.Detect(conn); err == nil {
+				fmt.Printf("ok (%s)\n", profile.Name)
+				err = setupReader(p, &node, conn, profile)
+			}
+			conn.Close()
+		}
+		if err == nil {
+			break
+		}
+		fmt.Printf("failed: %v\n", err)
+
+		retry, err := p.confirm("Try again?", true)
+		if err != nil {
+			return node, err
+		}
+		if !retry {
+			keep, err := p.confirm("Keep the node anyway?", false)
+			if err != nil {
+				return node, err
+			}
+			if keep {
+				break
+			}
+			return node, errors.New("no node configured")
+		}
+	}
+
+	if node.Name, err = p.ask("Display name (optional)", node.Name); err != nil {
+		return node, err
+	}
+	return node, nil
+}
+
+// setupReader picks the node's log reader and checks it returns any of
+// the watched messages.
+func setupReader(p prompter, node *config.Node, conn transport.Conn, profile profiles.Profile) error {
+	def := "1"
+	if len(profile.LogReaders) == 1 && profile.LogReaders[0] == readers.Tmux {
+		def = "2"
+	}
+	choice, err := p.ask("Log reader: 1) systemd service 2) tmux pane", def)
+	if err != nil {
+		return err
+	}
+
+	switch choice {
+	case "2":
+		node.LogReader = readers.Tmux
+		if node.TmuxPane, err = p.ask("tmux pane (target as for tmux -t)", node.TmuxPane); err != nil {
+			return err
+		}
+	default:
+		node.LogReader = ""
+		service, err := p.ask("Service name", node.ServiceName())
+		if err != nil {
+			return err
+		}
+		node.Service = ""
+		if service != config.DefaultService {
+			node.Service = service
+		}
+	}
+
+	reader, err := readers.ForNode(*node, nil)
+	if err != nil {
+		return err
+	}
+	if !profile.SupportsReader(reader) {
+		fmt.Printf("Note: %s logs are not available on %s nodes, only stats will be shown.\n", reader.Name(), profile.Name)
+		return nil
+	}
+
+	fmt.Print("Testing log reader... ")
+	format, _ := parsers.NewLogFormat("", "")
+	logs, err := reader.ReadLogs(conn, format.Filter(config.DefaultMessages))
+	if err != nil {
+		fmt.Printf("failed (%v), check the node is running and logging\n", err)
+		return nil
+	}
+	if strings.TrimSpace(logs) == "" {
+		fmt.Println("no watched messages found, check the node is running and logging")
+		return nil
+	}
+	fmt.Printf("ok, %d matching line(s)\n", strings.Count(strings.TrimSpace(logs), "\n")+1)
+	return nil
+}
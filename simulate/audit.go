@@ -0,0 +1,39 @@
+package simulate
+
+import "strings"
+
+// listeners answers `ss -Hlntu`: SSH, the Q node's p2p port and its
+// gRPC on localhost. sim-02 also has a Redis left open to the world.
+func (n *node) listeners() string {
+	lines := []string{
+		"tcp   LISTEN 0      4096         0.0.0.0:22        0.0.0.0:*",
+		"tcp   LISTEN 0      4096            [::]:22           [::]:*",
+		"udp   UNCONN 0      0            0.0.0.0:8336      0.0.0.0:*",
+		"tcp   LISTEN 0      4096       127.0.0.1:8337      0.0.0.0:*",
+		"udp   UNCONN 0      0      127.0.0.53%lo:53        0.0.0.0:*",
+	}
+	if n.name == "sim-02" {
+		lines = append(lines, "tcp   LISTEN 0      511          0.0.0.0:6379      0.0.0.0:*")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ruleset answers `nft list ruleset` with a firewall that drops what it
+// doesn't accept, except on sim-04 whose input policy was left at
+// accept.
+func (n *node) ruleset() string {
+	policy := "drop"
+	if n.name == "sim-04" {
+		policy = "accept"
+	}
+	return `table inet filter {
+	chain input {
+		type filter hook input priority filter; policy ` + policy + `;
+		ct state established,related accept
+		iif "lo" accept
+		tcp dport 22 accept
+		udp dport 8336 accept
+	}
+}
+`
+}
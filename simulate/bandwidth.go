@@ -0,0 +1,27 @@
+package simulate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// iperf3 answers a bandwidth benchmark against the server in cmd. The
+// nodes are on gigabit links that deliver less at times.
+func (n *node) iperf3(cmd string) string {
+	fields := strings.Fields(cmd)
+	server := "iperf.example"
+	for i, field := range fields {
+		if field == "-c" && i+1 < len(fields) {
+			server = strings.Trim(fields[i+1], "'")
+		}
+	}
+	bps := (600 + n.rand.Float64()*340) * 1e6
+	return fmt.Sprintf(`{"start":{"connecting_to":{"host":%q,"port":5201}},"end":{"sum_sent":{"bits_per_second":%.0f},"sum_received":{"bits_per_second":%.0f}}}`+"\n",
+		server, bps*1.01, bps)
+}
+
+// speedtest answers speedtest-cli with an asymmetric home connection.
+func (n *node) speedtest() string {
+	return fmt.Sprintf(`{"download":%.0f,"upload":%.0f,"ping":%.3f,"server":{"sponsor":"Example ISP","name":"Frankfurt"}}`+"\n",
+		(200+n.rand.Float64()*50)*1e6, (40+n.rand.Float64()*10)*1e6, 8+n.rand.Float64()*4)
+}
@@ -0,0 +1,41 @@
+package simulate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// keyFiles answers the key check's loop over the files it names. The
+// files are the q user's and only it can access them, except sim-02's
+// keys.yml, which someone made world readable. sim-04's config.yml is
+// rewritten two minutes into the simulation.
+func (n *node) keyFiles(cmd string) string {
+	list, _, _ := strings.Cut(strings.TrimPrefix(cmd, "for f in "), "; do")
+	if n.started.IsZero() {
+		n.started = time.Now()
+	}
+	var b strings.Builder
+	for _, file := range strings.Fields(list) {
+		file = strings.ReplaceAll(strings.ReplaceAll(file, `"$HOME"`, "/home/q"), "'", "")
+		if !strings.Contains(file, ".config") {
+			fmt.Fprintf(&b, "missing||||%s\n", file)
+			continue
+		}
+		if !strings.HasSuffix(file, ".yml") {
+			fmt.Fprintf(&b, "700|q|directory||%s\n", file)
+			continue
+		}
+		mode := "600"
+		if n.name == "sim-02" && strings.HasSuffix(file, "keys.yml") {
+			mode = "644"
+		}
+		contents := n.name + file
+		if n.name == "sim-04" && strings.HasSuffix(file, "config.yml") && time.Since(n.started) > 2*time.Minute {
+			contents += " rewritten"
+		}
+		fmt.Fprintf(&b, "%s|q|regular file|%x  -|%s\n", mode, sha256.Sum256([]byte(contents)), file)
+	}
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package simulate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// monitorAddress is where the simulated monitor connects from.
+const monitorAddress = "198.51.100.7"
+
+var loginsSince = regexp.MustCompile(`--since @(\d+)`)
+
+// logins answers the login check: the monitor's own connection, then
+// sshd's logins since the time asked in `journalctl -o short-unix`
+// form. The monitor logs in every poll, scanners try now and then, and
+// sim-03 was logged in to as root from an unknown address an hour
+// before the simulation started.
+func (n *node) logins(cmd string) string {
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+	if match := loginsSince.FindStringSubmatch(cmd); match != nil {
+		seconds, _ := strconv.ParseInt(match[1], 10, 64)
+		since = time.Unix(seconds, 0)
+	}
+	if n.started.IsZero() {
+		n.started = now
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s 50022 192.0.2.%d 22\n", monitorAddress, 10+len(n.name))
+	line := func(t time.Time, msg string) {
+		if t.After(since) && !t.After(now) {
+			fmt.Fprintf(&b, "%d.%06d %s sshd[%d]: %s\n", t.Unix(), t.Nanosecond()/1000, n.name, 1000+n.rand.Intn(9000), msg)
+		}
+	}
+	if n.name == "sim-03" {
+		line(n.started.Add(-time.Hour), "Accepted password for root from 203.0.113.66 port 40112 ssh2")
+	}
+	scanners := []string{"45.95.147.12", "218.92.0.31", "103.152.18.4"}
+	for i, attempts := 0, n.rand.Intn(4); i < attempts; i++ {
+		line(now.Add(-time.Duration(n.rand.Intn(int(Interval)))), fmt.Sprintf("Failed password for invalid user admin from %s port %d ssh2",
+			scanners[n.rand.Intn(len(scanners))], 30000+n.rand.Intn(30000)))
+	}
+	line(now, fmt.Sprintf("Accepted publickey for q from %s port 50022 ssh2", monitorAddress))
+	return b.String()
+}
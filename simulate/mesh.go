@@ -0,0 +1,37 @@
+package simulate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pingTarget finds the names of the nodes in the mesh check's command.
+var pingTarget = regexp.MustCompile(`'([^']*)' "\$out"`)
+
+// pings answers the mesh check of the nodes in cmd. Each pair of nodes
+// has its own latency, pairs with sim-03 are far apart and a few pings
+// get lost.
+func (n *node) pings(cmd string) string {
+	var b strings.Builder
+	for _, match := range pingTarget.FindAllStringSubmatch(cmd, -1) {
+		target := match[1]
+		var seed int
+		for _, r := range n.name + target {
+			seed += int(r)
+		}
+		rtt := 0.3 + float64(seed%40)*0.9
+		if n.name == "sim-03" || target == "sim-03" {
+			rtt += 140
+		}
+		rtt *= 1 + n.rand.Float64()*0.2
+		received := 3
+		if n.rand.Float64() < 0.05 {
+			received = 2
+		}
+		fmt.Fprintf(&b, "## %s\nPING %s 56(84) bytes of data.\n\n--- %s ping statistics ---\n", target, target, target)
+		fmt.Fprintf(&b, "3 packets transmitted, %d received, %d%% packet loss, time 2003ms\n", received, (3-received)*100/3)
+		fmt.Fprintf(&b, "rtt min/avg/max/mdev = %.3f/%.3f/%.3f/%.3f ms\n", rtt*0.9, rtt, rtt*1.1, rtt*0.05)
+	}
+	return b.String()
+}
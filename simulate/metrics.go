@@ -0,0 +1,67 @@
+package simulate
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// metricsPort is the port simulated nodes serve Prometheus metrics on.
+const metricsPort = 8080
+
+// DialNode serves the node's Prometheus metrics over an in-memory
+// connection, as if through an SSH port forward.
+func (n *node) DialNode(addr string) (net.Conn, error) {
+	if _, port, _ := net.SplitHostPort(addr); port != fmt.Sprint(metricsPort) {
+		return nil, fmt.Errorf("simulated connection refused: %s", addr)
+	}
+	n.mu.Lock()
+	body := n.metrics()
+	n.mu.Unlock()
+
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		req, err := http.ReadRequest(bufio.NewReader(server))
+		if err != nil {
+			return
+		}
+		req.Body.Close()
+		resp := &http.Response{
+			StatusCode:    http.StatusOK,
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"text/plain; version=0.0.4"}},
+			Body:          http.NoBody,
+			ContentLength: int64(len(body)),
+		}
+		if req.URL.Path != "/metrics" {
+			resp.StatusCode, resp.ContentLength = http.StatusNotFound, 0
+		} else {
+			resp.Body = readCloser{strings.NewReader(body)}
+		}
+		resp.Write(server)
+	}()
+	return client, nil
+}
+
+type readCloser struct{ *strings.Reader }
+
+func (readCloser) Close() error { return nil }
+
+// metrics is the node's metrics page in the Prometheus text format.
+func (n *node) metrics() string {
+	var b strings.Builder
+	b.WriteString("# HELP q_peers Connected peers by kind.\n# TYPE q_peers gauge\n")
+	fmt.Fprintf(&b, "q_peers{kind=\"mesh\"} %d\n", n.peers*2/3)
+	fmt.Fprintf(&b, "q_peers{kind=\"gossip\"} %d\n", n.peers-n.peers*2/3)
+	b.WriteString("# TYPE q_frame gauge\n")
+	fmt.Fprintf(&b, "q_frame %d\n", n.frame)
+	b.WriteString("# TYPE q_proof_seconds histogram\n")
+	fmt.Fprintf(&b, "q_proof_seconds_bucket{le=\"1\"} %d\n", n.frame%100)
+	fmt.Fprintf(&b, "q_proof_seconds_sum %.3f\n", float64(n.frame%100)*0.42)
+	fmt.Fprintf(&b, "q_proof_seconds_count %d\n", n.frame%100)
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package simulate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// ServerPassword is the password the nodes of a Server log in with.
+const ServerPassword = "demo"
+
+// Server is an SSH server on localhost answering like the nodes of
+// Nodes, each on a port of its own, so they are polled through the SSH
+// transport like real nodes are: the commands the readers run, their
+// exit codes and stderr, and the port forwards to the nodes' metrics.
+type Server struct {
+	// Nodes are the served nodes, with the address and credentials to
+	// poll them with.
+	Nodes []config.Node
+
+	config    *ssh.ServerConfig
+	dialer    *Dialer
+	listeners []net.Listener
+	wg        sync.WaitGroup
+}
+
+// Listen opens the ports of n nodes on localhost from port on, or on
+// free ones if port is 0, with a host key made up for the server. Serve
+// answers them.
+func Listen(n, port int) (*Server, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		config: &ssh.ServerConfig{
+			PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+				if subtle.ConstantTimeCompare(password, []byte(ServerPassword)) != 1 {
+					return nil, errors.New("wrong password")
+				}
+				return nil, nil
+			},
+		},
+		dialer: NewDialer(),
+	}
+	s.config.AddHostKey(signer)
+	for i, node := range Nodes(n) {
+		addr := "127.0.0.1:0"
+		if port != 0 {
+			addr = net.JoinHostPort("127.0.0.1", strconv.Itoa(port+i))
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.listeners = append(s.listeners, listener)
+		node.IP = "127.0.0.1"
+		node.Port = listener.Addr().(*net.TCPAddr).Port
+		node.Password = ServerPassword
+		s.Nodes = append(s.Nodes, node)
+	}
+	return s, nil
+}
+
+// Serve answers the connections to the nodes until Close.
+func (s *Server) Serve() {
+	for i, listener := range s.listeners {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go s.serveConn(s.Nodes[i], conn)
+			}
+		}()
+	}
+	s.wg.Wait()
+}
+
+// Close stops listening, the connections open keep being answered.
+func (s *Server) Close() error {
+	var err error
+	for _, listener := range s.listeners {
+		err = errors.Join(err, listener.Close())
+	}
+	return err
+}
+
+// serveConn answers a connection to n. A node that fails the poll,
+// like Dialer's do, hangs up before the handshake.
+func (s *Server) serveConn(n config.Node, conn net.Conn) {
+	defer conn.Close()
+	c, err := s.dialer.Dial(n)
+	if err != nil {
+		return
+	}
+	sim := c.(*node)
+	_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			go serveSession(sim, newChannel)
+		case "direct-tcpip":
+			go serveForward(sim, newChannel)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+		}
+	}
+}
+
+// serveSession runs the command of an exec request, the only one the
+// transport sends, and answers its output and exit status.
+func serveSession(sim *node, newChannel ssh.NewChannel) {
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	for req := range reqs {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		var exec struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &exec); err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+
+		output, err := sim.Run(exec.Command)
+		status := 0
+		var cmdErr *transport.CommandError
+		switch {
+		case errors.As(err, &cmdErr):
+			io.WriteString(channel.Stderr(), cmdErr.Stderr)
+			status = cmdErr.ExitCode
+		case err != nil:
+			io.WriteString(channel.Stderr(), err.Error()+"\n")
+			status = 1
+		}
+		io.WriteString(channel, output)
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+		return
+	}
+}
+
+// serveForward connects a port forward to the node's services, its
+// metrics.
+func serveForward(sim *node, newChannel ssh.NewChannel) {
+	var forward struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &forward); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "bad forward request")
+		return
+	}
+	target, err := sim.DialNode(net.JoinHostPort(forward.Host, strconv.Itoa(int(forward.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, fmt.Sprint(err))
+		return
+	}
+	defer target.Close()
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		io.Copy(target, channel)
+		target.Close()
+	}()
+	io.Copy(channel, target)
+}
@@ -0,0 +1,263 @@
+// Package simulate provides fake nodes that answer the collector's
+// commands with synthetic metrics and logs, in the process or over SSH,
+// so the UI and alerting can be exercised without a real fleet.
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// Interval is the polling interval used in simulation mode, short enough
+// to see things change.
+const Interval = 5 * time.Second
+
+// downChance is the probability of a simulated node failing a poll.
+const downChance = 0.05
+
+// Nodes returns n fake node definitions. Every fourth one is a
+// Raspberry Pi, every third serves Prometheus metrics on localhost.
+func Nodes(n int) []config.Node {
+	nodes := make([]config.Node, n)
+	for i := range nodes {
+		// addresses from the documentation range, so nothing is looked
+		// up or dialed
+		nodes[i] = config.Node{
+			Name:        fmt.Sprintf("sim-%02d", i+1),
+			IP:          fmt.Sprintf("192.0.2.%d", i%254+1),
+			Username:    "sim",
+			RaspberryPi: i%4 == 3,
+		}
+		if i%3 == 1 {
+			nodes[i].Metrics = &config.Metrics{Port: metricsPort}
+		}
+	}
+	return nodes
+}
+
+// Dialer is a transport.Dialer for nodes returned by Nodes. Each node keeps
+// its own random walk of metrics across polls.
+type Dialer struct {
+	mu    sync.Mutex
+	nodes map[string]*node
+}
+
+func NewDialer() *Dialer {
+	return &Dialer{nodes: make(map[string]*node)}
+}
+
+func (d *Dialer) Dial(n config.Node) (transport.Conn, error) {
+	d.mu.Lock()
+	sim, ok := d.nodes[n.Name]
+	if !ok {
+		sim = newNode(n.Name)
+		d.nodes[n.Name] = sim
+	}
+	d.mu.Unlock()
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if sim.rand.Float64() < downChance {
+		return nil, errors.New("failed to dial: simulated connection timeout")
+	}
+	sim.step()
+
+	return sim, nil
+}
+
+// node is the state of one simulated node. It doubles as its own
+// transport.Conn.
+type node struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	name string
+
+	cpu      float64
+	memUsed  float64
+	memTotal int
+	diskUsed float64
+	peers    int
+	frame    int
+	peerID   string
+	zone     string
+	lastLog  time.Time
+	started  time.Time
+	throttle uint32
+}
+
+func newNode(name string) *node {
+	var seed int64
+	for _, r := range name {
+		seed = seed*31 + int64(r)
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	totals := []int{7940, 15990, 32060, 64230}
+	return &node{
+		rand:     r,
+		name:     name,
+		cpu:      20 + r.Float64()*60,
+		memTotal: totals[r.Intn(len(totals))],
+		memUsed:  0.3 + r.Float64()*0.4,
+		diskUsed: 0.2 + r.Float64()*0.5,
+		peers:    10 + r.Intn(40),
+		frame:    100000 + r.Intn(1000),
+		peerID:   fmt.Sprintf("QmSim%040d", r.Int63()),
+		zone:     []string{"UTC +0000", "CEST +0200", "EDT -0400", "JST +0900"}[r.Intn(4)],
+	}
+}
+
+// step advances the random walk by one poll.
+func (n *node) step() {
+	n.cpu = clamp(n.cpu+n.rand.NormFloat64()*8, 1, 100)
+	n.memUsed = clamp(n.memUsed+n.rand.NormFloat64()*0.03, 0.05, 0.99)
+	n.diskUsed = clamp(n.diskUsed+n.rand.Float64()*0.002, 0, 0.99)
+	n.peers = int(clamp(float64(n.peers+n.rand.Intn(7)-3), 0, 200))
+	n.frame += 1 + n.rand.Intn(5)
+
+	// occasional under-voltage, remembered since "boot"
+	n.throttle &^= 0x5
+	if n.rand.Float64() < 0.1 {
+		n.throttle |= 0x50005
+	}
+}
+
+func (n *node) Run(cmd string) (string, error) {
+	if inner, ok := transport.Uncompressed(cmd); ok {
+		output, err := n.Run(inner)
+		if err != nil {
+			return "", err
+		}
+		return transport.CompressOutput(output), nil
+	}
+	if n.hangs(cmd) {
+		// the watchdog gives up on it long before
+		time.Sleep(3 * Interval)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch {
+	case cmd == "echo $SHELL":
+		return "/bin/bash\n", nil
+	case strings.HasPrefix(cmd, "for tool in"):
+		return "", nil
+	case cmd == "uname -s":
+		return "Linux\n", nil
+	case strings.HasPrefix(cmd, "date"):
+		return n.zone + "\n", nil
+	case strings.HasPrefix(cmd, "top"):
+		user := n.cpu * 0.8
+		system := n.cpu * 0.15
+		steal := n.cpu * 0.05
+		return fmt.Sprintf("%%Cpu(s): %4.1f us, %4.1f sy,  0.0 ni, %4.1f id,  0.0 wa,  0.0 hi,  0.0 si, %4.1f st\n",
+			user, system, 100-n.cpu, steal), nil
+	case strings.HasPrefix(cmd, "free"):
+		used := int(float64(n.memTotal) * n.memUsed)
+		return fmt.Sprintf("               total        used        free      shared  buff/cache   available\n"+
+			"Mem:          %6d      %6d      %6d           0           0      %6d\n"+
+			"Swap:              0           0           0\n",
+			n.memTotal, used, n.memTotal-used, n.memTotal-used), nil
+	case strings.HasPrefix(cmd, "df"):
+		size := int64(309237645) // 1K blocks, ~295G
+		used := int64(float64(size) * n.diskUsed)
+		output := fmt.Sprintf("Filesystem     1024-blocks      Used Available Capacity Mounted on\n"+
+			"/dev/sda1        %9d %9d %9d      %2d%% /\n",
+			size, used, size-used, int(n.diskUsed*100))
+		// paths other than / are on a data disk of their own
+		for i, path := range strings.Fields(cmd)[2:] {
+			if path = strings.Trim(path, "'"); path != "/" {
+				output += fmt.Sprintf("/dev/sd%c1        %9d %9d %9d      %2d%% %s\n",
+					'b'+i, size*4, used, size*4-used, int(n.diskUsed*25), path)
+			}
+		}
+		return output, nil
+	case strings.HasPrefix(cmd, "vcgencmd"):
+		return fmt.Sprintf("throttled=0x%x\ntemp=%.1f'C\n", n.throttle, 40+n.cpu/4), nil
+	case strings.HasPrefix(cmd, "(out=$(ping"):
+		return n.pings(cmd), nil
+	case strings.Contains(cmd, "iperf3 -J"):
+		return n.iperf3(cmd), nil
+	case strings.HasPrefix(cmd, "speedtest-cli"):
+		return n.speedtest(), nil
+	case cmd == "ss -Hlntu":
+		return n.listeners(), nil
+	case strings.HasSuffix(cmd, "nft list ruleset"):
+		return n.ruleset(), nil
+	case strings.HasPrefix(cmd, "for f in "):
+		return n.keyFiles(cmd), nil
+	case strings.HasPrefix(cmd, `echo "$SSH_CONNECTION"`):
+		return n.logins(cmd), nil
+	case strings.HasPrefix(cmd, "journalctl"), strings.HasPrefix(cmd, "tmux"):
+		return n.logs(), nil
+	default:
+		name, _, _ := strings.Cut(cmd, " ")
+		return "", &transport.CommandError{Command: cmd, ExitCode: 127, Stderr: "sh: " + name + ": not found\n"}
+	}
+}
+
+// hangs decides whether cmd hangs: sim-02's journal now and then takes
+// longer than a poll, like a journald stuck on a slow disk.
+func (n *node) hangs(cmd string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.name == "sim-02" && strings.HasPrefix(cmd, "journalctl") && n.rand.Float64() < 0.2
+}
+
+// logs writes entries spread between the previous read and now, like a
+// real node would have logged them in the meantime.
+func (n *node) logs() string {
+	now := time.Now()
+	from := n.lastLog
+	if from.IsZero() {
+		from = now.Add(-Interval)
+	}
+	n.lastLog = now
+	at := func(fraction float64) float64 {
+		t := from.Add(time.Duration(float64(now.Sub(from)) * fraction))
+		return float64(t.UnixNano()) / 1e9
+	}
+
+	lines := []string{
+		fmt.Sprintf(`{"level":"info","ts":%.3f,"caller":"node/main.go:1","msg":"connecting to bootstrap","peer_id":%q}`, at(0.2), n.peerID),
+		fmt.Sprintf(`{"level":"info","ts":%.3f,"caller":"p2p/blossomsub.go:1","msg":"peers in store","peer_store_count":%d,"network_peer_count":%d}`, at(0.4), n.peers*3, n.peers),
+	}
+	// sim-03 sits behind a NAT without a port forward, so its peers
+	// only reach it through relays
+	direct, relayed, reachability := n.peers, 0, "public"
+	if n.name == "sim-03" {
+		direct, relayed, reachability = 0, n.peers, "private"
+	}
+	lines = append(lines, fmt.Sprintf(`{"level":"info","ts":%.3f,"caller":"p2p/blossomsub.go:1","msg":"peer connection stats","direct_connections":%d,"relayed_connections":%d,"reachability":%q,"protocol_version":"2.0.4"}`,
+		at(0.3), direct, relayed, reachability))
+	broadcasts := 1 + n.rand.Intn(3)
+	for i := 1; i <= broadcasts; i++ {
+		frame := n.frame - broadcasts + i
+		// the network values move with the frame, so nodes that fall
+		// behind report old ones
+		lines = append(lines, fmt.Sprintf(`{"level":"info","ts":%.3f,"caller":"master/broadcast.go:1","msg":"broadcasting self-test info","current_frame":%d,"difficulty":%d,"ring_size":%d}`,
+			at(0.4+0.6*float64(i)/float64(broadcasts)), frame, 150000+(frame-100000)/500*500, 8+(frame-100000)/1000))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (n *node) Close() error {
+	return nil
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/price"
+	"github.com/fid1699/q-monitor-cli/server"
+	"github.com/fid1699/q-monitor-cli/ui"
+)
+
+// supervisorTokenEnv hands a supervised collector the token its TUI
+// connects with, see runSupervised.
+const supervisorTokenEnv = "Q_MONITOR_SUPERVISOR_TOKEN"
+
+const (
+	// startTimeout is how long the TUI waits for the collector to serve
+	// at the start.
+	startTimeout = 15 * time.Second
+	// restartDelay is the wait before restarting a crashed collector,
+	// doubled for every crash soon after its start, up to
+	// maxRestartDelay.
+	restartDelay    = time.Second
+	maxRestartDelay = time.Minute
+	// maxPartialLine is the longest line of the collector's error output
+	// read for why it crashed.
+	maxPartialLine = 64 * 1024
+)
+
+// runSupervised runs the TUI with the collector in a child process,
+// `q-monitor serve` on a local port, which is restarted whenever it
+// exits, so a panic in a parser doesn't end a multi-day run. The TUI
+// reconnects and says when and why the collector restarted.
+func runSupervised(cfg *config.Config, simulateNodes int) error {
+	if err := ui.ValidateKeys(cfg.Display.Keys); err != nil {
+		return fmt.Errorf("in config: %w", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	args := []string{"serve", "--listen", addr}
+	if simulateNodes > 0 {
+		args = append(args, "--simulate", strconv.Itoa(simulateNodes))
+	}
+	if cfg.LowBandwidth != nil {
+		args = append(args, "--low-bandwidth")
+	}
+	child := &collectorProcess{args: args, token: hex.EncodeToString(secret)}
+	if err := child.start(); err != nil {
+		return err
+	}
+	defer child.stop()
+
+	client, err := child.dial(addr)
+	if err != nil {
+		return err
+	}
+	tui := connectedTUI(client, cfg)
+	if cfg.Earnings != nil {
+		tui.Earnings = cfg.Earnings
+		go price.Watch(*cfg.Earnings, tui.SetPrice)
+	}
+	go child.supervise(tui.Notice)
+	return tui.Run()
+}
+
+// collectorProcess is the child process a supervised TUI connects to.
+type collectorProcess struct {
+	args  []string
+	token string
+
+	mu      sync.Mutex
+	started time.Time
+	cmd     *exec.Cmd
+	// input is the child's stdin, it exits once that is closed
+	input    io.WriteCloser
+	output   *crashOutput
+	exited   chan error
+	stopping bool
+}
+
+func (p *collectorProcess) start() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, p.args...)
+	cmd.Env = append(os.Environ(), supervisorTokenEnv+"="+p.token)
+	output := &crashOutput{}
+	cmd.Stdout, cmd.Stderr = io.Discard, output
+	input, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the collector: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started, p.cmd, p.input, p.output, p.exited = time.Now(), cmd, input, output, exited
+	return nil
+}
+
+// stop ends the child for good.
+func (p *collectorProcess) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopping = true
+	p.input.Close()
+	p.cmd.Process.Kill()
+}
+
+// dial connects to the child once it serves, or returns why it exited
+// instead.
+func (p *collectorProcess) dial(addr string) (*server.Client, error) {
+	deadline := time.Now().Add(startTimeout)
+	for {
+		client, err := server.Dial(addr, config.Server{Token: p.token})
+		if err == nil {
+			return client, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("the collector didn't start: %w", err)
+		}
+		select {
+		case err := <-p.exited:
+			return nil, fmt.Errorf("the collector exited: %s", p.output.reason(err))
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// supervise restarts the child whenever it exits, telling notice when
+// and why.
+func (p *collectorProcess) supervise(notice func(string)) {
+	delay := restartDelay
+	for {
+		p.mu.Lock()
+		exited, output, started := p.exited, p.output, p.started
+		p.mu.Unlock()
+		err := <-exited
+		p.mu.Lock()
+		stopping := p.stopping
+		p.mu.Unlock()
+		if stopping {
+			return
+		}
+
+		reason := output.reason(err)
+		if time.Since(started) > maxRestartDelay {
+			delay = restartDelay
+		}
+		for {
+			time.Sleep(delay)
+			delay = min(2*delay, maxRestartDelay)
+			if err := p.start(); err != nil {
+				notice(fmt.Sprintf("monitor crashed at %s: %s, restarting failed: %v", time.Now().Format("15:04"), reason, err))
+				continue
+			}
+			break
+		}
+		notice(fmt.Sprintf("monitor restarted at %s after crash: %s", time.Now().Format("15:04"), reason))
+	}
+}
+
+// exitWithInput ends a supervised collector once its stdin closes, i.e.
+// once its TUI is gone, even if the TUI crashed itself.
+func exitWithInput() {
+	io.Copy(io.Discard, os.Stdin)
+	os.Exit(0)
+}
+
+// crashOutput reads a child's error output for why it crashed: the
+// first panic it printed. The goroutine traces after it are dropped.
+type crashOutput struct {
+	mu      sync.Mutex
+	partial []byte
+	panic   string
+}
+
+func (c *crashOutput) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.partial = append(c.partial, p...)
+	for {
+		end := bytes.IndexByte(c.partial, '\n')
+		if end < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(c.partial[:end]))
+		c.partial = c.partial[end+1:]
+		if c.panic == "" && (strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "fatal error: ")) {
+			c.panic = line
+		}
+	}
+	if len(c.partial) > maxPartialLine {
+		c.partial = c.partial[:0]
+	}
+	return len(p), nil
+}
+
+// reason says why the child exited, err being what Wait returned.
+func (c *crashOutput) reason(err error) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.panic != "":
+		return c.panic
+	case err != nil:
+		return err.Error()
+	default:
+		return "exited"
+	}
+}
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/fid1699/q-monitor-cli/bootstrap"
+)
+
+// Failure is the kind of a failed connection, so a wrong password, a ban
+// and a node that is down are told apart.
+type Failure string
+
+const (
+	// FailureOther is any failure not classified below, e.g. a failed
+	// command.
+	FailureOther Failure = "other"
+	// FailureAuth is a node that refused the credentials.
+	FailureAuth Failure = "auth"
+	// FailureRefused is a node where nothing accepts connections on the
+	// SSH port.
+	FailureRefused Failure = "refused"
+	// FailureBanned is a node that closed the connection during the
+	// handshake, which is what fail2ban and sshd's MaxStartups do.
+	FailureBanned Failure = "banned"
+	// FailureHostKey is a node whose host key doesn't match known_hosts.
+	FailureHostKey Failure = "host_key"
+	// FailureTimeout is a node that didn't answer in time.
+	FailureTimeout Failure = "timeout"
+	// FailureBootstrap is a bootstrap peer that doesn't answer.
+	FailureBootstrap Failure = "bootstrap"
+	// FailureHung is a session torn down by the watchdog, see Watch, or
+	// a command that timed out, see Timeout.
+	FailureHung Failure = "hung"
+)
+
+// failureText are the title and hint shown for each failure.
+var failureText = map[Failure][2]string{
+	FailureOther:     {"poll failed", ""},
+	FailureAuth:      {"login failed", "the node refused the credentials, check username and password; retries are not attempted so fail2ban isn't triggered"},
+	FailureRefused:   {"connection refused", "sshd isn't running on the port, or a firewall rejects this address"},
+	FailureBanned:    {"banned or rate limited", "the node closed the connection during the handshake, it may have banned this address (fail2ban) or limit concurrent logins (sshd MaxStartups)"},
+	FailureHostKey:   {"host key mismatch", "the host key changed since it was added to ~/.ssh/known_hosts, the node was reinstalled or the connection is intercepted"},
+	FailureTimeout:   {"timeout", "the node didn't answer in time, it or its network is down"},
+	FailureBootstrap: {"bootstrap peer down", "the peer doesn't answer from the monitor host; if the Q nodes are up the bootstrap is down, if they are all down too check this host's network"},
+	FailureHung:      {"session hung", "a command didn't finish within its timeout or the poll's budget, e.g. df on a dead NFS mount; the connection was closed so hung sessions don't pile up"},
+}
+
+// RemoteError is a poll error that happened elsewhere, e.g. on the
+// monitor server a TUI is connected to, with the kind it was classified
+// as there. errors.As finds the CommandError it was, if any.
+type RemoteError struct {
+	Message string
+	Kind    Failure
+	Command *CommandError
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+func (e *RemoteError) Unwrap() error {
+	if e.Command == nil {
+		return nil
+	}
+	return e.Command
+}
+
+// Classify works out the kind of a poll error.
+func Classify(err error) Failure {
+	var netErr net.Error
+	var remote *RemoteError
+	switch {
+	case errors.As(err, &remote):
+		return remote.Kind
+	case errors.Is(err, bootstrap.ErrUnreachable):
+		return FailureBootstrap
+	case errors.Is(err, ErrHung), errors.Is(err, ErrTimeout):
+		return FailureHung
+	case errors.Is(err, ErrHostKeyMismatch):
+		return FailureHostKey
+	case err != nil && isAuthError(err):
+		return FailureAuth
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailureRefused
+	case errors.Is(err, io.EOF), errors.Is(err, syscall.ECONNRESET):
+		if strings.Contains(err.Error(), "handshake failed") {
+			return FailureBanned
+		}
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return FailureTimeout
+	case errors.Is(err, ErrUnreachable):
+		return FailureTimeout
+	}
+	return FailureOther
+}
+
+// Title names the failure in a few words, e.g. "login failed".
+func (f Failure) Title() string {
+	return failureText[f][0]
+}
+
+// Hint explains the failure and what to check, empty for FailureOther.
+func (f Failure) Hint() string {
+	return failureText[f][1]
+}
+
+// Describe prefixes err with the title of its failure, e.g. "login
+// failed: ...". Unclassified errors are described by themselves.
+func Describe(err error) string {
+	failure := Classify(err)
+	if failure == FailureOther {
+		return err.Error()
+	}
+	return failure.Title() + ": " + err.Error()
+}
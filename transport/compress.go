@@ -0,0 +1,84 @@
+package transport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompressTools are the programs a compressed command runs on the node.
+var CompressTools = []string{"gzip", "base64", "mktemp"}
+
+// compressHead and compressTail wrap a quoted command so its output is
+// gzipped on the node and encoded in base64, as the session rewrites
+// line endings. The command's exit status goes through a file, the
+// pipeline's is base64's.
+const (
+	compressHead = `e=$(mktemp) || exit 1; { sh -c `
+	compressTail = `; echo $? >"$e"; } | gzip -c | base64; s=$(cat "$e"); rm -f "$e"; exit "$s"`
+)
+
+// Compress wraps a connection to a POSIX node so the output of every
+// command is sent gzipped. The SSH client can't negotiate compression
+// of the session itself, so it is done by the commands; that only pays
+// off for output of a few KB, like logs.
+func Compress(conn Conn) Conn {
+	return compressConn{Conn: conn}
+}
+
+type compressConn struct {
+	Conn
+}
+
+func (c compressConn) Run(cmd string) (string, error) {
+	output, err := c.Conn.Run(compressHead + ShellQuote(cmd) + compressTail)
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) {
+		failed := *commandErr
+		failed.Command = cmd
+		return "", &failed
+	}
+	if err != nil {
+		return "", err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(output), ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the output of '%s': %w", cmd, err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress the output of '%s': %w", cmd, err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress the output of '%s': %w", cmd, err)
+	}
+	return string(plain), nil
+}
+
+// Uncompressed returns the command a compressed command runs, for
+// connections that answer commands themselves like the simulator's.
+func Uncompressed(cmd string) (string, bool) {
+	quoted, ok := strings.CutPrefix(cmd, compressHead)
+	if !ok {
+		return "", false
+	}
+	quoted, ok = strings.CutSuffix(quoted, compressTail)
+	if !ok || len(quoted) < 2 {
+		return "", false
+	}
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], `'\''`, "'"), true
+}
+
+// CompressOutput encodes output the way a compressed command sends it.
+func CompressOutput(output string) string {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(output))
+	w.Close()
+	return base64.StdEncoding.EncodeToString(b.Bytes()) + "\n"
+}
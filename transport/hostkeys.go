@@ -0,0 +1,51 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrHostKeyMismatch is returned for nodes whose host key differs from
+// the one in known_hosts, which is either a reinstalled node or someone
+// in between.
+var ErrHostKeyMismatch = errors.New("host key mismatch")
+
+var (
+	knownHostsOnce     sync.Once
+	knownHostsCallback ssh.HostKeyCallback
+)
+
+// checkHostKey checks host keys against ~/.ssh/known_hosts. Nodes that
+// are not listed there, or all nodes if there is no such file, are
+// accepted without a check.
+func checkHostKey(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsOnce.Do(func() {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return
+		}
+		knownHostsCallback, _ = knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	})
+	if knownHostsCallback == nil {
+		return nil
+	}
+
+	err := knownHostsCallback(hostname, remote, key)
+	var keyErr *knownhosts.KeyError
+	switch {
+	case errors.As(err, &keyErr) && len(keyErr.Want) == 0:
+		// not a known host
+		return nil
+	case errors.As(err, &keyErr):
+		return fmt.Errorf("%w: %s is listed in known_hosts with another key, got %s",
+			ErrHostKeyMismatch, hostname, ssh.FingerprintSHA256(key))
+	}
+	return err
+}
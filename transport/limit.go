@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+const (
+	// retryDelay is the wait before the first retry of a failed
+	// connection, doubled for every further one.
+	retryDelay = 2 * time.Second
+	// maxRetries is how many retries one dial may take from the budget,
+	// so a node that is down doesn't use it up in a single poll.
+	maxRetries = 3
+	// budgetWindow is how long a retry budget lasts before it is full
+	// again.
+	budgetWindow = time.Hour
+)
+
+// Limited is a Dialer that paces new connections across all nodes and
+// retries failed ones while the node's retry budget lasts.
+type Limited struct {
+	Dialer Dialer
+	limits config.SSHLimits
+
+	mu sync.Mutex
+	// next is the earliest time the next connection may be opened
+	next    time.Time
+	budgets map[string]*budget
+}
+
+// budget is what is left of a node's retries in the current window.
+type budget struct {
+	left  int
+	reset time.Time
+}
+
+// Limit wraps dialer with the limits, zero limits dial straight through.
+func Limit(dialer Dialer, limits config.SSHLimits) *Limited {
+	return &Limited{Dialer: dialer, limits: limits, budgets: make(map[string]*budget)}
+}
+
+func (l *Limited) Dial(node config.Node) (Conn, error) {
+	for attempt := 0; ; attempt++ {
+		l.wait()
+		conn, err := l.Dialer.Dial(node)
+		if err == nil || attempt == maxRetries || !retryable(err) || !l.spend(node) {
+			return conn, err
+		}
+		time.Sleep(retryDelay << attempt)
+	}
+}
+
+// wait blocks until the connection rate allows another connection.
+func (l *Limited) wait() {
+	if l.limits.ConnectionsPerSecond <= 0 {
+		return
+	}
+	interval := time.Duration(float64(time.Second) / l.limits.ConnectionsPerSecond)
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(interval)
+	l.mu.Unlock()
+
+	time.Sleep(at.Sub(now))
+}
+
+// spend takes a retry from the node's budget, false if none is left.
+func (l *Limited) spend(node config.Node) bool {
+	if l.limits.RetryBudget <= 0 {
+		return false
+	}
+	key := net.JoinHostPort(node.IP, strconv.Itoa(node.SSHPort()))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b := l.budgets[key]
+	if b == nil || now.After(b.reset) {
+		b = &budget{left: l.limits.RetryBudget, reset: now.Add(budgetWindow)}
+		l.budgets[key] = b
+	}
+	if b.left == 0 {
+		return false
+	}
+	b.left--
+	return true
+}
+
+// retryable reports whether a failed connection is worth retrying. Failed
+// logins and bans only get worse with retries, and a host key doesn't
+// change back by itself.
+func retryable(err error) bool {
+	switch Classify(err) {
+	case FailureAuth, FailureBanned, FailureHostKey:
+		return false
+	}
+	return true
+}
+
+// isAuthError reports whether the node refused the credentials.
+func isAuthError(err error) bool {
+	return strings.Contains(err.Error(), "unable to authenticate")
+}
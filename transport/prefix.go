@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// WithPrefix wraps a connection so that every command runs behind the
+// node's command prefix and with its environment. The command is passed
+// to `sh -c` so both apply to the whole pipeline, not just its first
+// command. Connections of nodes without either are returned as they are.
+func WithPrefix(conn Conn, node config.Node) Conn {
+	if node.CommandPrefix == "" && len(node.Env) == 0 {
+		return conn
+	}
+
+	var b strings.Builder
+	if node.CommandPrefix != "" {
+		b.WriteString(node.CommandPrefix + " ")
+	}
+	if len(node.Env) > 0 {
+		names := make([]string, 0, len(node.Env))
+		for name := range node.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("env")
+		for _, name := range names {
+			b.WriteString(" " + ShellQuote(name+"="+node.Env[name]))
+		}
+		b.WriteString(" ")
+	}
+	return prefixConn{Conn: conn, prefix: b.String()}
+}
+
+type prefixConn struct {
+	Conn
+	prefix string
+}
+
+func (c prefixConn) Run(cmd string) (string, error) {
+	return c.Conn.Run(c.prefix + "sh -c " + ShellQuote(cmd))
+}
+
+// AsUser wraps a runner so that its commands run as another user of the
+// node, switched to with sudo (config.SwitchSudo, also for an empty
+// switch) or su (config.SwitchSu). Like WithPrefix it passes the
+// command to sh -c.
+func AsUser(runner Runner, user, switchWith string) Runner {
+	if switchWith == config.SwitchSu {
+		return userRunner{runner: runner, prefix: "su -s /bin/sh " + ShellQuote(user) + " -c "}
+	}
+	return userRunner{runner: runner, prefix: "sudo -n -u " + ShellQuote(user) + " sh -c "}
+}
+
+type userRunner struct {
+	runner Runner
+	prefix string
+}
+
+func (r userRunner) Run(cmd string) (string, error) {
+	return r.runner.Run(r.prefix + ShellQuote(cmd))
+}
+
+// ShellQuote wraps s in single quotes for a POSIX shell.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
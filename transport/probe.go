@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// ProbeTimeout is how long a node gets to answer the reachability probe.
+// A node that doesn't gets one more try of RetryTimeout, for a lost
+// packet or a slow link, so nodes that are hard down fail within both
+// instead of waiting for the operating system's connect timeout every
+// poll.
+const (
+	ProbeTimeout = time.Second
+	RetryTimeout = 5 * time.Second
+)
+
+// ErrUnreachable is returned for nodes that didn't answer the probe.
+var ErrUnreachable = errors.New("node unreachable")
+
+// connect opens the TCP connection the SSH session runs on. It is the
+// TCP probe, bounded by ProbeTimeout and retried once within
+// RetryTimeout if it times out, unless the node disables probing. With
+// the ICMP probe the node is pinged first.
+func connect(node config.Node, address string) (net.Conn, error) {
+	switch node.Probe {
+	case config.ProbeNone:
+		return net.Dial("tcp", address)
+	case config.ProbeICMP:
+		if err := ping(node.IP); errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("failed to probe with icmp: %w", err)
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: no ping reply: %w", ErrUnreachable, err)
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", address, ProbeTimeout)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		// a refused connection is an answer, a missing one may be a lost
+		// SYN or an LTE link
+		conn, err = net.DialTimeout("tcp", address, RetryTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	return conn, nil
+}
+
+// ping sends a single echo request with the system's ping, which unlike
+// a raw socket doesn't need privileges.
+func ping(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ProbeTimeout)
+	defer cancel()
+
+	count := "-c"
+	if runtime.GOOS == "windows" {
+		count = "-n"
+	}
+	if err := exec.CommandContext(ctx, "ping", count, "1", host).Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
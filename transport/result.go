@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CommandError is a command that ran on the node but exited with a
+// non-zero status.
+type CommandError struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *CommandError) Error() string {
+	detail := fmt.Sprintf("exit status %d", e.ExitCode)
+	if e.Missing() {
+		detail = "not found on the node"
+	}
+	if e.Stderr != "" {
+		detail += ": " + firstLine(e.Stderr)
+	}
+	return fmt.Sprintf("failed to run command '%s': %s", e.Command, detail)
+}
+
+// Missing reports whether the command failed because a program it runs
+// isn't installed, rather than because the node is broken. Shells exit
+// with 127; in pipelines only the message on stderr tells.
+func (e *CommandError) Missing() bool {
+	return e.ExitCode == 127 ||
+		strings.Contains(e.Stderr, "not found") ||
+		strings.Contains(e.Stderr, "is not recognized")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// Result is the outcome of one command.
+type Result struct {
+	Command  string
+	ExitCode int
+	// Stderr is only kept for failed commands.
+	Stderr   string
+	Duration time.Duration
+	Err      error
+}
+
+// Recorder wraps a connection and keeps the result of every command run
+// through it.
+type Recorder struct {
+	Conn
+	results []Result
+}
+
+func Record(conn Conn) *Recorder {
+	return &Recorder{Conn: conn}
+}
+
+func (r *Recorder) Run(cmd string) (string, error) {
+	start := time.Now()
+	output, err := r.Conn.Run(cmd)
+	result := Result{Command: cmd, Duration: time.Since(start), Err: err}
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) {
+		result.ExitCode = commandErr.ExitCode
+		result.Stderr = commandErr.Stderr
+	}
+	r.results = append(r.results, result)
+	return output, err
+}
+
+// Results returns the commands run so far, in order.
+func (r *Recorder) Results() []Result {
+	return r.results
+}
@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// posixShells are login shells the commands run in as they are.
+var posixShells = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "ash": true, "busybox": true,
+	"zsh": true, "ksh": true, "mksh": true, "yash": true,
+}
+
+// DetectShell works out the kind of a node's login shell from $SHELL,
+// which every supported shell expands. It returns an empty kind if the
+// node doesn't report one, and an error for shells the commands can't be
+// run from.
+func DetectShell(runner Runner) (string, error) {
+	output, err := runner.Run("echo $SHELL")
+	if err != nil {
+		return "", fmt.Errorf("failed to detect the login shell: %w", err)
+	}
+	shell := strings.TrimSpace(output)
+	if !strings.HasPrefix(shell, "/") {
+		return "", nil
+	}
+
+	switch name := path.Base(shell); {
+	case posixShells[name]:
+		return config.ShellPOSIX, nil
+	case name == "fish":
+		return config.ShellFish, nil
+	case name == "csh" || name == "tcsh":
+		return config.ShellCsh, nil
+	default:
+		return "", fmt.Errorf("login shell %s is not supported, change it or set \"shell\" on the node to posix, fish or csh", shell)
+	}
+}
+
+// WithShell wraps a connection so the POSIX commands of the collector
+// also work from a non-POSIX login shell: they are passed to `sh -c`,
+// quoted for the login shell.
+func WithShell(conn Conn, shell string) Conn {
+	switch shell {
+	case config.ShellFish:
+		return shellConn{Conn: conn, quote: fishQuote}
+	case config.ShellCsh:
+		return shellConn{Conn: conn, quote: cshQuote}
+	default:
+		return conn
+	}
+}
+
+type shellConn struct {
+	Conn
+	quote func(string) string
+}
+
+func (c shellConn) Run(cmd string) (string, error) {
+	return c.Conn.Run("sh -c " + c.quote(cmd))
+}
+
+// fishQuote wraps s in single quotes for fish, which unlike POSIX shells
+// takes backslash escapes inside them.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// cshQuote wraps s in single quotes for csh and tcsh, in which a newline
+// still ends the command and ! still expands the history, unless a
+// backslash escapes them.
+func cshQuote(s string) string {
+	s = strings.ReplaceAll(s, "'", `'\''`)
+	s = strings.ReplaceAll(s, "!", `\!`)
+	return "'" + strings.ReplaceAll(s, "\n", "\\\n") + "'"
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in               string
+		posix, fish, csh string
+	}{
+		{"uptime", `'uptime'`, `'uptime'`, `'uptime'`},
+		{"it's", `'it'\''s'`, `'it\'s'`, `'it'\''s'`},
+		{`a\b`, `'a\b'`, `'a\\b'`, `'a\b'`},
+		{"echo hi!", `'echo hi!'`, `'echo hi!'`, `'echo hi\!'`},
+		{"a\nb", "'a\nb'", "'a\nb'", "'a\\\nb'"},
+		{`$HOME "x"`, `'$HOME "x"'`, `'$HOME "x"'`, `'$HOME "x"'`},
+	}
+	for _, tt := range tests {
+		if got := ShellQuote(tt.in); got != tt.posix {
+			t.Errorf("ShellQuote(%q) = %q, want %q", tt.in, got, tt.posix)
+		}
+		if got := fishQuote(tt.in); got != tt.fish {
+			t.Errorf("fishQuote(%q) = %q, want %q", tt.in, got, tt.fish)
+		}
+		if got := cshQuote(tt.in); got != tt.csh {
+			t.Errorf("cshQuote(%q) = %q, want %q", tt.in, got, tt.csh)
+		}
+	}
+}
+
+// TestQuoteShells runs the quoted strings through the shells installed,
+// which have to give them back as they were.
+func TestQuoteShells(t *testing.T) {
+	inputs := []string{"uptime", "it's", `a\b`, "echo hi!", "a\nb", `$HOME "x" $(id) ` + "`id`", "grep -E '(a|b)' <x >y; z & w"}
+	shells := []struct {
+		name, flag string
+		quote      func(string) string
+	}{
+		{"sh", "-c", ShellQuote},
+		{"fish", "-c", fishQuote},
+		{"csh", "-fc", cshQuote},
+		{"tcsh", "-fc", cshQuote},
+	}
+	for _, shell := range shells {
+		path, err := exec.LookPath(shell.name)
+		if err != nil {
+			continue
+		}
+		for _, in := range inputs {
+			out, err := exec.Command(path, shell.flag, "printf %s "+shell.quote(in)).Output()
+			if err != nil {
+				t.Errorf("%s: printf %s: %v", shell.name, shell.quote(in), err)
+				continue
+			}
+			if string(out) != in {
+				t.Errorf("%s: %q came back as %q", shell.name, in, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package transport abstracts how the collector runs commands on a node,
+// so the SSH implementation can be swapped for a simulated one.
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// handshakeTimeout bounds the SSH handshake and login, so a node that
+// accepts the connection but never answers doesn't hang the poll.
+const handshakeTimeout = 30 * time.Second
+
+// Runner runs a single command on a node and returns its stdout.
+type Runner interface {
+	Run(cmd string) (string, error)
+}
+
+// Conn is an open connection to a node.
+type Conn interface {
+	Runner
+	Close() error
+}
+
+// Dialer opens connections to nodes.
+type Dialer interface {
+	Dial(node config.Node) (Conn, error)
+}
+
+// SSH dials nodes over SSH with the credentials from their config.
+type SSH struct{}
+
+func (SSH) Dial(node config.Node) (Conn, error) {
+	var auth []ssh.AuthMethod
+	if node.KeyFile != "" {
+		signer, err := LoadKey(node.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	auth = append(auth, ssh.Password(node.Password))
+
+	clientConfig := &ssh.ClientConfig{
+		User:            node.Username,
+		Auth:            auth,
+		HostKeyCallback: checkHostKey,
+	}
+
+	address := net.JoinHostPort(node.IP, strconv.Itoa(node.SSHPort()))
+	conn, err := connect(node, address)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	sc, chans, reqs, err := ssh.NewClientConn(conn, address, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return sshConn{client: ssh.NewClient(sc, chans, reqs)}, nil
+}
+
+// LoadKey reads an unencrypted private key file.
+func LoadKey(path string) (ssh.Signer, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, rest)
+	}
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+type sshConn struct {
+	client *ssh.Client
+}
+
+// Run opens a new session per command, SSH sessions can only run one.
+func (c sshConn) Run(cmd string) (string, error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	var b, stderr bytes.Buffer
+	session.Stdout = &b
+	session.Stderr = &stderr
+	if err := session.Run(cmd); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return "", &CommandError{Command: cmd, ExitCode: exitErr.ExitStatus(), Stderr: stderr.String()}
+		}
+		return "", fmt.Errorf("failed to run command '%s': %w", cmd, err)
+	}
+
+	// Windows nodes answer with CRLF line endings
+	return strings.ReplaceAll(b.String(), "\r\n", "\n"), nil
+}
+
+func (c sshConn) Close() error {
+	return c.client.Close()
+}
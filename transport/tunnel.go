@@ -0,0 +1,14 @@
+package transport
+
+import "net"
+
+// Tunnel is a connection that reaches services listening on the node
+// itself, e.g. on its localhost only, like an SSH port forward does.
+type Tunnel interface {
+	DialNode(addr string) (net.Conn, error)
+}
+
+// DialNode opens a TCP connection from the node to addr.
+func (c sshConn) DialNode(addr string) (net.Conn, error) {
+	return c.client.Dial("tcp", addr)
+}
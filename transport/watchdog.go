@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrHung is returned for commands still running when their session's
+// deadline passed, e.g. a df on a dead NFS mount.
+var ErrHung = errors.New("deadline passed")
+
+// ErrTimeout is returned for commands that ran longer than their own
+// timeout, see Timeout.
+var ErrTimeout = errors.New("command timed out")
+
+// Watch wraps a connection so that the session it carries ends within
+// deadline. A command still running then is given up on: the connection
+// is closed, which ends the command's session and with it the goroutine
+// waiting for it, and the command fails with ErrHung. Later commands fail
+// the same way. A zero deadline returns conn as it is.
+func Watch(conn Conn, deadline time.Duration) Conn {
+	if deadline <= 0 {
+		return conn
+	}
+	return &watchConn{Conn: conn, deadline: deadline, until: time.Now().Add(deadline)}
+}
+
+type watchConn struct {
+	Conn
+	deadline time.Duration
+	until    time.Time
+	// hung is set once the connection was closed for a hung command
+	hung bool
+}
+
+type runResult struct {
+	output string
+	err    error
+}
+
+func (c *watchConn) Run(cmd string) (string, error) {
+	if c.hung {
+		return "", fmt.Errorf("%w: not run, the connection was closed after %s", ErrHung, c.deadline)
+	}
+	done := make(chan runResult, 1)
+	go func() {
+		output, err := c.Conn.Run(cmd)
+		done <- runResult{output, err}
+	}()
+
+	timer := time.NewTimer(time.Until(c.until))
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result.output, result.err
+	case <-timer.C:
+		c.hung = true
+		c.Conn.Close()
+		return "", fmt.Errorf("%w: '%s' still running after %s, connection closed", ErrHung, cmd, c.deadline)
+	}
+}
+
+// Close closes the connection unless the watchdog already did.
+func (c *watchConn) Close() error {
+	if c.hung {
+		return nil
+	}
+	return c.Conn.Close()
+}
+
+// Timeout wraps a connection so that every command gets timeout to
+// finish. Unlike with Watch the connection stays open for the next
+// command: the one still running is abandoned and fails with
+// ErrTimeout, its session ends when the connection is closed. A zero
+// timeout returns conn as it is.
+func Timeout(conn Conn, timeout time.Duration) Conn {
+	if timeout <= 0 {
+		return conn
+	}
+	return &timeoutConn{Conn: conn, timeout: timeout}
+}
+
+type timeoutConn struct {
+	Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) Run(cmd string) (string, error) {
+	done := make(chan runResult, 1)
+	go func() {
+		output, err := c.Conn.Run(cmd)
+		done <- runResult{output, err}
+	}()
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result.output, result.err
+	case <-timer.C:
+		return "", fmt.Errorf("%w: '%s' still running after %s", ErrTimeout, cmd, c.timeout)
+	}
+}
@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+)
+
+const (
+	// silenceDuration is how long the silence key silences a node for.
+	silenceDuration = time.Hour
+	// noticeDuration is how long the footer shows why an
+	// acknowledgement or silence failed.
+	noticeDuration = 10 * time.Second
+)
+
+// Alerts acknowledges alerts and silences nodes for the TUI, shared
+// with whoever else watches the same alert engine: the local one, or
+// the one of the monitor server the TUI is connected to.
+type Alerts interface {
+	Acknowledge(key, by string) error
+	Silence(node, by string, d time.Duration) error
+}
+
+// AlertsChanged shows the firing alerts and silences of state.
+func (t *TUI) AlertsChanged(state alert.State) {
+	t.app.QueueUpdateDraw(func() {
+		t.firing = make(map[string]alert.Alert, len(state.Active))
+		for _, a := range state.Active {
+			t.firing[a.Key] = a
+		}
+		t.silences = state.Silences
+		if t.tiled() || t.accessible {
+			for _, p := range t.panels {
+				t.render(p)
+			}
+		}
+		t.renderFooter()
+	})
+}
+
+// acknowledge acknowledges the focused node's firing alerts that nobody
+// has yet.
+func (t *TUI) acknowledge() {
+	t.acknowledgeAll(t.unacknowledged([]int{t.focused}))
+}
+
+// unacknowledged returns the keys of the firing alerts of the panels
+// that nobody has acknowledged yet.
+func (t *TUI) unacknowledged(panels []int) []string {
+	nodes := make(map[string]bool, len(panels))
+	for _, i := range panels {
+		nodes[t.panels[i].node.DisplayName()] = true
+	}
+	var keys []string
+	for key, a := range t.firing {
+		if nodes[a.Node.DisplayName()] && a.AckedBy == "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// acknowledgeAll acknowledges the alerts of the keys, stopping at the
+// first that fails.
+func (t *TUI) acknowledgeAll(keys []string) {
+	if t.Alerts == nil || len(keys) == 0 {
+		return
+	}
+	go func() {
+		for _, key := range keys {
+			if err := t.Alerts.Acknowledge(key, t.User); err != nil {
+				t.alertFailed(err)
+				return
+			}
+		}
+	}()
+}
+
+// silence silences the focused node for silenceDuration, or lifts its
+// silence.
+func (t *TUI) silence() {
+	if t.Alerts == nil {
+		return
+	}
+	node := t.panels[t.focused].node.DisplayName()
+	d := silenceDuration
+	if _, ok := t.silenced(node); ok {
+		d = 0
+	}
+	go func() {
+		if err := t.Alerts.Silence(node, t.User, d); err != nil {
+			t.alertFailed(err)
+		}
+	}()
+}
+
+func (t *TUI) alertFailed(err error) {
+	t.app.QueueUpdateDraw(func() {
+		t.alertErr, t.alertErrAt = err, time.Now()
+		t.renderFooter()
+	})
+}
+
+// silenced returns the node's silence if it lasts.
+func (t *TUI) silenced(node string) (alert.Silence, bool) {
+	now := time.Now()
+	for _, s := range t.silences {
+		if s.Node == node && s.Until.After(now) {
+			return s, true
+		}
+	}
+	return alert.Silence{}, false
+}
+
+// alertsLine is the footer line of the firing alerts, who acknowledged
+// them, and the silenced nodes.
+func (t *TUI) alertsLine() string {
+	var b strings.Builder
+	if len(t.firing) == 0 {
+		b.WriteString("[green::b]Alerts: [white]none firing")
+	} else {
+		keys := make([]string, 0, len(t.firing))
+		for key := range t.firing {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			if by := t.firing[key].AckedBy; by != "" {
+				keys[i] = fmt.Sprintf("%s [gray](acked by %s)[white]", key, tview.Escape(by))
+			}
+		}
+		b.WriteString(fmt.Sprintf("[red::b]Alerts (%d): [white]%s", len(keys), strings.Join(keys, ", ")))
+	}
+
+	now := time.Now()
+	var silenced []string
+	for _, s := range t.silences {
+		if s.Until.After(now) {
+			silenced = append(silenced, fmt.Sprintf("%s until %s (%s)", s.Node, t.format.clock(s.Until, nil), tview.Escape(s.By)))
+		}
+	}
+	if len(silenced) > 0 {
+		b.WriteString("  [yellow::b]Silenced: [white]" + strings.Join(silenced, ", "))
+	}
+	if t.alertErr != nil && now.Sub(t.alertErrAt) < noticeDuration {
+		b.WriteString("  [red]" + tview.Escape(t.alertErr.Error()))
+	}
+	return b.String() + "\n"
+}
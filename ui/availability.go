@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/availability"
+)
+
+// detailOutages is how many of a node's latest outages the detail view
+// lists.
+const detailOutages = 5
+
+// availability renders the node's availability over the reported
+// periods and its latest outages, for the detail view.
+func (t *TUI) availability(p *panel) string {
+	name := p.node.DisplayName()
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("[green::b]Availability:[white]")
+	for _, period := range availability.Periods {
+		share, ok := t.Availability.Availability(name, period, now)
+		value := "-"
+		if ok {
+			value = t.format.Float(share*100, 2) + "%"
+		}
+		b.WriteString(fmt.Sprintf(" [gray]%s [white]%s", availability.PeriodName(period), value))
+	}
+	b.WriteString("\n")
+
+	outages := t.Availability.Outages(name, now.Add(-availability.Periods[len(availability.Periods)-1]))
+	if len(outages) > detailOutages {
+		outages = outages[len(outages)-detailOutages:]
+	}
+	for i := len(outages) - 1; i >= 0; i-- {
+		b.WriteString("  " + t.format.outage(outages[i], now) + "\n")
+	}
+	return b.String()
+}
+
+// outage describes an outage in a line: when and how long, and the
+// reason of its first failed poll.
+func (f *Formatter) outage(o availability.Outage, now time.Time) string {
+	duration := o.Duration(now).Round(time.Second)
+	when := fmt.Sprintf("%s for %s", o.Start.Local().Format("Jan 2 15:04"), duration)
+	if o.End.IsZero() {
+		when = fmt.Sprintf("%s, down for %s so far", o.Start.Local().Format("Jan 2 15:04"), duration)
+	}
+	reason, _ := f.cut(o.Reason)
+	return fmt.Sprintf("[red]%s [gray]%s", tview.Escape(when), reason)
+}
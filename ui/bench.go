@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/parsers"
+)
+
+// benchmarkRuns is how many bandwidth measurements the detail view keeps
+// per node.
+const benchmarkRuns = 10
+
+// Benchmark measures the bandwidth of a node for the TUI, by its index
+// in the TUI's nodes.
+type Benchmark interface {
+	MeasureBandwidth(node int) (parsers.Bandwidth, error)
+}
+
+// benchmarkRun is a bandwidth measurement of a node, or why it failed.
+type benchmarkRun struct {
+	time      time.Time
+	bandwidth parsers.Bandwidth
+	err       error
+}
+
+// benchmark measures the focused node's bandwidth, showing the result in
+// its detail view.
+func (t *TUI) benchmark() {
+	i := t.focused
+	p := t.panels[i]
+	if t.Benchmark == nil || p.benchmarking {
+		return
+	}
+	p.benchmarking = true
+	if t.detailOpen() {
+		t.renderDetail()
+	} else {
+		t.openDetail()
+	}
+	go func() {
+		bandwidth, err := t.Benchmark.MeasureBandwidth(i)
+		t.app.QueueUpdateDraw(func() {
+			p.benchmarking = false
+			p.benchmarks = append(p.benchmarks, benchmarkRun{time: time.Now(), bandwidth: bandwidth, err: err})
+			if len(p.benchmarks) > benchmarkRuns {
+				p.benchmarks = p.benchmarks[1:]
+			}
+			if t.detailOpen() && t.panels[t.focused] == p {
+				t.renderDetail()
+			}
+		})
+	}()
+}
+
+// benchmarksText lists the panel's bandwidth measurements, newest first.
+func (p *panel) benchmarksText(key string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("[green::b]Bandwidth [-::-][gray](%s to measure)\n", key))
+	if p.benchmarking {
+		b.WriteString("[yellow]measuring...\n")
+	} else if len(p.benchmarks) == 0 {
+		b.WriteString("[gray]not measured yet\n")
+	}
+	var loc *time.Location
+	if p.snapshot != nil {
+		loc = p.snapshot.Status.Location
+	}
+	for i := len(p.benchmarks) - 1; i >= 0; i-- {
+		run := p.benchmarks[i]
+		b.WriteString("[gray]" + p.format.clock(run.time, loc) + " ")
+		if run.err != nil {
+			b.WriteString("[red]" + tview.Escape(run.err.Error()) + "\n")
+			continue
+		}
+		b.WriteString(fmt.Sprintf("[white]down %s up %s [gray]Mbit/s", p.format.Float(run.bandwidth.Download/1e6, 0),
+			p.format.Float(run.bandwidth.Upload/1e6, 0)))
+		if run.bandwidth.Latency > 0 {
+			b.WriteString(fmt.Sprintf(", %s ms", p.format.Float(float64(run.bandwidth.Latency)/float64(time.Millisecond), 1)))
+		}
+		b.WriteString(" to " + tview.Escape(run.bandwidth.Server) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+const (
+	// compactWidth is the width of a compact tile including the gap to
+	// the next column.
+	compactWidth = 46
+	// compactHeight is how many lines a compact tile takes.
+	compactHeight = 2
+	// previewHeight is the height of the preview below the compact
+	// grid, enough for a full status with a few log messages.
+	previewHeight = 14
+)
+
+// keyFields are the log fields shown on compact tiles.
+var keyFields = []struct {
+	key   string
+	label string
+}{
+	{key: "network_peer_count", label: "peers"},
+	{key: "current_frame", label: "frame"},
+}
+
+// Compact renders a node in two lines: its state badge with the node
+// name and the key log numbers, then CPU, memory and disk usage.
+func (f *Formatter) Compact(node config.Node, snapshot *collector.Snapshot, state State) string {
+	output := fmt.Sprintf("%s [white::b]%s[-::-]", f.badge(state), tview.Escape(padRight(node.DisplayName(), 14)))
+
+	switch {
+	case snapshot == nil:
+		return output + "\n  [gray]waiting for first poll\n"
+	case snapshot.Err != nil:
+		return output + fmt.Sprintf("\n  [red]%s\n", tview.Escape(transport.Describe(snapshot.Err)))
+	}
+
+	status := snapshot.Status
+	if peer := status.Bootstrap; peer != nil {
+		return output + fmt.Sprintf("\n  [gray]bootstrap [white]%s  [gray]latency [white]%s\n", peer.Addr.Transport, latency(peer.Latency))
+	}
+	for _, field := range keyFields {
+		if value, ok := latestField(status, field.key); ok {
+			output += fmt.Sprintf(" [gray]%s [white]%s", field.label, f.field(field.key, value))
+		}
+	}
+	output += "\n"
+
+	// sections that timed out show as such rather than as 0%, the ones
+	// the node doesn't collect not at all
+	cpu, memory := "[gray]n/a", "[gray]n/a"
+	if status.Collected(collector.SectionCPU) {
+		cpu = f.Percent(status.CPU.User + status.CPU.System)
+	}
+	if status.Memory.TotalMB > 0 {
+		memory = f.Percent(float64(status.Memory.UsedMB) / float64(status.Memory.TotalMB) * 100)
+	}
+	if !slices.Contains(status.Disabled, collector.SectionCPU) {
+		output += fmt.Sprintf("  [gray]cpu [white]%s", cpu)
+	}
+	if !slices.Contains(status.Disabled, collector.SectionMemory) {
+		output += fmt.Sprintf("  [gray]mem [white]%s", memory)
+	}
+	if len(status.Disks) > 0 {
+		output += fmt.Sprintf("  [gray]disk [white]%s", f.Percent(status.Disks[0].UsedPercent()))
+	}
+	return output + "\n"
+}
+
+// latestField returns a numeric field from the first log message that
+// has it.
+func latestField(status collector.Status, key string) (float64, bool) {
+	for _, message := range status.Logs {
+		if value, ok := message.Fields[key].(float64); ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// degraded reports whether a node has firing alerts.
+func (t *TUI) degraded(node config.Node) bool {
+	for _, a := range t.firing {
+		if a.Node.DisplayName() == node.DisplayName() {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TUI) renderPreview() {
+	if len(t.panels) == 0 {
+		return
+	}
+	t.renderPreviewTitle()
+	p := t.panels[t.focused]
+	t.preview.SetWrap(p.wrapped())
+	t.preview.SetText(p.text(p.format.sections, p.fullError))
+}
+
+// renderPreviewTitle names the focused node on the preview, with the age
+// of its last poll.
+func (t *TUI) renderPreviewTitle() {
+	if len(t.panels) == 0 {
+		return
+	}
+	p := t.panels[t.focused]
+	t.preview.SetTitle(" " + tview.Escape(p.node.DisplayName()) + " " + t.pausedMark(p) + t.updated(p) + "[-] ")
+}
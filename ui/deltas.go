@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+)
+
+// deltas are what panels showing the changes since the previous poll
+// measure against: the status of the previous successful poll, and how
+// much the key log numbers changed since the value seen before.
+type deltas struct {
+	previous collector.Status
+	fields   map[string]float64
+}
+
+// trackDeltas keeps what the panel's deltas are measured against, from
+// the successful snapshot that replaced previous.
+func (p *panel) trackDeltas(previous *collector.Snapshot, snapshot collector.Snapshot) {
+	if previous != nil && previous.Err == nil {
+		p.previous = &previous.Status
+	}
+	if p.fields == nil {
+		p.fields = make(map[string]float64)
+		p.changes = make(map[string]float64)
+	}
+	for _, field := range keyFields {
+		value, ok := latestField(snapshot.Status, field.key)
+		if !ok {
+			continue
+		}
+		if before, seen := p.fields[field.key]; seen {
+			p.changes[field.key] = value - before
+		}
+		p.fields[field.key] = value
+	}
+}
+
+// toggleDeltas switches every panel between the values of the last poll
+// and their changes since the one before.
+func (t *TUI) toggleDeltas() {
+	t.deltas = !t.deltas
+	for _, p := range t.panels {
+		p.deltas = t.deltas
+		t.render(p)
+	}
+	if t.tiled() {
+		t.renderPreview()
+	}
+}
+
+// signed formats v with its sign, also a plus.
+func (f *Formatter) signed(v float64, decimals int) string {
+	s := f.Float(v, decimals)
+	if !strings.HasPrefix(s, "-") {
+		s = "+" + s
+	}
+	return s
+}
+
+// points formats a change of a percentage in percentage points.
+func (f *Formatter) points(v float64) string {
+	return f.signed(v, 1) + " pp"
+}
+
+// sizeChange formats a change of a size in bytes as GiB.
+func (f *Formatter) sizeChange(bytes int64) string {
+	return f.signed(float64(bytes)/(1<<30), 1) + " GiB"
+}
+
+// delta renders the change of a stats section since the previous poll,
+// empty if either poll doesn't have the section and the values are
+// shown instead. The logs section gets the changes of the key log
+// numbers above it.
+func (f *Formatter) delta(name string, status collector.Status) string {
+	previous := f.deltas.previous
+	switch name {
+	case config.SectionCPU:
+		if !status.Collected(collector.SectionCPU) || !previous.Collected(collector.SectionCPU) {
+			return ""
+		}
+		return fmt.Sprintf("[green::b]CPU Δ: [white]User Space: %s; System Space: %s; Steal: %s\n",
+			f.points(status.CPU.User-previous.CPU.User), f.points(status.CPU.System-previous.CPU.System),
+			f.points(status.CPU.Steal-previous.CPU.Steal))
+	case config.SectionMemory:
+		if status.Memory.TotalMB == 0 || previous.Memory.TotalMB == 0 {
+			return ""
+		}
+		const mb = 1024 * 1024
+		used := func(m parsers.MemoryUsage) float64 { return float64(m.UsedMB) / float64(m.TotalMB) * 100 }
+		return fmt.Sprintf("[green::b]Memory Δ: [white]Used Memory: %s (%s)\n",
+			f.sizeChange(int64(status.Memory.UsedMB-previous.Memory.UsedMB)*mb), f.points(used(status.Memory)-used(previous.Memory)))
+	case config.SectionStorage:
+		if len(status.Disks) == 0 || len(previous.Disks) == 0 {
+			return ""
+		}
+		return "[green::b]Storage Δ: [white]" + f.diskChanges(status.Disks, previous.Disks)
+	case config.SectionLogs:
+		changes := f.fieldChanges()
+		if changes == "" {
+			return ""
+		}
+		values := *f
+		values.deltas = nil
+		return changes + values.section(name, status)
+	}
+	return ""
+}
+
+// diskChanges renders a line per mount like diskUsage, with the changes
+// of its use and free space, or that it is new.
+func (f *Formatter) diskChanges(disks, previous []parsers.DiskUsage) string {
+	before := make(map[string]parsers.DiskUsage, len(previous))
+	for _, disk := range previous {
+		before[disk.Mount] = disk
+	}
+	width := 0
+	for _, disk := range disks {
+		width = max(width, textWidth(disk.Mount))
+	}
+	var b strings.Builder
+	for i, disk := range disks {
+		if i > 0 {
+			b.WriteString(strings.Repeat(" ", textWidth("Storage Δ: ")))
+		}
+		mount := tview.Escape(padRight(disk.Mount, width))
+		old, ok := before[disk.Mount]
+		if !ok {
+			b.WriteString(fmt.Sprintf("%s [gray]new since the previous poll[white]\n", mount))
+			continue
+		}
+		b.WriteString(fmt.Sprintf("%s %s used, %s free\n", mount, f.points(disk.UsedPercent()-old.UsedPercent()), f.sizeChange(disk.Avail-old.Avail)))
+	}
+	return b.String()
+}
+
+// fieldChanges renders how much the key log numbers changed, empty if
+// none was seen twice yet.
+func (f *Formatter) fieldChanges() string {
+	var parts []string
+	for _, field := range keyFields {
+		if change, ok := f.deltas.fields[field.key]; ok {
+			parts = append(parts, fmt.Sprintf("%s %s", field.label, f.signed(change, 0)))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "[yellow::b]Log Δ: [white]" + strings.Join(parts, "; ") + "\n"
+}
@@ -0,0 +1,265 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/history"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+const (
+	// historySize is how many polls the graphs cover, two hours at the
+	// default interval.
+	historySize = 120
+	// graphHeight is the height of a graph in lines, each line has four
+	// braille dots.
+	graphHeight = 2
+	// tailSize is how many watched log lines the detail view keeps per
+	// node.
+	tailSize = 500
+	// splitStep is how far one key press moves the split of the detail
+	// view, in percent of its width.
+	splitStep = 10
+)
+
+// graph is a metric with history shown in the detail view.
+type graph struct {
+	name string
+	// percent graphs have a fixed 0-100 scale, others scale to their
+	// maximum
+	percent bool
+	value   func(status collector.Status) (float64, bool)
+}
+
+var graphs = []graph{
+	{name: "CPU", percent: true, value: func(status collector.Status) (float64, bool) {
+		return status.CPU.User + status.CPU.System, status.Bootstrap == nil && status.Collected(collector.SectionCPU)
+	}},
+	{name: "Memory", percent: true, value: func(status collector.Status) (float64, bool) {
+		if status.Memory.TotalMB == 0 {
+			return 0, false
+		}
+		return float64(status.Memory.UsedMB) / float64(status.Memory.TotalMB) * 100, true
+	}},
+	{name: "Peers", value: func(status collector.Status) (float64, bool) {
+		return latestField(status, "network_peer_count")
+	}},
+	{name: "Difficulty", value: func(status collector.Status) (float64, bool) {
+		return latestField(status, "difficulty")
+	}},
+	{name: "Latency ms", value: func(status collector.Status) (float64, bool) {
+		if status.Bootstrap == nil {
+			return 0, false
+		}
+		return float64(status.Bootstrap.Latency.Milliseconds()), true
+	}},
+}
+
+// record adds a successful poll to the panel's history and its log
+// lines to the tail.
+func (p *panel) record(snapshot collector.Snapshot) {
+	p.tail = append(p.tail, snapshot.Status.LogLines...)
+	if len(p.tail) > tailSize {
+		p.tail = slices.Clone(p.tail[len(p.tail)-tailSize:])
+	}
+	if p.history == nil {
+		p.history = make([]*history.Series, len(graphs))
+		for i := range graphs {
+			p.history[i] = history.NewSeries(historySize)
+		}
+	}
+	for i, g := range graphs {
+		if value, ok := g.value(snapshot.Status); ok {
+			p.history[i].Add(snapshot.Time, value)
+		}
+	}
+}
+
+// graphs renders the panel's history, one graph per metric.
+func (p *panel) graphs() string {
+	var b strings.Builder
+	for i, g := range graphs {
+		if p.history == nil || p.history[i].Len() == 0 {
+			continue
+		}
+		values := p.history[i].Values()
+		low, high := values[0], values[0]
+		for _, v := range values {
+			low = math.Min(low, v)
+			high = math.Max(high, v)
+		}
+		scale := 100.0
+		value := func(v float64) string { return p.format.Percent(v) }
+		if !g.percent {
+			scale = high
+			value = func(v float64) string { return p.format.Int(int64(v)) }
+		}
+		samples := p.history[i].Samples()
+		minutes := int(samples[len(samples)-1].Time.Sub(samples[0].Time).Minutes())
+
+		b.WriteString(fmt.Sprintf("[green::b]%s [white]%s [gray]min %s max %s over %d min\n",
+			g.name, value(values[len(values)-1]), value(low), value(high), minutes))
+		for _, line := range braille(values, scale, historySize/2, graphHeight) {
+			b.WriteString("[teal]" + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// commands lists the commands of the last poll with their exit code and
+// duration. Failed ones show why, a missing program apart from a broken
+// node.
+func (f *Formatter) commands(results []transport.Result) string {
+	var b strings.Builder
+	b.WriteString("[green::b]Commands [-::-][gray](last poll)\n")
+	for _, result := range results {
+		color := "green"
+		if result.Err != nil {
+			color = "red"
+		}
+		b.WriteString(fmt.Sprintf("[%s]%3d [gray]%6s [white]%s\n", color, result.ExitCode,
+			result.Duration.Round(time.Millisecond), tview.Escape(result.Command)))
+		var commandErr *transport.CommandError
+		switch {
+		case errors.As(result.Err, &commandErr) && commandErr.Missing():
+			b.WriteString(fmt.Sprintf("    [red]missing program: [white]%s\n", tview.Escape(firstLine(commandErr.Stderr))))
+		case commandErr != nil:
+			b.WriteString(fmt.Sprintf("    [red]stderr: [white]%s\n", tview.Escape(firstLine(commandErr.Stderr))))
+		case result.Err != nil:
+			b.WriteString(fmt.Sprintf("    [red]%s\n", tview.Escape(result.Err.Error())))
+		}
+	}
+	return b.String()
+}
+
+// firstLine returns the first line of s, for one line summaries of
+// multiline output.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// braille draws values as an area chart of width by height braille
+// cells, two values per cell and four levels per line. The newest value
+// is on the right.
+func braille(values []float64, scale float64, width, height int) []string {
+	if len(values) > width*2 {
+		values = values[len(values)-width*2:]
+	}
+	// dots of a cell column from the bottom up
+	left := [4]rune{0x40, 0x04, 0x02, 0x01}
+	right := [4]rune{0x80, 0x20, 0x10, 0x08}
+
+	levels := make([]int, width*2)
+	pad := width*2 - len(values)
+	for i := range levels {
+		levels[i] = -1
+	}
+	for i, v := range values {
+		level := 0
+		if scale > 0 {
+			level = int(math.Round(v / scale * float64(height*4)))
+		}
+		levels[pad+i] = min(max(level, 0), height*4)
+	}
+
+	lines := make([]string, height)
+	for row := 0; row < height; row++ {
+		bottom := (height - 1 - row) * 4
+		var line strings.Builder
+		for cell := 0; cell < width; cell++ {
+			l, r := levels[cell*2], levels[cell*2+1]
+			if l < 0 && r < 0 {
+				line.WriteRune(' ')
+				continue
+			}
+			dots := rune(0x2800)
+			for dot := 0; dot < 4; dot++ {
+				if l > bottom+dot {
+					dots |= left[dot]
+				}
+				if r > bottom+dot {
+					dots |= right[dot]
+				}
+			}
+			line.WriteRune(dots)
+		}
+		lines[row] = line.String()
+	}
+	return lines
+}
+
+// tailText renders the panel's log tail, oldest line first.
+func (p *panel) tailText() string {
+	if len(p.tail) == 0 {
+		return "[gray]no watched log lines yet\n"
+	}
+	var loc *time.Location
+	if p.snapshot != nil {
+		loc = p.snapshot.Status.Location
+	}
+	var b strings.Builder
+	for _, line := range p.tail {
+		if !line.Time.IsZero() {
+			b.WriteString("[gray]" + p.format.clock(line.Time, loc) + "[white] ")
+		}
+		b.WriteString(tview.Escape(line.Line) + "\n")
+	}
+	return b.String()
+}
+
+// moveSplit moves the split between the detail view's metrics and its
+// log tail by splitStep, dir is 1 (right) or -1 (left). Either side can
+// take the whole width.
+func (t *TUI) moveSplit(dir int) {
+	t.split = min(max(t.split+dir*splitStep, 0), 100)
+	t.detailPane.ResizeItem(t.detail, 0, t.split)
+	t.detailPane.ResizeItem(t.tail, 0, 100-t.split)
+}
+
+// openDetail shows the focused node in full with its history.
+func (t *TUI) openDetail() {
+	t.pages.ShowPage(detailPage)
+	t.renderDetail()
+}
+
+func (t *TUI) closeDetail() {
+	t.pages.HidePage(detailPage)
+	t.setFocus(t.focused)
+}
+
+func (t *TUI) detailOpen() bool {
+	return t.pageVisible(detailPage)
+}
+
+func (t *TUI) renderDetail() {
+	p := t.panels[t.focused]
+	t.detail.SetTitle(fmt.Sprintf(" %s [gray](%s to close) ", tview.Escape(p.node.DisplayName()), t.keys.label(actionClose)))
+	t.tail.SetTitle(fmt.Sprintf(" Logs [gray](%s %s to resize) ", t.keys.label(actionSplitLeft), t.keys.label(actionSplitRight)))
+	t.tail.SetText(p.tailText()).ScrollToEnd()
+	// the detail view has the space for every section and whole errors
+	text := p.text(config.DefaultSections, true)
+	if t.Availability != nil {
+		text += t.availability(p)
+	}
+	text += "\n" + p.graphs()
+	if t.Benchmark != nil {
+		text += "\n" + p.benchmarksText(t.keys.label(actionBenchmark))
+	}
+	if p.snapshot != nil && p.snapshot.Status.Storage != "" {
+		text += "\n[green::b]Storage [-::-][gray](raw output)\n[white]" + tview.Escape(p.snapshot.Status.Storage)
+	}
+	if p.snapshot != nil && len(p.snapshot.Status.Commands) > 0 {
+		text += "\n" + p.format.commands(p.snapshot.Status.Commands)
+	}
+	t.detail.SetText(text)
+}
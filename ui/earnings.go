@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/price"
+)
+
+// SetPrice updates the token price of the earnings estimate. It is safe
+// to call from any goroutine, e.g. as the update func of price.Watch.
+// Failed fetches keep the last price, marked as outdated.
+func (t *TUI) SetPrice(quote price.Quote, err error) {
+	t.app.QueueUpdateDraw(func() {
+		if err == nil {
+			t.quote = quote
+		}
+		t.quoteErr = err
+		t.renderFooter()
+	})
+}
+
+// earnings sums the reward rates of the nodes that are up, the ones
+// actually earning, and converts them at the last price.
+func (t *TUI) earnings() string {
+	var quil float64
+	for _, p := range t.panels {
+		switch t.state(p) {
+		case StateUp, StateDegraded:
+			quil += t.Earnings.Reward(p.node)
+		}
+	}
+
+	output := fmt.Sprintf("  [green::b]Earnings: [white]~%s QUIL/day", t.format.Float(quil, 2))
+	switch {
+	case t.quote.Time.IsZero() && t.quoteErr != nil:
+		return output + fmt.Sprintf(" [red](no price: %s)", tview.Escape(t.quoteErr.Error()))
+	case t.quote.Time.IsZero():
+		return output + " [gray](no price yet)"
+	}
+	currency := t.Earnings.CurrencyName()
+	output += fmt.Sprintf(" [white]≈ %s %s [gray](at %s %s", t.format.Float(quil*t.quote.Price, 2), currency,
+		t.format.Float(t.quote.Price, 4), currency)
+	if t.quoteErr != nil {
+		return output + fmt.Sprintf(" from %s, [red]price api failing[gray])", t.quote.Time.Format("15:04"))
+	}
+	return output + ")"
+}
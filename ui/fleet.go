@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+const (
+	// fleetPage is the page of the fleet actions, a menu centered over
+	// the grid.
+	fleetPage = "fleet"
+	// fleetRows is how many node sets the menu shows at once, it
+	// scrolls through the rest; fleetActionRows is how many actions
+	// there are at most.
+	fleetRows       = 16
+	fleetActionRows = 4
+)
+
+// Polls pauses and refreshes the polls of nodes for the TUI, by their
+// index in the TUI's nodes.
+type Polls interface {
+	Pause(node int, paused bool) error
+	Refresh(node int) error
+}
+
+// nodeSet is a set of nodes the fleet actions act on: all of them, a
+// group's or a tag's.
+type nodeSet struct {
+	name   string
+	panels []int
+}
+
+// nodeSets are the sets the fleet actions pick from, the groups and the
+// tags in the order they first appear in the config.
+func (t *TUI) nodeSets() []nodeSet {
+	all := nodeSet{name: "All nodes"}
+	for i := range t.panels {
+		all.panels = append(all.panels, i)
+	}
+	sets := []nodeSet{all}
+	for _, g := range t.groups {
+		sets = append(sets, nodeSet{name: "Group " + g.name, panels: g.panels})
+	}
+	var tags []string
+	tagged := make(map[string][]int)
+	for i, p := range t.panels {
+		for _, tag := range p.node.Tags {
+			if _, ok := tagged[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			tagged[tag] = append(tagged[tag], i)
+		}
+	}
+	for _, tag := range tags {
+		sets = append(sets, nodeSet{name: "Tag " + tag, panels: tagged[tag]})
+	}
+	return sets
+}
+
+// newFleet builds the menu of the fleet actions, sized for the node
+// sets.
+func (t *TUI) newFleet() tview.Primitive {
+	t.fleet = tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	t.fleet.SetBorder(true)
+	rows := min(max(len(t.nodeSets()), fleetActionRows), fleetRows) + 2
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(t.fleet, rows, 0, true).
+			AddItem(nil, 0, 1, false), 60, 0, true).
+		AddItem(nil, 0, 1, false)
+}
+
+// toggleFleet opens the fleet actions on the node sets to pick from, or
+// closes them.
+func (t *TUI) toggleFleet() {
+	if t.pageVisible(fleetPage) {
+		t.pages.HidePage(fleetPage)
+		t.setFocus(t.focused)
+		return
+	}
+	t.fleet.Clear().SetTitle(fmt.Sprintf(" Fleet actions [gray](%s to close) ", t.keys.label(actionClose)))
+	for _, set := range t.nodeSets() {
+		t.fleet.AddItem(tview.Escape(fmt.Sprintf("%s (%d)", set.name, len(set.panels))), "", 0, func() {
+			t.fleetActions(set)
+		})
+	}
+	t.pages.ShowPage(fleetPage)
+	// the arrow keys and Enter pick from it
+	t.app.SetFocus(t.fleet)
+}
+
+// fleetActions lists what can be done to a set of nodes: acknowledge
+// their firing alerts, pause or resume their polls, and poll them now.
+// The actions that need what the TUI lacks, like polls of its own when
+// connected to a server, are left out.
+func (t *TUI) fleetActions(set nodeSet) {
+	t.fleet.Clear().SetTitle(" " + tview.Escape(set.name) + " ")
+	if t.Alerts != nil {
+		keys := t.unacknowledged(set.panels)
+		t.fleet.AddItem(fmt.Sprintf("Acknowledge the firing alerts (%d)", len(keys)), "", 0, func() {
+			t.acknowledgeAll(keys)
+			t.toggleFleet()
+		})
+	}
+	if t.Polls != nil {
+		paused := 0
+		for _, i := range set.panels {
+			if t.panels[i].paused {
+				paused++
+			}
+		}
+		t.fleet.AddItem(fmt.Sprintf("Pause polling (%d polled)", len(set.panels)-paused), "", 0, func() {
+			t.pausePolls(set.panels, true)
+			t.toggleFleet()
+		})
+		t.fleet.AddItem(fmt.Sprintf("Resume polling (%d paused)", paused), "", 0, func() {
+			t.pausePolls(set.panels, false)
+			t.toggleFleet()
+		})
+		t.fleet.AddItem("Poll now", "", 0, func() {
+			t.refreshPolls(set.panels)
+			t.toggleFleet()
+		})
+	}
+	if t.fleet.GetItemCount() == 0 {
+		t.fleet.AddItem("[gray]Nothing to do without alerts or polls of the TUI's own", "", 0, t.toggleFleet)
+	}
+}
+
+// pausePolls pauses or resumes the polls of the panels' nodes. Paused
+// nodes keep their last poll and don't go stale.
+func (t *TUI) pausePolls(panels []int, paused bool) {
+	for _, i := range panels {
+		if err := t.Polls.Pause(i, paused); err != nil {
+			t.alertErr, t.alertErrAt = err, time.Now()
+			break
+		}
+		p := t.panels[i]
+		p.paused = paused
+		t.render(p)
+	}
+	if t.tiled() {
+		t.renderPreviewTitle()
+	}
+	t.renderFooter()
+}
+
+// refreshPolls polls the panels' nodes now, out of their schedule.
+func (t *TUI) refreshPolls(panels []int) {
+	for _, i := range panels {
+		if err := t.Polls.Refresh(i); err != nil {
+			t.alertErr, t.alertErrAt = err, time.Now()
+			t.renderFooter()
+			return
+		}
+	}
+}
+
+// pausedMark marks the title of a paused node.
+func (t *TUI) pausedMark(p *panel) string {
+	switch {
+	case !p.paused:
+		return ""
+	case t.accessible:
+		return ", paused"
+	}
+	return "[yellow]paused[-] "
+}
+
+// pausedCount is the footer's count of the paused nodes, empty if none
+// is.
+func (t *TUI) pausedCount() string {
+	n := 0
+	for _, p := range t.panels {
+		if p.paused {
+			n++
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [yellow]%d paused", n)
+}
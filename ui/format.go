@@ -0,0 +1,532 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/bootstrap"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/profiles"
+	"github.com/fid1699/q-monitor-cli/proxmox"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// addressNotice is how long a panel points out that the node's hostname
+// resolved to a new address.
+const addressNotice = time.Hour
+
+// shownMetrics is how many scraped metrics a panel lists, the node's
+// metrics.show config picks which.
+const shownMetrics = 8
+
+// Status renders a node status as tview markup, with the panel sections
+// of the display settings.
+func (f *Formatter) Status(node config.Node, status collector.Status) string {
+	return f.status(node, status, f.sections, "")
+}
+
+// status renders the node's name and notices, then the given sections in
+// order. queries are the rendered pinned queries, shown as the queries
+// section.
+func (f *Formatter) status(node config.Node, status collector.Status, sections []string, queries string) string {
+	output := fmt.Sprintf("[blue::b]Node: %s", tview.Escape(node.DisplayName()))
+	if node.Name != "" && node.IP != "" {
+		output += fmt.Sprintf(" [gray](%s)", node.IP)
+	}
+	if status.Address != "" && status.Address != node.IP {
+		output += fmt.Sprintf(" [gray]at %s", status.Address)
+	}
+	if status.OS != "" && status.OS != profiles.Linux {
+		output += fmt.Sprintf(" [gray](%s)", status.OS)
+	}
+	if f.nodeTimes && status.Location != nil {
+		output += fmt.Sprintf(" [gray]%s", time.Now().In(status.Location).Format("MST"))
+	}
+	output += "\n"
+	if status.Bootstrap != nil {
+		return output + f.bootstrap(*status.Bootstrap)
+	}
+	if !status.AddressChanged.IsZero() && time.Since(status.AddressChanged) < addressNotice {
+		output += fmt.Sprintf("[yellow::b]IP changed [white]from %s to %s at %s\n",
+			status.PreviousAddress, status.Address, f.clock(status.AddressChanged, status.Location))
+	}
+	if len(status.Missing) > 0 {
+		output += fmt.Sprintf("[yellow::b]Missing: [white]%s\n", strings.Join(status.Missing, ", "))
+	}
+	for _, section := range sections {
+		if section == config.SectionQueries {
+			output += queries
+		} else {
+			output += f.section(section, status)
+		}
+	}
+	return output
+}
+
+// section renders one panel section, empty if the status has nothing
+// for it or the node doesn't collect it.
+func (f *Formatter) section(name string, status collector.Status) string {
+	if slices.Contains(status.Disabled, name) {
+		return ""
+	}
+	if f.deltas != nil {
+		if delta := f.delta(name, status); delta != "" {
+			return delta
+		}
+	}
+	switch name {
+	case config.SectionCPU:
+		if err := status.Errors[collector.SectionCPU]; err != nil {
+			return fmt.Sprintf("[green::b]CPU Usage: [red]%s\n", f.clip(err.Error()))
+		}
+		return fmt.Sprintf("[green::b]CPU Usage: [white]%s\n", f.cpuUsage(status.CPU))
+	case config.SectionMemory:
+		if err := status.Errors[collector.SectionMemory]; err != nil {
+			return fmt.Sprintf("[green::b]Memory Usage: [red]%s\n", f.clip(err.Error()))
+		}
+		return fmt.Sprintf("[green::b]Memory Usage: [white]%s\n", f.memoryUsage(status.Memory))
+	case config.SectionStorage:
+		if err := status.Errors[collector.SectionStorage]; err != nil {
+			return fmt.Sprintf("[green::b]Storage Usage: [red]%s\n", f.clip(err.Error()))
+		}
+		return "[green::b]Storage Usage: [white]" + f.diskUsage(status.Disks)
+	case config.SectionService:
+		if err := status.Errors[collector.SectionService]; err != nil {
+			return fmt.Sprintf("[green::b]Service: [red]%s\n", f.clip(err.Error()))
+		} else if status.Service != "" {
+			return fmt.Sprintf("[green::b]Service: [white]%s\n", status.Service)
+		}
+	case config.SectionPi:
+		if err := status.Errors[collector.SectionPi]; err != nil {
+			return fmt.Sprintf("[green::b]Pi: [red]%s\n", f.clip(err.Error()))
+		} else if status.Pi != nil {
+			return fmt.Sprintf("[green::b]Pi: [white]%s\n", f.piStatus(*status.Pi))
+		}
+	case config.SectionProxmox:
+		if err := status.Errors[collector.SectionProxmox]; err != nil {
+			return fmt.Sprintf("[green::b]Proxmox: [red]%s\n", f.clip(err.Error()))
+		} else if status.Proxmox != nil {
+			return fmt.Sprintf("[green::b]Proxmox: [white]%s\n", f.proxmoxGuest(*status.Proxmox))
+		}
+	case config.SectionMetrics:
+		if err := status.Errors[collector.SectionMetrics]; err != nil {
+			return fmt.Sprintf("[green::b]Metrics: [red]%s\n", f.clip(err.Error()))
+		} else if len(status.Metrics) > 0 {
+			return fmt.Sprintf("[green::b]Metrics: [white]%s\n", f.metrics(status.Metrics))
+		}
+	case config.SectionAudit:
+		if err := status.Errors[collector.SectionAudit]; err != nil {
+			return fmt.Sprintf("[green::b]Audit: [red]%s\n", f.clip(err.Error()))
+		} else if status.Audit != nil {
+			return fmt.Sprintf("[green::b]Audit: %s\n", f.audit(*status.Audit, status.Location))
+		}
+	case config.SectionLogins:
+		if err := status.Errors[collector.SectionLogins]; err != nil {
+			return fmt.Sprintf("[green::b]Logins: [red]%s\n", f.clip(err.Error()))
+		} else if status.Logins != nil {
+			return fmt.Sprintf("[green::b]Logins: %s\n", f.logins(*status.Logins, status.Location))
+		}
+	case config.SectionKeys:
+		if err := status.Errors[collector.SectionKeys]; err != nil {
+			return fmt.Sprintf("[green::b]Keys: [red]%s\n", f.clip(err.Error()))
+		} else if len(status.Keys) > 0 {
+			return fmt.Sprintf("[green::b]Keys: %s\n", f.keys(status.Keys, status.Location))
+		}
+	case config.SectionChecks:
+		if err := status.Errors[collector.SectionChecks]; err != nil {
+			return fmt.Sprintf("[green::b]Checks: [red]%s\n", f.clip(err.Error()))
+		} else if len(status.Checks) > 0 {
+			return f.checks(status.Checks)
+		}
+	case config.SectionExternal:
+		if len(status.Signals) > 0 {
+			return fmt.Sprintf("[green::b]External: %s\n", f.signals(status.Signals, status.Location))
+		}
+	case config.SectionNetwork:
+		if err := status.Errors[collector.SectionExplorer]; err != nil {
+			return fmt.Sprintf("[green::b]Network: [red]%s\n", f.clip(err.Error()))
+		} else if v := status.Visibility; v != nil && v.Visible {
+			return fmt.Sprintf("[green::b]Network: [white]seen by the explorer [gray](checked %s)\n", f.clock(v.Checked, status.Location))
+		} else if v != nil {
+			return fmt.Sprintf("[red::b]Network: [white]not seen by the explorer [gray](checked %s)\n", f.clock(v.Checked, status.Location))
+		}
+	case config.SectionConnectivity:
+		if c := status.Connectivity; c != nil && c.RelayedOnly() {
+			return fmt.Sprintf("[red::b]Connectivity: [white]%s\n", f.connectivity(*c))
+		} else if c != nil {
+			return fmt.Sprintf("[green::b]Connectivity: [white]%s\n", f.connectivity(*c))
+		}
+	case config.SectionLogs:
+		if err := status.Errors[collector.SectionLogs]; err != nil {
+			return fmt.Sprintf("[yellow::b]Logs: [red]%s\n", f.clip(err.Error()))
+		} else if status.LogsSkipped != "" {
+			return fmt.Sprintf("[yellow::b]Logs: [gray]%s\n", status.LogsSkipped)
+		} else if len(status.Logs) > 0 {
+			return fmt.Sprintf("[yellow::b]Logs: [white]%s", f.logMessages(status.Logs, status.Window, status.Location))
+		}
+		return fmt.Sprintf("[yellow::b]Logs: [gray]%s\n", formatInactivity(status.LastActivity))
+	}
+	return ""
+}
+
+// connectivity renders whether the node's peers are connected directly
+// or through relays, what its NAT detection found and the protocol
+// versions it speaks, as far as its logs told.
+func (f *Formatter) connectivity(c parsers.Connectivity) string {
+	var parts []string
+	switch {
+	case !c.Counted:
+	case c.RelayedOnly():
+		parts = append(parts, fmt.Sprintf("[red]relayed only (%d peers)[white]", c.Relayed))
+	case c.Relayed > 0:
+		parts = append(parts, fmt.Sprintf("direct, [yellow]%d of %d relayed[white]", c.Relayed, c.Direct+c.Relayed))
+	case c.Direct > 0:
+		parts = append(parts, "direct")
+	default:
+		parts = append(parts, "[gray]no connections[white]")
+	}
+	switch c.Reachability {
+	case "":
+	case "public":
+		parts = append(parts, "public")
+	case "private":
+		parts = append(parts, "[yellow]private[white]")
+	default:
+		parts = append(parts, "[gray]reachability "+tview.Escape(c.Reachability)+"[white]")
+	}
+	if len(c.Protocols) > 0 {
+		parts = append(parts, "[gray]protocol "+tview.Escape(strings.Join(c.Protocols, ", "))+"[white]")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// bootstrap renders a bootstrap peer node, which only reports that it
+// answered.
+func (f *Formatter) bootstrap(peer bootstrap.Status) string {
+	output := fmt.Sprintf("[green::b]Bootstrap peer: [white]answers over %s in %s\n", peer.Addr.Transport, latency(peer.Latency))
+	output += fmt.Sprintf("[green::b]Address: [white]%s\n", net.JoinHostPort(peer.Addr.Host, peer.Addr.Port))
+	if peer.Addr.PeerID != "" {
+		output += fmt.Sprintf("[green::b]Peer ID: [gray]%s\n", peer.Addr.PeerID)
+	}
+	return output
+}
+
+// latency rounds a round trip to what is worth showing.
+func latency(d time.Duration) string {
+	if d < 10*time.Millisecond {
+		return d.Round(100 * time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+func (f *Formatter) piStatus(pi parsers.PiStatus) string {
+	output := f.Float(pi.Temperature, 1) + "°C"
+	if current := pi.Current(); len(current) > 0 {
+		output += fmt.Sprintf(" [red::b]%s[white::-]", strings.ToUpper(strings.Join(current, ", ")))
+	}
+	if past := pi.SinceBoot(); len(past) > 0 {
+		output += fmt.Sprintf(" [yellow]since boot: %s[white]", strings.Join(past, ", "))
+	}
+	return output
+}
+
+func (f *Formatter) proxmoxGuest(guest proxmox.Guest) string {
+	output := fmt.Sprintf("host %s (cpu %s, mem %s); vm %s, %d vCPU, %s",
+		guest.Host, f.Percent(guest.HostCPU*100), f.Percent(guest.HostMemory*100),
+		guest.Status, guest.CPUs, f.Size(guest.MaxMemory))
+	if guest.Balloon > 0 && guest.Balloon < guest.MaxMemory {
+		output += fmt.Sprintf(" [yellow]ballooned to %s[white]", f.Size(guest.Balloon))
+	}
+	return output
+}
+
+// audit flags the unexpected open ports and missing firewall rules of
+// a node's last audit.
+func (f *Formatter) audit(audit collector.Audit, loc *time.Location) string {
+	var problems []string
+	if len(audit.Unexpected) > 0 {
+		problems = append(problems, "[red]unexpected ports "+strings.Join(audit.Unexpected, ", "))
+	}
+	if len(audit.MissingRules) > 0 {
+		problems = append(problems, "[red]missing rules "+tview.Escape(strings.Join(audit.MissingRules, "; ")))
+	}
+	if len(problems) == 0 {
+		problems = append(problems, fmt.Sprintf("[white]%d open ports as expected", len(audit.Ports)))
+	}
+	return strings.Join(problems, "[white], ") + fmt.Sprintf(" [gray](checked %s)", f.clock(audit.Checked, loc))
+}
+
+// keys lists what's wrong with the key files, or that nothing is.
+func (f *Formatter) keys(files []collector.KeyFile, loc *time.Location) string {
+	var problems []string
+	for _, file := range files {
+		path := tview.Escape(file.Path)
+		if file.Missing {
+			problems = append(problems, "[yellow]"+path+" missing")
+			continue
+		}
+		if !file.Changed.IsZero() {
+			problems = append(problems, fmt.Sprintf("[red]%s changed at %s", path, f.clock(file.Changed, loc)))
+		}
+		if file.Loose {
+			problems = append(problems, fmt.Sprintf("[red]%s mode %03o", path, file.Mode.Perm()))
+		}
+		if file.WrongOwner() {
+			problems = append(problems, fmt.Sprintf("[red]%s owned by %s", path, tview.Escape(file.Owner)))
+		}
+	}
+	if len(problems) == 0 {
+		return fmt.Sprintf("[white]%d files as expected", len(files))
+	}
+	return strings.Join(problems, "[white], ")
+}
+
+// signalColors are the colors of the signals' states.
+var signalColors = map[string]string{
+	collector.SignalOK:       "white",
+	collector.SignalWarning:  "yellow",
+	collector.SignalCritical: "red",
+}
+
+// checks renders a line per check of the node, with what it printed in
+// the color of its state.
+func (f *Formatter) checks(checks []collector.CheckResult) string {
+	var b strings.Builder
+	for _, check := range checks {
+		output := check.Output
+		if output == "" {
+			output = check.State
+		}
+		b.WriteString(fmt.Sprintf("[green::b]%s: [%s]%s\n", tview.Escape(check.Name), signalColors[check.State], f.clip(output)))
+	}
+	return b.String()
+}
+
+// signals lists the node's reported signals with their state, and the
+// message of those that aren't ok.
+func (f *Formatter) signals(signals []collector.Signal, loc *time.Location) string {
+	parts := make([]string, len(signals))
+	for i, signal := range signals {
+		name := tview.Escape(signal.Name)
+		switch {
+		case signal.Stale:
+			parts[i] = fmt.Sprintf("[gray]%s stale since %s", name, f.clock(signal.Reported, loc))
+		case signal.State == collector.SignalOK:
+			parts[i] = fmt.Sprintf("[white]%s ok", name)
+		default:
+			parts[i] = fmt.Sprintf("[%s]%s %s", signalColors[signal.State], name, signal.State)
+			if signal.Message != "" {
+				parts[i] += ": " + f.clip(signal.Message)
+			}
+		}
+	}
+	return strings.Join(parts, "[white], ")
+}
+
+// logins sums up the logins from unexpected sources in the last day,
+// naming the sources that got in.
+func (f *Formatter) logins(logins collector.Logins, loc *time.Location) string {
+	var output string
+	switch len(logins.New) {
+	case 0:
+		output = "[white]no new sources since yesterday"
+	case 1:
+		output = "[red]1 new source since yesterday: "
+	default:
+		output = fmt.Sprintf("[red]%d new sources since yesterday: ", len(logins.New))
+	}
+	sources := make([]string, len(logins.New))
+	for i, source := range logins.New {
+		sources[i] = fmt.Sprintf("%s as %s at %s", source.Address, tview.Escape(strings.Join(source.Users, ", ")), f.clock(source.Last, loc))
+	}
+	output += strings.Join(sources, "; ")
+	if logins.Failed > 0 {
+		addresses := "addresses"
+		if logins.FailedSources == 1 {
+			addresses = "address"
+		}
+		output += fmt.Sprintf("[white], [gray]%s failed from %s %s", f.Int(int64(logins.Failed)), f.Int(int64(logins.FailedSources)), addresses)
+	}
+	return output
+}
+
+// metrics lists the first shownMetrics scraped metrics, with how many
+// more there are.
+func (f *Formatter) metrics(metrics []parsers.Metric) string {
+	parts := make([]string, 0, shownMetrics)
+	for i, m := range metrics {
+		if i == shownMetrics {
+			parts = append(parts, fmt.Sprintf("[gray]+%d more[white]", len(metrics)-i))
+			break
+		}
+		// whole values may be identifiers like frame numbers, which are
+		// shown as exposed
+		value := f.Float(m.Value, 2)
+		if m.Value == math.Trunc(m.Value) {
+			value = fmt.Sprintf("%.0f", m.Value)
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", tview.Escape(m.Series()), value))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (f *Formatter) cpuUsage(usage parsers.CPUUsage) string {
+	return fmt.Sprintf("User Space: %s; System Space: %s; Steal: %s",
+		f.Percent(usage.User), f.Percent(usage.System), f.Percent(usage.Steal))
+}
+
+func (f *Formatter) memoryUsage(usage parsers.MemoryUsage) string {
+	const mb = 1024 * 1024
+	var percent float64
+	if usage.TotalMB > 0 {
+		percent = float64(usage.UsedMB) / float64(usage.TotalMB) * 100
+	}
+	return fmt.Sprintf("Total Memory: %s; Used Memory: %s (%s)",
+		f.Size(int64(usage.TotalMB)*mb), f.Size(int64(usage.UsedMB)*mb), f.Percent(percent))
+}
+
+// diskUsage renders a line per mount, lined up below the first one, so
+// the columns match between mounts and polls. The detail view has the
+// raw output.
+func (f *Formatter) diskUsage(disks []parsers.DiskUsage) string {
+	if len(disks) == 0 {
+		return "\n"
+	}
+	width := 0
+	for _, disk := range disks {
+		width = max(width, textWidth(disk.Mount))
+	}
+	var b strings.Builder
+	for i, disk := range disks {
+		if i > 0 {
+			b.WriteString(strings.Repeat(" ", len("Storage Usage: ")))
+		}
+		b.WriteString(fmt.Sprintf("%s %s used, %s free of %s\n", tview.Escape(padRight(disk.Mount, width)),
+			f.Percent(disk.UsedPercent()), f.Size(disk.Avail), f.Size(disk.Total)))
+	}
+	return b.String()
+}
+
+// logMessages renders the watched messages with the time of their
+// latest entry, in the clock the display settings pick.
+func (f *Formatter) logMessages(messages []parsers.LogMessage, window time.Duration, loc *time.Location) string {
+	var result strings.Builder
+
+	for _, message := range messages {
+		keys := make([]string, 0, len(message.Fields))
+		for key := range message.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		if !message.Time.IsZero() {
+			result.WriteString("[gray]" + f.clock(message.Time, loc) + "[white] ")
+		}
+		result.WriteString(fmt.Sprintf("{ msg: %v %s", message.Msg, formatRate(message.Count, window)))
+		for _, key := range keys {
+			switch v := message.Fields[key].(type) {
+			case float64:
+				result.WriteString(fmt.Sprintf("; %s: %s", key, f.field(key, v)))
+			case int, int64:
+				result.WriteString(fmt.Sprintf("; %s: %d", key, v))
+			default:
+				result.WriteString(fmt.Sprintf("; %s: %v", key, v))
+			}
+		}
+		result.WriteString(" }\n")
+	}
+
+	return result.String()
+}
+
+// clock formats a time of day on the monitor's clock, or on the node's
+// with the zone's name if the display settings say so and the zone is
+// known.
+func (f *Formatter) clock(t time.Time, loc *time.Location) string {
+	if f.nodeTimes && loc != nil {
+		return t.In(loc).Format("15:04:05 MST")
+	}
+	return t.Local().Format("15:04:05")
+}
+
+// field formats a numeric log field. Counts get thousands separators,
+// identifiers like frame numbers are shown as logged.
+func (f *Formatter) field(key string, v float64) string {
+	if strings.HasSuffix(key, "count") && v == math.Trunc(v) {
+		return f.Int(int64(v))
+	}
+	return fmt.Sprintf("%.0f", v)
+}
+
+// failure explains a failed poll: what kind of failure it was and what
+// to check, then the error itself.
+func (f *Formatter) failure(node config.Node, err error) string {
+	failure := transport.Classify(err)
+	if failure == transport.FailureOther && node.Bootstrap != "" {
+		return fmt.Sprintf("Error probing bootstrap peer %s: %s\n", tview.Escape(node.DisplayName()), f.clip(err.Error()))
+	}
+	if failure == transport.FailureOther {
+		return fmt.Sprintf("Error fetching status for node %s: %s\n", tview.Escape(node.DisplayName()), f.clip(err.Error()))
+	}
+	hint, _ := f.cut(failure.Hint())
+	return fmt.Sprintf("[red::b]%s:[-::-] %s\n[gray]%s\n", strings.ToUpper(failure.Title()[:1])+failure.Title()[1:],
+		hint, f.clip(err.Error()))
+}
+
+// clip escapes an error for tview and cuts it short like cut, with a
+// pointer to the key of the full error if it did.
+func (f *Formatter) clip(s string) string {
+	text, cut := f.cut(s)
+	if cut && f.errorKey != "" {
+		text += fmt.Sprintf(" [gray](%s for the full error)", f.errorKey)
+	}
+	return text
+}
+
+// cut escapes s for tview and cuts it short at a word boundary if it
+// is longer than the error length, reporting whether it did.
+func (f *Formatter) cut(s string) (string, bool) {
+	if f.errorLength <= 0 || textWidth(s) <= f.errorLength {
+		return tview.Escape(s), false
+	}
+	cut := cells.Truncate(s, f.errorLength, "")
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return tview.Escape(strings.TrimRight(cut, " ,:;")) + "…", true
+}
+
+// formatInactivity explains an empty log section instead of re-showing
+// entries that were already displayed.
+func formatInactivity(lastActivity time.Time) string {
+	if lastActivity.IsZero() {
+		return "no watched log activity seen yet"
+	}
+	minutes := int(time.Since(lastActivity).Minutes())
+	if minutes < 1 {
+		return "no new log activity in the last minute"
+	}
+	return fmt.Sprintf("no new log activity in %d minutes", minutes)
+}
+
+// formatRate shows how often a message was logged, per minute once the
+// window between two polls is known.
+func formatRate(count int, window time.Duration) string {
+	if window <= 0 {
+		return fmt.Sprintf("×%d", count)
+	}
+	rate := float64(count) / window.Minutes()
+	if rate == float64(int(rate)) || rate >= 10 {
+		return fmt.Sprintf("×%.0f/min", rate)
+	}
+	return fmt.Sprintf("×%.1f/min", rate)
+}
@@ -0,0 +1,295 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// ungrouped names the section of nodes without a group when other nodes
+// have one.
+const ungrouped = "other"
+
+// group is a section of the grid with the nodes of one config group,
+// headed by a line that rolls up their states.
+type group struct {
+	name   string
+	header *tview.TextView
+	// panels are indexes into TUI.panels, in config order
+	panels    []int
+	collapsed bool
+}
+
+// newGroups sorts the panels into groups in the order the groups first
+// appear in the config. It returns nil if no node has a group, so the
+// grid has no headers.
+func newGroups(panels []*panel) []*group {
+	var groups []*group
+	byName := make(map[string]*group)
+	named := false
+	for i, p := range panels {
+		name := p.node.Group
+		if name != "" {
+			named = true
+		} else {
+			name = ungrouped
+		}
+		g, ok := byName[name]
+		if !ok {
+			g = &group{name: name, header: tview.NewTextView().SetDynamicColors(true).SetWrap(false)}
+			byName[name] = g
+			groups = append(groups, g)
+		}
+		g.panels = append(g.panels, i)
+		p.group = g
+	}
+	if !named {
+		for _, p := range panels {
+			p.group = nil
+		}
+		return nil
+	}
+	return groups
+}
+
+// layout places the group headers and the panels of expanded groups in
+// the grid, t.columns panels per row, below the header of the table if
+// the layout has one. Panel rows are height lines high, zero shares the
+// screen between them.
+func (t *TUI) layout(height int) {
+	t.grid.Clear()
+	var rows []int
+	if t.preset == config.LayoutTable {
+		t.grid.AddItem(t.header, 0, 0, 1, t.columns, 0, 0, false)
+		rows = append(rows, 1)
+	}
+	if t.groups == nil {
+		top := len(rows)
+		for i, p := range t.panels {
+			if i%t.columns == 0 {
+				rows = append(rows, height)
+			}
+			t.grid.AddItem(p.view, top+i/t.columns, i%t.columns, 1, 1, 0, 0, false)
+		}
+		t.grid.SetRows(rows...)
+		return
+	}
+
+	for _, g := range t.groups {
+		t.grid.AddItem(g.header, len(rows), 0, 1, t.columns, 0, 0, false)
+		rows = append(rows, 1)
+		if g.collapsed {
+			continue
+		}
+		row := len(rows)
+		for k, i := range g.panels {
+			if k%t.columns == 0 {
+				rows = append(rows, height)
+			}
+			t.grid.AddItem(t.panels[i].view, row+k/t.columns, k%t.columns, 1, 1, 0, 0, false)
+		}
+	}
+	t.grid.SetRows(rows...)
+}
+
+// relayout redraws the grid after a group was collapsed or expanded, or
+// the layout changed.
+func (t *TUI) relayout() {
+	if t.columns == 0 {
+		// not arranged for the screen yet
+		return
+	}
+	switch t.preset {
+	case config.LayoutGrid:
+		t.grid.SetGap(0, 0)
+		t.layout(0)
+	case config.LayoutList:
+		t.grid.SetGap(0, 2)
+		t.layout(compactHeight)
+	default:
+		t.grid.SetGap(0, 0)
+		t.layout(1)
+	}
+}
+
+// renderGroup rolls up the states of a group's nodes into its header:
+// the worst state first, then how many nodes are in each state.
+func (t *TUI) renderGroup(g *group) {
+	counts := make(map[State]int)
+	worst := StateUnknown
+	for _, i := range g.panels {
+		state := t.panels[i].state
+		counts[state]++
+		if state.Level() > worst.Level() || (state.Level() == worst.Level() && state > worst) {
+			worst = state
+		}
+	}
+
+	marker := "▾"
+	switch {
+	case t.format.ascii || t.accessible:
+		marker = "-"
+		if g.collapsed {
+			marker = "+"
+		}
+	case g.collapsed:
+		marker = "▸"
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("[white::b]%s %s[-::-] %s", marker, tview.Escape(g.name), t.format.badge(worst)))
+	if t.accessible {
+		b.WriteString(" " + worst.Words())
+	}
+	nodes := "nodes"
+	if len(g.panels) == 1 {
+		nodes = "node"
+	}
+	b.WriteString(fmt.Sprintf(" [gray]%d %s:", len(g.panels), nodes))
+	for state := range stateStyles {
+		if n := counts[State(state)]; n > 0 {
+			b.WriteString(fmt.Sprintf(" [white]%d %s", n, State(state)))
+		}
+	}
+	if t.accessible && g.collapsed {
+		b.WriteString(", collapsed")
+	}
+	g.header.SetText(b.String())
+}
+
+// visible reports whether panel i can take the focus. Of a collapsed
+// group only the first panel can, and stands in for the header.
+func (t *TUI) visible(i int) bool {
+	g := t.panels[i].group
+	return g == nil || !g.collapsed || g.panels[0] == i
+}
+
+// cell is where a panel that can take the focus is in the grid. The
+// panel of a collapsed group is in its header's row.
+type cell struct {
+	panel, row, column int
+}
+
+// cells returns the panels that can take the focus in the order they
+// are shown.
+func (t *TUI) cells() []cell {
+	columns := max(t.columns, 1)
+	var cells []cell
+	if t.groups == nil {
+		for i := range t.panels {
+			cells = append(cells, cell{i, i / columns, i % columns})
+		}
+		return cells
+	}
+	row := 0
+	for _, g := range t.groups {
+		row++
+		if g.collapsed {
+			cells = append(cells, cell{g.panels[0], row - 1, 0})
+			continue
+		}
+		for k, i := range g.panels {
+			cells = append(cells, cell{i, row + k/columns, k % columns})
+		}
+		row += (len(g.panels) + columns - 1) / columns
+	}
+	return cells
+}
+
+// focusedCell returns the position of the focused panel in cells.
+func (t *TUI) focusedCell(cells []cell) int {
+	for k, c := range cells {
+		if c.panel == t.focused {
+			return k
+		}
+	}
+	return 0
+}
+
+// step moves the focus to the next visible panel in the order they are
+// shown, dir is 1 or -1.
+func (t *TUI) step(dir int) {
+	cells := t.cells()
+	n := len(cells)
+	at := t.focusedCell(cells)
+	t.setFocus(cells[(at+dir+n)%n].panel)
+}
+
+// stepRow moves the focus to the panel in the same column of the next
+// row with panels, dir is 1 (down) or -1 (up), or to the row's last
+// panel if it is shorter. It stays put in the first and last row.
+func (t *TUI) stepRow(dir int) {
+	cells := t.cells()
+	k := t.focusedCell(cells)
+	from := cells[k]
+	for k += dir; k >= 0 && k < len(cells) && cells[k].row == from.row; k += dir {
+	}
+	if k < 0 || k >= len(cells) {
+		return
+	}
+	target := -1
+	for row := cells[k].row; k >= 0 && k < len(cells) && cells[k].row == row; k += dir {
+		// every row has a first column, so there is a target
+		if c := cells[k]; c.column <= from.column && (target < 0 || c.column > cells[target].column) {
+			target = k
+		}
+	}
+	t.setFocus(cells[target].panel)
+}
+
+// toggleGroup collapses or expands the focused node's group. Collapsing
+// moves the focus to the group's header.
+func (t *TUI) toggleGroup() {
+	g := t.panels[t.focused].group
+	if g == nil {
+		return
+	}
+	t.setCollapsed(g, !g.collapsed)
+	t.relayout()
+	if g.collapsed {
+		t.setFocus(g.panels[0])
+	} else {
+		t.setFocus(t.focused)
+	}
+}
+
+// toggleGroups collapses every group if any is expanded, or expands
+// them all.
+func (t *TUI) toggleGroups() {
+	if t.groups == nil {
+		return
+	}
+	collapse := false
+	for _, g := range t.groups {
+		collapse = collapse || !g.collapsed
+	}
+	for _, g := range t.groups {
+		t.setCollapsed(g, collapse)
+	}
+	t.relayout()
+	if collapse {
+		t.setFocus(t.panels[t.focused].group.panels[0])
+	} else {
+		t.setFocus(t.focused)
+	}
+}
+
+func (t *TUI) setCollapsed(g *group, collapsed bool) {
+	g.collapsed = collapsed
+	t.renderGroup(g)
+}
+
+// highlightGroups marks the header of a collapsed group that has the
+// focus.
+func (t *TUI) highlightGroups() {
+	for _, g := range t.groups {
+		if g.collapsed && t.panels[t.focused].group == g {
+			g.header.SetBackgroundColor(tcell.ColorDarkSlateGray)
+		} else {
+			g.header.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// helpPage is the page of the help overlay.
+const helpPage = "help"
+
+// newHelp builds the help overlay, a box with the bound keys and the
+// legend of the state badges centered over the grid.
+func newHelp(format *Formatter, keys *keymap) tview.Primitive {
+	help := "[yellow::b]Keys[-::-]\n" + tview.Escape(keys.help()) + "\n[yellow::b]States[-::-]\n" + format.legend()
+	text := tview.NewTextView().SetDynamicColors(true).SetText(help)
+	text.SetBorder(true).SetTitle(" Help ")
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(text, strings.Count(help, "\n")+3, 0, false).
+			AddItem(nil, 0, 1, false), 72, 0, false).
+		AddItem(nil, 0, 1, false)
+}
+
+// pageVisible reports whether a page is shown.
+func (t *TUI) pageVisible(name string) bool {
+	for _, visible := range t.pages.GetPageNames(true) {
+		if visible == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TUI) toggleHelp() {
+	if t.pageVisible(helpPage) {
+		t.pages.HidePage(helpPage)
+	} else {
+		t.pages.ShowPage(helpPage)
+	}
+}
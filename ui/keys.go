@@ -0,0 +1,278 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// Actions keys are bound to, by the names config.Keys.Bind uses.
+const (
+	actionNext       = "next"
+	actionPrevious   = "previous"
+	actionLeft       = "left"
+	actionRight      = "right"
+	actionUp         = "up"
+	actionDown       = "down"
+	actionFirst      = "first"
+	actionLast       = "last"
+	actionDetail     = "detail"
+	actionQuery      = "query"
+	actionClear      = "clear"
+	actionError      = "error"
+	actionWrap       = "wrap"
+	actionStats      = "stats"
+	actionMesh       = "mesh"
+	actionTimeline   = "timeline"
+	actionLayout     = "layout"
+	actionDeltas     = "deltas"
+	actionGroup      = "group"
+	actionGroups     = "groups"
+	actionHelp       = "help"
+	actionClose      = "close"
+	actionAck        = "ack"
+	actionSilence    = "silence"
+	actionBenchmark  = "benchmark"
+	actionFleet      = "fleet"
+	actionScreenshot = "screenshot"
+	// the detail view's split between metrics and logs
+	actionSplitLeft  = "split-left"
+	actionSplitRight = "split-right"
+)
+
+// actions are all actions with their help text, in the order the help
+// lists them.
+var actions = []struct {
+	name string
+	help string
+}{
+	{actionNext, "move the focus to the next node"},
+	{actionPrevious, "move the focus to the previous node"},
+	{actionLeft, "move the focus left"},
+	{actionRight, "move the focus right"},
+	{actionUp, "move the focus up a row"},
+	{actionDown, "move the focus down a row"},
+	{actionFirst, "move the focus to the first node"},
+	{actionLast, "move the focus to the last node"},
+	{actionDetail, "open or close the detail view of the focused node"},
+	{actionQuery, "pin a query on the focused node's log entries"},
+	{actionClear, "clear the queries pinned to the focused node"},
+	{actionError, "show the focused node's errors in full, or cut short"},
+	{actionWrap, "wrap the focused node's long lines, or cut them off"},
+	{actionSplitLeft, "give the detail view's log tail more room"},
+	{actionSplitRight, "give the detail view's metrics more room"},
+	{actionAck, "acknowledge the focused node's firing alerts"},
+	{actionSilence, "silence the focused node for an hour, or end its silence"},
+	{actionBenchmark, "measure the focused node's bandwidth"},
+	{actionFleet, "act on all nodes, a group's or a tag's: acknowledge, pause, poll now"},
+	{actionStats, "show or hide the fleet statistics"},
+	{actionMesh, "show or hide the latency matrix of the mesh"},
+	{actionTimeline, "show or hide the log lines of all nodes in one timeline"},
+	{actionDeltas, "show the changes since the previous poll on the panels, or the values"},
+	{actionLayout, "switch the layout: grid, list or table"},
+	{actionGroup, "collapse or expand the focused node's group"},
+	{actionGroups, "collapse or expand all groups"},
+	{actionScreenshot, "save the screen to a text and an HTML file"},
+	{actionHelp, "show or hide this help"},
+	{actionClose, "close the detail view, statistics, mesh, timeline, fleet actions or this help"},
+}
+
+// defaultKeys are the bindings of the default preset. The arrow keys are
+// left to the panels, which scroll with them.
+var defaultKeys = map[string][]string{
+	actionNext:       {"Tab"},
+	actionPrevious:   {"Backtab"},
+	actionDetail:     {"Enter"},
+	actionQuery:      {":"},
+	actionClear:      {"c"},
+	actionError:      {"e"},
+	actionWrap:       {"w"},
+	actionSplitLeft:  {"<"},
+	actionSplitRight: {">"},
+	actionAck:        {"a"},
+	actionSilence:    {"m"},
+	actionBenchmark:  {"b"},
+	actionFleet:      {"f"},
+	actionStats:      {"s"},
+	actionMesh:       {"p"},
+	actionTimeline:   {"t"},
+	actionLayout:     {"v"},
+	actionDeltas:     {"d"},
+	actionGroup:      {"g"},
+	actionGroups:     {"G"},
+	actionScreenshot: {"x"},
+	actionHelp:       {"?"},
+	actionClose:      {"Esc"},
+}
+
+// viKeys change the default preset for vi users. Groups fold with z
+// like vim's folds, since g and G go to the first and last node.
+var viKeys = map[string][]string{
+	actionLeft:   {"h"},
+	actionDown:   {"j"},
+	actionUp:     {"k"},
+	actionRight:  {"l"},
+	actionFirst:  {"gg"},
+	actionLast:   {"G"},
+	actionGroup:  {"z"},
+	actionGroups: {"Z"},
+	actionClose:  {"Esc", "q"},
+}
+
+// keyLabels are how the help shows keys whose names read oddly.
+var keyLabels = map[string]string{"Backtab": "Shift-Tab"}
+
+// keymap looks up the actions of key presses, remembering the first key
+// of a two key sequence until the second one.
+type keymap struct {
+	// actions are the action of every bound key or sequence, keys the
+	// keys of every bound action
+	actions map[string]string
+	keys    map[string][]string
+	// prefixes are the first keys of the sequences
+	prefixes map[string]bool
+	typed    string
+}
+
+// ValidateKeys checks the key bindings of the display settings: that the
+// actions and keys exist, and that no key does two things.
+func ValidateKeys(keys config.Keys) error {
+	_, err := newKeymap(keys)
+	return err
+}
+
+func newKeymap(keys config.Keys) (*keymap, error) {
+	bound := make(map[string][]string, len(defaultKeys))
+	for action, names := range defaultKeys {
+		bound[action] = names
+	}
+	if keys.Preset == config.KeysVi {
+		for action, names := range viKeys {
+			bound[action] = names
+		}
+	}
+	for action, names := range keys.Bind {
+		if !knownAction(action) {
+			return nil, fmt.Errorf("unknown key action %q", action)
+		}
+		bound[action] = strings.Fields(names)
+	}
+
+	k := &keymap{actions: make(map[string]string), keys: bound, prefixes: make(map[string]bool)}
+	for _, a := range actions {
+		for _, key := range bound[a.name] {
+			if !validKey(key) {
+				return nil, fmt.Errorf("unknown key %q for %s", key, a.name)
+			}
+			if other, ok := k.actions[key]; ok {
+				return nil, fmt.Errorf("key %q is bound to both %s and %s", key, other, a.name)
+			}
+			k.actions[key] = a.name
+			if utf8.RuneCountInString(key) == 2 {
+				first, _ := utf8.DecodeRuneInString(key)
+				k.prefixes[string(first)] = true
+			}
+		}
+	}
+	for prefix := range k.prefixes {
+		if action, ok := k.actions[prefix]; ok {
+			return nil, fmt.Errorf("key %q of %s starts a key sequence of another action", prefix, action)
+		}
+	}
+	return k, nil
+}
+
+func knownAction(name string) bool {
+	for _, a := range actions {
+		if a.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validKey reports whether key is a character, two of them, or the name
+// of a key.
+func validKey(key string) bool {
+	return isKeyName(key) || key != "" && utf8.RuneCountInString(key) <= 2
+}
+
+func isKeyName(key string) bool {
+	if key == "Space" {
+		return true
+	}
+	for _, name := range tcell.KeyNames {
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// keyName names a key press the way bindings do: the character for
+// printable keys, Space, or tcell's name such as Tab, Esc or Ctrl-R.
+func keyName(event *tcell.EventKey) string {
+	switch {
+	case event.Key() != tcell.KeyRune:
+		return tcell.KeyNames[event.Key()]
+	case event.Rune() == ' ':
+		return "Space"
+	}
+	return string(event.Rune())
+}
+
+// press returns the action of a key press. ok is false for keys bound to
+// nothing; the first key of a sequence is bound but has no action yet.
+func (k *keymap) press(event *tcell.EventKey) (action string, ok bool) {
+	name := keyName(event)
+	typed := k.typed
+	k.typed = ""
+	if event.Key() == tcell.KeyRune && typed != "" {
+		if action, ok := k.actions[typed+name]; ok {
+			return action, true
+		}
+	}
+	if event.Key() == tcell.KeyRune && k.prefixes[name] {
+		k.typed = name
+		return "", true
+	}
+	action, ok = k.actions[name]
+	return action, ok
+}
+
+// label names the first key of an action for hints on screen, empty if
+// the action has no key.
+func (k *keymap) label(action string) string {
+	if len(k.keys[action]) == 0 {
+		return ""
+	}
+	name := k.keys[action][0]
+	if label, ok := keyLabels[name]; ok {
+		return label
+	}
+	return name
+}
+
+// help lists the bound keys, an action per line.
+func (k *keymap) help() string {
+	var b strings.Builder
+	for _, a := range actions {
+		names := k.keys[a.name]
+		if len(names) == 0 {
+			continue
+		}
+		labels := make([]string, len(names))
+		for i, name := range names {
+			labels[i] = name
+			if label, ok := keyLabels[name]; ok {
+				labels[i] = label
+			}
+		}
+		b.WriteString(padRight(strings.Join(labels, " / "), 16) + " " + a.help + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+const (
+	// gridWidth and gridHeight are the smallest a grid panel gets before
+	// the grid adds a row or the fleet is shown in a list instead, about
+	// a status with a few log messages.
+	gridWidth  = 70
+	gridHeight = 12
+	// footerHeight is the height of the footer below every layout.
+	footerHeight = recentEvents + 2
+	// rowNameWidth is how much of the node name a table row shows.
+	rowNameWidth = 20
+)
+
+// layouts are the layouts actionLayout cycles through, in order.
+var layouts = []string{config.LayoutGrid, config.LayoutList, config.LayoutTable}
+
+// tiled reports whether the nodes are shown as tiles of a line or two,
+// with the focused node in full in the preview below them.
+func (t *TUI) tiled() bool {
+	return t.preset != config.LayoutGrid
+}
+
+// pickLayout picks the layout for n nodes on a screen of the given size:
+// the grid while its panels get gridHeight lines, the list while its
+// tiles fit above the preview, and the table otherwise.
+func pickLayout(n, width, height int) string {
+	columns := gridColumns(n, width)
+	if (n+columns-1)/columns*gridHeight <= height-footerHeight {
+		return config.LayoutGrid
+	}
+	columns = max(width/compactWidth, 1)
+	if (n+columns-1)/columns*compactHeight <= height-footerHeight-previewHeight {
+		return config.LayoutList
+	}
+	return config.LayoutTable
+}
+
+// gridColumns is how many panels of n go side by side in the grid: as
+// many as are gridWidth wide.
+func gridColumns(n, width int) int {
+	return max(min(width/gridWidth, n), 1)
+}
+
+// arrange lays out the nodes for the screen, which it keeps for the
+// screenshots. It runs before every draw and only touches the grid when the screen's size changed the layout
+// or its column count; the layout is picked anew until the user picks
+// one.
+func (t *TUI) arrange(screen tcell.Screen) bool {
+	t.screen = screen
+	width, height := screen.Size()
+	preset := t.preset
+	if t.autoLayout {
+		preset = pickLayout(len(t.panels), width, height)
+	}
+	columns := 1
+	switch preset {
+	case config.LayoutGrid:
+		columns = gridColumns(len(t.panels), width)
+	case config.LayoutList:
+		columns = max(width/compactWidth, 1)
+	}
+	if preset == t.preset && columns == t.columns {
+		return false
+	}
+	t.columns = columns
+	if preset != t.preset {
+		t.setLayout(preset)
+	} else {
+		t.relayout()
+	}
+	return false
+}
+
+// cycleLayout switches to the next layout, which stays until the next
+// switch whatever the size of the screen.
+func (t *TUI) cycleLayout() {
+	next := layouts[0]
+	for i, l := range layouts {
+		if l == t.preset {
+			next = layouts[(i+1)%len(layouts)]
+		}
+	}
+	t.autoLayout = false
+	// arrange sets the columns before the next draw
+	t.columns = 0
+	t.setLayout(next)
+}
+
+// setLayout switches the panels, the preview and the grid to a layout.
+func (t *TUI) setLayout(preset string) {
+	t.preset = preset
+	t.root.Clear().AddItem(t.grid, 0, 1, false)
+	if t.tiled() {
+		t.root.AddItem(t.preview, previewHeight, 0, false)
+	}
+	t.root.AddItem(t.footer, footerHeight, 0, false)
+	for _, p := range t.panels {
+		p.view.SetBorder(!t.tiled())
+		p.view.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
+		t.render(p)
+	}
+	t.relayout()
+	// not setFocus, arrange runs while the application is locked and
+	// the focused panel keeps the focus anyway
+	if t.tiled() && len(t.panels) > 0 {
+		t.highlightTiles()
+	}
+}
+
+// tableHeader labels the columns of Row.
+func (f *Formatter) tableHeader() string {
+	header := fmt.Sprintf("[gray]%s %s %6s %6s %6s", strings.Repeat(" ", f.badgeWidth()), padRight("NODE", rowNameWidth), "CPU", "MEM", "DISK")
+	for _, field := range keyFields {
+		header += " " + padRight(strings.ToUpper(field.label), 10)
+	}
+	return header
+}
+
+// Row renders a node in one line under tableHeader: its state badge and
+// name, CPU, memory and disk usage and the key log numbers, or why its
+// last poll failed.
+func (f *Formatter) Row(node config.Node, snapshot *collector.Snapshot, state State) string {
+	badge := f.badge(state)
+	output := fmt.Sprintf("%s%s [white::b]%s[-::-]", badge, strings.Repeat(" ", f.badgeWidth()-tview.TaggedStringWidth(badge)),
+		tview.Escape(padRight(truncate(node.DisplayName(), rowNameWidth), rowNameWidth)))
+
+	switch {
+	case snapshot == nil:
+		return output + " [gray]waiting for first poll\n"
+	case snapshot.Err != nil:
+		return output + fmt.Sprintf(" [red]%s\n", tview.Escape(transport.Describe(snapshot.Err)))
+	}
+	status := snapshot.Status
+	if peer := status.Bootstrap; peer != nil {
+		return output + fmt.Sprintf(" [gray]bootstrap [white]%s  [gray]latency [white]%s\n", peer.Addr.Transport, latency(peer.Latency))
+	}
+
+	cpu, memory, disk := "   n/a", "   n/a", "   n/a"
+	if status.Collected(collector.SectionCPU) {
+		cpu = f.Percent(status.CPU.User + status.CPU.System)
+	}
+	if status.Memory.TotalMB > 0 {
+		memory = f.Percent(float64(status.Memory.UsedMB) / float64(status.Memory.TotalMB) * 100)
+	}
+	if len(status.Disks) > 0 {
+		disk = f.Percent(status.Disks[0].UsedPercent())
+	}
+	output += fmt.Sprintf(" [white]%s %s %s", cpu, memory, disk)
+	for _, field := range keyFields {
+		value := "-"
+		if v, ok := latestField(status, field.key); ok {
+			value = f.field(field.key, v)
+		}
+		output += " " + padRight(value, 10)
+	}
+	return output + "\n"
+}
+
+// badgeWidth is how many cells the widest state badge takes.
+func (f *Formatter) badgeWidth() int {
+	width := 0
+	for state := range stateStyles {
+		width = max(width, tview.TaggedStringWidth(f.badge(State(state))))
+	}
+	return width
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// Lines is the line oriented frontend: one plain text line per node and
+// poll and one per alert that fires or resolves, without colors or
+// cursor movement. It suits screen readers, `watch` style terminals and
+// logging to a file. It implements collector.Sink and alert.Notifier.
+type Lines struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format *Formatter
+	// firing alert kinds by node
+	firing map[string]map[string]bool
+}
+
+func NewLines(w io.Writer, display config.Display) *Lines {
+	display.Accessible = true
+	return &Lines{
+		w:      w,
+		format: NewFormatter(display),
+		firing: make(map[string]map[string]bool),
+	}
+}
+
+func (l *Lines) Consume(snapshot collector.Snapshot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	name := snapshot.Node.DisplayName()
+	var kinds []string
+	for kind := range l.firing[name] {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	state := NodeState(snapshot.Node, &snapshot, len(kinds) > 0, 0, snapshot.Time)
+	var parts []string
+	if snapshot.Err != nil {
+		parts = append(parts, "down, "+transport.Describe(snapshot.Err))
+	} else {
+		if len(kinds) > 0 {
+			parts = append(parts, "alerts "+strings.Join(kinds, ", "))
+		}
+		parts = append(parts, l.metrics(snapshot.Status)...)
+	}
+	fmt.Fprintf(l.w, "%s %s %s: %s\n", snapshot.Time.Format("15:04:05"), name, state.Words(), strings.Join(parts, "; "))
+}
+
+// metrics lists the key numbers of a status.
+func (l *Lines) metrics(status collector.Status) []string {
+	if peer := status.Bootstrap; peer != nil {
+		return []string{fmt.Sprintf("bootstrap %s latency %s", peer.Addr.Transport, latency(peer.Latency))}
+	}
+	percent := func(v float64) string { return strings.TrimSpace(l.format.Percent(v)) }
+	var parts []string
+	if status.Collected(collector.SectionCPU) {
+		parts = append(parts, "cpu "+percent(status.CPU.User+status.CPU.System))
+	}
+	if status.Memory.TotalMB > 0 {
+		parts = append(parts, "memory "+percent(float64(status.Memory.UsedMB)/float64(status.Memory.TotalMB)*100))
+	}
+	for _, disk := range status.Disks {
+		parts = append(parts, fmt.Sprintf("disk %s %s", disk.Mount, percent(disk.UsedPercent())))
+	}
+	for _, field := range keyFields {
+		if value, ok := latestField(status, field.key); ok {
+			parts = append(parts, field.label+" "+l.format.field(field.key, value))
+		}
+	}
+	return parts
+}
+
+func (l *Lines) Notify(a alert.Alert) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	name := a.Node.DisplayName()
+	if a.Firing {
+		if l.firing[name] == nil {
+			l.firing[name] = make(map[string]bool)
+		}
+		l.firing[name][a.Kind] = true
+		fmt.Fprintf(l.w, "%s ALERT %s %s: %s\n", a.Time.Format("15:04:05"), name, a.Kind, a.Message)
+	} else {
+		delete(l.firing[name], a.Kind)
+		fmt.Fprintf(l.w, "%s RESOLVED %s %s\n", a.Time.Format("15:04:05"), name, a.Kind)
+	}
+}
+
+// Run blocks until the process is interrupted.
+func (l *Lines) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+)
+
+const (
+	// meshPage is the page of the latency matrix between the nodes of
+	// the mesh, shown above the grid.
+	meshPage = "mesh"
+	// meshCell is the width of a matrix column.
+	meshCell = 8
+	// meshSlow and meshFar are the round trip times from which a pair
+	// of nodes is shown in yellow and red.
+	meshSlow = 50 * time.Millisecond
+	meshFar  = 150 * time.Millisecond
+)
+
+// MeshMatrix is how the nodes of the mesh reached each other in their
+// last polls.
+type MeshMatrix struct {
+	// Names are the nodes of the mesh in config order, both the rows
+	// (the pinging nodes) and the columns (the pinged ones).
+	Names []string
+	// Pings are the pings by row and column, nil where the row's node
+	// didn't report one.
+	Pings [][]*collector.MeshPing
+	// Errors are why nodes didn't report their pings, by name.
+	Errors map[string]string
+}
+
+// Mesh builds the latency matrix from the last snapshot of each node.
+// Nodes are in the mesh if they pinged other nodes or were pinged.
+func Mesh(snapshots []*collector.Snapshot) MeshMatrix {
+	var sources []*collector.Snapshot
+	targets := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		if snapshot == nil {
+			continue
+		}
+		if _, failed := snapshot.Status.Errors[collector.SectionMesh]; failed || len(snapshot.Status.Mesh) > 0 {
+			sources = append(sources, snapshot)
+		}
+		for _, ping := range snapshot.Status.Mesh {
+			targets[ping.Target] = true
+		}
+	}
+
+	matrix := MeshMatrix{Errors: make(map[string]string)}
+	for _, snapshot := range snapshots {
+		if snapshot == nil {
+			continue
+		}
+		name := snapshot.Node.DisplayName()
+		if targets[name] || slices.Contains(sources, snapshot) {
+			matrix.Names = append(matrix.Names, name)
+		}
+	}
+	matrix.Pings = make([][]*collector.MeshPing, len(matrix.Names))
+	for i := range matrix.Pings {
+		matrix.Pings[i] = make([]*collector.MeshPing, len(matrix.Names))
+	}
+	for _, snapshot := range snapshots {
+		if snapshot == nil {
+			continue
+		}
+		row := slices.Index(matrix.Names, snapshot.Node.DisplayName())
+		if row < 0 {
+			continue
+		}
+		switch {
+		case snapshot.Err != nil:
+			matrix.Errors[matrix.Names[row]] = snapshot.Err.Error()
+		case snapshot.Status.Errors[collector.SectionMesh] != nil:
+			matrix.Errors[matrix.Names[row]] = snapshot.Status.Errors[collector.SectionMesh].Error()
+		}
+		for _, ping := range snapshot.Status.Mesh {
+			if column := slices.Index(matrix.Names, ping.Target); column >= 0 {
+				matrix.Pings[row][column] = &ping
+			}
+		}
+	}
+	return matrix
+}
+
+// Mesh renders the latency matrix, the average round trip time in
+// milliseconds from each row's node to each column's, colored by how
+// far apart they are. Any loss is shown instead of the time.
+func (f *Formatter) Mesh(matrix MeshMatrix) string {
+	if len(matrix.Names) == 0 {
+		return "[gray]No node reported pings yet, see mesh in the config.\n"
+	}
+	corner := `from \ to`
+	width := len(corner)
+	for _, name := range matrix.Names {
+		width = max(width, textWidth(name))
+	}
+	width = min(width, 2*meshCell+1)
+
+	var b strings.Builder
+	b.WriteString("[green::b]" + padRight(corner, width))
+	for _, name := range matrix.Names {
+		b.WriteString(" " + tview.Escape(padLeft(truncate(name, meshCell-1), meshCell-1)))
+	}
+	b.WriteString("\n")
+	for row, name := range matrix.Names {
+		b.WriteString("[white::b]" + tview.Escape(padRight(truncate(name, width), width)) + "[-::-]")
+		for column, ping := range matrix.Pings[row] {
+			b.WriteString(" " + f.meshCell(row == column, ping))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString(fmt.Sprintf("\n[gray]Average round trip times in ms, yellow from %s and red from %s, or the share of pings lost.\n",
+		meshSlow, meshFar))
+	var failed []string
+	for _, name := range matrix.Names {
+		if err, ok := matrix.Errors[name]; ok {
+			failed = append(failed, fmt.Sprintf("[red]%s: [white]%s", tview.Escape(name), f.clip(err)))
+		}
+	}
+	for row, pings := range matrix.Pings {
+		for _, ping := range pings {
+			if ping != nil && ping.Error != "" {
+				failed = append(failed, fmt.Sprintf("[red]%s to %s: [white]%s",
+					tview.Escape(matrix.Names[row]), tview.Escape(ping.Target), f.clip(ping.Error)))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		b.WriteString(strings.Join(failed, "\n") + "\n")
+	}
+	return b.String()
+}
+
+// meshCell is one pair of nodes in the matrix.
+func (f *Formatter) meshCell(self bool, ping *collector.MeshPing) string {
+	cell := func(color, text string) string {
+		return fmt.Sprintf("[%s]%*s[white]", color, meshCell-1, text)
+	}
+	switch {
+	case self:
+		return cell("gray", "")
+	case ping == nil:
+		return cell("gray", "-")
+	case ping.Error != "":
+		return cell("red", "error")
+	case ping.Loss >= 100:
+		return cell("red", "lost")
+	case ping.Loss > 0:
+		return cell("red", fmt.Sprintf("%.0f%%", ping.Loss))
+	}
+	ms := float64(ping.RTT) / float64(time.Millisecond)
+	color := "green"
+	if ping.RTT >= meshFar {
+		color = "red"
+	} else if ping.RTT >= meshSlow {
+		color = "yellow"
+	}
+	return cell(color, f.Float(ms, 1))
+}
+
+func (t *TUI) toggleMesh() {
+	if t.pageVisible(meshPage) {
+		t.pages.HidePage(meshPage)
+		return
+	}
+	t.pages.ShowPage(meshPage)
+	t.renderMesh()
+}
+
+func (t *TUI) renderMesh() {
+	snapshots := make([]*collector.Snapshot, len(t.panels))
+	for i, p := range t.panels {
+		snapshots[i] = p.snapshot
+	}
+	t.mesh.SetText(t.format.Mesh(Mesh(snapshots)))
+}
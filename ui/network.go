@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+)
+
+// networkFields are log fields that describe the network rather than
+// the node. Nodes in sync with the network agree on them, so a change
+// every node reports is network-wide and a node that differs is behind.
+var networkFields = []struct {
+	key   string
+	label string
+}{
+	{key: "difficulty", label: "Difficulty"},
+	{key: "ring_size", label: "Ring size"},
+}
+
+// maxOutliers is how many nodes that disagree with the rest are named per
+// value.
+const maxOutliers = 5
+
+// NetworkValue is a network field as reported across the fleet.
+type NetworkValue struct {
+	Key   string
+	Label string
+	// Values are the reported values with the nodes reporting them, the
+	// one most nodes report first.
+	Values []ReportedValue
+}
+
+type ReportedValue struct {
+	Value float64
+	Nodes []string
+}
+
+// network collects the network fields from the snapshots of the
+// reporting nodes, leaving out fields no node logs.
+func network(snapshots []*collector.Snapshot) []NetworkValue {
+	var fields []NetworkValue
+	for _, field := range networkFields {
+		byValue := make(map[float64][]string)
+		for _, snapshot := range snapshots {
+			if snapshot == nil || snapshot.Err != nil {
+				continue
+			}
+			if value, ok := latestField(snapshot.Status, field.key); ok {
+				byValue[value] = append(byValue[value], snapshot.Node.DisplayName())
+			}
+		}
+		if len(byValue) == 0 {
+			continue
+		}
+		reported := NetworkValue{Key: field.key, Label: field.label}
+		for value, nodes := range byValue {
+			sort.Strings(nodes)
+			reported.Values = append(reported.Values, ReportedValue{Value: value, Nodes: nodes})
+		}
+		// most nodes first, ties to the higher value, which is the newer
+		// one for values that only grow
+		sort.Slice(reported.Values, func(i, j int) bool {
+			a, b := reported.Values[i], reported.Values[j]
+			if len(a.Nodes) != len(b.Nodes) {
+				return len(a.Nodes) > len(b.Nodes)
+			}
+			return a.Value > b.Value
+		})
+		fields = append(fields, reported)
+	}
+	return fields
+}
+
+// network renders the network fields: the value most nodes agree on and
+// the nodes reporting something else.
+func (f *Formatter) network(fields []NetworkValue) string {
+	var b strings.Builder
+	b.WriteString("[green::b]Network\n")
+	for _, field := range fields {
+		reporting := 0
+		for _, value := range field.Values {
+			reporting += len(value.Nodes)
+		}
+		common := field.Values[0]
+		agree := "all"
+		if len(common.Nodes) < reporting {
+			agree = fmt.Sprintf("%d of", len(common.Nodes))
+		}
+		b.WriteString(fmt.Sprintf("  [gray]%-11s [white]%s [gray]on %s %d nodes\n", field.Label,
+			f.networkValue(common.Value), agree, reporting))
+		for _, value := range field.Values[1:] {
+			nodes := value.Nodes
+			more := ""
+			if len(nodes) > maxOutliers {
+				nodes, more = nodes[:maxOutliers], fmt.Sprintf(" and %d more", len(nodes)-maxOutliers)
+			}
+			b.WriteString(fmt.Sprintf("  %-11s [yellow]%s [gray]on %s%s\n", "", f.networkValue(value.Value),
+				tview.Escape(strings.Join(nodes, ", ")), more))
+		}
+	}
+	return b.String()
+}
+
+func (f *Formatter) networkValue(v float64) string {
+	if v == math.Trunc(v) {
+		return f.Int(int64(v))
+	}
+	return f.Float(v, 2)
+}
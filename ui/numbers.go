@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// numberFormat holds the separators of a locale.
+type numberFormat struct {
+	decimal   string
+	thousands string
+}
+
+// locales maps a language to its separators. Regions are ignored, so
+// "de-CH" formats like "de".
+var locales = map[string]numberFormat{
+	"en":    {decimal: ".", thousands: ","},
+	"de":    {decimal: ",", thousands: "."},
+	"es":    {decimal: ",", thousands: "."},
+	"it":    {decimal: ",", thousands: "."},
+	"nl":    {decimal: ",", thousands: "."},
+	"fr":    {decimal: ",", thousands: " "},
+	"ru":    {decimal: ",", thousands: " "},
+	"ch":    {decimal: ".", thousands: "'"},
+	"plain": {decimal: ".", thousands: ""},
+}
+
+// Formatter renders statuses with the number format of the configured
+// locale. Sizes are always shown in GiB with one decimal, percentages
+// with a fixed width so panels line up between polls and nodes.
+type Formatter struct {
+	numbers    numberFormat
+	accessible bool
+	ascii      bool
+	// sections are the panel sections, see config.Display.Sections
+	sections []string
+	// nodeTimes shows times in the node's time zone
+	nodeTimes bool
+	// errorLength is where errors are cut short, zero keeps them whole.
+	// errorKey names the key that shows them in full, errors cut short
+	// don't point to it without one.
+	errorLength int
+	errorKey    string
+	// deltas, if set, shows the stats sections as their changes since
+	// the previous poll
+	deltas *deltas
+}
+
+// NewFormatter returns a formatter for the display settings, unknown
+// locales format like "en".
+func NewFormatter(display config.Display) *Formatter {
+	lang := strings.ToLower(display.Locale)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	numbers, ok := locales[lang]
+	if !ok {
+		numbers = locales["en"]
+	}
+	return &Formatter{numbers: numbers, accessible: display.Accessible, ascii: display.ASCII, sections: display.PanelSections(),
+		nodeTimes: display.Times == config.TimesNode, errorLength: display.ErrorCutoff()}
+}
+
+// Float formats v with the given number of decimals.
+func (f *Formatter) Float(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	b.WriteString(f.group(whole))
+	if fraction != "" {
+		b.WriteString(f.numbers.decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// Int formats n with thousands separators.
+func (f *Formatter) Int(n int64) string {
+	if n < 0 {
+		return "-" + f.group(strconv.FormatInt(-n, 10))
+	}
+	return f.group(strconv.FormatInt(n, 10))
+}
+
+// group inserts the thousands separator into a string of digits.
+func (f *Formatter) group(digits string) string {
+	if f.numbers.thousands == "" || len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(f.numbers.thousands)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// Percent formats a percentage right aligned to "100.0%".
+func (f *Formatter) Percent(v float64) string {
+	return padLeft(f.Float(v, 1)+"%", 6)
+}
+
+// Size formats a size in bytes as GiB with one decimal.
+func (f *Formatter) Size(bytes int64) string {
+	return f.Float(float64(bytes)/(1<<30), 1) + " GiB"
+}
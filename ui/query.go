@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/parsers"
+)
+
+// pin is a query pinned to a panel. It keeps its last result so the line
+// doesn't disappear on polls without a matching log entry.
+type pin struct {
+	query parsers.Query
+	value string
+	found bool
+}
+
+func (t *TUI) openQuery() {
+	t.input.SetText("")
+	t.input.SetLabel(fmt.Sprintf("query %s> ", t.panels[t.focused].node.DisplayName()))
+	t.root.AddItem(t.input, 1, 0, true)
+	t.app.SetFocus(t.input)
+}
+
+func (t *TUI) queryDone(key tcell.Key) {
+	expr := strings.TrimSpace(t.input.GetText())
+	t.root.RemoveItem(t.input)
+	t.setFocus(t.focused)
+
+	if key != tcell.KeyEnter || expr == "" {
+		return
+	}
+
+	query, err := parsers.ParseQuery(expr)
+	if err != nil {
+		t.footer.SetText(fmt.Sprintf("[red::b]%s", tview.Escape(err.Error())))
+		return
+	}
+
+	p := t.panels[t.focused]
+	p.pins = append(p.pins, pin{query: query})
+	if p.snapshot != nil && p.snapshot.Err == nil {
+		p.updatePins(p.snapshot.Status.Logs)
+	}
+	t.render(p)
+}
+
+// updatePins evaluates the pinned queries against the log entries of the
+// latest poll. The first entry the path exists in wins.
+func (p *panel) updatePins(logs []parsers.LogMessage) {
+	for i := range p.pins {
+		for _, message := range logs {
+			entry := make(map[string]interface{}, len(message.Fields)+1)
+			for key, value := range message.Fields {
+				entry[key] = value
+			}
+			entry["msg"] = message.Msg
+
+			if value, ok := p.pins[i].query.Eval(entry); ok {
+				p.pins[i].value = parsers.FormatValue(value)
+				p.pins[i].found = true
+				break
+			}
+		}
+	}
+}
+
+func (p *panel) formatPins() string {
+	var b strings.Builder
+	for _, pin := range p.pins {
+		value := "[gray]no matching log entry yet"
+		if pin.found {
+			value = "[white]" + tview.Escape(pin.value)
+		}
+		b.WriteString(fmt.Sprintf("[magenta::b]%s: %s\n", tview.Escape(pin.query.String()), value))
+	}
+	return b.String()
+}
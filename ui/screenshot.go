@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	// ScreenshotWidth and ScreenshotHeight are the size of the screen
+	// Screenshot draws on when there is no terminal to go by.
+	ScreenshotWidth  = 160
+	ScreenshotHeight = 48
+	// screenshotName is the name of the files the screenshot key saves,
+	// in the working directory, by the time they are taken.
+	screenshotName = "q-monitor-20060102-150405"
+)
+
+// screenText writes what the screen shows as plain text, a line per
+// row without its trailing spaces.
+func screenText(w io.Writer, screen tcell.Screen) error {
+	width, height := screen.Size()
+	var b strings.Builder
+	for y := 0; y < height; y++ {
+		var line strings.Builder
+		for x := 0; x < width; {
+			r, combining, _, cellWidth := screen.GetContent(x, y)
+			line.WriteRune(r)
+			line.WriteString(string(combining))
+			x += max(cellWidth, 1)
+		}
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// screenHTML writes what the screen shows as an HTML page, its colors
+// and bold, underlined and dim text kept as styles of the runs of
+// cells that share them.
+func screenHTML(w io.Writer, screen tcell.Screen) error {
+	width, height := screen.Size()
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>q-monitor</title>\n</head>\n")
+	b.WriteString("<body style=\"background:#000000\">\n<pre style=\"color:#ffffff;background:#000000;font-family:monospace\">")
+	for y := 0; y < height; y++ {
+		var run strings.Builder
+		style := ""
+		flush := func() {
+			if run.Len() == 0 {
+				return
+			}
+			text := html.EscapeString(run.String())
+			if style == "" {
+				b.WriteString(text)
+			} else {
+				fmt.Fprintf(&b, "<span style=\"%s\">%s</span>", style, text)
+			}
+			run.Reset()
+		}
+		for x := 0; x < width; {
+			r, combining, cellStyle, cellWidth := screen.GetContent(x, y)
+			if css := styleCSS(cellStyle); css != style {
+				flush()
+				style = css
+			}
+			run.WriteRune(r)
+			run.WriteString(string(combining))
+			x += max(cellWidth, 1)
+		}
+		flush()
+		b.WriteString("\n")
+	}
+	b.WriteString("</pre>\n</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// styleCSS is the inline CSS of a cell style, empty for the default
+// look.
+func styleCSS(style tcell.Style) string {
+	fg, bg, attrs := style.Decompose()
+	if attrs&tcell.AttrReverse != 0 {
+		fg, bg = bg, fg
+		if fg == tcell.ColorDefault {
+			fg = tcell.ColorBlack
+		}
+		if bg == tcell.ColorDefault {
+			bg = tcell.ColorWhite
+		}
+	}
+	var css []string
+	if hex := fg.Hex(); hex >= 0 {
+		css = append(css, fmt.Sprintf("color:#%06x", hex))
+	}
+	if hex := bg.Hex(); hex >= 0 {
+		css = append(css, fmt.Sprintf("background:#%06x", hex))
+	}
+	if attrs&tcell.AttrBold != 0 {
+		css = append(css, "font-weight:bold")
+	}
+	if attrs&tcell.AttrUnderline != 0 {
+		css = append(css, "text-decoration:underline")
+	}
+	if attrs&tcell.AttrDim != 0 {
+		css = append(css, "opacity:0.7")
+	}
+	return strings.Join(css, ";")
+}
+
+// writeScreenshot saves what the screen shows to path, as HTML if its
+// extension is .html or .htm and as plain text otherwise.
+func writeScreenshot(path string, screen tcell.Screen) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := screenText
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".html" || ext == ".htm" {
+		write = screenHTML
+	}
+	if err := write(f, screen); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// screenshot saves the screen as plain text and as HTML in the working
+// directory, telling in the footer where.
+func (t *TUI) screenshot() {
+	if t.screen == nil {
+		return
+	}
+	name := time.Now().Format(screenshotName)
+	for _, path := range []string{name + ".txt", name + ".html"} {
+		if err := writeScreenshot(path, t.screen); err != nil {
+			t.notice = "screenshot failed: " + err.Error()
+			t.renderFooter()
+			return
+		}
+	}
+	t.notice = fmt.Sprintf("screenshot saved to %s.txt and .html", name)
+	t.renderFooter()
+}
+
+// Screenshot draws the TUI on a screen of the given size that isn't
+// shown, once load returns, and saves it to path like the screenshot
+// key does, as HTML if its extension is .html or .htm and as plain
+// text otherwise. load feeds the TUI its snapshots, it has to return
+// once it Consumed them. Screenshot runs the TUI in place of Run.
+func (t *TUI) Screenshot(path string, width, height int, load func()) error {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	// initializes the screen, which sets its size back
+	t.app.SetScreen(screen)
+	screen.SetSize(width, height)
+
+	var saved error
+	go func() {
+		load()
+		// the updates are run in order, so the snapshots are drawn
+		// by now
+		t.app.QueueUpdate(func() {
+			t.renderFooter()
+			t.app.ForceDraw()
+			saved = writeScreenshot(path, screen)
+			t.app.Stop()
+		})
+	}()
+	if err := t.app.SetRoot(t.pages, true).Run(); err != nil {
+		return err
+	}
+	return saved
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+)
+
+// stallAfter is how long a node that is up can go without watched log
+// activity before it counts as stalled.
+const stallAfter = 10 * time.Minute
+
+// Level is the severity of a node state, spelled out in accessible
+// mode and plain output.
+type Level int
+
+const (
+	LevelUnknown Level = iota
+	LevelOK
+	LevelWarn
+	LevelCrit
+)
+
+var levelNames = [...]string{"UNKNOWN", "OK", "WARN", "CRIT"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// State is what a node is doing, as far as the monitor can tell.
+type State int
+
+const (
+	// StateUnknown is a node that wasn't polled yet.
+	StateUnknown State = iota
+	StateUp
+	// StateDegraded is a node that is up with firing alerts.
+	StateDegraded
+	// StateStalled is a node that is up but hasn't logged any watched
+	// message for stallAfter.
+	StateStalled
+	// StateStale is a node whose last poll is too old to trust, e.g.
+	// because an SSH session hangs.
+	StateStale
+	StateDown
+	// StateMaintenance is a node marked as in maintenance in the config.
+	StateMaintenance
+)
+
+// stateStyle is how a state looks everywhere it is shown.
+type stateStyle struct {
+	name  string
+	level Level
+	glyph string
+	ascii string
+	// word replaces the glyph in accessible mode, padded to one width
+	word  string
+	color string
+	// description is shown in the legend
+	description string
+}
+
+var stateStyles = [...]stateStyle{
+	StateUnknown:     {name: "unknown", level: LevelUnknown, glyph: "○", ascii: ".", word: "-----", color: "gray", description: "not polled yet"},
+	StateUp:          {name: "up", level: LevelOK, glyph: "●", ascii: "o", word: "OK   ", color: "green", description: "up"},
+	StateDegraded:    {name: "degraded", level: LevelWarn, glyph: "▲", ascii: "!", word: "WARN ", color: "yellow", description: "up with alerts firing"},
+	StateStalled:     {name: "stalled", level: LevelWarn, glyph: "◆", ascii: "~", word: "STALL", color: "yellow", description: "up, but no watched log activity for 10 minutes"},
+	StateStale:       {name: "stale", level: LevelWarn, glyph: "◌", ascii: "?", word: "STALE", color: "fuchsia", description: "no poll completed recently, e.g. a hung SSH session"},
+	StateDown:        {name: "down", level: LevelCrit, glyph: "✖", ascii: "x", word: "CRIT ", color: "red", description: "unreachable or failing to report"},
+	StateMaintenance: {name: "maintenance", level: LevelOK, glyph: "■", ascii: "m", word: "MAINT", color: "blue", description: "in maintenance, alerts are off"},
+}
+
+func (s State) String() string {
+	return stateStyles[s].name
+}
+
+func (s State) Level() Level {
+	return stateStyles[s].level
+}
+
+// Words describes the state by its level, and its name where the level
+// alone doesn't tell, e.g. "WARN, stalled".
+func (s State) Words() string {
+	switch s {
+	case StateUp, StateDegraded, StateDown, StateUnknown:
+		return s.Level().String()
+	default:
+		return s.Level().String() + ", " + s.String()
+	}
+}
+
+// NodeState works out the state of a node from its last snapshot and
+// whether it has firing alerts. Snapshots older than staleAfter are
+// stale, zero disables the check.
+func NodeState(node config.Node, snapshot *collector.Snapshot, degraded bool, staleAfter time.Duration, now time.Time) State {
+	switch {
+	case node.Maintenance:
+		return StateMaintenance
+	case snapshot == nil:
+		return StateUnknown
+	case staleAfter > 0 && now.Sub(snapshot.Time) > staleAfter:
+		return StateStale
+	case snapshot.Err != nil:
+		return StateDown
+	case degraded:
+		return StateDegraded
+	}
+	status := snapshot.Status
+	if status.LogsSkipped == "" && !status.Failed(collector.SectionLogs) && !status.LastActivity.IsZero() && now.Sub(status.LastActivity) > stallAfter {
+		return StateStalled
+	}
+	return StateUp
+}
+
+// badge marks a state with its colored glyph, or with a word in
+// accessible mode so it doesn't depend on color.
+func (f *Formatter) badge(state State) string {
+	style := stateStyles[state]
+	switch {
+	case f.accessible:
+		return "[" + style.color + "]" + style.word
+	case f.ascii:
+		return "[" + style.color + "]" + style.ascii
+	default:
+		return "[" + style.color + "]" + style.glyph
+	}
+}
+
+// legend explains the badges, for the help overlay.
+func (f *Formatter) legend() string {
+	var output string
+	for state := range stateStyles {
+		style := stateStyles[state]
+		output += fmt.Sprintf("%s [white]%s [gray](%s)\n", f.badge(State(state)), style.description, style.level)
+	}
+	return output
+}
@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+)
+
+const (
+	// statsPage is the page of the fleet statistics, shown above the
+	// grid.
+	statsPage = "stats"
+	// fullDisk is the disk usage in percent from which a node counts as
+	// running out of disk in the fleet statistics.
+	fullDisk = 90.0
+	// frameBuckets is how many bars the frame histogram has.
+	frameBuckets = 8
+	// barWidth is the width of the longest histogram bar.
+	barWidth = 30
+)
+
+// FleetStats are aggregates over the last successful poll of every node.
+type FleetStats struct {
+	Nodes     int
+	Reporting int
+	// Bootstrap counts the bootstrap peer nodes that were polled,
+	// BootstrapUp those that answered. They don't count as nodes.
+	Bootstrap   int
+	BootstrapUp int
+	// Peers is the sum of the peer counts of the nodes that log one.
+	Peers int64
+	// CPU is the CPU usage of each reporting node, sorted.
+	CPU []float64
+	// FullDisks are the names of the nodes with a disk at or above
+	// fullDisk.
+	FullDisks []string
+	// Frames is the current frame of each node that logs one, sorted.
+	Frames []float64
+	// Network are the network fields the nodes log, see networkFields.
+	Network []NetworkValue
+}
+
+// Fleet computes the fleet statistics from the last snapshot of each
+// node. Nodes without a snapshot or whose last poll failed only count in
+// Nodes.
+func Fleet(snapshots []*collector.Snapshot) FleetStats {
+	stats := FleetStats{Nodes: len(snapshots)}
+	for _, snapshot := range snapshots {
+		if snapshot != nil && snapshot.Node.Bootstrap != "" {
+			stats.Nodes--
+			stats.Bootstrap++
+			if snapshot.Err == nil {
+				stats.BootstrapUp++
+			}
+			continue
+		}
+		if snapshot == nil || snapshot.Err != nil {
+			continue
+		}
+		status := snapshot.Status
+		stats.Reporting++
+		if status.Collected(collector.SectionCPU) {
+			stats.CPU = append(stats.CPU, status.CPU.User+status.CPU.System)
+		}
+		if peers, ok := latestField(status, "network_peer_count"); ok {
+			stats.Peers += int64(peers)
+		}
+		if frame, ok := latestField(status, "current_frame"); ok {
+			stats.Frames = append(stats.Frames, frame)
+		}
+		for _, disk := range status.Disks {
+			if disk.UsedPercent() >= fullDisk {
+				stats.FullDisks = append(stats.FullDisks, snapshot.Node.DisplayName())
+				break
+			}
+		}
+	}
+	sort.Float64s(stats.CPU)
+	sort.Float64s(stats.Frames)
+	sort.Strings(stats.FullDisks)
+	stats.Network = network(snapshots)
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating
+// between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	low, high := int(math.Floor(rank)), int(math.Ceil(rank))
+	return sorted[low] + (sorted[high]-sorted[low])*(rank-float64(low))
+}
+
+// Stats renders the fleet statistics with a histogram of the current
+// frames, so nodes lagging behind the rest stand out.
+func (f *Formatter) Stats(stats FleetStats) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("[green::b]Nodes: [white]%d of %d reporting\n", stats.Reporting, stats.Nodes))
+	switch {
+	case stats.Bootstrap == 0:
+	case stats.BootstrapUp == 0:
+		b.WriteString(fmt.Sprintf("[red::b]Bootstrap: [white]none of %d peers answering\n", stats.Bootstrap))
+	default:
+		b.WriteString(fmt.Sprintf("[green::b]Bootstrap: [white]%d of %d peers answering\n", stats.BootstrapUp, stats.Bootstrap))
+	}
+	if stats.Reporting == 0 {
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("[green::b]Peers: [white]%s in total\n", f.Int(stats.Peers)))
+	b.WriteString(fmt.Sprintf("[green::b]CPU: [white]median %s [gray]p90 [white]%s [gray]max [white]%s\n",
+		f.Percent(percentile(stats.CPU, 50)), f.Percent(percentile(stats.CPU, 90)), f.Percent(stats.CPU[len(stats.CPU)-1])))
+	if len(stats.FullDisks) == 0 {
+		b.WriteString(fmt.Sprintf("[green::b]Disk: [white]no node above %s\n", strings.TrimSpace(f.Percent(fullDisk))))
+	} else {
+		b.WriteString(fmt.Sprintf("[red::b]Disk: [white]%d above %s: %s\n", len(stats.FullDisks),
+			strings.TrimSpace(f.Percent(fullDisk)), tview.Escape(strings.Join(stats.FullDisks, ", "))))
+	}
+	if len(stats.Network) > 0 {
+		b.WriteString("\n" + f.network(stats.Network))
+	}
+	if len(stats.Frames) > 0 {
+		b.WriteString("\n" + f.histogram(stats.Frames))
+	}
+	return b.String()
+}
+
+// histogram draws the distribution of sorted frames in up to
+// frameBuckets bars of equal frame ranges, the newest frames last.
+func (f *Formatter) histogram(frames []float64) string {
+	low, high := frames[0], frames[len(frames)-1]
+	buckets := frameBuckets
+	if spread := int(high-low) + 1; spread < buckets {
+		buckets = spread
+	}
+	size := math.Ceil((high - low + 1) / float64(buckets))
+	counts := make([]int, buckets)
+	most := 0
+	for _, frame := range frames {
+		i := min(int((frame-low)/size), buckets-1)
+		counts[i]++
+		most = max(most, counts[i])
+	}
+
+	bar := "█"
+	if f.ascii {
+		bar = "#"
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("[green::b]Frames: [white]%s to %s [gray](%s behind at most)\n",
+		f.Int(int64(low)), f.Int(int64(high)), f.Int(int64(high-low))))
+	for i, count := range counts {
+		from := low + float64(i)*size
+		to := math.Min(from+size-1, high)
+		b.WriteString(fmt.Sprintf("[gray]%s-%s [teal]%s [white]%d\n", padLeft(f.Int(int64(from)), 10), padRight(f.Int(int64(to)), 10),
+			padRight(strings.Repeat(bar, count*barWidth/most), barWidth), count))
+	}
+	return b.String()
+}
+
+func (t *TUI) toggleStats() {
+	if t.pageVisible(statsPage) {
+		t.pages.HidePage(statsPage)
+		return
+	}
+	t.pages.ShowPage(statsPage)
+	t.renderStats()
+}
+
+func (t *TUI) renderStats() {
+	snapshots := make([]*collector.Snapshot, len(t.panels))
+	for i, p := range t.panels {
+		snapshots[i] = p.snapshot
+	}
+	t.stats.SetText(t.format.Stats(Fleet(snapshots)))
+}
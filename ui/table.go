@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/transport"
+)
+
+// tableRefresh is how often the table is redrawn, polls arriving in
+// between are drawn together.
+const tableRefresh = time.Second
+
+// clearScreen moves the cursor home and clears the screen, the one escape
+// sequence the table uses.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// Table is the watch frontend: a plain text table with a row per node,
+// without tview. It either redraws the screen in place, which only needs
+// a terminal that understands clearScreen, or appends a timestamped table
+// after each poll, for files and tee. It implements collector.Sink and
+// alert.Notifier.
+type Table struct {
+	// StaleAfter is how old the last snapshot of a node can get before
+	// the node is shown as stale, zero never marks nodes stale. It must
+	// be set before Run.
+	StaleAfter time.Duration
+
+	mu        sync.Mutex
+	w         io.Writer
+	redraw    bool
+	format    *Formatter
+	nodes     []config.Node
+	snapshots []*collector.Snapshot
+	// firing alert kinds by node
+	firing  map[string]map[string]bool
+	changed bool
+}
+
+// NewTable returns a table of the nodes written to w, redrawn in place if
+// redraw is set.
+func NewTable(w io.Writer, nodes []config.Node, display config.Display, redraw bool) *Table {
+	return &Table{
+		w:         w,
+		redraw:    redraw,
+		format:    NewFormatter(display),
+		nodes:     nodes,
+		snapshots: make([]*collector.Snapshot, len(nodes)),
+		firing:    make(map[string]map[string]bool),
+	}
+}
+
+func (t *Table) Consume(snapshot collector.Snapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.snapshots[snapshot.Index] = &snapshot
+	t.changed = true
+}
+
+func (t *Table) Notify(a alert.Alert) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	name := a.Node.DisplayName()
+	if a.Firing {
+		if t.firing[name] == nil {
+			t.firing[name] = make(map[string]bool)
+		}
+		t.firing[name][a.Kind] = true
+	} else {
+		delete(t.firing[name], a.Kind)
+	}
+	t.changed = true
+}
+
+// Run draws the table until the process is interrupted. A redrawn table
+// is refreshed every tableRefresh so ages and stale nodes stay current,
+// an appended one only after new polls.
+func (t *Table) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(tableRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			t.mu.Lock()
+			if t.changed || t.redraw {
+				t.draw(now)
+				t.changed = false
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+// draw writes the table with a summary line of the node states above it.
+func (t *Table) draw(now time.Time) {
+	var b strings.Builder
+	if t.redraw {
+		b.WriteString(clearScreen)
+	} else {
+		b.WriteString("\n")
+	}
+
+	states := make([]State, len(t.nodes))
+	counts := make(map[State]int)
+	for i, node := range t.nodes {
+		states[i] = NodeState(node, t.snapshots[i], len(t.firing[node.DisplayName()]) > 0, t.StaleAfter, now)
+		counts[states[i]]++
+	}
+	b.WriteString(fmt.Sprintf("%s  %d nodes", now.Format("15:04:05"), len(t.nodes)))
+	for state := range stateStyles {
+		if n := counts[State(state)]; n > 0 {
+			b.WriteString(fmt.Sprintf(", %d %s", n, State(state)))
+		}
+	}
+	b.WriteString("\n\n")
+
+	header := []string{"NODE", "STATE", "CPU", "MEM", "DISK"}
+	for _, field := range keyFields {
+		header = append(header, strings.ToUpper(field.label))
+	}
+	rows := [][]string{append(header, "POLLED", "NOTE")}
+	for i, node := range t.nodes {
+		rows = append(rows, t.row(node, t.snapshots[i], states[i], now))
+	}
+	b.WriteString(tabulate(rows, 2))
+
+	io.WriteString(t.w, b.String())
+}
+
+// row is the table row of a node, with "-" for what its last poll didn't
+// report.
+func (t *Table) row(node config.Node, snapshot *collector.Snapshot, state State, now time.Time) []string {
+	metrics := make([]string, 3+len(keyFields))
+	for i := range metrics {
+		metrics[i] = "-"
+	}
+	polled, note := "-", ""
+	if snapshot != nil {
+		polled = now.Sub(snapshot.Time).Round(time.Second).String() + " ago"
+	}
+
+	switch {
+	case snapshot == nil:
+	case snapshot.Err != nil:
+		note = transport.Describe(snapshot.Err)
+	case snapshot.Status.Bootstrap != nil:
+		peer := snapshot.Status.Bootstrap
+		note = fmt.Sprintf("bootstrap peer, %s over %s", latency(peer.Latency), peer.Addr.Transport)
+	default:
+		status := snapshot.Status
+		percent := func(v float64) string { return strings.TrimSpace(t.format.Percent(v)) }
+		if status.Collected(collector.SectionCPU) {
+			metrics[0] = percent(status.CPU.User + status.CPU.System)
+		}
+		if status.Memory.TotalMB > 0 {
+			metrics[1] = percent(float64(status.Memory.UsedMB) / float64(status.Memory.TotalMB) * 100)
+		}
+		if len(status.Disks) > 0 {
+			metrics[2] = percent(status.Disks[0].UsedPercent())
+		}
+		for i, field := range keyFields {
+			if value, ok := latestField(status, field.key); ok {
+				metrics[3+i] = t.format.field(field.key, value)
+			}
+		}
+		var kinds []string
+		for kind := range t.firing[node.DisplayName()] {
+			kinds = append(kinds, kind)
+		}
+		if len(kinds) > 0 {
+			sort.Strings(kinds)
+			note = "alerts " + strings.Join(kinds, ", ")
+		}
+	}
+
+	row := append([]string{node.DisplayName(), state.Words()}, metrics...)
+	return append(row, polled, note)
+}
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/parsers"
+)
+
+const (
+	// timelinePage is the page of the log timeline of all nodes, shown
+	// above the grid.
+	timelinePage = "timeline"
+	// timelineSize is how many lines the timeline shows, the newest.
+	timelineSize = 500
+	// clusterWindow is how close together the nodes have to log a
+	// message for the timeline to mark them as a cluster, clusterNodes
+	// how many nodes at least.
+	clusterWindow = time.Minute
+	clusterNodes  = 3
+)
+
+// TimelineEntry is a watched log line of a node in the timeline.
+type TimelineEntry struct {
+	Node string
+	Line parsers.LogLine
+	// Cluster numbers the entries of the same message that many nodes
+	// logged in a short time, from 1; zero if the entry is in none.
+	Cluster int
+}
+
+// Cluster is a message many nodes logged within clusterWindow, like
+// all of them losing the bootstrap peers at once.
+type Cluster struct {
+	Msg   string
+	Nodes int
+	// Span is the time from its first entry to its last.
+	Span time.Duration
+}
+
+// Timeline merges the watched log lines of the nodes, by node name, in
+// the order they were logged, and finds the clusters among them: a
+// message clusterNodes nodes (all of them in smaller fleets) started
+// logging within clusterWindow, see onset. Lines without a timestamp
+// can't be placed and are left out.
+func Timeline(lines map[string][]parsers.LogLine) ([]TimelineEntry, []Cluster) {
+	var entries []TimelineEntry
+	for node, nodeLines := range lines {
+		for _, line := range nodeLines {
+			if !line.Time.IsZero() {
+				entries = append(entries, TimelineEntry{Node: node, Line: line})
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Line.Time.Equal(entries[j].Line.Time) {
+			return entries[i].Line.Time.Before(entries[j].Line.Time)
+		}
+		return entries[i].Node < entries[j].Node
+	})
+	if len(entries) > timelineSize {
+		entries = entries[len(entries)-timelineSize:]
+	}
+
+	needed := min(clusterNodes, len(lines))
+	if needed < 2 {
+		return entries, nil
+	}
+	var clusters []Cluster
+	for i := range entries {
+		if entries[i].Cluster != 0 {
+			continue
+		}
+		msg, start := entries[i].Line.Msg, entries[i].Line.Time
+		if !onset(entries, i) {
+			continue
+		}
+		members := []int{i}
+		nodes := map[string]bool{entries[i].Node: true}
+		for j := i + 1; j < len(entries) && entries[j].Line.Time.Sub(start) <= clusterWindow; j++ {
+			if entries[j].Cluster == 0 && entries[j].Line.Msg == msg {
+				members = append(members, j)
+				nodes[entries[j].Node] = true
+			}
+		}
+		if len(nodes) < needed {
+			continue
+		}
+		clusters = append(clusters, Cluster{
+			Msg:   msg,
+			Nodes: len(nodes),
+			Span:  entries[members[len(members)-1]].Line.Time.Sub(start),
+		})
+		for _, j := range members {
+			entries[j].Cluster = len(clusters)
+		}
+	}
+	return entries, clusters
+}
+
+// onset reports whether the i-th entry's message starts anew: the
+// timeline goes back a clusterWindow before it, and no node logged the
+// message in that time. Messages the nodes log all the time never do.
+func onset(entries []TimelineEntry, i int) bool {
+	start := entries[i].Line.Time
+	if start.Sub(entries[0].Line.Time) < clusterWindow {
+		return false
+	}
+	for j := i - 1; j >= 0 && start.Sub(entries[j].Line.Time) <= clusterWindow; j-- {
+		if entries[j].Line.Msg == entries[i].Line.Msg {
+			return false
+		}
+	}
+	return true
+}
+
+// Timeline renders the merged log lines on the monitor's clock, each
+// with its node. The entries of a cluster are marked in yellow below a
+// line saying how many nodes logged the message how close together.
+func (f *Formatter) Timeline(entries []TimelineEntry, clusters []Cluster) string {
+	if len(entries) == 0 {
+		return "[gray]No node logged a watched message with a timestamp yet.\n"
+	}
+	width := 0
+	for _, entry := range entries {
+		width = max(width, textWidth(entry.Node))
+	}
+	width = min(width, 20)
+
+	var b strings.Builder
+	shown := make(map[int]bool)
+	for _, entry := range entries {
+		marker := "  "
+		if c := entry.Cluster; c != 0 {
+			if !shown[c] {
+				shown[c] = true
+				cluster := clusters[c-1]
+				b.WriteString(fmt.Sprintf("[yellow::b]┏ %s on %d nodes within %s[-::-]\n",
+					tview.Escape(cluster.Msg), cluster.Nodes, cluster.Span.Round(time.Second)))
+			}
+			marker = "[yellow]┃ "
+		}
+		b.WriteString(fmt.Sprintf("%s[gray]%s [white::b]%s[-::-] %s\n", marker, f.clock(entry.Line.Time, nil),
+			tview.Escape(padRight(truncate(entry.Node, width), width)), tview.Escape(entry.Line.Line)))
+	}
+	return b.String()
+}
+
+func (t *TUI) toggleTimeline() {
+	if t.pageVisible(timelinePage) {
+		t.pages.HidePage(timelinePage)
+		t.setFocus(t.focused)
+		return
+	}
+	t.pages.ShowPage(timelinePage)
+	t.renderTimeline()
+	t.timeline.ScrollToEnd()
+	// the arrow keys scroll it
+	t.app.SetFocus(t.timeline)
+}
+
+func (t *TUI) renderTimeline() {
+	lines := make(map[string][]parsers.LogLine, len(t.panels))
+	for _, p := range t.panels {
+		lines[p.node.DisplayName()] = p.tail
+	}
+	t.timeline.SetText(t.format.Timeline(Timeline(lines)))
+}
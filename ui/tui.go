@@ -0,0 +1,634 @@
+// Package ui is the terminal frontend of the monitor.
+//
+// TUI is the interactive frontend, a collector.Sink and
+// collector.Handler fed by a Collector or a server connection, and run
+// with TUI.Run. Table and Lines are plain frontends for terminals and
+// pipes that can't take one. The rest, e.g. NodeState, Fleet and
+// Formatter, are the pieces they share, for other frontends showing
+// nodes the same way.
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/fid1699/q-monitor-cli/alert"
+	"github.com/fid1699/q-monitor-cli/availability"
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/config"
+	"github.com/fid1699/q-monitor-cli/history"
+	"github.com/fid1699/q-monitor-cli/parsers"
+	"github.com/fid1699/q-monitor-cli/price"
+)
+
+// recentEvents is how many events the footer shows.
+const recentEvents = 5
+
+// detailPage is the page of the detail view, shown above the grid.
+const detailPage = "detail"
+
+// stateRefresh is how often node states are re-evaluated between polls,
+// so stalled and stale nodes show up without a new snapshot and the
+// panel titles count the seconds since the last poll.
+const stateRefresh = time.Second
+
+// TUI shows the nodes in one of the layouts, a grid with a panel per
+// node by default, with a footer
+// summing up node states and listing firing alerts and recent events.
+// It implements collector.Sink, collector.Handler and alert.Watcher.
+type TUI struct {
+	// StaleAfter is how old the last snapshot of a node can get before
+	// the node is shown as stale, zero never marks nodes stale. It must
+	// be set before Run.
+	StaleAfter time.Duration
+	// Earnings, if set, adds an estimate of the fleet's daily earnings
+	// to the footer, see SetPrice. It must be set before Run.
+	Earnings *config.Earnings
+	// Availability, if set, adds the nodes' availability and outages to
+	// the detail view. It must be set before Run.
+	Availability *availability.Tracker
+	// Alerts, if set, lets the focused node's alerts be acknowledged and
+	// the node silenced, in the name of User. They must be set before
+	// Run.
+	Alerts Alerts
+	User   string
+	// Benchmark, if set, measures the focused node's bandwidth on
+	// demand. It must be set before Run.
+	Benchmark Benchmark
+	// Polls, if set, lets the fleet actions pause and refresh the polls
+	// of the nodes. It must be set before Run.
+	Polls Polls
+
+	app    *tview.Application
+	pages  *tview.Pages
+	root   *tview.Flex
+	grid   *tview.Grid
+	footer *tview.TextView
+	input  *tview.InputField
+	// preview shows the focused node in full below the tiles of the
+	// list and table layouts, header labels the table's columns
+	preview *tview.TextView
+	header  *tview.TextView
+	// deltas shows the panels' changes since the previous poll
+	deltas bool
+	// preset is the layout, see config.Display.Layout; autoLayout picks
+	// it by the size of the screen until the user picks one
+	preset     string
+	autoLayout bool
+	accessible bool
+	// detail shows the focused node in full with its history, next to
+	// the tail of its log in the detailPane; split is the detail's
+	// share of the width in percent
+	detail     *tview.TextView
+	tail       *tview.TextView
+	detailPane *tview.Flex
+	split      int
+	// stats shows the fleet statistics, mesh the latency matrix,
+	// timeline the log lines of all nodes, fleet the fleet actions
+	stats    *tview.TextView
+	mesh     *tview.TextView
+	timeline *tview.TextView
+	fleet    *tview.List
+	format   *Formatter
+	keys     *keymap
+	// screen is the screen last drawn on
+	screen tcell.Screen
+
+	// only touched from the UI goroutine
+	panels []*panel
+	// groups are the sections of the grid, nil if no node has a group
+	groups  []*group
+	focused int
+	events  []collector.Event
+	firing  map[string]alert.Alert
+	// silences are the silenced nodes, alertErr why acknowledging or
+	// silencing last failed, at alertErrAt
+	silences   []alert.Silence
+	alertErr   error
+	alertErrAt time.Time
+	columns    int
+	// quote is the last token price, quoteErr why the last fetch failed
+	quote    price.Quote
+	quoteErr error
+	// notice is a note about the monitor itself, see Notice
+	notice string
+}
+
+// panel is the view of a single node and what it last showed.
+type panel struct {
+	node     config.Node
+	format   *Formatter
+	view     *tview.TextView
+	snapshot *collector.Snapshot
+	pins     []pin
+	history  []*history.Series
+	tail     []parsers.LogLine
+	group    *group
+	// state is the state the panel was last rendered with
+	state State
+	// wrap wraps the panel's long lines, fullError shows its errors
+	// whole and wrapped
+	wrap      bool
+	fullError bool
+	// deltas shows the changes since the previous poll, see
+	// trackDeltas for what they are measured against
+	deltas   bool
+	previous *collector.Status
+	fields   map[string]float64
+	changes  map[string]float64
+	// benchmarks are the last bandwidth measurements of the node,
+	// benchmarking is set while one runs
+	benchmarks   []benchmarkRun
+	benchmarking bool
+	// paused is set while the fleet actions paused the node's polls
+	paused bool
+}
+
+// New builds the view for the given nodes. Seems to run well
+// for up to 10 nodes on a laptop monitor, can probably
+// work for a few more on a desktop monitor, and you can also
+// run on multiple monitors with different node configs.
+// Larger fleets fit in the list and table layouts, which show two lines
+// or one per node and the focused one in full below them; unless
+// display.layout picks one, the layout is picked by the fleet and the
+// size of the screen.
+func New(nodes []config.Node, display config.Display) *TUI {
+	t := &TUI{
+		app:        tview.NewApplication(),
+		pages:      tview.NewPages(),
+		grid:       tview.NewGrid().SetRows(0).SetColumns(0),
+		footer:     tview.NewTextView().SetDynamicColors(true),
+		input:      tview.NewInputField().SetLabel("query> "),
+		preview:    tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		header:     tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		detail:     tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		tail:       tview.NewTextView().SetDynamicColors(true),
+		split:      50,
+		stats:      tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		mesh:       tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		timeline:   tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		preset:     display.PanelLayout(),
+		accessible: display.Accessible,
+		panels:     make([]*panel, len(nodes)),
+		firing:     make(map[string]alert.Alert),
+	}
+	t.format = NewFormatter(display)
+	keys, err := newKeymap(display.Keys)
+	if err != nil {
+		// ValidateKeys tells the user, the TUI still works
+		keys, _ = newKeymap(config.Keys{})
+	}
+	t.keys = keys
+	t.format.errorKey = keys.label(actionError)
+	for i, node := range nodes {
+		textView := tview.NewTextView().
+			SetDynamicColors(true).
+			SetRegions(true).
+			SetWrap(false)
+		t.panels[i] = &panel{node: node, format: t.format, view: textView, wrap: display.Wrap}
+	}
+	t.groups = newGroups(t.panels)
+	t.root = tview.NewFlex().SetDirection(tview.FlexRow)
+	t.preview.SetBorder(true)
+	t.header.SetText(t.format.tableHeader())
+	if t.preset == "" {
+		t.preset, t.autoLayout = config.LayoutGrid, true
+	}
+	// the grid is filled in once the size of the screen is known
+	t.setLayout(t.preset)
+	t.app.SetBeforeDrawFunc(t.arrange)
+	t.detail.SetBorder(true)
+	t.tail.SetBorder(true)
+	t.detailPane = tview.NewFlex().
+		AddItem(t.detail, 0, t.split, false).
+		AddItem(t.tail, 0, 100-t.split, false)
+	t.stats.SetBorder(true).SetTitle(fmt.Sprintf(" Fleet [gray](%s or %s to close) ", t.keys.label(actionStats), t.keys.label(actionClose)))
+	t.mesh.SetBorder(true).SetTitle(fmt.Sprintf(" Mesh [gray](%s or %s to close) ", t.keys.label(actionMesh), t.keys.label(actionClose)))
+	t.timeline.SetBorder(true).SetTitle(fmt.Sprintf(" Timeline [gray](%s or %s to close) ", t.keys.label(actionTimeline), t.keys.label(actionClose)))
+	t.pages.AddPage("main", t.root, true, true).
+		AddPage(detailPage, t.detailPane, true, false).
+		AddPage(statsPage, t.stats, true, false).
+		AddPage(meshPage, t.mesh, true, false).
+		AddPage(timelinePage, t.timeline, true, false).
+		AddPage(fleetPage, t.newFleet(), true, false).
+		AddPage(helpPage, newHelp(t.format, t.keys), true, false)
+	t.input.SetDoneFunc(t.queryDone)
+	t.app.SetInputCapture(t.handleKey)
+	t.setFocus(0)
+	t.renderFooter()
+
+	return t
+}
+
+// Consume runs on the sink's own goroutine, so every change goes through
+// QueueUpdateDraw and tview never sees concurrent writes.
+func (t *TUI) Consume(snapshot collector.Snapshot) {
+	t.app.QueueUpdateDraw(func() {
+		p := t.panels[snapshot.Index]
+		previous := p.snapshot
+		p.snapshot = &snapshot
+		if snapshot.Err == nil {
+			p.updatePins(snapshot.Status.Logs)
+			p.record(snapshot)
+			p.trackDeltas(previous, snapshot)
+		}
+		t.render(p)
+		if t.pageVisible(statsPage) {
+			t.renderStats()
+		}
+		if t.pageVisible(meshPage) {
+			t.renderMesh()
+		}
+		if t.pageVisible(timelinePage) {
+			t.renderTimeline()
+		}
+	})
+}
+
+// render redraws a panel, in the list and table layouts its tile and
+// the preview if the panel has the focus.
+func (t *TUI) render(p *panel) {
+	p.state = t.state(p)
+	if p.group != nil {
+		t.renderGroup(p.group)
+	}
+	if t.detailOpen() && p == t.panels[t.focused] {
+		t.renderDetail()
+	}
+	switch t.preset {
+	case config.LayoutGrid:
+		t.renderTitle(p)
+		p.view.SetWrap(p.wrapped())
+		if p.snapshot != nil {
+			p.view.SetText(p.text(p.format.sections, p.fullError))
+		}
+		return
+	case config.LayoutList:
+		p.view.SetText(p.format.Compact(p.node, p.snapshot, p.state))
+	default:
+		p.view.SetText(p.format.Row(p.node, p.snapshot, p.state))
+	}
+	if p == t.panels[t.focused] {
+		t.renderPreview()
+	}
+}
+
+// renderTitle puts the node's state and the age of its last poll on its
+// panel border. In accessible mode they are spelled out and the focused
+// panel is named in words as well as marked by the border color.
+func (t *TUI) renderTitle(p *panel) {
+	state := p.state
+	if !t.accessible {
+		deltas := ""
+		if p.deltas {
+			deltas = "[yellow]Δ[-] "
+		}
+		p.view.SetTitle(fmt.Sprintf(" %s[-] %s %s%s%s[-] ", t.format.badge(state), tview.Escape(p.node.DisplayName()), deltas, t.pausedMark(p), t.updated(p)))
+		return
+	}
+	title := fmt.Sprintf(" %s: %s ", tview.Escape(p.node.DisplayName()), state.Words())
+	if p.snapshot != nil {
+		title = fmt.Sprintf(" %s: %s, updated %s ago ", tview.Escape(p.node.DisplayName()), state.Words(), age(p.snapshot.Time))
+	}
+	if p.deltas {
+		title = strings.TrimSuffix(title, " ") + ", showing changes "
+	}
+	if p.paused {
+		title = strings.TrimSuffix(title, " ") + t.pausedMark(p) + " "
+	}
+	if p == t.panels[t.focused] {
+		title = " focused," + title
+	}
+	p.view.SetTitle(title)
+}
+
+// updated is how long ago the panel's node was last polled, in red once
+// the node is stale so a hung session shows on the panel and not only
+// in its state. It is empty before the first poll.
+func (t *TUI) updated(p *panel) string {
+	if p.snapshot == nil {
+		return ""
+	}
+	color := "gray"
+	if t.StaleAfter > 0 && !p.paused && time.Since(p.snapshot.Time) > t.StaleAfter {
+		color = "red"
+	}
+	return fmt.Sprintf("[%s]updated %s ago", color, age(p.snapshot.Time))
+}
+
+// age is the time since t in whole seconds.
+func age(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
+
+func (t *TUI) state(p *panel) State {
+	staleAfter := t.StaleAfter
+	if p.paused {
+		// its last poll is old on purpose
+		staleAfter = 0
+	}
+	return NodeState(p.node, p.snapshot, t.degraded(p.node), staleAfter, time.Now())
+}
+
+// refreshStates redraws every panel whose state changed without a new
+// snapshot, the ages of the last polls, and the footer summary.
+func (t *TUI) refreshStates() {
+	for range time.Tick(stateRefresh) {
+		t.app.QueueUpdateDraw(func() {
+			for _, p := range t.panels {
+				if state := t.state(p); state != p.state {
+					t.render(p)
+				} else if !t.tiled() {
+					t.renderTitle(p)
+				}
+			}
+			if t.tiled() {
+				t.renderPreviewTitle()
+			}
+			t.renderFooter()
+		})
+	}
+}
+
+// text is the status of the panel's node with the given sections,
+// including its pinned queries if sections has them. Errors are cut
+// short unless fullErrors is set.
+func (p *panel) text(sections []string, fullErrors bool) string {
+	if p.snapshot == nil {
+		return "[gray]waiting for first poll\n"
+	}
+	format := p.format
+	if fullErrors || (p.deltas && p.previous != nil) {
+		changed := *p.format
+		if fullErrors {
+			changed.errorLength = 0
+		}
+		if p.deltas && p.previous != nil {
+			changed.deltas = &deltas{previous: *p.previous, fields: p.changes}
+		}
+		format = &changed
+	}
+	if p.snapshot.Err != nil {
+		text := format.failure(p.node, p.snapshot.Err)
+		if slices.Contains(sections, config.SectionQueries) {
+			text += p.formatPins()
+		}
+		return text
+	}
+	return format.status(p.node, p.snapshot.Status, sections, p.formatPins())
+}
+
+// wrapped reports whether the panel wraps long lines, which it does
+// while showing full errors so they can be read.
+func (p *panel) wrapped() bool {
+	return p.wrap || p.fullError
+}
+
+// handleKey runs the actions of the keys bound in the keymap, see
+// actions. Enter on a collapsed group expands it rather than opening
+// the detail view. Keys bound to nothing go to the focused panel, which
+// scrolls with the arrow keys.
+func (t *TUI) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	if t.app.GetFocus() == t.input {
+		return event
+	}
+	action, ok := t.keys.press(event)
+	if action == actionScreenshot {
+		// of whatever is shown
+		t.screenshot()
+		return nil
+	}
+	if t.pageVisible(helpPage) {
+		if action == actionClose || action == actionHelp {
+			t.toggleHelp()
+		}
+		return nil
+	}
+	if t.pageVisible(statsPage) {
+		if action == actionClose || action == actionStats {
+			t.toggleStats()
+		} else if action == actionHelp {
+			t.toggleHelp()
+		}
+		return nil
+	}
+	if t.pageVisible(meshPage) {
+		if action == actionClose || action == actionMesh {
+			t.toggleMesh()
+		} else if action == actionHelp {
+			t.toggleHelp()
+		}
+		return nil
+	}
+	if t.pageVisible(fleetPage) {
+		switch {
+		case action == actionClose || action == actionFleet:
+			t.toggleFleet()
+		case action == actionHelp:
+			t.toggleHelp()
+		default:
+			// the menu moves and picks with the arrow keys and Enter
+			return event
+		}
+		return nil
+	}
+	if t.pageVisible(timelinePage) {
+		switch {
+		case action == actionClose || action == actionTimeline:
+			t.toggleTimeline()
+		case action == actionHelp:
+			t.toggleHelp()
+		case !ok:
+			// scrolls the timeline
+			return event
+		}
+		return nil
+	}
+	switch {
+	case !ok:
+		return event
+	case action == "":
+		// the start of a key sequence
+		return nil
+	case action == actionHelp:
+		t.toggleHelp()
+		return nil
+	case len(t.panels) == 0:
+		return event
+	}
+
+	switch action {
+	case actionDetail:
+		if g := t.panels[t.focused].group; g != nil && g.collapsed && !t.detailOpen() {
+			t.toggleGroup()
+		} else if t.detailOpen() {
+			t.closeDetail()
+		} else {
+			t.openDetail()
+		}
+	case actionClose:
+		if !t.detailOpen() {
+			return event
+		}
+		t.closeDetail()
+	case actionNext, actionRight:
+		t.step(1)
+	case actionPrevious, actionLeft:
+		t.step(-1)
+	case actionDown:
+		t.stepRow(1)
+	case actionUp:
+		t.stepRow(-1)
+	case actionFirst:
+		cells := t.cells()
+		t.setFocus(cells[0].panel)
+	case actionLast:
+		cells := t.cells()
+		t.setFocus(cells[len(cells)-1].panel)
+	case actionQuery:
+		if !t.detailOpen() {
+			t.openQuery()
+		}
+	case actionStats:
+		t.toggleStats()
+	case actionMesh:
+		t.toggleMesh()
+	case actionTimeline:
+		t.toggleTimeline()
+	case actionLayout:
+		t.cycleLayout()
+	case actionDeltas:
+		t.toggleDeltas()
+	case actionGroup:
+		if !t.detailOpen() {
+			t.toggleGroup()
+		}
+	case actionGroups:
+		if !t.detailOpen() {
+			t.toggleGroups()
+		}
+	case actionClear:
+		p := t.panels[t.focused]
+		p.pins = nil
+		t.render(p)
+	case actionError:
+		p := t.panels[t.focused]
+		p.fullError = !p.fullError
+		t.render(p)
+	case actionWrap:
+		p := t.panels[t.focused]
+		p.wrap = !p.wrap
+		t.render(p)
+	case actionSplitLeft:
+		t.moveSplit(-1)
+	case actionSplitRight:
+		t.moveSplit(1)
+	case actionAck:
+		t.acknowledge()
+	case actionSilence:
+		t.silence()
+	case actionBenchmark:
+		t.benchmark()
+	case actionFleet:
+		t.toggleFleet()
+	}
+	return nil
+}
+
+func (t *TUI) setFocus(i int) {
+	if len(t.panels) == 0 {
+		return
+	}
+	previous := t.panels[t.focused]
+	previous.view.SetBorderColor(tview.Styles.BorderColor)
+	t.focused = i
+	t.panels[i].view.SetBorderColor(tcell.ColorYellow)
+	t.app.SetFocus(t.panels[i].view)
+	if t.accessible && !t.tiled() {
+		t.renderTitle(previous)
+		t.renderTitle(t.panels[i])
+	}
+	if t.tiled() {
+		t.highlightTiles()
+	}
+	t.highlightGroups()
+	if t.detailOpen() {
+		t.renderDetail()
+	}
+}
+
+// highlightTiles marks the focused node's tile and shows the node in
+// the preview.
+func (t *TUI) highlightTiles() {
+	for i, p := range t.panels {
+		if i == t.focused {
+			p.view.SetBackgroundColor(tcell.ColorDarkSlateGray)
+		} else {
+			p.view.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
+		}
+	}
+	t.renderPreview()
+}
+
+func (t *TUI) HandleEvent(event collector.Event) {
+	t.app.QueueUpdateDraw(func() {
+		t.events = append(t.events, event)
+		if len(t.events) > recentEvents {
+			t.events = t.events[len(t.events)-recentEvents:]
+		}
+		t.renderFooter()
+	})
+}
+
+// Notice shows a note about the monitor itself in the footer until the
+// next one, e.g. that the collector was restarted.
+func (t *TUI) Notice(text string) {
+	t.app.QueueUpdateDraw(func() {
+		t.notice = text
+		t.renderFooter()
+	})
+}
+
+func (t *TUI) renderFooter() {
+	var b strings.Builder
+
+	counts := make(map[State]int)
+	for _, p := range t.panels {
+		counts[t.state(p)]++
+	}
+	b.WriteString("[green::b]Nodes:")
+	for state := range stateStyles {
+		if n := counts[State(state)]; n > 0 {
+			b.WriteString(fmt.Sprintf(" %s [white]%d %s", t.format.badge(State(state)), n, State(state)))
+		}
+	}
+	b.WriteString(t.pausedCount())
+	if t.Earnings != nil {
+		b.WriteString(t.earnings())
+	}
+	if key := t.keys.label(actionHelp); key != "" {
+		b.WriteString(fmt.Sprintf(" [gray](%s for help)", key))
+	}
+	if t.notice != "" {
+		b.WriteString(" [yellow]" + tview.Escape(t.notice))
+	}
+	b.WriteString("\n")
+	b.WriteString(t.alertsLine())
+
+	for _, event := range t.events {
+		b.WriteString(tview.Escape(event.String()) + "\n")
+	}
+
+	t.footer.SetText(b.String())
+}
+
+// Run blocks until the application exits.
+func (t *TUI) Run() error {
+	go t.refreshStates()
+	return t.app.SetRoot(t.pages, true).Run()
+}
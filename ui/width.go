@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// cells measures text in terminal cells the way tview draws it: wide
+// glyphs like CJK take two, combining marks none, and characters of
+// ambiguous width, e.g. the block and box drawing ones, one whatever the
+// locale.
+var cells = &runewidth.Condition{EastAsianWidth: false, StrictEmojiNeutral: true}
+
+// textWidth returns how many cells s takes.
+func textWidth(s string) int {
+	return cells.StringWidth(s)
+}
+
+// truncate cuts s to at most n cells, ending it in "…" if it was cut.
+// It never splits a character.
+func truncate(s string, n int) string {
+	return cells.Truncate(s, n, "…")
+}
+
+// padRight fills s with spaces to n cells, left aligning it.
+func padRight(s string, n int) string {
+	return s + strings.Repeat(" ", max(n-textWidth(s), 0))
+}
+
+// padLeft fills s with spaces to n cells, right aligning it.
+func padLeft(s string, n int) string {
+	return strings.Repeat(" ", max(n-textWidth(s), 0)) + s
+}
+
+// tabulate lays out rows of plain text in columns gap cells apart, like a
+// text/tabwriter that counts cells instead of runes. The last column
+// isn't padded.
+func tabulate(rows [][]string, gap int) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], textWidth(cell))
+		}
+	}
+	var b strings.Builder
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(row)-1 {
+				b.WriteString(cell)
+				break
+			}
+			b.WriteString(padRight(cell, widths[i]+gap))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
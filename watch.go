@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/fid1699/q-monitor-cli/collector"
+	"github.com/fid1699/q-monitor-cli/ui"
+)
+
+// runWatch implements `q-monitor watch [--plain]`, a plain text table of
+// the nodes without the full screen UI, for tmux panes and terminals
+// without cursor control. The table is redrawn in place on a terminal;
+// with --plain, or when the output is not a terminal, a new table is
+// appended after every poll so it can be piped to tee.
+func runWatch(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	plain := flags.Bool("plain", false, "append a table after every poll instead of redrawing the screen")
+	simulateNodes := flags.Int("simulate", 0, "poll `N` simulated nodes instead of the configured ones")
+	flags.Parse(args)
+
+	cfg := loadConfig(*simulateNodes)
+	pipeline := collector.NewPipeline()
+	c, alerts, _ := newCollector(cfg, pipeline, *simulateNodes)
+
+	redraw := !*plain && isTerminal(os.Stdout) && os.Getenv("TERM") != "dumb"
+	table := ui.NewTable(os.Stdout, cfg.Nodes, cfg.Display, redraw)
+	table.StaleAfter = 3 * c.Interval
+	pipeline.Register(table)
+	alerts.AddNotifier(table)
+
+	go c.Run(context.Background())
+	return table.Run()
+}